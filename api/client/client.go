@@ -34,9 +34,11 @@ type Client struct {
 	Retries         int           // Number of retries on retriable errors.
 	baseURL         *url.URL
 	httpClient      *http.Client
+	namespace       string
 
-	Headers  map[string]string
-	Rulesets *RulesetService
+	Headers    map[string]string
+	Rulesets   *RulesetService
+	Namespaces *NamespaceService
 }
 
 // New creates an HTTP client that uses a base url to communicate with the api server.
@@ -72,6 +74,10 @@ func New(baseURL string, opts ...Option) (*Client, error) {
 		client: &c,
 	}
 
+	c.Namespaces = &NamespaceService{
+		client: &c,
+	}
+
 	return &c, nil
 }
 
@@ -191,19 +197,11 @@ func (c *Client) do(ctx context.Context, req *http.Request, v interface{}) (*htt
 
 	c.Logger.Debug().Str("url", req.URL.String()).Int("status", resp.StatusCode).Msg("request sent")
 
-	dec := json.NewDecoder(resp.Body)
-
 	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
-		var apiErr api.Error
-
-		_ = dec.Decode(&apiErr)
-
-		apiErr.Response = resp
-
-		return resp, &apiErr
+		return resp, decodeError(resp)
 	}
 
-	err = dec.Decode(v)
+	err = json.NewDecoder(resp.Body).Decode(v)
 	if err != nil && err != io.EOF {
 		return nil, err
 	}
@@ -211,6 +209,17 @@ func (c *Client) do(ctx context.Context, req *http.Request, v interface{}) (*htt
 	return resp, nil
 }
 
+// decodeError decodes the error returned by the server in the body of the response.
+func decodeError(resp *http.Response) *api.Error {
+	var apiErr api.Error
+
+	_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+
+	apiErr.Response = resp
+
+	return &apiErr
+}
+
 // Option allows Client customization.
 type Option func(*Client) error
 
@@ -230,8 +239,71 @@ func Header(k, v string) Option {
 	}
 }
 
-// ListOptions contains pagination options.
+// Token sets the bearer token used to authenticate the requests,
+// either a static token or a JWT.
+func Token(token string) Option {
+	return func(c *Client) error {
+		c.Headers["Authorization"] = "Bearer " + token
+		return nil
+	}
+}
+
+// Author sets the author recorded in the history of the rulesets created by the client.
+// It is ignored by the server if the requests are authenticated.
+func Author(name string) Option {
+	return func(c *Client) error {
+		c.Headers[api.AuthorHeader] = name
+		return nil
+	}
+}
+
+// Namespace makes the client manage the rulesets of the given namespace
+// instead of the default ones of the server.
+func Namespace(name string) Option {
+	return func(c *Client) error {
+		c.namespace = name
+		return nil
+	}
+}
+
+// PutOption customizes the creation of ruleset versions.
+type PutOption func(*putOptions)
+
+type putOptions struct {
+	message         string
+	expectedVersion string
+}
+
+// Message records the given message in the history of the ruleset.
+func Message(msg string) PutOption {
+	return func(o *putOptions) {
+		o.message = msg
+	}
+}
+
+// ExpectedVersion makes the server reject the new version unless
+// the given version is still the latest version of the ruleset.
+func ExpectedVersion(version string) PutOption {
+	return func(o *putOptions) {
+		o.expectedVersion = version
+	}
+}
+
+// IsVersionMismatch reports whether the error was returned by RulesetService.Put because
+// the expected version wasn't the latest one, and returns the latest version, empty if the ruleset doesn't exist.
+func IsVersionMismatch(err error) (string, bool) {
+	aerr, ok := err.(*api.Error)
+	if !ok || aerr.Response == nil || aerr.Response.StatusCode != http.StatusPreconditionFailed {
+		return "", false
+	}
+
+	return strings.Trim(aerr.Response.Header.Get("ETag"), `"`), true
+}
+
+// ListOptions contains pagination and listing options.
 type ListOptions struct {
 	Limit    int
 	Continue string
+	// LatestOnly makes List return only the latest version of each ruleset.
+	LatestOnly bool
 }
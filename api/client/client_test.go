@@ -27,3 +27,12 @@ func TestNewRequestPrefersSpecifiedUserAgentHeaderToDefault(t *testing.T) {
 	ua := req.Header.Get("User-Agent")
 	require.Equal(t, expUA, ua)
 }
+
+// When a token is provided, newRequest sends it as a bearer token.
+func TestNewRequestSendsToken(t *testing.T) {
+	client, err := New("http://www.example.com", Token("secret"))
+	require.NoError(t, err)
+	req, err := client.newRequest("my-method", "/api/test", nil)
+	require.NoError(t, err)
+	require.Equal(t, "Bearer secret", req.Header.Get("Authorization"))
+}
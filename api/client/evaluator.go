@@ -3,6 +3,7 @@ package client
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/heetch/regula"
 	"github.com/heetch/regula/api"
@@ -16,37 +17,17 @@ type Evaluator struct {
 	wg     sync.WaitGroup
 }
 
-// NewEvaluator uses the given client to fetch a list of rulesets starting with the given prefix
+// NewEvaluator uses the given client to fetch the latest version of the rulesets starting with the given prefix
 // and returns an evaluator that holds the results in memory.
 // If watch is true, the evaluator will watch for changes on the server and automatically update
 // the underlying RulesetBuffer.
 // If watch is set to true, the Close method must always be called to gracefully close the watcher.
 func NewEvaluator(ctx context.Context, client *Client, prefix string, watch bool) (*Evaluator, error) {
-	ls, err := client.Rulesets.List(ctx, prefix, &ListOptions{
-		Limit: 100, // TODO(asdine): make it configurable in future releases
-	})
-	if err != nil {
-		return nil, err
-	}
-
 	buf := regula.NewRulesetBuffer()
 
-	for _, re := range ls.Rulesets {
-		buf.Add(re.Path, re.Version, re.Ruleset)
-	}
-
-	for ls.Continue != "" {
-		ls, err = client.Rulesets.List(ctx, prefix, &ListOptions{
-			Limit:    100, // TODO(asdine): make it configurable in future releases
-			Continue: ls.Continue,
-		})
-		if err != nil {
-			return nil, err
-		}
-
-		for _, re := range ls.Rulesets {
-			buf.Add(re.Path, re.Version, re.Ruleset)
-		}
+	revision, err := load(ctx, client, prefix, buf)
+	if err != nil {
+		return nil, err
 	}
 
 	ev := Evaluator{
@@ -60,18 +41,39 @@ func NewEvaluator(ctx context.Context, client *Client, prefix string, watch bool
 		go func() {
 			defer ev.wg.Done()
 
-			ch := client.Rulesets.Watch(ctx, prefix, ls.Revision)
+			for {
+				for wr := range client.Rulesets.Watch(ctx, prefix, revision) {
+					if wr.Err != nil {
+						client.Logger.Error().Err(wr.Err).Msg("Watching failed")
+						continue
+					}
 
-			for wr := range ch {
-				if wr.Err != nil {
-					client.Logger.Error().Err(err).Msg("Watching failed")
+					for _, ev := range wr.Events.Events {
+						switch ev.Type {
+						case api.PutEvent:
+							buf.Add(ev.Path, ev.Version, ev.Ruleset)
+						case api.DeleteEvent:
+							buf.Remove(ev.Path, ev.Version)
+						}
+					}
 				}
 
-				for _, ev := range wr.Events.Events {
-					switch ev.Type {
-					case api.PutEvent:
-						buf.Add(ev.Path, ev.Version, ev.Ruleset)
+				// the watcher also stops if the revision was compacted by the server,
+				// in which case the rulesets are loaded again to watch from their current revision.
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					default:
 					}
+
+					revision, err = load(ctx, client, prefix, buf)
+					if err == nil {
+						break
+					}
+
+					client.Logger.Error().Err(err).Msg("Loading rulesets failed")
+					time.Sleep(client.WatchRetryDelay)
 				}
 			}
 		}()
@@ -80,6 +82,32 @@ func NewEvaluator(ctx context.Context, client *Client, prefix string, watch bool
 	return &ev, nil
 }
 
+// load adds the latest version of the rulesets starting with the given prefix to the buffer
+// and returns the revision they were read at.
+func load(ctx context.Context, client *Client, prefix string, buf *regula.RulesetBuffer) (string, error) {
+	opt := ListOptions{
+		Limit:      100, // TODO(asdine): make it configurable in future releases
+		LatestOnly: true,
+	}
+
+	for {
+		ls, err := client.Rulesets.List(ctx, prefix, &opt)
+		if err != nil {
+			return "", err
+		}
+
+		for _, re := range ls.Rulesets {
+			buf.Add(re.Path, re.Version, re.Ruleset)
+		}
+
+		if ls.Continue == "" {
+			return ls.Revision, nil
+		}
+
+		opt.Continue = ls.Continue
+	}
+}
+
 // Close stops the watcher if running.
 func (e *Evaluator) Close() error {
 	if e.cancel != nil {
@@ -20,6 +20,9 @@ func TestEvaluator(t *testing.T) {
 	t.Run("Watch disabled", func(t *testing.T) {
 		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if _, ok := r.URL.Query()["list"]; ok {
+				// only the latest versions are needed
+				assert.Contains(t, r.URL.Query(), "latest")
+
 				if continueToken := r.URL.Query().Get("continue"); continueToken != "" {
 					assert.Equal(t, "some-token", continueToken)
 					fmt.Fprintf(w, `{"revision": "revB", "rulesets": [{"path": "a", "version":"2"}]}`)
@@ -91,6 +94,87 @@ func TestEvaluator(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, "2", version)
 	})
+
+	t.Run("Watch deletions", func(t *testing.T) {
+		watchCount := 0
+		didWatch := make(chan struct{})
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := r.URL.Query()["list"]; ok {
+				fmt.Fprintf(w, `{"revision": "revA", "rulesets": [{"path": "a", "version":"1"}, {"path": "ab", "version":"1"}]}`)
+				return
+			}
+
+			watchCount++
+
+			if watchCount > 1 {
+				close(didWatch)
+				return
+			}
+
+			fmt.Fprintf(w, `{"events": [{"type": "DELETE", "path": "a", "version": "1"}], "revision": "revB"}`)
+		}))
+		defer ts.Close()
+
+		cli, err := client.New(ts.URL)
+		require.NoError(t, err)
+		cli.Logger = zerolog.New(ioutil.Discard)
+
+		ev, err := client.NewEvaluator(context.Background(), cli, "a", true)
+		require.NoError(t, err)
+
+		<-didWatch
+		err = ev.Close()
+		require.NoError(t, err)
+
+		_, _, err = ev.Latest("a")
+		require.Equal(t, regula.ErrRulesetNotFound, err)
+
+		_, version, err := ev.Latest("ab")
+		require.NoError(t, err)
+		require.Equal(t, "1", version)
+	})
+
+	t.Run("Watch compacted", func(t *testing.T) {
+		listCount := 0
+		didWatch := make(chan struct{})
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := r.URL.Query()["list"]; ok {
+				listCount++
+				fmt.Fprintf(w, `{"revision": "rev%d", "rulesets": [{"path": "a", "version":"%d"}]}`, listCount, listCount)
+				return
+			}
+
+			switch r.URL.Query().Get("revision") {
+			case "rev1":
+				w.WriteHeader(http.StatusGone)
+				fmt.Fprintf(w, `{"error": "revision compacted"}`)
+			case "rev2":
+				// the rulesets were loaded again before watching from their revision
+				close(didWatch)
+				<-r.Context().Done()
+			default:
+				t.Errorf("unexpected revision %s", r.URL.Query().Get("revision"))
+			}
+		}))
+		defer ts.Close()
+
+		cli, err := client.New(ts.URL)
+		require.NoError(t, err)
+		cli.Logger = zerolog.New(ioutil.Discard)
+
+		ev, err := client.NewEvaluator(context.Background(), cli, "a", true)
+		require.NoError(t, err)
+
+		<-didWatch
+		err = ev.Close()
+		require.NoError(t, err)
+
+		_, version, err := ev.Latest("a")
+		require.NoError(t, err)
+		require.Equal(t, "2", version)
+	})
 }
 
 var (
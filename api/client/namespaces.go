@@ -0,0 +1,40 @@
+package client
+
+import (
+	"context"
+	ppath "path"
+
+	"github.com/heetch/regula/api"
+)
+
+// NamespaceService handles communication with the namespace related
+// methods of the Regula API.
+type NamespaceService struct {
+	client *Client
+}
+
+// List fetches all the namespaces.
+func (s *NamespaceService) List(ctx context.Context) (*api.Namespaces, error) {
+	req, err := s.client.newRequest("GET", "./namespaces/", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp api.Namespaces
+
+	_, err = s.client.try(ctx, req, &resp)
+	return &resp, err
+}
+
+// Create creates the given namespace. Creating an existing namespace is not an error.
+func (s *NamespaceService) Create(ctx context.Context, name string) (*api.Namespace, error) {
+	req, err := s.client.newRequest("PUT", "./"+ppath.Join("namespaces", name), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp api.Namespace
+
+	_, err = s.client.try(ctx, req, &resp)
+	return &resp, err
+}
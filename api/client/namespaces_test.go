@@ -0,0 +1,67 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/heetch/regula/api"
+	"github.com/heetch/regula/api/client"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespaces(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/namespaces/":
+			fmt.Fprintf(w, `{"namespaces": [{"name": "a"}, {"name": "b"}]}`)
+		case r.Method == "PUT" && r.URL.Path == "/namespaces/c":
+			fmt.Fprintf(w, `{"name": "c"}`)
+		case r.Method == "GET" && r.URL.Path == "/namespaces/c/rulesets/a/b":
+			assert.Contains(t, r.URL.Query(), "versions")
+			fmt.Fprintf(w, `{"path": "a/b", "versions": [{"version": "1"}]}`)
+		case r.Method == "GET" && r.URL.Path == "/namespaces/c/rulesets/":
+			assert.Contains(t, r.URL.Query(), "list")
+			fmt.Fprintf(w, `{"revision": "rev", "rulesets": [{"path": "a/b"}]}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	cli, err := client.New(ts.URL)
+	require.NoError(t, err)
+	cli.Logger = zerolog.New(ioutil.Discard)
+
+	t.Run("List", func(t *testing.T) {
+		nl, err := cli.Namespaces.List(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, []api.Namespace{{Name: "a"}, {Name: "b"}}, nl.Namespaces)
+	})
+
+	t.Run("Create", func(t *testing.T) {
+		n, err := cli.Namespaces.Create(context.Background(), "c")
+		require.NoError(t, err)
+		require.Equal(t, "c", n.Name)
+	})
+
+	t.Run("Rulesets", func(t *testing.T) {
+		cli, err := client.New(ts.URL, client.Namespace("c"))
+		require.NoError(t, err)
+		cli.Logger = zerolog.New(ioutil.Discard)
+
+		versions, err := cli.Rulesets.Versions(context.Background(), "a/b", nil)
+		require.NoError(t, err)
+		require.Equal(t, "a/b", versions.Path)
+
+		list, err := cli.Rulesets.List(context.Background(), "", nil)
+		require.NoError(t, err)
+		require.Len(t, list.Rulesets, 1)
+	})
+}
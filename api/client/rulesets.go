@@ -1,16 +1,21 @@
 package client
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
+	"io"
 	"net/http"
 	ppath "path"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/heetch/regula"
 	"github.com/heetch/regula/api"
 	"github.com/heetch/regula/rule"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context/ctxhttp"
 )
 
 // RulesetService handles communication with the ruleset related
@@ -20,8 +25,13 @@ type RulesetService struct {
 }
 
 func (s *RulesetService) joinPath(path string) string {
-	path = "./" + ppath.Join("rulesets/", path)
-	if path == "./rulesets" {
+	root := "rulesets"
+	if s.client.namespace != "" {
+		root = ppath.Join("namespaces", s.client.namespace, "rulesets")
+	}
+
+	path = "./" + ppath.Join(root, path)
+	if path == "./"+root {
 		return path + "/"
 	}
 
@@ -29,6 +39,7 @@ func (s *RulesetService) joinPath(path string) string {
 }
 
 // List fetches all the rulesets starting with the given prefix.
+// By default every version of the rulesets is returned, use opt.LatestOnly to only fetch the latest ones.
 func (s *RulesetService) List(ctx context.Context, prefix string, opt *ListOptions) (*api.Rulesets, error) {
 	req, err := s.client.newRequest("GET", s.joinPath(prefix), nil)
 	if err != nil {
@@ -46,6 +57,10 @@ func (s *RulesetService) List(ctx context.Context, prefix string, opt *ListOptio
 		if opt.Continue != "" {
 			q.Add("continue", opt.Continue)
 		}
+
+		if opt.LatestOnly {
+			q.Add("latest", "")
+		}
 	}
 
 	req.URL.RawQuery = q.Encode()
@@ -64,53 +79,357 @@ func (s *RulesetService) Eval(ctx context.Context, path string, params rule.Para
 
 // EvalVersion evaluates the given ruleset version with the given params.
 // It implements the regula.Evaluator interface and thus can be passed to the regula.Engine.
+// The params are sent in a JSON body so that regula.Params keep their type.
 func (s *RulesetService) EvalVersion(ctx context.Context, path, version string, params rule.Params) (*regula.EvalResult, error) {
-	req, err := s.client.newRequest("GET", s.joinPath(path), nil)
+	p, err := encodeParams(params)
 	if err != nil {
 		return nil, err
 	}
 
-	q := req.URL.Query()
-	q.Add("eval", "")
+	req, err := s.client.newRequest("POST", s.joinPath(path)+"/eval", &api.EvalRequest{
+		Params:  p,
+		Version: version,
+		Explain: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var resp api.EvalResult
+
+	_, err = s.client.try(ctx, req, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	res := regula.EvalResult{
+		Value:   resp.Value,
+		Version: resp.Version,
+	}
+	if resp.Explanation != nil {
+		res.Rule = resp.Explanation.Rule
+	}
+
+	return &res, nil
+}
+
+// encodeParams returns the JSON representation of the given params.
+// regula.Params are sent as is, other implementations are encoded as strings which the server parses
+// according to the types expected by the ruleset.
+func encodeParams(params rule.Params) (map[string]interface{}, error) {
+	switch p := params.(type) {
+	case nil:
+		return map[string]interface{}{}, nil
+	case regula.Params:
+		return p, nil
+	}
+
+	m := make(map[string]interface{})
 	for _, k := range params.Keys() {
 		v, err := params.EncodeValue(k)
 		if err != nil {
 			return nil, err
 		}
 
-		q.Add(k, v)
+		m[k] = v
 	}
-	if version != "" {
-		q.Add("version", version)
+
+	return m, nil
+}
+
+// EvalBatch evaluates the given rulesets with every given set of params in a single request.
+// It implements the regula.BatchEvaluator interface and thus is used by regula.Engine.EvalBatch.
+func (s *RulesetService) EvalBatch(ctx context.Context, paths []string, params []rule.Params) ([]regula.BatchResult, error) {
+	return s.evalBatch(ctx, "", paths, params)
+}
+
+// EvalPrefix evaluates all the rulesets under the given prefix with every given set of params in a single request.
+func (s *RulesetService) EvalPrefix(ctx context.Context, prefix string, params []rule.Params) ([]regula.BatchResult, error) {
+	return s.evalBatch(ctx, prefix, nil, params)
+}
+
+func (s *RulesetService) evalBatch(ctx context.Context, prefix string, paths []string, params []rule.Params) ([]regula.BatchResult, error) {
+	body := api.EvalBatchRequest{
+		Paths:  paths,
+		Params: make([]map[string]interface{}, len(params)),
 	}
+
+	for i, p := range params {
+		m, err := encodeParams(p)
+		if err != nil {
+			return nil, err
+		}
+
+		body.Params[i] = m
+	}
+
+	req, err := s.client.newRequest("POST", s.joinPath(prefix), &body)
+	if err != nil {
+		return nil, err
+	}
+
+	q := req.URL.Query()
+	q.Add("eval", "")
 	req.URL.RawQuery = q.Encode()
 
-	var resp api.EvalResult
+	var resp api.EvalBatchResults
 
 	_, err = s.client.try(ctx, req, &resp)
 	if err != nil {
 		return nil, err
 	}
 
-	return &regula.EvalResult{
-		Value:   resp.Value,
-		Version: resp.Version,
-	}, nil
+	results := make([]regula.BatchResult, len(resp.Results))
+	for i, r := range resp.Results {
+		results[i] = regula.BatchResult{
+			Path:   r.Path,
+			Params: r.Params,
+		}
+
+		if r.Error != "" {
+			results[i].Err = evalError(r.Error)
+			continue
+		}
+
+		// older servers don't report the rule that matched.
+		results[i].Result = &regula.EvalResult{
+			Value:   r.Value,
+			Version: r.Version,
+			Rule:    r.Rule,
+		}
+	}
+
+	return results, nil
+}
+
+// evalError returns the error corresponding to the given message
+// so that callers can compare it with the errors of the regula and rule packages.
+func evalError(msg string) error {
+	for _, err := range []error{
+		regula.ErrRulesetNotFound,
+		rule.ErrNoMatch,
+		rule.ErrParamNotFound,
+		rule.ErrParamTypeMismatch,
+	} {
+		if err.Error() == msg {
+			return err
+		}
+	}
+
+	return &api.Error{Err: msg}
 }
 
 // Put creates a ruleset version on the given path.
-func (s *RulesetService) Put(ctx context.Context, path string, rs *regula.Ruleset) (*api.Ruleset, error) {
+// If an expected version is given and isn't the latest version anymore, the returned error
+// can be passed to IsVersionMismatch to fetch the actual latest version.
+func (s *RulesetService) Put(ctx context.Context, path string, rs *regula.Ruleset, opts ...PutOption) (*api.Ruleset, error) {
 	req, err := s.client.newRequest("PUT", s.joinPath(path), rs)
 	if err != nil {
 		return nil, err
 	}
 
+	var o putOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.message != "" {
+		req.Header.Set(api.MessageHeader, o.message)
+	}
+
+	if o.expectedVersion != "" {
+		req.Header.Set("If-Match", `"`+o.expectedVersion+`"`)
+	}
+
 	var resp api.Ruleset
 
 	_, err = s.client.try(ctx, req, &resp)
 	return &resp, err
 }
 
+// PutWithMessage creates a ruleset version on the given path, recording the given message
+// in the history of the ruleset.
+func (s *RulesetService) PutWithMessage(ctx context.Context, path string, rs *regula.Ruleset, message string) (*api.Ruleset, error) {
+	return s.Put(ctx, path, rs, Message(message))
+}
+
+// PutBatch creates a version of every given ruleset at once: either all of them are stored, or none of them.
+// Only the Path and Ruleset fields of the rulesets are used. Expected versions are ignored.
+// Batches are limited to store.MaxBatchSize rulesets, larger ones are rejected by the server with a 400 error.
+func (s *RulesetService) PutBatch(ctx context.Context, rulesets []api.Ruleset, opts ...PutOption) (*api.Rulesets, error) {
+	req, err := s.client.newRequest("PUT", s.joinPath(""), &api.Rulesets{Rulesets: rulesets})
+	if err != nil {
+		return nil, err
+	}
+
+	q := req.URL.Query()
+	q.Add("batch", "")
+	req.URL.RawQuery = q.Encode()
+
+	var o putOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.message != "" {
+		req.Header.Set(api.MessageHeader, o.message)
+	}
+
+	var resp api.Rulesets
+
+	_, err = s.client.try(ctx, req, &resp)
+	return &resp, err
+}
+
+// Versions fetches the versions of the ruleset stored on the given path, newest first.
+func (s *RulesetService) Versions(ctx context.Context, path string, opt *ListOptions) (*api.RulesetVersions, error) {
+	req, err := s.client.newRequest("GET", s.joinPath(path), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	q := req.URL.Query()
+	q.Add("versions", "")
+
+	if opt != nil {
+		if opt.Limit != 0 {
+			q.Add("limit", strconv.Itoa(opt.Limit))
+		}
+
+		if opt.Continue != "" {
+			q.Add("continue", opt.Continue)
+		}
+	}
+
+	req.URL.RawQuery = q.Encode()
+
+	var resp api.RulesetVersions
+
+	_, err = s.client.try(ctx, req, &resp)
+	return &resp, err
+}
+
+// History fetches the changes made to the ruleset stored on the given path, newest first.
+func (s *RulesetService) History(ctx context.Context, path string, opt *ListOptions) (*api.History, error) {
+	req, err := s.client.newRequest("GET", s.joinPath(path), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	q := req.URL.Query()
+	q.Add("history", "")
+
+	if opt != nil {
+		if opt.Limit != 0 {
+			q.Add("limit", strconv.Itoa(opt.Limit))
+		}
+
+		if opt.Continue != "" {
+			q.Add("continue", opt.Continue)
+		}
+	}
+
+	req.URL.RawQuery = q.Encode()
+
+	var resp api.History
+
+	_, err = s.client.try(ctx, req, &resp)
+	return &resp, err
+}
+
+// Rollback makes the given version the latest version of the ruleset stored on the given path.
+func (s *RulesetService) Rollback(ctx context.Context, path, version string) (*api.Ruleset, error) {
+	req, err := s.client.newRequest("POST", s.joinPath(path), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	q := req.URL.Query()
+	q.Add("rollback", "")
+	q.Add("version", version)
+	req.URL.RawQuery = q.Encode()
+
+	var resp api.Ruleset
+
+	_, err = s.client.try(ctx, req, &resp)
+	return &resp, err
+}
+
+// Delete removes the ruleset stored on the given path.
+// If hard is false, the server keeps the versions aside and preserves the signature of the ruleset.
+func (s *RulesetService) Delete(ctx context.Context, path string, hard bool) error {
+	req, err := s.client.newRequest("DELETE", s.joinPath(path), nil)
+	if err != nil {
+		return err
+	}
+
+	if hard {
+		q := req.URL.Query()
+		q.Add("hard", "")
+		req.URL.RawQuery = q.Encode()
+	}
+
+	_, err = s.client.try(ctx, req, nil)
+	return err
+}
+
+// Signatures returns the history of the signatures of the ruleset stored on the given path, the current one last.
+func (s *RulesetService) Signatures(ctx context.Context, path string) (*api.Signatures, error) {
+	req, err := s.client.newRequest("GET", s.joinPath(path), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	q := req.URL.Query()
+	q.Add("signature", "")
+	req.URL.RawQuery = q.Encode()
+
+	var resp api.Signatures
+
+	_, err = s.client.try(ctx, req, &resp)
+	return &resp, err
+}
+
+// MigrateSignature applies the given migration to the signature of the ruleset stored on the given path
+// and returns the new signature.
+func (s *RulesetService) MigrateSignature(ctx context.Context, path string, m *api.SignatureMigration) (*api.Signature, error) {
+	req, err := s.client.newRequest("POST", s.joinPath(path), m)
+	if err != nil {
+		return nil, err
+	}
+
+	q := req.URL.Query()
+	q.Add("signature", "")
+	req.URL.RawQuery = q.Encode()
+
+	var resp api.Signature
+
+	_, err = s.client.try(ctx, req, &resp)
+	return &resp, err
+}
+
+// Diff returns the differences between two versions of the ruleset stored on the given path.
+// If to is empty, the from version is compared with the latest version.
+func (s *RulesetService) Diff(ctx context.Context, path, from, to string) (*api.RulesetDiff, error) {
+	req, err := s.client.newRequest("GET", s.joinPath(path), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	q := req.URL.Query()
+	q.Add("diff", "")
+	q.Add("from", from)
+	if to != "" {
+		q.Add("to", to)
+	}
+	req.URL.RawQuery = q.Encode()
+
+	var resp api.RulesetDiff
+
+	_, err = s.client.try(ctx, req, &resp)
+	return &resp, err
+}
+
 // WatchResponse contains a list of events occured on a group of rulesets.
 // If an error occurs during the watching, the Err field will be populated.
 type WatchResponse struct {
@@ -118,10 +437,21 @@ type WatchResponse struct {
 	Err    error
 }
 
+// errWatchInterrupted is returned when a watch ended without error and must be resumed.
+var errWatchInterrupted = errors.New("watch interrupted")
+
+// ErrRevisionCompacted is sent by Watch when the server doesn't have the changes that occurred
+// after the requested revision anymore. The rulesets must be fetched again before watching
+// from their new revision.
+var ErrRevisionCompacted = errors.New("revision compacted")
+
 // Watch watchs the given path for changes and sends the events in the returned channel.
 // If revision is empty it will start to watch for changes occuring from the moment the request is performed,
 // otherwise it will watch for any changes occured from the given revision.
+// The events are streamed by the server on a single connection which is automatically reopened,
+// resuming from the last received revision, if it is interrupted.
 // The given context must be used to stop the watcher.
+// If the revision was compacted by the server, ErrRevisionCompacted is sent and the channel is closed.
 func (s *RulesetService) Watch(ctx context.Context, prefix string, revision string) <-chan WatchResponse {
 	ch := make(chan WatchResponse)
 
@@ -143,51 +473,140 @@ func (s *RulesetService) Watch(ctx context.Context, prefix string, revision stri
 
 			q := req.URL.Query()
 			q.Add("watch", "")
+			q.Add("stream", "")
 			if revision != "" {
 				q.Add("revision", revision)
 			}
 			req.URL.RawQuery = q.Encode()
 
-			var events api.Events
-			_, err = s.client.do(ctx, req, &events)
-			if err != nil {
-				if e, ok := err.(*api.Error); ok {
-					switch e.Response.StatusCode {
-					case http.StatusNotFound:
-						ch <- WatchResponse{Err: err}
-						return
-					case http.StatusInternalServerError:
-						s.client.Logger.Debug().Err(err).Msg("watch request failed: internal server error")
-					default:
-						s.client.Logger.Error().Err(err).Int("status", e.Response.StatusCode).Msg("watch request returned unexpected status")
-					}
-				} else {
-					switch err {
-					case context.Canceled:
-						fallthrough
-					case context.DeadlineExceeded:
-						s.client.Logger.Debug().Msg("watch context done")
-						return
-					default:
-						s.client.Logger.Error().Err(err).Msg("watch request failed")
-					}
-				}
-
-				// avoid too many requests on errors.
-				time.Sleep(s.client.WatchRetryDelay)
+			err = s.watch(ctx, req, func(events *api.Events) {
+				ch <- WatchResponse{Events: events}
+				revision = events.Revision
+			})
+			if err == nil {
 				continue
 			}
 
-			if events.Timeout {
-				s.client.Logger.Debug().Msg("watch request timed out")
-				time.Sleep(s.client.WatchRetryDelay)
-				continue
+			if err == ErrRevisionCompacted {
+				ch <- WatchResponse{Err: err}
+				return
 			}
 
-			ch <- WatchResponse{Events: &events}
-			revision = events.Revision
+			if e, ok := err.(*api.Error); ok {
+				switch e.Response.StatusCode {
+				case http.StatusNotFound:
+					ch <- WatchResponse{Err: err}
+					return
+				case http.StatusGone:
+					ch <- WatchResponse{Err: ErrRevisionCompacted}
+					return
+				case http.StatusInternalServerError:
+					s.client.Logger.Debug().Err(err).Msg("watch request failed: internal server error")
+				default:
+					s.client.Logger.Error().Err(err).Int("status", e.Response.StatusCode).Msg("watch request returned unexpected status")
+				}
+			} else {
+				switch err {
+				case context.Canceled:
+					fallthrough
+				case context.DeadlineExceeded:
+					s.client.Logger.Debug().Msg("watch context done")
+					return
+				case errWatchInterrupted:
+					s.client.Logger.Debug().Str("revision", revision).Msg("watch interrupted, resuming")
+				default:
+					s.client.Logger.Error().Err(err).Str("revision", revision).Msg("watch request failed")
+				}
+			}
+
+			// avoid too many requests on errors.
+			time.Sleep(s.client.WatchRetryDelay)
 		}
 	}()
 
 	return ch
 }
+
+// watch sends the watch request and passes every batch of events received to the send function
+// until the stream is closed. Servers that don't support streams answer with a single batch,
+// in which case nil is returned if it isn't empty.
+func (s *RulesetService) watch(ctx context.Context, req *http.Request, send func(*api.Events)) error {
+	resp, err := ctxhttp.Do(ctx, s.client.httpClient, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	s.client.Logger.Debug().Str("url", req.URL.String()).Int("status", resp.StatusCode).Msg("request sent")
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return decodeError(resp)
+	}
+
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		var events api.Events
+
+		err = json.NewDecoder(resp.Body).Decode(&events)
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		if events.Timeout {
+			return errWatchInterrupted
+		}
+
+		send(&events)
+		return nil
+	}
+
+	var event, data string
+
+	rd := bufio.NewReader(resp.Body)
+	for {
+		line, err := rd.ReadString('\n')
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			if err == io.EOF {
+				return errWatchInterrupted
+			}
+
+			return err
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "":
+			// a blank line dispatches the event.
+			switch event {
+			case "events":
+				var events api.Events
+
+				err = json.Unmarshal([]byte(data), &events)
+				if err != nil {
+					return errors.Wrap(err, "failed to decode watch events")
+				}
+
+				send(&events)
+			case "error":
+				var apiErr api.Error
+
+				_ = json.Unmarshal([]byte(data), &apiErr)
+				if apiErr.Err == ErrRevisionCompacted.Error() {
+					return ErrRevisionCompacted
+				}
+				return errors.Errorf("watch stream failed: %s", apiErr.Err)
+			}
+
+			event, data = "", ""
+		case strings.HasPrefix(line, ":"):
+			// heartbeat
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data += strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		}
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/heetch/regula"
 	"github.com/heetch/regula/api"
 	"github.com/heetch/regula/api/client"
+	"github.com/heetch/regula/diff"
 	"github.com/heetch/regula/rule"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
@@ -221,6 +222,7 @@ func TestRulesetService(t *testing.T) {
 					assert.Contains(t, r.URL.Query(), "list")
 					assert.Equal(t, "some-token", r.URL.Query().Get("continue"))
 					assert.Equal(t, "10", r.URL.Query().Get("limit"))
+					assert.Contains(t, r.URL.Query(), "latest")
 					assert.Equal(t, tc.url, r.URL.Path)
 					fmt.Fprintf(w, `{"revision": "rev", "rulesets": [{"path": "a"}]}`)
 				}))
@@ -232,8 +234,9 @@ func TestRulesetService(t *testing.T) {
 				cli.Headers["hk2"] = "hv2"
 
 				rs, err := cli.Rulesets.List(context.Background(), tc.path, &client.ListOptions{
-					Limit:    10,
-					Continue: "some-token",
+					Limit:      10,
+					Continue:   "some-token",
+					LatestOnly: true,
 				})
 				require.NoError(t, err)
 				require.Len(t, rs.Rulesets, 1)
@@ -245,10 +248,18 @@ func TestRulesetService(t *testing.T) {
 		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			assert.NotEmpty(t, r.Header.Get("User-Agent"))
 			assert.Equal(t, "application/json", r.Header.Get("Accept"))
-			assert.Contains(t, r.URL.Query(), "eval")
-			assert.Contains(t, r.URL.Query(), "foo")
-			assert.Equal(t, "/rulesets/path/to/ruleset", r.URL.Path)
-			fmt.Fprintf(w, `{"value": {"data": "baz", "type": "string", "kind": "value"}, "version": "1234"}`)
+			assert.Equal(t, "POST", r.Method)
+			assert.Equal(t, "/rulesets/path/to/ruleset/eval", r.URL.Path)
+
+			// the params keep their type
+			var req api.EvalRequest
+			dec := json.NewDecoder(r.Body)
+			dec.UseNumber()
+			assert.NoError(t, dec.Decode(&req))
+			assert.Equal(t, map[string]interface{}{"foo": "bar", "version": json.Number("10"), "ok": true}, req.Params)
+			assert.Empty(t, req.Version)
+			assert.True(t, req.Explain)
+			fmt.Fprintf(w, `{"value": {"data": "baz", "type": "string", "kind": "value"}, "version": "1234", "explanation": {"rule": 1}}`)
 		}))
 		defer ts.Close()
 
@@ -256,15 +267,39 @@ func TestRulesetService(t *testing.T) {
 		require.NoError(t, err)
 		cli.Logger = zerolog.New(ioutil.Discard)
 
-		exp := regula.EvalResult{Value: rule.StringValue("baz"), Version: "1234"}
+		ruleIdx := 1
+		exp := regula.EvalResult{Value: rule.StringValue("baz"), Version: "1234", Rule: &ruleIdx}
 
 		resp, err := cli.Rulesets.Eval(context.Background(), "path/to/ruleset", regula.Params{
-			"foo": "bar",
+			"foo":     "bar",
+			"version": int64(10),
+			"ok":      true,
 		})
 		require.NoError(t, err)
 		require.Equal(t, &exp, resp)
 	})
 
+	t.Run("EvalRulesetVersion", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req api.EvalRequest
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, "1234", req.Version)
+			assert.Empty(t, req.Params)
+			fmt.Fprintf(w, `{"value": {"data": "baz", "type": "string", "kind": "value"}, "version": "1234"}`)
+		}))
+		defer ts.Close()
+
+		cli, err := client.New(ts.URL)
+		require.NoError(t, err)
+		cli.Logger = zerolog.New(ioutil.Discard)
+
+		resp, err := cli.Rulesets.EvalVersion(context.Background(), "path/to/ruleset", "1234", nil)
+		require.NoError(t, err)
+		require.Equal(t, "1234", resp.Version)
+		// the rule is unknown without explanation
+		require.Nil(t, resp.Rule)
+	})
+
 	t.Run("PutRuleset", func(t *testing.T) {
 		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			assert.NotEmpty(t, r.Header.Get("User-Agent"))
@@ -288,6 +323,300 @@ func TestRulesetService(t *testing.T) {
 		require.Equal(t, "v", ars.Version)
 	})
 
+	t.Run("ListVersions", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "application/json", r.Header.Get("Accept"))
+			assert.Contains(t, r.URL.Query(), "versions")
+			assert.Equal(t, "10", r.URL.Query().Get("limit"))
+			assert.Equal(t, "abc123", r.URL.Query().Get("continue"))
+			assert.Equal(t, "/rulesets/a", r.URL.Path)
+			fmt.Fprintf(w, `{"path": "a", "versions": [{"version": "v2", "createdAt": "2018-01-02T00:00:00Z"}, {"version": "v1", "createdAt": "2018-01-01T00:00:00Z"}], "continue": "xyz"}`)
+		}))
+		defer ts.Close()
+
+		cli, err := client.New(ts.URL)
+		require.NoError(t, err)
+		cli.Logger = zerolog.New(ioutil.Discard)
+
+		vs, err := cli.Rulesets.Versions(context.Background(), "a", &client.ListOptions{
+			Limit:    10,
+			Continue: "abc123",
+		})
+		require.NoError(t, err)
+		require.Len(t, vs.Versions, 2)
+		require.Equal(t, "v2", vs.Versions[0].Version)
+		require.Equal(t, time.Date(2018, 1, 2, 0, 0, 0, 0, time.UTC), vs.Versions[0].CreatedAt)
+		require.Equal(t, "xyz", vs.Continue)
+	})
+
+	t.Run("PutRulesetMessage", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "bob", r.Header.Get(api.AuthorHeader))
+			assert.Equal(t, "some message", r.Header.Get(api.MessageHeader))
+			assert.Equal(t, "/rulesets/a", r.URL.Path)
+			fmt.Fprintf(w, `{"path": "a", "version": "v"}`)
+		}))
+		defer ts.Close()
+
+		cli, err := client.New(ts.URL, client.Author("bob"))
+		require.NoError(t, err)
+		cli.Logger = zerolog.New(ioutil.Discard)
+
+		rs, err := regula.NewInt64Ruleset(rule.New(rule.True(), rule.Int64Value(1)))
+		require.NoError(t, err)
+
+		ars, err := cli.Rulesets.PutWithMessage(context.Background(), "a", rs, "some message")
+		require.NoError(t, err)
+		require.Equal(t, "v", ars.Version)
+	})
+
+	t.Run("PutRulesetExpectedVersion", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, `"v1"`, r.Header.Get("If-Match"))
+			w.Header().Set("ETag", `"v2"`)
+			w.WriteHeader(http.StatusPreconditionFailed)
+			fmt.Fprintf(w, `{"error": "version mismatch"}`)
+		}))
+		defer ts.Close()
+
+		cli, err := client.New(ts.URL)
+		require.NoError(t, err)
+		cli.Logger = zerolog.New(ioutil.Discard)
+
+		rs, err := regula.NewInt64Ruleset(rule.New(rule.True(), rule.Int64Value(1)))
+		require.NoError(t, err)
+
+		_, err = cli.Rulesets.Put(context.Background(), "a", rs, client.ExpectedVersion("v1"))
+		latest, ok := client.IsVersionMismatch(err)
+		require.True(t, ok)
+		require.Equal(t, "v2", latest)
+
+		_, ok = client.IsVersionMismatch(fmt.Errorf("some error"))
+		require.False(t, ok)
+	})
+
+	t.Run("EvalBatch", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "POST", r.Method)
+			assert.Contains(t, r.URL.Query(), "eval")
+			assert.Equal(t, "/rulesets/", r.URL.Path)
+
+			var req api.EvalBatchRequest
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, []string{"a", "b"}, req.Paths)
+			assert.Equal(t, []map[string]interface{}{{"foo": "bar", "n": float64(10)}, {"foo": "baz"}}, req.Params)
+			fmt.Fprintf(w, `{"results": [{"path": "a", "params": 0, "value": {"data": "success", "type": "string", "kind": "value"}, "version": "v", "rule": 0}, {"path": "a", "params": 1, "value": {"data": "success", "type": "string", "kind": "value"}, "version": "v"}, {"path": "b", "params": 0, "error": "ruleset not found"}, {"path": "b", "params": 1, "error": "ruleset not found"}]}`)
+		}))
+		defer ts.Close()
+
+		cli, err := client.New(ts.URL)
+		require.NoError(t, err)
+		cli.Logger = zerolog.New(ioutil.Discard)
+
+		res, err := regula.NewEngine(cli.Rulesets).EvalBatch(context.Background(), []string{"a", "b"}, regula.Params{"foo": "bar", "n": int64(10)}, regula.Params{"foo": "baz"})
+		require.NoError(t, err)
+		require.Len(t, res, 4)
+		require.Equal(t, rule.StringValue("success"), res[0].Result.Value)
+		require.Equal(t, "v", res[0].Result.Version)
+		require.NotNil(t, res[0].Result.Rule)
+		require.Equal(t, 0, *res[0].Result.Rule)
+		// older servers don't report the rule
+		require.Nil(t, res[1].Result.Rule)
+		require.Equal(t, "b", res[2].Path)
+		require.Equal(t, regula.ErrRulesetNotFound, res[2].Err)
+	})
+
+	t.Run("EvalPrefix", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/rulesets/a", r.URL.Path)
+
+			var req api.EvalBatchRequest
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Empty(t, req.Paths)
+			fmt.Fprintf(w, `{"results": [{"path": "a/1", "params": 0, "error": "rule doesn't match the given params"}, {"path": "a/1", "params": 1, "error": "some error"}]}`)
+		}))
+		defer ts.Close()
+
+		cli, err := client.New(ts.URL)
+		require.NoError(t, err)
+		cli.Logger = zerolog.New(ioutil.Discard)
+
+		res, err := cli.Rulesets.EvalPrefix(context.Background(), "a", []rule.Params{regula.Params{"foo": "bar"}, regula.Params{}})
+		require.NoError(t, err)
+		require.Len(t, res, 2)
+		require.Equal(t, rule.ErrNoMatch, res[0].Err)
+		require.EqualError(t, res[1].Err, "some error")
+	})
+
+	t.Run("PutBatch", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "PUT", r.Method)
+			assert.Contains(t, r.URL.Query(), "batch")
+			assert.Equal(t, "/rulesets/", r.URL.Path)
+			assert.Equal(t, "some message", r.Header.Get(api.MessageHeader))
+
+			var batch api.Rulesets
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&batch))
+			assert.Len(t, batch.Rulesets, 2)
+			fmt.Fprintf(w, `{"rulesets": [{"path": "a", "version": "v1"}, {"path": "b", "version": "v2"}]}`)
+		}))
+		defer ts.Close()
+
+		cli, err := client.New(ts.URL)
+		require.NoError(t, err)
+		cli.Logger = zerolog.New(ioutil.Discard)
+
+		rs, err := regula.NewInt64Ruleset(rule.New(rule.True(), rule.Int64Value(1)))
+		require.NoError(t, err)
+
+		res, err := cli.Rulesets.PutBatch(context.Background(), []api.Ruleset{
+			{Path: "a", Ruleset: rs},
+			{Path: "b", Ruleset: rs},
+		}, client.Message("some message"))
+		require.NoError(t, err)
+		require.Len(t, res.Rulesets, 2)
+		require.Equal(t, "v2", res.Rulesets[1].Version)
+	})
+
+	t.Run("History", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "application/json", r.Header.Get("Accept"))
+			assert.Contains(t, r.URL.Query(), "history")
+			assert.Equal(t, "10", r.URL.Query().Get("limit"))
+			assert.Equal(t, "abc123", r.URL.Query().Get("continue"))
+			assert.Equal(t, "/rulesets/a", r.URL.Path)
+			fmt.Fprintf(w, `{"path": "a", "entries": [{"path": "a", "version": "v2", "previousVersion": "v1", "author": "bob", "message": "fix", "time": "2018-01-02T00:00:00Z"}], "continue": "xyz"}`)
+		}))
+		defer ts.Close()
+
+		cli, err := client.New(ts.URL)
+		require.NoError(t, err)
+		cli.Logger = zerolog.New(ioutil.Discard)
+
+		h, err := cli.Rulesets.History(context.Background(), "a", &client.ListOptions{
+			Limit:    10,
+			Continue: "abc123",
+		})
+		require.NoError(t, err)
+		require.Len(t, h.Entries, 1)
+		require.Equal(t, api.AuditEntry{
+			Path:            "a",
+			Version:         "v2",
+			PreviousVersion: "v1",
+			Author:          "bob",
+			Message:         "fix",
+			Time:            time.Date(2018, 1, 2, 0, 0, 0, 0, time.UTC),
+		}, h.Entries[0])
+		require.Equal(t, "xyz", h.Continue)
+	})
+
+	t.Run("RollbackRuleset", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "application/json", r.Header.Get("Accept"))
+			assert.Equal(t, "POST", r.Method)
+			assert.Contains(t, r.URL.Query(), "rollback")
+			assert.Equal(t, "v1", r.URL.Query().Get("version"))
+			assert.Equal(t, "/rulesets/a", r.URL.Path)
+			fmt.Fprintf(w, `{"path": "a", "version": "v1"}`)
+		}))
+		defer ts.Close()
+
+		cli, err := client.New(ts.URL)
+		require.NoError(t, err)
+		cli.Logger = zerolog.New(ioutil.Discard)
+
+		rs, err := cli.Rulesets.Rollback(context.Background(), "a", "v1")
+		require.NoError(t, err)
+		require.Equal(t, "a", rs.Path)
+		require.Equal(t, "v1", rs.Version)
+	})
+
+	t.Run("DeleteRuleset", func(t *testing.T) {
+		for _, hard := range []bool{false, true} {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "DELETE", r.Method)
+				assert.Equal(t, "/rulesets/a", r.URL.Path)
+				_, ok := r.URL.Query()["hard"]
+				assert.Equal(t, hard, ok)
+				w.WriteHeader(http.StatusNoContent)
+			}))
+
+			cli, err := client.New(ts.URL)
+			require.NoError(t, err)
+			cli.Logger = zerolog.New(ioutil.Discard)
+
+			err = cli.Rulesets.Delete(context.Background(), "a", hard)
+			require.NoError(t, err)
+			ts.Close()
+		}
+	})
+
+	t.Run("Signatures", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "GET", r.Method)
+			assert.Contains(t, r.URL.Query(), "signature")
+			assert.Equal(t, "/rulesets/a", r.URL.Path)
+			fmt.Fprintf(w, `{"path": "a", "signatures": [{"returnType": "string", "paramTypes": {"a": "int64"}}]}`)
+		}))
+		defer ts.Close()
+
+		cli, err := client.New(ts.URL)
+		require.NoError(t, err)
+		cli.Logger = zerolog.New(ioutil.Discard)
+
+		sigs, err := cli.Rulesets.Signatures(context.Background(), "a")
+		require.NoError(t, err)
+		require.Equal(t, []api.Signature{{ReturnType: "string", ParamTypes: map[string]string{"a": "int64"}}}, sigs.Signatures)
+	})
+
+	t.Run("MigrateSignature", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "POST", r.Method)
+			assert.Contains(t, r.URL.Query(), "signature")
+			assert.Equal(t, "/rulesets/a", r.URL.Path)
+
+			var m api.SignatureMigration
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&m))
+			assert.Equal(t, []string{"a"}, m.DeprecateParams)
+
+			fmt.Fprintf(w, `{"returnType": "string", "paramTypes": {"a": "int64"}, "deprecated": ["a"]}`)
+		}))
+		defer ts.Close()
+
+		cli, err := client.New(ts.URL)
+		require.NoError(t, err)
+		cli.Logger = zerolog.New(ioutil.Discard)
+
+		sig, err := cli.Rulesets.MigrateSignature(context.Background(), "a", &api.SignatureMigration{
+			DeprecateParams: []string{"a"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"a"}, sig.Deprecated)
+	})
+
+	t.Run("DiffRuleset", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "application/json", r.Header.Get("Accept"))
+			assert.Contains(t, r.URL.Query(), "diff")
+			assert.Equal(t, "v1", r.URL.Query().Get("from"))
+			assert.Equal(t, "v2", r.URL.Query().Get("to"))
+			assert.Equal(t, "/rulesets/a", r.URL.Path)
+			fmt.Fprintf(w, `{"path": "a", "from": "v1", "to": "v2", "changes": {"rules": [{"change": "moved", "from": 1, "to": 0}]}}`)
+		}))
+		defer ts.Close()
+
+		cli, err := client.New(ts.URL)
+		require.NoError(t, err)
+		cli.Logger = zerolog.New(ioutil.Discard)
+
+		d, err := cli.Rulesets.Diff(context.Background(), "a", "v1", "v2")
+		require.NoError(t, err)
+		require.Equal(t, "v1", d.From)
+		require.Equal(t, "v2", d.To)
+		require.Len(t, d.Changes.Rules, 1)
+		require.Equal(t, diff.Moved, d.Changes.Rules[0].Change)
+	})
+
 	t.Run("WatchRuleset", func(t *testing.T) {
 		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			assert.NotEmpty(t, r.Header.Get("User-Agent"))
@@ -375,6 +704,39 @@ func TestRulesetService(t *testing.T) {
 		require.Error(t, evs.Err)
 	})
 
+	t.Run("WatchRuleset/Compacted", func(t *testing.T) {
+		responses := []func(w http.ResponseWriter){
+			func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusGone)
+				fmt.Fprintf(w, `{"error": "revision compacted"}`)
+			},
+			func(w http.ResponseWriter) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				fmt.Fprintf(w, "event: error\ndata: {\"error\": \"revision compacted\"}\n\n")
+			},
+		}
+
+		for _, respond := range responses {
+			respond := respond
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				respond(w)
+			}))
+			defer ts.Close()
+
+			cli, err := client.New(ts.URL)
+			require.NoError(t, err)
+			cli.Logger = zerolog.New(ioutil.Discard)
+
+			ch := cli.Rulesets.Watch(context.Background(), "a", "1")
+			evs := <-ch
+			require.Equal(t, client.ErrRevisionCompacted, evs.Err)
+
+			// the watcher doesn't retry
+			_, ok := <-ch
+			require.False(t, ok)
+		}
+	})
+
 	t.Run("WatchRuleset/Errors", func(t *testing.T) {
 		statuses := []int{
 			http.StatusRequestTimeout,
@@ -408,6 +770,83 @@ func TestRulesetService(t *testing.T) {
 		}
 	})
 
+	t.Run("WatchRuleset/Stream", func(t *testing.T) {
+		var i int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/rulesets/a", r.URL.Path)
+			assert.Contains(t, r.URL.Query(), "stream")
+
+			w.Header().Set("Content-Type", "text/event-stream")
+
+			// the first stream is interrupted after one batch, the watcher must resume from its revision.
+			switch atomic.AddInt32(&i, 1) {
+			case 1:
+				assert.Equal(t, "rev0", r.URL.Query().Get("revision"))
+				fmt.Fprintf(w, ": heartbeat\n\nid: rev1\nevent: events\ndata: {\"events\": [{\"type\": \"PUT\", \"path\": \"a/b\"}], \"revision\": \"rev1\"}\n\n")
+			case 2:
+				assert.Equal(t, "rev1", r.URL.Query().Get("revision"))
+				fmt.Fprintf(w, "id: rev2\nevent: events\ndata: {\"events\": [{\"type\": \"PUT\", \"path\": \"a/c\"}], \"revision\": \"rev2\"}\n\n")
+				fmt.Fprintf(w, "id: rev3\nevent: events\ndata: {\"events\": [{\"type\": \"DELETE\", \"path\": \"a/b\"}], \"revision\": \"rev3\"}\n\n")
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+			default:
+				t.Error("shouldn't reconnect")
+			}
+		}))
+		defer ts.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		cli, err := client.New(ts.URL)
+		require.NoError(t, err)
+		cli.Logger = zerolog.New(ioutil.Discard)
+		cli.WatchRetryDelay = 1 * time.Millisecond
+
+		ch := cli.Rulesets.Watch(ctx, "a", "rev0")
+
+		var paths []string
+		for _, rev := range []string{"rev1", "rev2", "rev3"} {
+			evs := <-ch
+			require.NoError(t, evs.Err)
+			require.Equal(t, rev, evs.Events.Revision)
+			paths = append(paths, evs.Events.Events[0].Path)
+		}
+		require.Equal(t, []string{"a/b", "a/c", "a/b"}, paths)
+
+		cancel()
+		_, ok := <-ch
+		require.False(t, ok)
+	})
+
+	t.Run("WatchRuleset/StreamError", func(t *testing.T) {
+		var i int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+
+			if atomic.AddInt32(&i, 1) == 1 {
+				fmt.Fprintf(w, "event: error\ndata: {\"error\": \"internal_error\"}\n\n")
+				return
+			}
+
+			fmt.Fprintf(w, "id: rev\nevent: events\ndata: {\"events\": [{\"type\": \"PUT\", \"path\": \"a\"}], \"revision\": \"rev\"}\n\n")
+		}))
+		defer ts.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		cli, err := client.New(ts.URL)
+		require.NoError(t, err)
+		cli.Logger = zerolog.New(ioutil.Discard)
+		cli.WatchRetryDelay = 1 * time.Millisecond
+
+		ch := cli.Rulesets.Watch(ctx, "a", "")
+		evs := <-ch
+		require.NoError(t, evs.Err)
+		require.Equal(t, "rev", evs.Events.Revision)
+	})
+
 	t.Run("WatchRuleset/Cancel", func(t *testing.T) {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
@@ -0,0 +1,210 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/heetch/regula"
+	"github.com/heetch/regula/rule"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Client evaluates rulesets using the gRPC API.
+// It implements the regula.Evaluator interface and thus can be passed to the regula.Engine.
+type Client struct {
+	// Rulesets gives access to the whole gRPC API.
+	// The token of the client is sent along with every call.
+	Rulesets RulesetsClient
+
+	conn      *grpc.ClientConn
+	namespace string
+	token     string
+}
+
+// Dial connects to the gRPC API listening on the given address.
+// The connection is closed by the Close method.
+func Dial(target string, dialOpts []grpc.DialOption, opts ...ClientOption) (*Client, error) {
+	conn, err := grpc.Dial(target, dialOpts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to dial %s", target)
+	}
+
+	c := NewClient(conn, opts...)
+	c.conn = conn
+	return c, nil
+}
+
+// NewClient creates a client using the given connection.
+func NewClient(conn *grpc.ClientConn, opts ...ClientOption) *Client {
+	var c Client
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	c.Rulesets = NewRulesetsClient(conn)
+	if c.token != "" {
+		c.Rulesets = &tokenClient{RulesetsClient: c.Rulesets, token: c.token}
+	}
+
+	return &c
+}
+
+// ClientOption allows Client customization.
+type ClientOption func(*Client)
+
+// Token sets the bearer token used to authenticate the requests, either a static token or a JWT.
+func Token(token string) ClientOption {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// Namespace makes the client evaluate the rulesets of the given namespace
+// instead of the default ones of the server.
+func Namespace(name string) ClientOption {
+	return func(c *Client) {
+		c.namespace = name
+	}
+}
+
+// Close closes the connection opened by Dial. It does nothing if the client was created by NewClient.
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+
+	return c.conn.Close()
+}
+
+// Eval evaluates the latest version of the given ruleset with the given params.
+func (c *Client) Eval(ctx context.Context, path string, params rule.Params) (*regula.EvalResult, error) {
+	req, err := c.evalRequest(path, "", params)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Rulesets.Eval(ctx, req)
+	return evalResult(resp, err)
+}
+
+// EvalVersion evaluates the given version of the ruleset with the given params.
+func (c *Client) EvalVersion(ctx context.Context, path, version string, params rule.Params) (*regula.EvalResult, error) {
+	req, err := c.evalRequest(path, version, params)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Rulesets.EvalVersion(ctx, req)
+	return evalResult(resp, err)
+}
+
+func (c *Client) evalRequest(path, version string, params rule.Params) (*EvalRequest, error) {
+	req := EvalRequest{
+		Path:      path,
+		Version:   version,
+		Namespace: c.namespace,
+	}
+
+	if params == nil {
+		return &req, nil
+	}
+
+	typed, _ := params.(regula.Params)
+
+	for _, k := range params.Keys() {
+		p := Param{Name: k}
+
+		switch v := typed[k].(type) {
+		case string:
+			p.Value = &Param_StringValue{StringValue: v}
+		case bool:
+			p.Value = &Param_BoolValue{BoolValue: v}
+		case int64:
+			p.Value = &Param_Int64Value{Int64Value: v}
+		case float64:
+			p.Value = &Param_Float64Value{Float64Value: v}
+		default:
+			// the type of the other implementations is unknown, send the string representation.
+			s, err := params.EncodeValue(k)
+			if err != nil {
+				return nil, err
+			}
+			p.Value = &Param_StringValue{StringValue: s}
+		}
+
+		req.Params = append(req.Params, &p)
+	}
+
+	return &req, nil
+}
+
+// evalResult converts the response of an evaluation, returning the errors of the regula
+// and rule packages so that callers can compare them.
+func evalResult(resp *EvalResponse, err error) (*regula.EvalResult, error) {
+	if err != nil {
+		st, ok := status.FromError(err)
+		if !ok {
+			return nil, err
+		}
+
+		switch st.Code() {
+		case codes.NotFound:
+			return nil, regula.ErrRulesetNotFound
+		case codes.InvalidArgument:
+			for _, e := range []error{rule.ErrNoMatch, rule.ErrParamNotFound, rule.ErrParamTypeMismatch} {
+				if st.Message() == e.Error() {
+					return nil, e
+				}
+			}
+		}
+
+		return nil, err
+	}
+
+	i := int(resp.Rule)
+
+	return &regula.EvalResult{
+		Value: &rule.Value{
+			Kind: "value",
+			Type: resp.GetValue().GetType(),
+			Data: resp.GetValue().GetData(),
+		},
+		Version: resp.Version,
+		Rule:    &i,
+	}, nil
+}
+
+// tokenClient sends a bearer token in the metadata of every call.
+type tokenClient struct {
+	RulesetsClient
+
+	token string
+}
+
+func (c *tokenClient) context(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+c.token)
+}
+
+func (c *tokenClient) Eval(ctx context.Context, in *EvalRequest, opts ...grpc.CallOption) (*EvalResponse, error) {
+	return c.RulesetsClient.Eval(c.context(ctx), in, opts...)
+}
+
+func (c *tokenClient) EvalVersion(ctx context.Context, in *EvalRequest, opts ...grpc.CallOption) (*EvalResponse, error) {
+	return c.RulesetsClient.EvalVersion(c.context(ctx), in, opts...)
+}
+
+func (c *tokenClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error) {
+	return c.RulesetsClient.List(c.context(ctx), in, opts...)
+}
+
+func (c *tokenClient) Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*Ruleset, error) {
+	return c.RulesetsClient.Put(c.context(ctx), in, opts...)
+}
+
+func (c *tokenClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Rulesets_WatchClient, error) {
+	return c.RulesetsClient.Watch(c.context(ctx), in, opts...)
+}
@@ -0,0 +1,1120 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: regula.proto
+
+package rpc
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+// Param is a typed evaluation parameter.
+type Param struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Types that are valid to be assigned to Value:
+	//	*Param_StringValue
+	//	*Param_BoolValue
+	//	*Param_Int64Value
+	//	*Param_Float64Value
+	Value                isParam_Value `protobuf_oneof:"value"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *Param) Reset()         { *m = Param{} }
+func (m *Param) String() string { return proto.CompactTextString(m) }
+func (*Param) ProtoMessage()    {}
+func (*Param) Descriptor() ([]byte, []int) {
+	return fileDescriptor_regula_78d74a02fe598444, []int{0}
+}
+func (m *Param) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Param.Unmarshal(m, b)
+}
+func (m *Param) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Param.Marshal(b, m, deterministic)
+}
+func (dst *Param) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Param.Merge(dst, src)
+}
+func (m *Param) XXX_Size() int {
+	return xxx_messageInfo_Param.Size(m)
+}
+func (m *Param) XXX_DiscardUnknown() {
+	xxx_messageInfo_Param.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Param proto.InternalMessageInfo
+
+func (m *Param) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type isParam_Value interface {
+	isParam_Value()
+}
+
+type Param_StringValue struct {
+	StringValue string `protobuf:"bytes,2,opt,name=string_value,json=stringValue,proto3,oneof"`
+}
+
+type Param_BoolValue struct {
+	BoolValue bool `protobuf:"varint,3,opt,name=bool_value,json=boolValue,proto3,oneof"`
+}
+
+type Param_Int64Value struct {
+	Int64Value int64 `protobuf:"varint,4,opt,name=int64_value,json=int64Value,proto3,oneof"`
+}
+
+type Param_Float64Value struct {
+	Float64Value float64 `protobuf:"fixed64,5,opt,name=float64_value,json=float64Value,proto3,oneof"`
+}
+
+func (*Param_StringValue) isParam_Value() {}
+
+func (*Param_BoolValue) isParam_Value() {}
+
+func (*Param_Int64Value) isParam_Value() {}
+
+func (*Param_Float64Value) isParam_Value() {}
+
+func (m *Param) GetValue() isParam_Value {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *Param) GetStringValue() string {
+	if x, ok := m.GetValue().(*Param_StringValue); ok {
+		return x.StringValue
+	}
+	return ""
+}
+
+func (m *Param) GetBoolValue() bool {
+	if x, ok := m.GetValue().(*Param_BoolValue); ok {
+		return x.BoolValue
+	}
+	return false
+}
+
+func (m *Param) GetInt64Value() int64 {
+	if x, ok := m.GetValue().(*Param_Int64Value); ok {
+		return x.Int64Value
+	}
+	return 0
+}
+
+func (m *Param) GetFloat64Value() float64 {
+	if x, ok := m.GetValue().(*Param_Float64Value); ok {
+		return x.Float64Value
+	}
+	return 0
+}
+
+// XXX_OneofFuncs is for the internal use of the proto package.
+func (*Param) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
+	return _Param_OneofMarshaler, _Param_OneofUnmarshaler, _Param_OneofSizer, []interface{}{
+		(*Param_StringValue)(nil),
+		(*Param_BoolValue)(nil),
+		(*Param_Int64Value)(nil),
+		(*Param_Float64Value)(nil),
+	}
+}
+
+func _Param_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
+	m := msg.(*Param)
+	// value
+	switch x := m.Value.(type) {
+	case *Param_StringValue:
+		b.EncodeVarint(2<<3 | proto.WireBytes)
+		b.EncodeStringBytes(x.StringValue)
+	case *Param_BoolValue:
+		t := uint64(0)
+		if x.BoolValue {
+			t = 1
+		}
+		b.EncodeVarint(3<<3 | proto.WireVarint)
+		b.EncodeVarint(t)
+	case *Param_Int64Value:
+		b.EncodeVarint(4<<3 | proto.WireVarint)
+		b.EncodeVarint(uint64(x.Int64Value))
+	case *Param_Float64Value:
+		b.EncodeVarint(5<<3 | proto.WireFixed64)
+		b.EncodeFixed64(math.Float64bits(x.Float64Value))
+	case nil:
+	default:
+		return fmt.Errorf("Param.Value has unexpected type %T", x)
+	}
+	return nil
+}
+
+func _Param_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error) {
+	m := msg.(*Param)
+	switch tag {
+	case 2: // value.string_value
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeStringBytes()
+		m.Value = &Param_StringValue{x}
+		return true, err
+	case 3: // value.bool_value
+		if wire != proto.WireVarint {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeVarint()
+		m.Value = &Param_BoolValue{x != 0}
+		return true, err
+	case 4: // value.int64_value
+		if wire != proto.WireVarint {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeVarint()
+		m.Value = &Param_Int64Value{int64(x)}
+		return true, err
+	case 5: // value.float64_value
+		if wire != proto.WireFixed64 {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeFixed64()
+		m.Value = &Param_Float64Value{math.Float64frombits(x)}
+		return true, err
+	default:
+		return false, nil
+	}
+}
+
+func _Param_OneofSizer(msg proto.Message) (n int) {
+	m := msg.(*Param)
+	// value
+	switch x := m.Value.(type) {
+	case *Param_StringValue:
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(len(x.StringValue)))
+		n += len(x.StringValue)
+	case *Param_BoolValue:
+		n += 1 // tag and wire
+		n += 1
+	case *Param_Int64Value:
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(x.Int64Value))
+	case *Param_Float64Value:
+		n += 1 // tag and wire
+		n += 8
+	case nil:
+	default:
+		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
+	}
+	return n
+}
+
+type EvalRequest struct {
+	Path                 string   `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Version              string   `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	Params               []*Param `protobuf:"bytes,3,rep,name=params,proto3" json:"params,omitempty"`
+	Namespace            string   `protobuf:"bytes,4,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *EvalRequest) Reset()         { *m = EvalRequest{} }
+func (m *EvalRequest) String() string { return proto.CompactTextString(m) }
+func (*EvalRequest) ProtoMessage()    {}
+func (*EvalRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_regula_78d74a02fe598444, []int{1}
+}
+func (m *EvalRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EvalRequest.Unmarshal(m, b)
+}
+func (m *EvalRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EvalRequest.Marshal(b, m, deterministic)
+}
+func (dst *EvalRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EvalRequest.Merge(dst, src)
+}
+func (m *EvalRequest) XXX_Size() int {
+	return xxx_messageInfo_EvalRequest.Size(m)
+}
+func (m *EvalRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_EvalRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EvalRequest proto.InternalMessageInfo
+
+func (m *EvalRequest) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *EvalRequest) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *EvalRequest) GetParams() []*Param {
+	if m != nil {
+		return m.Params
+	}
+	return nil
+}
+
+func (m *EvalRequest) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+// Value is the result of an evaluation.
+type Value struct {
+	Type                 string   `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Data                 string   `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Value) Reset()         { *m = Value{} }
+func (m *Value) String() string { return proto.CompactTextString(m) }
+func (*Value) ProtoMessage()    {}
+func (*Value) Descriptor() ([]byte, []int) {
+	return fileDescriptor_regula_78d74a02fe598444, []int{2}
+}
+func (m *Value) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Value.Unmarshal(m, b)
+}
+func (m *Value) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Value.Marshal(b, m, deterministic)
+}
+func (dst *Value) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Value.Merge(dst, src)
+}
+func (m *Value) XXX_Size() int {
+	return xxx_messageInfo_Value.Size(m)
+}
+func (m *Value) XXX_DiscardUnknown() {
+	xxx_messageInfo_Value.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Value proto.InternalMessageInfo
+
+func (m *Value) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *Value) GetData() string {
+	if m != nil {
+		return m.Data
+	}
+	return ""
+}
+
+type EvalResponse struct {
+	Value   *Value `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Version string `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	// Index of the rule that matched.
+	Rule                 int32    `protobuf:"varint,3,opt,name=rule,proto3" json:"rule,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *EvalResponse) Reset()         { *m = EvalResponse{} }
+func (m *EvalResponse) String() string { return proto.CompactTextString(m) }
+func (*EvalResponse) ProtoMessage()    {}
+func (*EvalResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_regula_78d74a02fe598444, []int{3}
+}
+func (m *EvalResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EvalResponse.Unmarshal(m, b)
+}
+func (m *EvalResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EvalResponse.Marshal(b, m, deterministic)
+}
+func (dst *EvalResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EvalResponse.Merge(dst, src)
+}
+func (m *EvalResponse) XXX_Size() int {
+	return xxx_messageInfo_EvalResponse.Size(m)
+}
+func (m *EvalResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_EvalResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EvalResponse proto.InternalMessageInfo
+
+func (m *EvalResponse) GetValue() *Value {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *EvalResponse) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *EvalResponse) GetRule() int32 {
+	if m != nil {
+		return m.Rule
+	}
+	return 0
+}
+
+type ListRequest struct {
+	Prefix               string   `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	Limit                int32    `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	Continue             string   `protobuf:"bytes,3,opt,name=continue,proto3" json:"continue,omitempty"`
+	LatestOnly           bool     `protobuf:"varint,4,opt,name=latest_only,json=latestOnly,proto3" json:"latest_only,omitempty"`
+	Namespace            string   `protobuf:"bytes,5,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListRequest) Reset()         { *m = ListRequest{} }
+func (m *ListRequest) String() string { return proto.CompactTextString(m) }
+func (*ListRequest) ProtoMessage()    {}
+func (*ListRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_regula_78d74a02fe598444, []int{4}
+}
+func (m *ListRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListRequest.Unmarshal(m, b)
+}
+func (m *ListRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListRequest.Marshal(b, m, deterministic)
+}
+func (dst *ListRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListRequest.Merge(dst, src)
+}
+func (m *ListRequest) XXX_Size() int {
+	return xxx_messageInfo_ListRequest.Size(m)
+}
+func (m *ListRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListRequest proto.InternalMessageInfo
+
+func (m *ListRequest) GetPrefix() string {
+	if m != nil {
+		return m.Prefix
+	}
+	return ""
+}
+
+func (m *ListRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *ListRequest) GetContinue() string {
+	if m != nil {
+		return m.Continue
+	}
+	return ""
+}
+
+func (m *ListRequest) GetLatestOnly() bool {
+	if m != nil {
+		return m.LatestOnly
+	}
+	return false
+}
+
+func (m *ListRequest) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+type Ruleset struct {
+	Path    string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Version string `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	// JSON encoded regula.Ruleset.
+	Ruleset              []byte   `protobuf:"bytes,3,opt,name=ruleset,proto3" json:"ruleset,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Ruleset) Reset()         { *m = Ruleset{} }
+func (m *Ruleset) String() string { return proto.CompactTextString(m) }
+func (*Ruleset) ProtoMessage()    {}
+func (*Ruleset) Descriptor() ([]byte, []int) {
+	return fileDescriptor_regula_78d74a02fe598444, []int{5}
+}
+func (m *Ruleset) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Ruleset.Unmarshal(m, b)
+}
+func (m *Ruleset) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Ruleset.Marshal(b, m, deterministic)
+}
+func (dst *Ruleset) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Ruleset.Merge(dst, src)
+}
+func (m *Ruleset) XXX_Size() int {
+	return xxx_messageInfo_Ruleset.Size(m)
+}
+func (m *Ruleset) XXX_DiscardUnknown() {
+	xxx_messageInfo_Ruleset.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Ruleset proto.InternalMessageInfo
+
+func (m *Ruleset) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *Ruleset) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *Ruleset) GetRuleset() []byte {
+	if m != nil {
+		return m.Ruleset
+	}
+	return nil
+}
+
+type ListResponse struct {
+	Rulesets             []*Ruleset `protobuf:"bytes,1,rep,name=rulesets,proto3" json:"rulesets,omitempty"`
+	Revision             string     `protobuf:"bytes,2,opt,name=revision,proto3" json:"revision,omitempty"`
+	Continue             string     `protobuf:"bytes,3,opt,name=continue,proto3" json:"continue,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *ListResponse) Reset()         { *m = ListResponse{} }
+func (m *ListResponse) String() string { return proto.CompactTextString(m) }
+func (*ListResponse) ProtoMessage()    {}
+func (*ListResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_regula_78d74a02fe598444, []int{6}
+}
+func (m *ListResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListResponse.Unmarshal(m, b)
+}
+func (m *ListResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListResponse.Marshal(b, m, deterministic)
+}
+func (dst *ListResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListResponse.Merge(dst, src)
+}
+func (m *ListResponse) XXX_Size() int {
+	return xxx_messageInfo_ListResponse.Size(m)
+}
+func (m *ListResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListResponse proto.InternalMessageInfo
+
+func (m *ListResponse) GetRulesets() []*Ruleset {
+	if m != nil {
+		return m.Rulesets
+	}
+	return nil
+}
+
+func (m *ListResponse) GetRevision() string {
+	if m != nil {
+		return m.Revision
+	}
+	return ""
+}
+
+func (m *ListResponse) GetContinue() string {
+	if m != nil {
+		return m.Continue
+	}
+	return ""
+}
+
+type PutRequest struct {
+	Path string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	// JSON encoded regula.Ruleset.
+	Ruleset []byte `protobuf:"bytes,2,opt,name=ruleset,proto3" json:"ruleset,omitempty"`
+	// If set, the ruleset is only stored if it is still the latest version.
+	ExpectedVersion      string   `protobuf:"bytes,3,opt,name=expected_version,json=expectedVersion,proto3" json:"expected_version,omitempty"`
+	Author               string   `protobuf:"bytes,4,opt,name=author,proto3" json:"author,omitempty"`
+	Message              string   `protobuf:"bytes,5,opt,name=message,proto3" json:"message,omitempty"`
+	Namespace            string   `protobuf:"bytes,6,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PutRequest) Reset()         { *m = PutRequest{} }
+func (m *PutRequest) String() string { return proto.CompactTextString(m) }
+func (*PutRequest) ProtoMessage()    {}
+func (*PutRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_regula_78d74a02fe598444, []int{7}
+}
+func (m *PutRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PutRequest.Unmarshal(m, b)
+}
+func (m *PutRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PutRequest.Marshal(b, m, deterministic)
+}
+func (dst *PutRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PutRequest.Merge(dst, src)
+}
+func (m *PutRequest) XXX_Size() int {
+	return xxx_messageInfo_PutRequest.Size(m)
+}
+func (m *PutRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PutRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PutRequest proto.InternalMessageInfo
+
+func (m *PutRequest) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *PutRequest) GetRuleset() []byte {
+	if m != nil {
+		return m.Ruleset
+	}
+	return nil
+}
+
+func (m *PutRequest) GetExpectedVersion() string {
+	if m != nil {
+		return m.ExpectedVersion
+	}
+	return ""
+}
+
+func (m *PutRequest) GetAuthor() string {
+	if m != nil {
+		return m.Author
+	}
+	return ""
+}
+
+func (m *PutRequest) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+func (m *PutRequest) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+type WatchRequest struct {
+	Prefix string `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	// Revision from which to stream the changes, the ones occurring after the call if empty.
+	Revision             string   `protobuf:"bytes,2,opt,name=revision,proto3" json:"revision,omitempty"`
+	Namespace            string   `protobuf:"bytes,3,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchRequest) Reset()         { *m = WatchRequest{} }
+func (m *WatchRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchRequest) ProtoMessage()    {}
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_regula_78d74a02fe598444, []int{8}
+}
+func (m *WatchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchRequest.Unmarshal(m, b)
+}
+func (m *WatchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchRequest.Marshal(b, m, deterministic)
+}
+func (dst *WatchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchRequest.Merge(dst, src)
+}
+func (m *WatchRequest) XXX_Size() int {
+	return xxx_messageInfo_WatchRequest.Size(m)
+}
+func (m *WatchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchRequest proto.InternalMessageInfo
+
+func (m *WatchRequest) GetPrefix() string {
+	if m != nil {
+		return m.Prefix
+	}
+	return ""
+}
+
+func (m *WatchRequest) GetRevision() string {
+	if m != nil {
+		return m.Revision
+	}
+	return ""
+}
+
+func (m *WatchRequest) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+type Event struct {
+	// PUT or DELETE.
+	Type    string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Path    string `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	Version string `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
+	// JSON encoded regula.Ruleset, empty for deletions.
+	Ruleset              []byte   `protobuf:"bytes,4,opt,name=ruleset,proto3" json:"ruleset,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Event) Reset()         { *m = Event{} }
+func (m *Event) String() string { return proto.CompactTextString(m) }
+func (*Event) ProtoMessage()    {}
+func (*Event) Descriptor() ([]byte, []int) {
+	return fileDescriptor_regula_78d74a02fe598444, []int{9}
+}
+func (m *Event) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Event.Unmarshal(m, b)
+}
+func (m *Event) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Event.Marshal(b, m, deterministic)
+}
+func (dst *Event) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Event.Merge(dst, src)
+}
+func (m *Event) XXX_Size() int {
+	return xxx_messageInfo_Event.Size(m)
+}
+func (m *Event) XXX_DiscardUnknown() {
+	xxx_messageInfo_Event.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Event proto.InternalMessageInfo
+
+func (m *Event) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *Event) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *Event) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *Event) GetRuleset() []byte {
+	if m != nil {
+		return m.Ruleset
+	}
+	return nil
+}
+
+type WatchResponse struct {
+	Events               []*Event `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
+	Revision             string   `protobuf:"bytes,2,opt,name=revision,proto3" json:"revision,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchResponse) Reset()         { *m = WatchResponse{} }
+func (m *WatchResponse) String() string { return proto.CompactTextString(m) }
+func (*WatchResponse) ProtoMessage()    {}
+func (*WatchResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_regula_78d74a02fe598444, []int{10}
+}
+func (m *WatchResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchResponse.Unmarshal(m, b)
+}
+func (m *WatchResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchResponse.Marshal(b, m, deterministic)
+}
+func (dst *WatchResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchResponse.Merge(dst, src)
+}
+func (m *WatchResponse) XXX_Size() int {
+	return xxx_messageInfo_WatchResponse.Size(m)
+}
+func (m *WatchResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchResponse proto.InternalMessageInfo
+
+func (m *WatchResponse) GetEvents() []*Event {
+	if m != nil {
+		return m.Events
+	}
+	return nil
+}
+
+func (m *WatchResponse) GetRevision() string {
+	if m != nil {
+		return m.Revision
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*Param)(nil), "regula.Param")
+	proto.RegisterType((*EvalRequest)(nil), "regula.EvalRequest")
+	proto.RegisterType((*Value)(nil), "regula.Value")
+	proto.RegisterType((*EvalResponse)(nil), "regula.EvalResponse")
+	proto.RegisterType((*ListRequest)(nil), "regula.ListRequest")
+	proto.RegisterType((*Ruleset)(nil), "regula.Ruleset")
+	proto.RegisterType((*ListResponse)(nil), "regula.ListResponse")
+	proto.RegisterType((*PutRequest)(nil), "regula.PutRequest")
+	proto.RegisterType((*WatchRequest)(nil), "regula.WatchRequest")
+	proto.RegisterType((*Event)(nil), "regula.Event")
+	proto.RegisterType((*WatchResponse)(nil), "regula.WatchResponse")
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// RulesetsClient is the client API for Rulesets service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type RulesetsClient interface {
+	// Eval evaluates the latest version of a ruleset.
+	Eval(ctx context.Context, in *EvalRequest, opts ...grpc.CallOption) (*EvalResponse, error)
+	// EvalVersion evaluates the given version of a ruleset.
+	EvalVersion(ctx context.Context, in *EvalRequest, opts ...grpc.CallOption) (*EvalResponse, error)
+	// List returns the rulesets under a prefix.
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
+	// Put creates a new version of a ruleset.
+	Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*Ruleset, error)
+	// Watch streams the changes made to the rulesets under a prefix.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Rulesets_WatchClient, error)
+}
+
+type rulesetsClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewRulesetsClient(cc *grpc.ClientConn) RulesetsClient {
+	return &rulesetsClient{cc}
+}
+
+func (c *rulesetsClient) Eval(ctx context.Context, in *EvalRequest, opts ...grpc.CallOption) (*EvalResponse, error) {
+	out := new(EvalResponse)
+	err := c.cc.Invoke(ctx, "/regula.Rulesets/Eval", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rulesetsClient) EvalVersion(ctx context.Context, in *EvalRequest, opts ...grpc.CallOption) (*EvalResponse, error) {
+	out := new(EvalResponse)
+	err := c.cc.Invoke(ctx, "/regula.Rulesets/EvalVersion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rulesetsClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error) {
+	out := new(ListResponse)
+	err := c.cc.Invoke(ctx, "/regula.Rulesets/List", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rulesetsClient) Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*Ruleset, error) {
+	out := new(Ruleset)
+	err := c.cc.Invoke(ctx, "/regula.Rulesets/Put", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rulesetsClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Rulesets_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Rulesets_serviceDesc.Streams[0], "/regula.Rulesets/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &rulesetsWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Rulesets_WatchClient interface {
+	Recv() (*WatchResponse, error)
+	grpc.ClientStream
+}
+
+type rulesetsWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *rulesetsWatchClient) Recv() (*WatchResponse, error) {
+	m := new(WatchResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// RulesetsServer is the server API for Rulesets service.
+type RulesetsServer interface {
+	// Eval evaluates the latest version of a ruleset.
+	Eval(context.Context, *EvalRequest) (*EvalResponse, error)
+	// EvalVersion evaluates the given version of a ruleset.
+	EvalVersion(context.Context, *EvalRequest) (*EvalResponse, error)
+	// List returns the rulesets under a prefix.
+	List(context.Context, *ListRequest) (*ListResponse, error)
+	// Put creates a new version of a ruleset.
+	Put(context.Context, *PutRequest) (*Ruleset, error)
+	// Watch streams the changes made to the rulesets under a prefix.
+	Watch(*WatchRequest, Rulesets_WatchServer) error
+}
+
+func RegisterRulesetsServer(s *grpc.Server, srv RulesetsServer) {
+	s.RegisterService(&_Rulesets_serviceDesc, srv)
+}
+
+func _Rulesets_Eval_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EvalRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RulesetsServer).Eval(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/regula.Rulesets/Eval",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RulesetsServer).Eval(ctx, req.(*EvalRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Rulesets_EvalVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EvalRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RulesetsServer).EvalVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/regula.Rulesets/EvalVersion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RulesetsServer).EvalVersion(ctx, req.(*EvalRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Rulesets_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RulesetsServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/regula.Rulesets/List",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RulesetsServer).List(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Rulesets_Put_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RulesetsServer).Put(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/regula.Rulesets/Put",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RulesetsServer).Put(ctx, req.(*PutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Rulesets_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RulesetsServer).Watch(m, &rulesetsWatchServer{stream})
+}
+
+type Rulesets_WatchServer interface {
+	Send(*WatchResponse) error
+	grpc.ServerStream
+}
+
+type rulesetsWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *rulesetsWatchServer) Send(m *WatchResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Rulesets_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "regula.Rulesets",
+	HandlerType: (*RulesetsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Eval",
+			Handler:    _Rulesets_Eval_Handler,
+		},
+		{
+			MethodName: "EvalVersion",
+			Handler:    _Rulesets_EvalVersion_Handler,
+		},
+		{
+			MethodName: "List",
+			Handler:    _Rulesets_List_Handler,
+		},
+		{
+			MethodName: "Put",
+			Handler:    _Rulesets_Put_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _Rulesets_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "regula.proto",
+}
+
+func init() { proto.RegisterFile("regula.proto", fileDescriptor_regula_78d74a02fe598444) }
+
+var fileDescriptor_regula_78d74a02fe598444 = []byte{
+	// 645 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x55, 0x4d, 0x6b, 0xdb, 0x4c,
+	0x10, 0xd6, 0x5a, 0x1f, 0xb6, 0x47, 0x0a, 0x79, 0xd9, 0x37, 0x0d, 0xc2, 0x14, 0xe2, 0xaa, 0x04,
+	0x5c, 0x5a, 0xd2, 0x92, 0x94, 0x52, 0x7a, 0x0c, 0x04, 0x7c, 0x28, 0x34, 0xdd, 0x43, 0x0a, 0xbd,
+	0xa4, 0x1b, 0x65, 0x13, 0x0b, 0x64, 0x49, 0xd5, 0xae, 0x4c, 0x7c, 0xeb, 0xaf, 0x28, 0xfd, 0x21,
+	0xed, 0xff, 0x2b, 0xfb, 0x25, 0x5b, 0xa6, 0x71, 0xc8, 0x6d, 0xe7, 0x99, 0xd9, 0x99, 0x67, 0x9e,
+	0x99, 0x95, 0x20, 0xaa, 0xd9, 0x6d, 0x93, 0xd3, 0xa3, 0xaa, 0x2e, 0x45, 0x89, 0x03, 0x6d, 0x25,
+	0xbf, 0x11, 0xf8, 0xe7, 0xb4, 0xa6, 0x73, 0x8c, 0xc1, 0x2b, 0xe8, 0x9c, 0xc5, 0x68, 0x8c, 0x26,
+	0x43, 0xa2, 0xce, 0xf8, 0x39, 0x44, 0x5c, 0xd4, 0x59, 0x71, 0x7b, 0xb9, 0xa0, 0x79, 0xc3, 0xe2,
+	0x9e, 0xf4, 0x4d, 0x1d, 0x12, 0x6a, 0xf4, 0x42, 0x82, 0xf8, 0x00, 0xe0, 0xaa, 0x2c, 0x73, 0x13,
+	0xe2, 0x8e, 0xd1, 0x64, 0x30, 0x75, 0xc8, 0x50, 0x62, 0x3a, 0xe0, 0x19, 0x84, 0x59, 0x21, 0xde,
+	0xbd, 0x35, 0x11, 0xde, 0x18, 0x4d, 0xdc, 0xa9, 0x43, 0x40, 0x81, 0x3a, 0xe4, 0x10, 0x76, 0x6e,
+	0xf2, 0x92, 0xae, 0x82, 0xfc, 0x31, 0x9a, 0xa0, 0xa9, 0x43, 0x22, 0x03, 0xab, 0xb0, 0xd3, 0x3e,
+	0xf8, 0xca, 0x9d, 0xfc, 0x40, 0x10, 0x9e, 0x2d, 0x68, 0x4e, 0xd8, 0xf7, 0x86, 0x71, 0x21, 0xc9,
+	0x57, 0x54, 0xcc, 0x2c, 0x79, 0x79, 0xc6, 0x31, 0xf4, 0x17, 0xac, 0xe6, 0x59, 0x59, 0x68, 0xde,
+	0xc4, 0x9a, 0xf8, 0x10, 0x82, 0x4a, 0xf6, 0xcc, 0x63, 0x77, 0xec, 0x4e, 0xc2, 0xe3, 0x9d, 0x23,
+	0xa3, 0x8d, 0x52, 0x82, 0x18, 0x27, 0x7e, 0x0a, 0x43, 0xa9, 0x02, 0xaf, 0x68, 0xaa, 0x59, 0x0f,
+	0xc9, 0x0a, 0x48, 0x5e, 0x83, 0xaf, 0xb9, 0x63, 0xf0, 0xc4, 0xb2, 0x6a, 0x85, 0x93, 0x67, 0x89,
+	0x5d, 0x53, 0x41, 0x4d, 0x61, 0x75, 0x4e, 0x28, 0x44, 0x9a, 0x32, 0xaf, 0xca, 0x82, 0x4b, 0x71,
+	0x75, 0x33, 0xea, 0xe2, 0x1a, 0x09, 0x95, 0x95, 0x68, 0xdf, 0x96, 0x26, 0x30, 0x78, 0x75, 0x93,
+	0x6b, 0xc1, 0x7d, 0xa2, 0xce, 0xc9, 0x4f, 0x04, 0xe1, 0xc7, 0x8c, 0x0b, 0x2b, 0xcb, 0x3e, 0x04,
+	0x55, 0xcd, 0x6e, 0xb2, 0x3b, 0x43, 0xce, 0x58, 0x78, 0x0f, 0xfc, 0x3c, 0x9b, 0x67, 0x42, 0xe5,
+	0xf4, 0x89, 0x36, 0xf0, 0x08, 0x06, 0x69, 0x59, 0x88, 0xac, 0x30, 0x63, 0x1c, 0x92, 0xd6, 0xc6,
+	0x07, 0x10, 0xe6, 0x54, 0x30, 0x2e, 0x2e, 0xcb, 0x22, 0x5f, 0x2a, 0x35, 0x06, 0x04, 0x34, 0xf4,
+	0xa9, 0xc8, 0x97, 0x5d, 0xb1, 0xfc, 0x4d, 0xb1, 0x3e, 0x43, 0x9f, 0x34, 0x39, 0xe3, 0xec, 0xb1,
+	0xa3, 0x8a, 0xa1, 0x5f, 0xeb, 0x8b, 0x8a, 0x52, 0x44, 0xac, 0x99, 0x70, 0x88, 0x74, 0xab, 0x46,
+	0xce, 0x97, 0x30, 0x30, 0x2e, 0x1e, 0x23, 0x35, 0xd6, 0x5d, 0xab, 0xa8, 0x29, 0x4d, 0xda, 0x00,
+	0xd9, 0x6a, 0xcd, 0x16, 0xd9, 0x5a, 0xc5, 0xd6, 0xde, 0x26, 0x43, 0xf2, 0x07, 0x01, 0x9c, 0x37,
+	0xe2, 0x81, 0xb5, 0xb3, 0x8c, 0x7b, 0x1d, 0xc6, 0xf8, 0x05, 0xfc, 0xc7, 0xee, 0x2a, 0x96, 0x0a,
+	0x76, 0x7d, 0x69, 0xdb, 0xd5, 0x05, 0x76, 0x2d, 0x7e, 0x61, 0xda, 0xde, 0x87, 0x80, 0x36, 0x62,
+	0x56, 0xd6, 0x66, 0xef, 0x8c, 0x25, 0x93, 0xcf, 0x19, 0xe7, 0xf4, 0xd6, 0x6a, 0x6c, 0xcd, 0xae,
+	0xfe, 0xc1, 0xa6, 0xfe, 0xdf, 0x20, 0xfa, 0x42, 0x45, 0x3a, 0x7b, 0x68, 0x31, 0xb6, 0xe9, 0xd2,
+	0xa9, 0xe0, 0x6e, 0x56, 0x48, 0xc1, 0x3f, 0x5b, 0xb0, 0x42, 0xdc, 0xf7, 0x1c, 0x94, 0x4e, 0xbd,
+	0x7f, 0xcf, 0xdc, 0xbd, 0x77, 0xe6, 0x5e, 0x77, 0xe6, 0x04, 0x76, 0x4c, 0x1b, 0x66, 0xe8, 0x87,
+	0x10, 0x30, 0x59, 0xd5, 0x8e, 0xbc, 0x7d, 0x44, 0x8a, 0x0b, 0x31, 0xce, 0x6d, 0x6d, 0x1d, 0xff,
+	0xea, 0xc1, 0x80, 0xd8, 0xbd, 0x38, 0x01, 0x4f, 0xbe, 0x51, 0xfc, 0xff, 0x2a, 0x4f, 0xfb, 0x91,
+	0x19, 0xed, 0x75, 0x41, 0x4d, 0x21, 0x71, 0xf0, 0x07, 0xfd, 0x2d, 0xb2, 0xb3, 0x7b, 0xd4, 0xdd,
+	0x13, 0xf0, 0xe4, 0x16, 0xaf, 0x2e, 0xad, 0x3d, 0xdf, 0xd1, 0x5e, 0x17, 0x6c, 0x2f, 0xbd, 0x02,
+	0xf7, 0xbc, 0x11, 0x18, 0xb7, 0x9f, 0xad, 0x76, 0x23, 0x47, 0x9b, 0x3b, 0x9f, 0x38, 0xf8, 0x3d,
+	0xf8, 0x4a, 0x34, 0xdc, 0xa6, 0x5b, 0x5f, 0x85, 0xd1, 0x93, 0x0d, 0xd4, 0x56, 0x79, 0x83, 0x4e,
+	0xfd, 0xaf, 0x6e, 0x5d, 0xa5, 0x57, 0x81, 0xfa, 0x65, 0x9c, 0xfc, 0x1d, 0x00, 0x9d, 0xad, 0x5b,
+	0x2e, 0x42, 0x06, 0x00, 0x00,
+}
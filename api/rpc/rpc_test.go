@@ -0,0 +1,306 @@
+package rpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"github.com/heetch/regula"
+	"github.com/heetch/regula/api/rpc"
+	"github.com/heetch/regula/api/server"
+	"github.com/heetch/regula/rule"
+	"github.com/heetch/regula/store"
+	"github.com/heetch/regula/store/memory"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// startServer starts a gRPC server serving the given rulesets and returns its address.
+func startServer(t *testing.T, rulesets store.RulesetService, cfg rpc.Config) (string, func()) {
+	log := zerolog.New(ioutil.Discard)
+	cfg.Logger = &log
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := rpc.NewServer(rulesets, cfg)
+	go srv.Serve(l)
+
+	return l.Addr().String(), srv.Stop
+}
+
+func dial(t *testing.T, addr string, opts ...rpc.ClientOption) *rpc.Client {
+	cli, err := rpc.Dial(addr, []grpc.DialOption{grpc.WithInsecure()}, opts...)
+	require.NoError(t, err)
+	return cli
+}
+
+func newRuleset(t *testing.T) *regula.Ruleset {
+	rs, err := regula.NewStringRuleset(
+		rule.New(rule.Eq(rule.StringParam("foo"), rule.StringValue("bar")), rule.StringValue("matched")),
+		rule.New(rule.Eq(rule.Int64Param("n"), rule.Int64Value(10)), rule.StringValue("ten")),
+	)
+	require.NoError(t, err)
+	return rs
+}
+
+func TestEval(t *testing.T) {
+	ctx := context.Background()
+	rulesets := memory.NewRulesetService()
+	entry, err := rulesets.Put(ctx, "a/b", newRuleset(t))
+	require.NoError(t, err)
+
+	addr, stop := startServer(t, rulesets, rpc.Config{})
+	defer stop()
+
+	cli := dial(t, addr)
+	defer cli.Close()
+
+	t.Run("OK", func(t *testing.T) {
+		res, err := cli.Eval(ctx, "a/b", regula.Params{"foo": "baz", "n": int64(10)})
+		require.NoError(t, err)
+		require.Equal(t, rule.StringValue("ten"), res.Value)
+		require.Equal(t, entry.Version, res.Version)
+		require.NotNil(t, res.Rule)
+		require.Equal(t, 1, *res.Rule)
+	})
+
+	t.Run("Version", func(t *testing.T) {
+		res, err := cli.EvalVersion(ctx, "a/b", entry.Version, regula.Params{"foo": "bar"})
+		require.NoError(t, err)
+		require.Equal(t, rule.StringValue("matched"), res.Value)
+		require.NotNil(t, res.Rule)
+		require.Equal(t, 0, *res.Rule)
+	})
+
+	t.Run("Engine", func(t *testing.T) {
+		s, res, err := regula.NewEngine(cli).GetString(ctx, "a/b", regula.Params{"foo": "bar"})
+		require.NoError(t, err)
+		require.Equal(t, "matched", s)
+		require.Equal(t, entry.Version, res.Version)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := cli.Eval(ctx, "a/c", nil)
+		require.Equal(t, regula.ErrRulesetNotFound, err)
+
+		_, err = cli.EvalVersion(ctx, "a/b", "unknown", nil)
+		require.Equal(t, regula.ErrRulesetNotFound, err)
+	})
+
+	t.Run("NoMatch", func(t *testing.T) {
+		_, err := cli.Eval(ctx, "a/b", regula.Params{"foo": "baz", "n": int64(1)})
+		require.Equal(t, rule.ErrNoMatch, err)
+	})
+
+	t.Run("TypeMismatch", func(t *testing.T) {
+		_, err := cli.Eval(ctx, "a/b", regula.Params{"foo": "baz", "n": "10"})
+		require.Equal(t, rule.ErrParamTypeMismatch, err)
+	})
+}
+
+func TestList(t *testing.T) {
+	ctx := context.Background()
+	rulesets := memory.NewRulesetService()
+	for _, path := range []string{"a/b", "a/c", "b/a"} {
+		_, err := rulesets.Put(ctx, path, newRuleset(t))
+		require.NoError(t, err)
+	}
+
+	addr, stop := startServer(t, rulesets, rpc.Config{})
+	defer stop()
+
+	cli := dial(t, addr)
+	defer cli.Close()
+
+	resp, err := cli.Rulesets.List(ctx, &rpc.ListRequest{Prefix: "a/", Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, resp.Rulesets, 1)
+	require.Equal(t, "a/b", resp.Rulesets[0].Path)
+	require.NotEmpty(t, resp.Continue)
+
+	var rs regula.Ruleset
+	require.NoError(t, json.Unmarshal(resp.Rulesets[0].Ruleset, &rs))
+	require.Equal(t, newRuleset(t), &rs)
+
+	resp, err = cli.Rulesets.List(ctx, &rpc.ListRequest{Prefix: "a/", Continue: resp.Continue})
+	require.NoError(t, err)
+	require.Len(t, resp.Rulesets, 1)
+	require.Equal(t, "a/c", resp.Rulesets[0].Path)
+
+	_, err = cli.Rulesets.List(ctx, &rpc.ListRequest{Continue: "bad"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestPut(t *testing.T) {
+	ctx := context.Background()
+	rulesets := memory.NewRulesetService()
+	addr, stop := startServer(t, rulesets, rpc.Config{})
+	defer stop()
+
+	cli := dial(t, addr)
+	defer cli.Close()
+
+	raw, err := json.Marshal(newRuleset(t))
+	require.NoError(t, err)
+
+	t.Run("OK", func(t *testing.T) {
+		rs, err := cli.Rulesets.Put(ctx, &rpc.PutRequest{Path: "a/b", Ruleset: raw, Author: "alice", Message: "first"})
+		require.NoError(t, err)
+		require.Equal(t, "a/b", rs.Path)
+		require.NotEmpty(t, rs.Version)
+
+		history, err := rulesets.History(ctx, "a/b", 0, "")
+		require.NoError(t, err)
+		require.Len(t, history.Entries, 1)
+		require.Equal(t, "alice", history.Entries[0].Author)
+		require.Equal(t, "first", history.Entries[0].Message)
+
+		// putting the same ruleset returns the latest version.
+		same, err := cli.Rulesets.Put(ctx, &rpc.PutRequest{Path: "a/b", Ruleset: raw})
+		require.NoError(t, err)
+		require.Equal(t, rs.Version, same.Version)
+	})
+
+	t.Run("VersionMismatch", func(t *testing.T) {
+		_, err := cli.Rulesets.Put(ctx, &rpc.PutRequest{Path: "a/b", Ruleset: raw, ExpectedVersion: "unknown"})
+		require.Equal(t, codes.Aborted, status.Code(err))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := cli.Rulesets.Put(ctx, &rpc.PutRequest{Path: "a/b", Ruleset: []byte("{")})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rulesets := memory.NewRulesetService()
+	_, err := rulesets.Put(ctx, "a/b", newRuleset(t))
+	require.NoError(t, err)
+
+	addr, stop := startServer(t, rulesets, rpc.Config{})
+	defer stop()
+
+	cli := dial(t, addr)
+	defer cli.Close()
+
+	list, err := cli.Rulesets.List(ctx, &rpc.ListRequest{Prefix: "a/"})
+	require.NoError(t, err)
+
+	stream, err := cli.Rulesets.Watch(ctx, &rpc.WatchRequest{Prefix: "a/", Revision: list.Revision})
+	require.NoError(t, err)
+
+	rs, err := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("other")))
+	require.NoError(t, err)
+
+	for _, path := range []string{"a/c", "a/d"} {
+		_, err = rulesets.Put(ctx, path, rs)
+		require.NoError(t, err)
+	}
+
+	var paths []string
+	for len(paths) < 2 {
+		resp, err := stream.Recv()
+		require.NoError(t, err)
+		require.NotEmpty(t, resp.Revision)
+
+		for _, ev := range resp.Events {
+			require.Equal(t, store.RulesetPutEvent, ev.Type)
+			paths = append(paths, ev.Path)
+		}
+	}
+	require.Equal(t, []string{"a/c", "a/d"}, paths)
+}
+
+func TestAuth(t *testing.T) {
+	ctx := context.Background()
+	rulesets := memory.NewRulesetService()
+	_, err := rulesets.Put(ctx, "a/b", newRuleset(t))
+	require.NoError(t, err)
+	_, err = rulesets.Put(ctx, "a/bc", newRuleset(t))
+	require.NoError(t, err)
+
+	ns := memory.NewNamespaceService()
+	require.NoError(t, ns.Create(ctx, "tenant"))
+	nsRulesets, err := ns.Rulesets(ctx, "tenant")
+	require.NoError(t, err)
+	_, err = nsRulesets.Put(ctx, "a/b", newRuleset(t))
+	require.NoError(t, err)
+
+	cfg := rpc.Config{
+		Namespaces: ns,
+		Auth: server.AuthConfig{
+			Tokens: map[string]string{"eval-token": "svc", "reader-token": "reader", "t-token": "t", "ab-token": "ab"},
+			Grants: map[string][]server.Grant{
+				"svc":    {{Prefix: "a/", Rights: []server.Right{server.RightEval}}},
+				"reader": {{Prefix: "", Rights: []server.Right{server.RightRead}}, {Prefix: "tenant:", Rights: []server.Right{server.RightRead}}},
+				"t":      {{Prefix: "t", Rights: []server.Right{server.RightEval}}},
+				"ab":     {{Prefix: "a/b", Rights: []server.Right{server.RightEval}}},
+			},
+		},
+	}
+
+	addr, stop := startServer(t, rulesets, cfg)
+	defer stop()
+
+	params := regula.Params{"foo": "bar"}
+
+	tests := []struct {
+		name string
+		opts []rpc.ClientOption
+		code codes.Code
+	}{
+		{"NoToken", nil, codes.Unauthenticated},
+		{"UnknownToken", []rpc.ClientOption{rpc.Token("unknown")}, codes.Unauthenticated},
+		{"MissingRight", []rpc.ClientOption{rpc.Token("reader-token")}, codes.PermissionDenied},
+		{"OK", []rpc.ClientOption{rpc.Token("eval-token")}, codes.OK},
+		// namespaced resources are prefixed by the namespace.
+		{"Namespace", []rpc.ClientOption{rpc.Token("eval-token"), rpc.Namespace("tenant")}, codes.PermissionDenied},
+		{"UnknownNamespace", []rpc.ClientOption{rpc.Token("eval-token"), rpc.Namespace("unknown")}, codes.PermissionDenied},
+		// grants of the default namespace don't match namespaces starting with the same prefix.
+		{"NamespacePrefix", []rpc.ClientOption{rpc.Token("t-token"), rpc.Namespace("tenant")}, codes.PermissionDenied},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cli := dial(t, addr, test.opts...)
+			defer cli.Close()
+
+			_, err := cli.Eval(ctx, "a/b", params)
+			require.Equal(t, test.code, status.Code(err))
+		})
+	}
+
+	t.Run("SiblingPrefix", func(t *testing.T) {
+		cli := dial(t, addr, rpc.Token("ab-token"))
+		defer cli.Close()
+
+		_, err := cli.Eval(ctx, "a/b", params)
+		require.NoError(t, err)
+
+		// grants match whole path segments.
+		_, err = cli.Eval(ctx, "a/bc", params)
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("Rulesets", func(t *testing.T) {
+		cli := dial(t, addr, rpc.Token("reader-token"))
+		defer cli.Close()
+
+		resp, err := cli.Rulesets.List(ctx, &rpc.ListRequest{Namespace: "tenant"})
+		require.NoError(t, err)
+		require.Len(t, resp.Rulesets, 1)
+
+		// grants without a namespace don't match the namespaced rulesets.
+		_, err = cli.Rulesets.List(ctx, &rpc.ListRequest{Namespace: "unknown"})
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+}
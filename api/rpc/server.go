@@ -0,0 +1,325 @@
+// Package rpc provides a gRPC API serving the rulesets of a store.RulesetService,
+// and a gRPC client implementing the regula.Evaluator interface.
+// The service is described in regula.proto.
+package rpc
+
+//go:generate protoc --go_out=plugins=grpc:. regula.proto
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/heetch/regula"
+	"github.com/heetch/regula/api/server"
+	"github.com/heetch/regula/rule"
+	"github.com/heetch/regula/store"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Config is used to configure the gRPC server.
+type Config struct {
+	Logger *zerolog.Logger
+	// Namespaces serves the rulesets of the namespaces. If nil, requests targeting a namespace are rejected.
+	Namespaces store.NamespaceService
+	// Auth configures the authentication and authorization of the requests, like for the HTTP API.
+	// Clients send their token in the "authorization" metadata, formatted as "Bearer <token>".
+	Auth server.AuthConfig
+}
+
+// NewServer returns a gRPC server serving the rulesets of the given service.
+func NewServer(rulesets store.RulesetService, cfg Config, opts ...grpc.ServerOption) *grpc.Server {
+	if cfg.Logger == nil {
+		lg := zerolog.New(os.Stderr).With().Timestamp().Logger()
+		cfg.Logger = &lg
+	}
+
+	srv := grpc.NewServer(opts...)
+	RegisterRulesetsServer(srv, &rulesetsServer{
+		rulesets:   rulesets,
+		namespaces: cfg.Namespaces,
+		auth:       cfg.Auth,
+		logger:     *cfg.Logger,
+	})
+
+	return srv
+}
+
+type rulesetsServer struct {
+	rulesets   store.RulesetService
+	namespaces store.NamespaceService
+	auth       server.AuthConfig
+	logger     zerolog.Logger
+}
+
+// Eval evaluates the latest version of a ruleset.
+func (s *rulesetsServer) Eval(ctx context.Context, req *EvalRequest) (*EvalResponse, error) {
+	return s.eval(ctx, req, "")
+}
+
+// EvalVersion evaluates the given version of a ruleset.
+func (s *rulesetsServer) EvalVersion(ctx context.Context, req *EvalRequest) (*EvalResponse, error) {
+	if req.Version == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing version")
+	}
+
+	return s.eval(ctx, req, req.Version)
+}
+
+func (s *rulesetsServer) eval(ctx context.Context, req *EvalRequest, version string) (*EvalResponse, error) {
+	rulesets, _, err := s.service(ctx, req.Namespace, req.Path, server.RightEval)
+	if err != nil {
+		return nil, err
+	}
+
+	params := make(regula.Params, len(req.Params))
+	for _, p := range req.Params {
+		switch v := p.Value.(type) {
+		case *Param_StringValue:
+			params[p.Name] = v.StringValue
+		case *Param_BoolValue:
+			params[p.Name] = v.BoolValue
+		case *Param_Int64Value:
+			params[p.Name] = v.Int64Value
+		case *Param_Float64Value:
+			params[p.Name] = v.Float64Value
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "missing value of param '%s'", p.Name)
+		}
+	}
+
+	var res *regula.EvalResult
+	if version != "" {
+		res, err = rulesets.EvalVersion(ctx, req.Path, version, params)
+	} else {
+		res, err = rulesets.Eval(ctx, req.Path, params)
+	}
+	if err != nil {
+		return nil, s.error(err)
+	}
+
+	resp := EvalResponse{
+		Value: &Value{
+			Type: res.Value.Type,
+			Data: res.Value.Data,
+		},
+		Version: res.Version,
+	}
+	// the stores always report the rule that matched.
+	if res.Rule != nil {
+		resp.Rule = int32(*res.Rule)
+	}
+
+	return &resp, nil
+}
+
+// List returns the rulesets under a prefix.
+func (s *rulesetsServer) List(ctx context.Context, req *ListRequest) (*ListResponse, error) {
+	rulesets, _, err := s.service(ctx, req.Namespace, req.Prefix, server.RightRead)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := rulesets.List(ctx, req.Prefix, store.ListOptions{
+		Limit:         int(req.Limit),
+		ContinueToken: req.Continue,
+		LatestOnly:    req.LatestOnly,
+	})
+	if err != nil {
+		return nil, s.error(err)
+	}
+
+	resp := ListResponse{
+		Rulesets: make([]*Ruleset, len(entries.Entries)),
+		Revision: entries.Revision,
+		Continue: entries.Continue,
+	}
+	for i := range entries.Entries {
+		resp.Rulesets[i], err = encodeEntry(&entries.Entries[i])
+		if err != nil {
+			return nil, s.error(err)
+		}
+	}
+
+	return &resp, nil
+}
+
+// Put creates a new version of a ruleset.
+func (s *rulesetsServer) Put(ctx context.Context, req *PutRequest) (*Ruleset, error) {
+	rulesets, subject, err := s.service(ctx, req.Namespace, req.Path, server.RightWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	var rs regula.Ruleset
+	err = json.Unmarshal(req.Ruleset, &rs)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid ruleset: %v", err)
+	}
+
+	audit := store.Audit{
+		Author:  req.Author,
+		Message: req.Message,
+	}
+	if subject != "" {
+		audit.Author = subject
+	}
+
+	var opts []store.PutOption
+	if req.ExpectedVersion != "" {
+		opts = append(opts, store.ExpectedVersion(req.ExpectedVersion))
+	}
+
+	entry, err := rulesets.Put(store.WithAudit(ctx, audit), req.Path, &rs, opts...)
+	if err != nil && err != store.ErrNotModified {
+		if err == store.ErrVersionMismatch {
+			if entry == nil {
+				return nil, status.Errorf(codes.Aborted, "version mismatch: the path '%s' doesn't exist", req.Path)
+			}
+
+			return nil, status.Errorf(codes.Aborted, "version mismatch: the latest version is '%s'", entry.Version)
+		}
+
+		return nil, s.error(err)
+	}
+
+	r, err := encodeEntry(entry)
+	if err != nil {
+		return nil, s.error(err)
+	}
+
+	return r, nil
+}
+
+// Watch streams the changes made to the rulesets under a prefix until the client cancels the call.
+func (s *rulesetsServer) Watch(req *WatchRequest, stream Rulesets_WatchServer) error {
+	ctx := stream.Context()
+
+	rulesets, _, err := s.service(ctx, req.Namespace, req.Prefix, server.RightRead)
+	if err != nil {
+		return err
+	}
+
+	revision := req.Revision
+	for {
+		events, err := rulesets.Watch(ctx, req.Prefix, revision)
+		if err != nil {
+			if ctx.Err() != nil {
+				return status.FromContextError(ctx.Err()).Err()
+			}
+
+			return s.error(err)
+		}
+
+		resp := WatchResponse{
+			Events:   make([]*Event, len(events.Events)),
+			Revision: events.Revision,
+		}
+		for i, ev := range events.Events {
+			resp.Events[i] = &Event{
+				Type:    ev.Type,
+				Path:    ev.Path,
+				Version: ev.Version,
+			}
+
+			if ev.Ruleset != nil {
+				resp.Events[i].Ruleset, err = json.Marshal(ev.Ruleset)
+				if err != nil {
+					return s.error(err)
+				}
+			}
+		}
+
+		err = stream.Send(&resp)
+		if err != nil {
+			return err
+		}
+
+		revision = events.Revision
+	}
+}
+
+// service authorizes the request to use the right on the given path of the namespace
+// and returns the service managing the rulesets of the namespace along with the authenticated subject,
+// which is empty if authentication is disabled.
+func (s *rulesetsServer) service(ctx context.Context, namespace, path string, right server.Right) (store.RulesetService, string, error) {
+	var subject string
+
+	if s.auth.Enabled() {
+		var token string
+		if md, ok := metadata.FromIncomingContext(ctx); ok && len(md["authorization"]) > 0 {
+			token = md["authorization"][0]
+		}
+		if !strings.HasPrefix(token, "Bearer ") {
+			return nil, "", status.Error(codes.Unauthenticated, "unauthenticated")
+		}
+
+		var err error
+		subject, err = s.auth.Authenticate(strings.TrimSpace(strings.TrimPrefix(token, "Bearer ")))
+		if err != nil {
+			return nil, "", status.Error(codes.Unauthenticated, "unauthenticated")
+		}
+
+		if !s.auth.Authorized(subject, server.Resource(namespace, path), right) {
+			return nil, "", status.Error(codes.PermissionDenied, "forbidden")
+		}
+	}
+
+	if namespace == "" {
+		return s.rulesets, subject, nil
+	}
+
+	if s.namespaces == nil {
+		return nil, "", status.Error(codes.Unimplemented, "namespaces are not supported")
+	}
+
+	rulesets, err := s.namespaces.Rulesets(ctx, namespace)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, "", status.Errorf(codes.NotFound, "the namespace '%s' doesn't exist", namespace)
+		}
+
+		return nil, "", s.error(err)
+	}
+
+	return rulesets, subject, nil
+}
+
+// error converts the errors returned by the store to gRPC errors.
+func (s *rulesetsServer) error(err error) error {
+	switch err {
+	case regula.ErrRulesetNotFound, store.ErrNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case rule.ErrParamNotFound, rule.ErrParamTypeMismatch, rule.ErrNoMatch, store.ErrInvalidContinueToken:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case store.ErrRevisionCompacted:
+		return status.Error(codes.OutOfRange, err.Error())
+	}
+
+	switch err.(type) {
+	case *regula.ParamError, *store.ValidationError:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// hide the error from the client, like the HTTP API.
+	s.logger.Error().Err(err).Msg("unexpected grpc error")
+	return status.Error(codes.Internal, "internal error")
+}
+
+func encodeEntry(entry *store.RulesetEntry) (*Ruleset, error) {
+	raw, err := json.Marshal(entry.Ruleset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Ruleset{
+		Path:    entry.Path,
+		Version: entry.Version,
+		Ruleset: raw,
+	}, nil
+}
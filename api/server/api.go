@@ -11,6 +11,7 @@ import (
 
 	"github.com/heetch/regula"
 	"github.com/heetch/regula/api"
+	"github.com/heetch/regula/diff"
 	"github.com/heetch/regula/rule"
 	"github.com/heetch/regula/store"
 	"github.com/pkg/errors"
@@ -19,15 +20,57 @@ import (
 type rulesetService struct {
 	*service
 
-	timeout      time.Duration
-	watchTimeout time.Duration
+	// namespace of the rulesets, empty for the default ones.
+	namespace      string
+	timeout        time.Duration
+	watchTimeout   time.Duration
+	watchHeartbeat time.Duration
 }
 
 func (s *rulesetService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/rulesets")
 	path = strings.TrimPrefix(path, "/")
 
+	// the rulesets of a batch are authorized one by one by the handler.
+	if _, ok := r.URL.Query()["batch"]; ok && r.Method == "PUT" && path == "" {
+		ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+		defer cancel()
+		s.putBatch(w, r.WithContext(ctx))
+		return
+	}
+
+	// the rulesets of a batch evaluation are authorized one by one by the handler.
+	if _, ok := r.URL.Query()["eval"]; ok && r.Method == "POST" {
+		ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+		defer cancel()
+		s.evalBatch(w, r.WithContext(ctx), path)
+		return
+	}
+
+	// typed evaluations are sent to /rulesets/{path}/eval.
+	if r.Method == "POST" && strings.HasSuffix(path, "/eval") && r.URL.RawQuery == "" {
+		path = strings.TrimSuffix(path, "/eval")
+		if !s.authorize(w, r, Resource(s.namespace, path), RightEval) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+		defer cancel()
+		s.evalJSON(w, r.WithContext(ctx), path)
+		return
+	}
+
+	if !s.authorize(w, r, Resource(s.namespace, path), requiredRight(r)) {
+		return
+	}
+
 	if _, ok := r.URL.Query()["watch"]; ok && r.Method == "GET" {
+		// streams are only bounded by the lifetime of the connection.
+		if _, ok := r.URL.Query()["stream"]; ok {
+			s.streamWatch(w, r, path)
+			return
+		}
+
 		ctx, cancel := context.WithTimeout(r.Context(), s.watchTimeout)
 		defer cancel()
 		s.watch(w, r.WithContext(ctx), path)
@@ -48,33 +91,79 @@ func (s *rulesetService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			s.eval(w, r, path)
 			return
 		}
+		if _, ok := r.URL.Query()["versions"]; ok && path != "" {
+			s.versions(w, r, path)
+			return
+		}
+		if _, ok := r.URL.Query()["history"]; ok && path != "" {
+			s.history(w, r, path)
+			return
+		}
+		if _, ok := r.URL.Query()["signature"]; ok && path != "" {
+			s.signatures(w, r, path)
+			return
+		}
+		if _, ok := r.URL.Query()["diff"]; ok && path != "" {
+			s.diff(w, r, path)
+			return
+		}
 	case "PUT":
 		if path != "" {
 			s.put(w, r, path)
 			return
 		}
+	case "POST":
+		if _, ok := r.URL.Query()["rollback"]; ok && path != "" {
+			s.rollback(w, r, path)
+			return
+		}
+		if _, ok := r.URL.Query()["signature"]; ok && path != "" {
+			s.migrateSignature(w, r, path)
+			return
+		}
+	case "DELETE":
+		if path != "" {
+			s.delete(w, r, path)
+			return
+		}
 	}
 
 	w.WriteHeader(http.StatusNotFound)
 }
 
+// requiredRight returns the right needed to serve the request.
+func requiredRight(r *http.Request) Right {
+	if r.Method != "GET" {
+		return RightWrite
+	}
+
+	if _, ok := r.URL.Query()["eval"]; ok {
+		return RightEval
+	}
+
+	return RightRead
+}
+
 // list fetches all the rulesets from the store and writes them to the http response.
+// If the latest query parameter is present, only the latest version of each ruleset is returned.
 func (s *rulesetService) list(w http.ResponseWriter, r *http.Request, prefix string) {
 	var (
-		err   error
-		limit int
+		err error
+		opt store.ListOptions
 	)
 
 	if l := r.URL.Query().Get("limit"); l != "" {
-		limit, err = strconv.Atoi(l)
+		opt.Limit, err = strconv.Atoi(l)
 		if err != nil {
 			s.writeError(w, r, errors.New("invalid limit"), http.StatusBadRequest)
 			return
 		}
 	}
 
-	continueToken := r.URL.Query().Get("continue")
-	entries, err := s.rulesets.List(r.Context(), prefix, limit, continueToken)
+	opt.ContinueToken = r.URL.Query().Get("continue")
+	_, opt.LatestOnly = r.URL.Query()["latest"]
+
+	entries, err := s.rulesets.List(r.Context(), prefix, opt)
 	if err != nil {
 		if err == store.ErrNotFound {
 			s.writeError(w, r, err, http.StatusNotFound)
@@ -102,13 +191,104 @@ func (s *rulesetService) list(w http.ResponseWriter, r *http.Request, prefix str
 	s.encodeJSON(w, r, &rl, http.StatusOK)
 }
 
+// versions fetches the versions of a ruleset from the store and writes them to the http response.
+func (s *rulesetService) versions(w http.ResponseWriter, r *http.Request, path string) {
+	var (
+		err   error
+		limit int
+	)
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		limit, err = strconv.Atoi(l)
+		if err != nil {
+			s.writeError(w, r, errors.New("invalid limit"), http.StatusBadRequest)
+			return
+		}
+	}
+
+	continueToken := r.URL.Query().Get("continue")
+	versions, err := s.rulesets.Versions(r.Context(), path, limit, continueToken)
+	if err != nil {
+		if err == store.ErrNotFound {
+			s.writeError(w, r, fmt.Errorf("the path '%s' doesn't exist", path), http.StatusNotFound)
+			return
+		}
+
+		if err == store.ErrInvalidContinueToken {
+			s.writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+
+		s.writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	rv := api.RulesetVersions{
+		Path:     versions.Path,
+		Versions: make([]api.RulesetVersion, len(versions.Versions)),
+		Continue: versions.Continue,
+	}
+	for i := range versions.Versions {
+		rv.Versions[i] = api.RulesetVersion(versions.Versions[i])
+	}
+
+	s.encodeJSON(w, r, &rv, http.StatusOK)
+}
+
+// history returns the changes made to a ruleset, newest first.
+func (s *rulesetService) history(w http.ResponseWriter, r *http.Request, path string) {
+	var (
+		err   error
+		limit int
+	)
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		limit, err = strconv.Atoi(l)
+		if err != nil {
+			s.writeError(w, r, errors.New("invalid limit"), http.StatusBadRequest)
+			return
+		}
+	}
+
+	continueToken := r.URL.Query().Get("continue")
+	history, err := s.rulesets.History(r.Context(), path, limit, continueToken)
+	if err != nil {
+		if err == store.ErrNotFound {
+			s.writeError(w, r, fmt.Errorf("the path '%s' doesn't exist", path), http.StatusNotFound)
+			return
+		}
+
+		if err == store.ErrInvalidContinueToken {
+			s.writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+
+		s.writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	h := api.History{
+		Path:     history.Path,
+		Entries:  make([]api.AuditEntry, len(history.Entries)),
+		Continue: history.Continue,
+	}
+	for i := range history.Entries {
+		h.Entries[i] = api.AuditEntry(history.Entries[i])
+	}
+
+	s.encodeJSON(w, r, &h, http.StatusOK)
+}
+
 func (s *rulesetService) eval(w http.ResponseWriter, r *http.Request, path string) {
 	var err error
 	var res *regula.EvalResult
 
+	// the keys read by this endpoint aren't params
 	params := make(params)
 	for k, v := range r.URL.Query() {
-		params[k] = v[0]
+		if k != "eval" && k != "version" {
+			params[k] = v[0]
+		}
 	}
 
 	if v, ok := r.URL.Query()["version"]; ok {
@@ -118,23 +298,262 @@ func (s *rulesetService) eval(w http.ResponseWriter, r *http.Request, path strin
 	}
 
 	if err != nil {
-		if err == regula.ErrRulesetNotFound {
-			s.writeError(w, r, fmt.Errorf("the path '%s' doesn't exist", path), http.StatusNotFound)
+		s.writeEvalError(w, r, err, path)
+		return
+	}
+
+	s.encodeJSON(w, r, &api.EvalResult{
+		Value:   res.Value,
+		Version: res.Version,
+	}, http.StatusOK)
+}
+
+// evalJSON evaluates a ruleset with the typed params read from the JSON request body.
+func (s *rulesetService) evalJSON(w http.ResponseWriter, r *http.Request, path string) {
+	var req api.EvalRequest
+
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+	err := dec.Decode(&req)
+	if err != nil {
+		s.writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	var res *regula.EvalResult
+
+	if req.Version != "" {
+		res, err = s.rulesets.EvalVersion(r.Context(), path, req.Version, jsonParams(req.Params))
+	} else {
+		res, err = s.rulesets.Eval(r.Context(), path, jsonParams(req.Params))
+	}
+
+	if err != nil {
+		s.writeEvalError(w, r, err, path)
+		return
+	}
+
+	result := api.EvalResult{
+		Value:   res.Value,
+		Version: res.Version,
+	}
+	if req.Explain {
+		result.Explanation = &api.Explanation{
+			Rule: res.Rule,
+		}
+	}
+
+	s.encodeJSON(w, r, &result, http.StatusOK)
+}
+
+// writeEvalError writes the error returned by the evaluation of the ruleset stored on the given path.
+func (s *rulesetService) writeEvalError(w http.ResponseWriter, r *http.Request, err error, path string) {
+	code := evalErrorCode(err)
+	if code == http.StatusNotFound {
+		err = fmt.Errorf("the path '%s' doesn't exist", path)
+	}
+
+	s.writeError(w, r, err, code)
+}
+
+// evalErrorCode returns the status code corresponding to an error returned by an evaluation.
+func evalErrorCode(err error) int {
+	if err == regula.ErrRulesetNotFound {
+		return http.StatusNotFound
+	}
+
+	if err == rule.ErrParamNotFound ||
+		err == rule.ErrParamTypeMismatch ||
+		err == rule.ErrNoMatch {
+		return http.StatusBadRequest
+	}
+
+	if _, ok := err.(*regula.ParamError); ok {
+		return http.StatusBadRequest
+	}
+
+	return http.StatusInternalServerError
+}
+
+// evalBatch evaluates several rulesets with one or more sets of params at once.
+// The paths are read from the request body and must be under the given prefix.
+// If none is given, all the rulesets under the prefix are evaluated.
+// Evaluation errors are reported per result and don't fail the request, internal ones being hidden
+// like in writeError.
+// Batches of more than api.MaxEvalBatchSize evaluations are rejected with a 400 error.
+func (s *rulesetService) evalBatch(w http.ResponseWriter, r *http.Request, prefix string) {
+	var req api.EvalBatchRequest
+
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+	err := dec.Decode(&req)
+	if err != nil {
+		s.writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	paths := req.Paths
+	if len(paths) == 0 {
+		if !s.authorize(w, r, Resource(s.namespace, prefix), RightEval) {
 			return
 		}
 
-		if err == rule.ErrParamNotFound ||
-			err == rule.ErrParamTypeMismatch ||
-			err == rule.ErrNoMatch {
-			s.writeError(w, r, err, http.StatusBadRequest)
+		paths, err = s.latestPaths(r.Context(), prefix)
+		if err != nil {
+			if err == store.ErrNotFound {
+				s.writeError(w, r, fmt.Errorf("the prefix '%s' doesn't exist", prefix), http.StatusNotFound)
+				return
+			}
+
+			s.writeError(w, r, err, http.StatusInternalServerError)
 			return
 		}
 
+		// the store lists the paths starting with the prefix, even if it ends in the middle of a segment.
+		var under []string
+		for _, path := range paths {
+			if underPrefix(path, prefix) {
+				under = append(under, path)
+			}
+		}
+		paths = under
+	}
+
+	if len(req.Params) == 0 {
+		req.Params = []map[string]interface{}{{}}
+	}
+
+	if len(paths)*len(req.Params) > api.MaxEvalBatchSize {
+		s.writeError(w, r, fmt.Errorf("too many evaluations in batch, the maximum is %d", api.MaxEvalBatchSize), http.StatusBadRequest)
+		return
+	}
+
+	for _, path := range paths {
+		if !underPrefix(path, prefix) {
+			s.writeError(w, r, fmt.Errorf("the path '%s' doesn't start with '%s'", path, prefix), http.StatusBadRequest)
+			return
+		}
+
+		if !s.authorize(w, r, Resource(s.namespace, path), RightEval) {
+			return
+		}
+	}
+
+	res := api.EvalBatchResults{
+		Results: make([]api.EvalBatchResult, 0, len(paths)*len(req.Params)),
+	}
+
+	for _, path := range paths {
+		for i, p := range req.Params {
+			er := api.EvalBatchResult{
+				Path:   path,
+				Params: i,
+			}
+
+			v, err := s.rulesets.Eval(r.Context(), path, jsonParams(p))
+			if err != nil {
+				if evalErrorCode(err) == http.StatusInternalServerError {
+					loggerFromRequest(r).Error().Err(err).Str("path", path).Msg("batch evaluation failed")
+					err = errInternal
+				}
+				er.Error = err.Error()
+			} else {
+				er.Value = v.Value
+				er.Version = v.Version
+				er.Rule = v.Rule
+			}
+
+			res.Results = append(res.Results, er)
+		}
+	}
+
+	s.encodeJSON(w, r, &res, http.StatusOK)
+}
+
+// underPrefix reports whether the path is the prefix itself or one of the paths below it.
+func underPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return true
+	}
+
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// latestPaths returns the paths of all the rulesets under the given prefix.
+func (s *rulesetService) latestPaths(ctx context.Context, prefix string) ([]string, error) {
+	var paths []string
+
+	opt := store.ListOptions{
+		Limit:      store.MaxLimit,
+		LatestOnly: true,
+	}
+
+	for {
+		entries, err := s.rulesets.List(ctx, prefix, opt)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range entries.Entries {
+			paths = append(paths, e.Path)
+		}
+
+		if entries.Continue == "" {
+			return paths, nil
+		}
+
+		opt.ContinueToken = entries.Continue
+	}
+}
+
+// diff compares two versions of a ruleset. If the to parameter is omitted,
+// the from version is compared with the latest version.
+func (s *rulesetService) diff(w http.ResponseWriter, r *http.Request, path string) {
+	from := r.URL.Query().Get("from")
+	if from == "" {
+		s.writeError(w, r, errors.New("missing from version"), http.StatusBadRequest)
+		return
+	}
+
+	fromEntry, err := s.rulesets.OneByVersion(r.Context(), path, from)
+	if err != nil {
+		s.writeEntryError(w, r, err, path, from)
+		return
+	}
+
+	var toEntry *store.RulesetEntry
+	if to := r.URL.Query().Get("to"); to != "" {
+		toEntry, err = s.rulesets.OneByVersion(r.Context(), path, to)
+	} else {
+		toEntry, err = s.rulesets.Latest(r.Context(), path)
+	}
+	if err != nil {
+		s.writeEntryError(w, r, err, path, r.URL.Query().Get("to"))
+		return
+	}
+
+	s.encodeJSON(w, r, &api.RulesetDiff{
+		Path:    path,
+		From:    fromEntry.Version,
+		To:      toEntry.Version,
+		Changes: diff.Rulesets(fromEntry.Ruleset, toEntry.Ruleset),
+	}, http.StatusOK)
+}
+
+// writeEntryError writes the error returned by the store when fetching a ruleset entry.
+func (s *rulesetService) writeEntryError(w http.ResponseWriter, r *http.Request, err error, path, version string) {
+	if err != store.ErrNotFound {
 		s.writeError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	s.encodeJSON(w, r, (*api.EvalResult)(res), http.StatusOK)
+	if version == "" {
+		s.writeError(w, r, fmt.Errorf("the path '%s' doesn't exist", path), http.StatusNotFound)
+		return
+	}
+
+	s.writeError(w, r, fmt.Errorf("the version '%s' of the path '%s' doesn't exist", version, path), http.StatusNotFound)
 }
 
 // watch watches a prefix for change and returns anything newer.
@@ -149,6 +568,9 @@ func (s *rulesetService) watch(w http.ResponseWriter, r *http.Request, prefix st
 		case store.ErrNotFound:
 			w.WriteHeader(http.StatusNotFound)
 			return
+		case store.ErrRevisionCompacted:
+			s.writeError(w, r, err, http.StatusGone)
+			return
 		default:
 			s.writeError(w, r, err, http.StatusInternalServerError)
 			return
@@ -167,7 +589,124 @@ func (s *rulesetService) watch(w http.ResponseWriter, r *http.Request, prefix st
 	s.encodeJSON(w, r, ae, http.StatusOK)
 }
 
-// put creates a new version of a ruleset.
+// streamWatch keeps the connection open and pushes the changes made under the prefix as Server-Sent Events.
+// Every event holds an api.Events batch and uses its revision as id, so that clients can resume
+// from the last event they received by sending it in the revision query parameter or in the Last-Event-ID header.
+// A heartbeat comment is sent when nothing changed for watchHeartbeat, which also detects the clients that went away.
+// Errors occurring once the stream is open are sent as an "error" event and end the stream.
+func (s *rulesetService) streamWatch(w http.ResponseWriter, r *http.Request, prefix string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.writeError(w, r, errors.New("streaming not supported"), http.StatusInternalServerError)
+		return
+	}
+
+	revision := r.URL.Query().Get("revision")
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		revision = id
+	}
+
+	type watchResult struct {
+		events *store.RulesetEvents
+		err    error
+	}
+
+	// a single goroutine watches the store so that no change is missed between two batches.
+	// The handler waits for it to return, so that the store is never used once the request is done.
+	ctx, cancel := context.WithCancel(r.Context())
+	results := make(chan watchResult)
+	done := make(chan struct{})
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	go func() {
+		defer close(done)
+
+		for {
+			events, err := s.rulesets.Watch(ctx, prefix, revision)
+			if err == nil && events == nil {
+				err = errors.New("watch returned no events")
+			}
+
+			select {
+			case results <- watchResult{events, err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+
+			revision = events.Revision
+		}
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(s.watchHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		var err error
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": heartbeat\n\n")
+		case res := <-results:
+			if res.err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+
+				msg := errInternal.Error()
+				if res.err == store.ErrRevisionCompacted {
+					msg = res.err.Error()
+				} else {
+					loggerFromRequest(r).Error().Err(res.err).Msg("watch stream failed")
+				}
+				data, _ := json.Marshal(&api.Error{Err: msg})
+				fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+				flusher.Flush()
+				return
+			}
+
+			ae := api.Events{
+				Events:   make([]api.Event, len(res.events.Events)),
+				Revision: res.events.Revision,
+			}
+			for i := range res.events.Events {
+				ae.Events[i] = api.Event(res.events.Events[i])
+			}
+
+			data, jerr := json.Marshal(&ae)
+			if jerr != nil {
+				loggerFromRequest(r).Error().Err(jerr).Msg("failed to encode watch events")
+				return
+			}
+
+			_, err = fmt.Fprintf(w, "id: %s\nevent: events\ndata: %s\n\n", ae.Revision, data)
+		}
+
+		if err != nil {
+			// the client went away.
+			return
+		}
+
+		flusher.Flush()
+	}
+}
+
+// put creates a new version of a ruleset. The change is recorded in the history of the ruleset
+// along with its author and message, read from the request headers.
+// If the If-Match header is set, the version is only created if the given version is still the latest one,
+// otherwise a 412 error is returned along with the actual latest version in the ETag header.
 func (s *rulesetService) put(w http.ResponseWriter, r *http.Request, path string) {
 	var rs regula.Ruleset
 
@@ -177,7 +716,81 @@ func (s *rulesetService) put(w http.ResponseWriter, r *http.Request, path string
 		return
 	}
 
-	entry, err := s.rulesets.Put(r.Context(), path, &rs)
+	var opts []store.PutOption
+	if v := r.Header.Get("If-Match"); v != "" {
+		opts = append(opts, store.ExpectedVersion(strings.Trim(strings.TrimSpace(v), `"`)))
+	}
+
+	entry, err := s.rulesets.Put(auditContext(r), path, &rs, opts...)
+	if err != nil && err != store.ErrNotModified {
+		if store.IsValidationError(err) {
+			s.writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+
+		if err == store.ErrVersionMismatch {
+			if entry == nil {
+				s.writeError(w, r, fmt.Errorf("version mismatch: the path '%s' doesn't exist", path), http.StatusPreconditionFailed)
+				return
+			}
+
+			w.Header().Set("ETag", `"`+entry.Version+`"`)
+			s.writeError(w, r, fmt.Errorf("version mismatch: the latest version is '%s'", entry.Version), http.StatusPreconditionFailed)
+			return
+		}
+
+		s.writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", `"`+entry.Version+`"`)
+	s.encodeJSON(w, r, (*api.Ruleset)(entry), http.StatusOK)
+}
+
+// auditContext returns the context of the request along with the author and message
+// of the change, read from the request headers. The authenticated subject, if any, is used as the author.
+func auditContext(r *http.Request) context.Context {
+	audit := store.Audit{
+		Author:  r.Header.Get(api.AuthorHeader),
+		Message: r.Header.Get(api.MessageHeader),
+	}
+	if subject, ok := r.Context().Value(subjectKey).(string); ok {
+		audit.Author = subject
+	}
+
+	return store.WithAudit(r.Context(), audit)
+}
+
+// putBatch creates a new version of every ruleset of the request body at once.
+// Either all of them are stored, or none of them. Unchanged rulesets are left untouched.
+// Batches of more than store.MaxBatchSize rulesets are rejected with a 400 error.
+func (s *rulesetService) putBatch(w http.ResponseWriter, r *http.Request) {
+	var batch api.Rulesets
+
+	err := json.NewDecoder(r.Body).Decode(&batch)
+	if err != nil {
+		s.writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	if len(batch.Rulesets) == 0 {
+		s.writeError(w, r, errors.New("empty batch"), http.StatusBadRequest)
+		return
+	}
+
+	entries := make([]store.RulesetEntry, len(batch.Rulesets))
+	for i, rs := range batch.Rulesets {
+		if !s.authorize(w, r, Resource(s.namespace, rs.Path), RightWrite) {
+			return
+		}
+
+		entries[i] = store.RulesetEntry{
+			Path:    rs.Path,
+			Ruleset: rs.Ruleset,
+		}
+	}
+
+	res, err := s.rulesets.PutBatch(auditContext(r), entries)
 	if err != nil && err != store.ErrNotModified {
 		if store.IsValidationError(err) {
 			s.writeError(w, r, err, http.StatusBadRequest)
@@ -188,5 +801,85 @@ func (s *rulesetService) put(w http.ResponseWriter, r *http.Request, path string
 		return
 	}
 
+	rulesets := api.Rulesets{
+		Rulesets: make([]api.Ruleset, len(res)),
+	}
+	for i := range res {
+		rulesets.Rulesets[i] = api.Ruleset(res[i])
+	}
+
+	s.encodeJSON(w, r, &rulesets, http.StatusOK)
+}
+
+// rollback makes the given version the latest version of a ruleset.
+func (s *rulesetService) rollback(w http.ResponseWriter, r *http.Request, path string) {
+	version := r.URL.Query().Get("version")
+	if version == "" {
+		s.writeError(w, r, errors.New("missing version"), http.StatusBadRequest)
+		return
+	}
+
+	entry, err := s.rulesets.Rollback(auditContext(r), path, version)
+	if err != nil && err != store.ErrNotModified {
+		s.writeEntryError(w, r, err, path, version)
+		return
+	}
+
 	s.encodeJSON(w, r, (*api.Ruleset)(entry), http.StatusOK)
 }
+
+// delete removes a ruleset. The versions are kept aside unless the hard parameter is provided.
+func (s *rulesetService) delete(w http.ResponseWriter, r *http.Request, path string) {
+	_, hard := r.URL.Query()["hard"]
+
+	err := s.rulesets.Delete(auditContext(r), path, hard)
+	if err != nil {
+		s.writeEntryError(w, r, err, path, "")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// signatures returns the history of the signatures of a ruleset.
+func (s *rulesetService) signatures(w http.ResponseWriter, r *http.Request, path string) {
+	sigs, err := s.rulesets.Signatures(r.Context(), path)
+	if err != nil {
+		s.writeEntryError(w, r, err, path, "")
+		return
+	}
+
+	res := api.Signatures{
+		Path:       path,
+		Signatures: make([]api.Signature, len(sigs)),
+	}
+	for i := range sigs {
+		res.Signatures[i] = api.Signature(sigs[i])
+	}
+
+	s.encodeJSON(w, r, &res, http.StatusOK)
+}
+
+// migrateSignature applies a migration to the signature of a ruleset.
+func (s *rulesetService) migrateSignature(w http.ResponseWriter, r *http.Request, path string) {
+	var m api.SignatureMigration
+
+	err := json.NewDecoder(r.Body).Decode(&m)
+	if err != nil {
+		s.writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	sig, err := s.rulesets.MigrateSignature(auditContext(r), path, (*store.SignatureMigration)(&m))
+	if err != nil {
+		if store.IsValidationError(err) {
+			s.writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+
+		s.writeEntryError(w, r, err, path, "")
+		return
+	}
+
+	s.encodeJSON(w, r, (*api.Signature)(sig), http.StatusOK)
+}
@@ -1,14 +1,17 @@
 package server
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -59,10 +62,12 @@ func TestAPI(t *testing.T) {
 				limit = "0"
 			}
 			token := uu.Query().Get("continue")
+			_, latest := uu.Query()["latest"]
 
-			s.ListFn = func(ctx context.Context, prefix string, lm int, tk string) (*store.RulesetEntries, error) {
-				assert.Equal(t, limit, strconv.Itoa(lm))
-				assert.Equal(t, token, tk)
+			s.ListFn = func(ctx context.Context, prefix string, opt store.ListOptions) (*store.RulesetEntries, error) {
+				assert.Equal(t, limit, strconv.Itoa(opt.Limit))
+				assert.Equal(t, token, opt.ContinueToken)
+				assert.Equal(t, latest, opt.LatestOnly)
 				return l, err
 			}
 			defer func() { s.ListFn = nil }()
@@ -95,6 +100,10 @@ func TestAPI(t *testing.T) {
 			call(t, "/rulesets/a?list", http.StatusOK, &l, nil)
 		})
 
+		t.Run("LatestOnly", func(t *testing.T) {
+			call(t, "/rulesets/a?list&latest&limit=10", http.StatusOK, &l, nil)
+		})
+
 		t.Run("NoResultOnRoot", func(t *testing.T) {
 			call(t, "/rulesets/?list", http.StatusOK, new(store.RulesetEntries), nil)
 		})
@@ -123,11 +132,11 @@ func TestAPI(t *testing.T) {
 
 			s.EvalFn = func(ctx context.Context, path string, params rule.Params) (*regula.EvalResult, error) {
 				testParamsFn(params)
-				return (*regula.EvalResult)(result), nil
+				return &regula.EvalResult{Value: result.Value, Version: result.Version}, nil
 			}
 
 			s.EvalVersionFn = func(ctx context.Context, path, version string, params rule.Params) (*regula.EvalResult, error) {
-				return (*regula.EvalResult)(result), nil
+				return &regula.EvalResult{Value: result.Value, Version: result.Version}, nil
 			}
 
 			w := httptest.NewRecorder()
@@ -177,6 +186,19 @@ func TestAPI(t *testing.T) {
 			require.Equal(t, 1, s.EvalVersionCount)
 		})
 
+		t.Run("OK - Endpoint keys", func(t *testing.T) {
+			exp := api.EvalResult{
+				Value:   rule.StringValue("success"),
+				Version: "123",
+			}
+
+			// only the keys read by the eval endpoint are kept out of the params
+			call(t, "/rulesets/path/to/my/ruleset?eval&version=123&limit=10&str=str", http.StatusOK, &exp, func(params rule.Params) {
+				require.ElementsMatch(t, []string{"limit", "str"}, params.Keys())
+			})
+			require.Equal(t, 1, s.EvalVersionCount)
+		})
+
 		t.Run("NOK - Ruleset not found", func(t *testing.T) {
 			s.EvalFn = func(ctx context.Context, path string, params rule.Params) (*regula.EvalResult, error) {
 				return nil, regula.ErrRulesetNotFound
@@ -202,6 +224,7 @@ func TestAPI(t *testing.T) {
 				rule.ErrParamNotFound,
 				rule.ErrParamTypeMismatch,
 				rule.ErrNoMatch,
+				&regula.ParamError{Name: "foo", Reason: "must be of type bool"},
 			}
 
 			for _, e := range errs {
@@ -218,6 +241,450 @@ func TestAPI(t *testing.T) {
 		})
 	})
 
+	t.Run("EvalJSON", func(t *testing.T) {
+		call := func(t *testing.T, url, body string, code int) *api.EvalResult {
+			t.Helper()
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", url, strings.NewReader(body))
+			h.ServeHTTP(w, r)
+
+			require.Equal(t, code, w.Code, w.Body.String())
+
+			if code != http.StatusOK {
+				return nil
+			}
+
+			var res api.EvalResult
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+			return &res
+		}
+
+		t.Run("OK", func(t *testing.T) {
+			resetStore(s)
+			s.EvalFn = func(ctx context.Context, path string, params rule.Params) (*regula.EvalResult, error) {
+				require.Equal(t, "path/to/my/ruleset", path)
+
+				// numbers keep their JSON type, even when named after a query parameter
+				_, err := params.GetString("version")
+				require.Equal(t, rule.ErrParamTypeMismatch, err)
+				i, err := params.GetInt64("version")
+				require.NoError(t, err)
+				require.Equal(t, int64(10), i)
+				// strings are parsed according to the expected type
+				v, err := params.GetString("str")
+				require.NoError(t, err)
+				require.Equal(t, "10", v)
+				i, err = params.GetInt64("str")
+				require.NoError(t, err)
+				require.Equal(t, int64(10), i)
+				b, err := params.GetBool("eval")
+				require.NoError(t, err)
+				require.True(t, b)
+
+				ruleIdx := 2
+				return &regula.EvalResult{Value: rule.StringValue("success"), Version: "v1", Rule: &ruleIdx}, nil
+			}
+
+			res := call(t, "/rulesets/path/to/my/ruleset/eval", `{"params": {"str": "10", "version": 10, "eval": true}}`, http.StatusOK)
+			require.Equal(t, &api.EvalResult{Value: rule.StringValue("success"), Version: "v1"}, res)
+			require.Equal(t, 1, s.EvalCount)
+		})
+
+		t.Run("VersionAndExplain", func(t *testing.T) {
+			resetStore(s)
+			s.EvalVersionFn = func(ctx context.Context, path, version string, params rule.Params) (*regula.EvalResult, error) {
+				require.Equal(t, "v1", version)
+				ruleIdx := 2
+				return &regula.EvalResult{Value: rule.StringValue("success"), Version: "v1", Rule: &ruleIdx}, nil
+			}
+
+			res := call(t, "/rulesets/a/eval", `{"params": {}, "version": "v1", "explain": true}`, http.StatusOK)
+			ruleIdx := 2
+			require.Equal(t, &api.Explanation{Rule: &ruleIdx}, res.Explanation)
+			require.Equal(t, 1, s.EvalVersionCount)
+		})
+
+		t.Run("ExplainUnknownRule", func(t *testing.T) {
+			resetStore(s)
+			s.EvalFn = func(ctx context.Context, path string, params rule.Params) (*regula.EvalResult, error) {
+				return &regula.EvalResult{Value: rule.StringValue("success"), Version: "v1"}, nil
+			}
+
+			res := call(t, "/rulesets/a/eval", `{"params": {}, "explain": true}`, http.StatusOK)
+			require.Equal(t, &api.Explanation{}, res.Explanation)
+			require.Equal(t, 1, s.EvalCount)
+		})
+
+		t.Run("Errors", func(t *testing.T) {
+			resetStore(s)
+			s.EvalFn = func(ctx context.Context, path string, params rule.Params) (*regula.EvalResult, error) {
+				return nil, regula.ErrRulesetNotFound
+			}
+			call(t, "/rulesets/a/eval", `{"params": {}}`, http.StatusNotFound)
+
+			s.EvalFn = func(ctx context.Context, path string, params rule.Params) (*regula.EvalResult, error) {
+				return nil, rule.ErrParamTypeMismatch
+			}
+			call(t, "/rulesets/a/eval", `{"params": {}}`, http.StatusBadRequest)
+
+			call(t, "/rulesets/a/eval", `{`, http.StatusBadRequest)
+		})
+	})
+
+	t.Run("EvalBatch", func(t *testing.T) {
+		call := func(t *testing.T, url, body string, code int) *api.EvalBatchResults {
+			t.Helper()
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", url, strings.NewReader(body))
+			h.ServeHTTP(w, r)
+
+			require.Equal(t, code, w.Code)
+
+			if code != http.StatusOK {
+				return nil
+			}
+
+			var res api.EvalBatchResults
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+			return &res
+		}
+
+		evalFn := func(ctx context.Context, path string, params rule.Params) (*regula.EvalResult, error) {
+			if path == "a/2" {
+				return nil, regula.ErrRulesetNotFound
+			}
+
+			v, err := params.GetString("foo")
+			if err != nil {
+				return nil, err
+			}
+
+			ruleIdx := 1
+			return &regula.EvalResult{Value: rule.StringValue(path + v), Version: "v", Rule: &ruleIdx}, nil
+		}
+		ruleIdx := 1
+
+		t.Run("Paths", func(t *testing.T) {
+			resetStore(s)
+			s.EvalFn = evalFn
+
+			res := call(t, "/rulesets/?eval", `{"paths": ["a/1", "a/2"], "params": [{"foo": "x"}, {}]}`, http.StatusOK)
+			require.Len(t, res.Results, 4)
+			require.Equal(t, api.EvalBatchResult{Path: "a/1", Params: 0, Value: rule.StringValue("a/1x"), Version: "v", Rule: &ruleIdx}, res.Results[0])
+			require.Equal(t, api.EvalBatchResult{Path: "a/1", Params: 1, Error: rule.ErrParamNotFound.Error()}, res.Results[1])
+			require.Equal(t, api.EvalBatchResult{Path: "a/2", Params: 0, Error: regula.ErrRulesetNotFound.Error()}, res.Results[2])
+			require.Equal(t, 4, s.EvalCount)
+		})
+
+		t.Run("Prefix", func(t *testing.T) {
+			resetStore(s)
+			s.EvalFn = evalFn
+			s.ListFn = func(ctx context.Context, prefix string, opt store.ListOptions) (*store.RulesetEntries, error) {
+				require.Equal(t, "a/", prefix)
+				require.True(t, opt.LatestOnly)
+				return &store.RulesetEntries{Entries: []store.RulesetEntry{{Path: "a/1"}, {Path: "a/3"}}}, nil
+			}
+
+			res := call(t, "/rulesets/a/?eval", `{"params": [{"foo": "x"}]}`, http.StatusOK)
+			require.Len(t, res.Results, 2)
+			require.Equal(t, "a/3", res.Results[1].Path)
+			require.Equal(t, rule.StringValue("a/3x"), res.Results[1].Value)
+		})
+
+		t.Run("PrefixNotFound", func(t *testing.T) {
+			resetStore(s)
+			s.ListFn = func(ctx context.Context, prefix string, opt store.ListOptions) (*store.RulesetEntries, error) {
+				return nil, store.ErrNotFound
+			}
+
+			call(t, "/rulesets/a/?eval", `{}`, http.StatusNotFound)
+		})
+
+		t.Run("PathOutsidePrefix", func(t *testing.T) {
+			resetStore(s)
+
+			call(t, "/rulesets/a/?eval", `{"paths": ["b/1"]}`, http.StatusBadRequest)
+			require.Zero(t, s.EvalCount)
+
+			// the prefix is matched on segment boundaries.
+			call(t, "/rulesets/a?eval", `{"paths": ["ab/1"]}`, http.StatusBadRequest)
+			require.Zero(t, s.EvalCount)
+		})
+
+		t.Run("PrefixBoundary", func(t *testing.T) {
+			resetStore(s)
+			s.EvalFn = evalFn
+			s.ListFn = func(ctx context.Context, prefix string, opt store.ListOptions) (*store.RulesetEntries, error) {
+				return &store.RulesetEntries{Entries: []store.RulesetEntry{{Path: "a"}, {Path: "a/1"}, {Path: "ab/1"}}}, nil
+			}
+
+			res := call(t, "/rulesets/a?eval", `{"params": [{"foo": "x"}]}`, http.StatusOK)
+			require.Len(t, res.Results, 2)
+			require.Equal(t, "a", res.Results[0].Path)
+			require.Equal(t, "a/1", res.Results[1].Path)
+		})
+
+		t.Run("TypedParams", func(t *testing.T) {
+			resetStore(s)
+			s.EvalFn = func(ctx context.Context, path string, params rule.Params) (*regula.EvalResult, error) {
+				n, err := params.GetInt64("n")
+				require.NoError(t, err)
+				require.Equal(t, int64(10), n)
+				b, err := params.GetBool("b")
+				require.NoError(t, err)
+				require.True(t, b)
+				return &regula.EvalResult{Value: rule.StringValue("ok"), Version: "v"}, nil
+			}
+
+			res := call(t, "/rulesets/?eval", `{"paths": ["a/1"], "params": [{"n": 10, "b": true}]}`, http.StatusOK)
+			require.Len(t, res.Results, 1)
+			require.Empty(t, res.Results[0].Error)
+		})
+
+		t.Run("InternalError", func(t *testing.T) {
+			resetStore(s)
+			s.EvalFn = func(ctx context.Context, path string, params rule.Params) (*regula.EvalResult, error) {
+				return nil, errors.New("connection to the store lost")
+			}
+
+			res := call(t, "/rulesets/?eval", `{"paths": ["a/1"]}`, http.StatusOK)
+			require.Len(t, res.Results, 1)
+			require.Equal(t, "internal_error", res.Results[0].Error)
+		})
+
+		t.Run("TooLarge", func(t *testing.T) {
+			resetStore(s)
+
+			paths := make([]string, api.MaxEvalBatchSize/2+1)
+			for i := range paths {
+				paths[i] = fmt.Sprintf("a/%d", i)
+			}
+			body, err := json.Marshal(api.EvalBatchRequest{Paths: paths, Params: []map[string]interface{}{{}, {}}})
+			require.NoError(t, err)
+
+			call(t, "/rulesets/?eval", string(body), http.StatusBadRequest)
+			require.Zero(t, s.EvalCount)
+		})
+
+		t.Run("BadBody", func(t *testing.T) {
+			call(t, "/rulesets/?eval", `{`, http.StatusBadRequest)
+		})
+	})
+
+	t.Run("Diff", func(t *testing.T) {
+		r1, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("a")))
+		r2, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("b")))
+		entries := map[string]*store.RulesetEntry{
+			"v1": {Path: "a", Version: "v1", Ruleset: r1},
+			"v2": {Path: "a", Version: "v2", Ruleset: r2},
+		}
+
+		call := func(t *testing.T, url string, code int) *api.RulesetDiff {
+			t.Helper()
+			resetStore(s)
+
+			s.OneByVersionFn = func(ctx context.Context, path, version string) (*store.RulesetEntry, error) {
+				assert.Equal(t, "a", path)
+				e, ok := entries[version]
+				if !ok {
+					return nil, store.ErrNotFound
+				}
+				return e, nil
+			}
+			s.LatestFn = func(ctx context.Context, path string) (*store.RulesetEntry, error) {
+				return entries["v2"], nil
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", url, nil)
+			h.ServeHTTP(w, r)
+
+			require.Equal(t, code, w.Code)
+
+			if code != http.StatusOK {
+				return nil
+			}
+
+			var res api.RulesetDiff
+			err := json.NewDecoder(w.Body).Decode(&res)
+			require.NoError(t, err)
+			return &res
+		}
+
+		t.Run("OK", func(t *testing.T) {
+			res := call(t, "/rulesets/a?diff&from=v1&to=v2", http.StatusOK)
+			require.Equal(t, "a", res.Path)
+			require.Equal(t, "v1", res.From)
+			require.Equal(t, "v2", res.To)
+			require.Len(t, res.Changes.Rules, 1)
+			require.Equal(t, rule.StringValue("b"), res.Changes.Rules[0].Result.To)
+			require.Equal(t, 2, s.OneByVersionCount)
+		})
+
+		t.Run("Latest", func(t *testing.T) {
+			res := call(t, "/rulesets/a?diff&from=v1", http.StatusOK)
+			require.Equal(t, "v2", res.To)
+			require.Equal(t, 1, s.LatestCount)
+		})
+
+		t.Run("Same", func(t *testing.T) {
+			res := call(t, "/rulesets/a?diff&from=v1&to=v1", http.StatusOK)
+			require.True(t, res.Changes.Empty())
+		})
+
+		t.Run("MissingFrom", func(t *testing.T) {
+			call(t, "/rulesets/a?diff&to=v2", http.StatusBadRequest)
+		})
+
+		t.Run("NotFound", func(t *testing.T) {
+			call(t, "/rulesets/a?diff&from=v1&to=v3", http.StatusNotFound)
+		})
+	})
+
+	t.Run("Versions", func(t *testing.T) {
+		now := time.Now().UTC().Truncate(time.Second)
+		vs := store.RulesetVersions{
+			Path: "a",
+			Versions: []store.RulesetVersion{
+				{Version: "v2", CreatedAt: now},
+				{Version: "v1", CreatedAt: now.Add(-time.Hour)},
+			},
+			Continue: "sometoken",
+		}
+
+		call := func(t *testing.T, u string, code int, vs *store.RulesetVersions, err error) {
+			t.Helper()
+			resetStore(s)
+
+			uu, uerr := url.Parse(u)
+			require.NoError(t, uerr)
+			limit := uu.Query().Get("limit")
+			if limit == "" {
+				limit = "0"
+			}
+			token := uu.Query().Get("continue")
+
+			s.VersionsFn = func(ctx context.Context, path string, lm int, tk string) (*store.RulesetVersions, error) {
+				assert.Equal(t, "a", path)
+				assert.Equal(t, limit, strconv.Itoa(lm))
+				assert.Equal(t, token, tk)
+				return vs, err
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", u, nil)
+			h.ServeHTTP(w, r)
+
+			require.Equal(t, code, w.Code)
+
+			if code == http.StatusOK {
+				var res api.RulesetVersions
+				err := json.NewDecoder(w.Body).Decode(&res)
+				require.NoError(t, err)
+				require.Equal(t, vs.Path, res.Path)
+				require.Equal(t, vs.Continue, res.Continue)
+				require.Len(t, res.Versions, len(vs.Versions))
+				for i := range vs.Versions {
+					require.EqualValues(t, vs.Versions[i], res.Versions[i])
+				}
+			}
+		}
+
+		t.Run("OK", func(t *testing.T) {
+			call(t, "/rulesets/a?versions&limit=2&continue=abc", http.StatusOK, &vs, nil)
+			require.Equal(t, 1, s.VersionsCount)
+		})
+
+		t.Run("NotFound", func(t *testing.T) {
+			call(t, "/rulesets/a?versions", http.StatusNotFound, nil, store.ErrNotFound)
+		})
+
+		t.Run("InvalidToken", func(t *testing.T) {
+			call(t, "/rulesets/a?versions&continue=bad", http.StatusBadRequest, nil, store.ErrInvalidContinueToken)
+		})
+
+		t.Run("InvalidLimit", func(t *testing.T) {
+			call(t, "/rulesets/a?versions&limit=badlimit", http.StatusBadRequest, nil, nil)
+		})
+
+		t.Run("UnexpectedError", func(t *testing.T) {
+			call(t, "/rulesets/a?versions", http.StatusInternalServerError, nil, errors.New("unexpected error"))
+		})
+	})
+
+	t.Run("History", func(t *testing.T) {
+		now := time.Now().UTC().Truncate(time.Second)
+		hs := store.AuditEntries{
+			Path: "a",
+			Entries: []store.AuditEntry{
+				{Path: "a", Version: "v2", PreviousVersion: "v1", Author: "bob", Message: "fix", Time: now},
+				{Path: "a", Version: "v1", Author: "alice", Time: now.Add(-time.Hour)},
+			},
+			Continue: "sometoken",
+		}
+
+		call := func(t *testing.T, u string, code int, hs *store.AuditEntries, err error) {
+			t.Helper()
+			resetStore(s)
+
+			uu, uerr := url.Parse(u)
+			require.NoError(t, uerr)
+			limit := uu.Query().Get("limit")
+			if limit == "" {
+				limit = "0"
+			}
+			token := uu.Query().Get("continue")
+
+			s.HistoryFn = func(ctx context.Context, path string, lm int, tk string) (*store.AuditEntries, error) {
+				assert.Equal(t, "a", path)
+				assert.Equal(t, limit, strconv.Itoa(lm))
+				assert.Equal(t, token, tk)
+				return hs, err
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", u, nil)
+			h.ServeHTTP(w, r)
+
+			require.Equal(t, code, w.Code)
+
+			if code == http.StatusOK {
+				var res api.History
+				err := json.NewDecoder(w.Body).Decode(&res)
+				require.NoError(t, err)
+				require.Equal(t, hs.Path, res.Path)
+				require.Equal(t, hs.Continue, res.Continue)
+				require.Len(t, res.Entries, len(hs.Entries))
+				for i := range hs.Entries {
+					require.EqualValues(t, hs.Entries[i], res.Entries[i])
+				}
+			}
+		}
+
+		t.Run("OK", func(t *testing.T) {
+			call(t, "/rulesets/a?history&limit=2&continue=abc", http.StatusOK, &hs, nil)
+			require.Equal(t, 1, s.HistoryCount)
+		})
+
+		t.Run("NotFound", func(t *testing.T) {
+			call(t, "/rulesets/a?history", http.StatusNotFound, nil, store.ErrNotFound)
+		})
+
+		t.Run("InvalidToken", func(t *testing.T) {
+			call(t, "/rulesets/a?history&continue=bad", http.StatusBadRequest, nil, store.ErrInvalidContinueToken)
+		})
+
+		t.Run("InvalidLimit", func(t *testing.T) {
+			call(t, "/rulesets/a?history&limit=badlimit", http.StatusBadRequest, nil, nil)
+		})
+
+		t.Run("UnexpectedError", func(t *testing.T) {
+			call(t, "/rulesets/a?history", http.StatusInternalServerError, nil, errors.New("unexpected error"))
+		})
+	})
+
 	t.Run("Watch", func(t *testing.T) {
 		r1, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(true)))
 		r2, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(true)))
@@ -293,6 +760,109 @@ func TestAPI(t *testing.T) {
 		t.Run("Timeout", func(t *testing.T) {
 			call(t, "/rulesets/?watch", http.StatusOK, nil, context.DeadlineExceeded)
 		})
+
+		t.Run("Compacted", func(t *testing.T) {
+			call(t, "/rulesets/?watch&revision=1", http.StatusGone, nil, store.ErrRevisionCompacted)
+		})
+	})
+
+	t.Run("WatchStream", func(t *testing.T) {
+		r1, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(true)))
+
+		t.Run("Events", func(t *testing.T) {
+			var revisions []string
+			s.WatchFn = func(ctx context.Context, prefix string, revision string) (*store.RulesetEvents, error) {
+				require.Equal(t, "a", prefix)
+				revisions = append(revisions, revision)
+
+				switch len(revisions) {
+				case 1:
+					return &store.RulesetEvents{
+						Events:   []store.RulesetEvent{{Type: store.RulesetPutEvent, Path: "a/b", Version: "1", Ruleset: r1}},
+						Revision: "rev1",
+					}, nil
+				case 2:
+					return &store.RulesetEvents{
+						Events:   []store.RulesetEvent{{Type: store.RulesetDeleteEvent, Path: "a/b", Version: "1"}},
+						Revision: "rev2",
+					}, nil
+				}
+
+				// the stream ends on errors.
+				return nil, errors.New("unexpected error")
+			}
+			defer func() { s.WatchFn = nil }()
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "/rulesets/a?watch&stream&revision=somerev", nil)
+			// the Last-Event-ID header sent by reconnecting clients takes precedence.
+			r.Header.Set("Last-Event-ID", "lastrev")
+			h.ServeHTTP(w, r)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			require.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+			require.Equal(t, []string{"lastrev", "rev1", "rev2"}, revisions)
+
+			e1, err := json.Marshal(api.Events{Events: []api.Event{{Type: store.RulesetPutEvent, Path: "a/b", Version: "1", Ruleset: r1}}, Revision: "rev1"})
+			require.NoError(t, err)
+			e2, err := json.Marshal(api.Events{Events: []api.Event{{Type: store.RulesetDeleteEvent, Path: "a/b", Version: "1"}}, Revision: "rev2"})
+			require.NoError(t, err)
+
+			require.Equal(t, "id: rev1\nevent: events\ndata: "+string(e1)+"\n\n"+
+				"id: rev2\nevent: events\ndata: "+string(e2)+"\n\n"+
+				"event: error\ndata: {\"error\":\"internal_error\"}\n\n", w.Body.String())
+		})
+
+		t.Run("NoEvents", func(t *testing.T) {
+			s.WatchFn = func(ctx context.Context, prefix string, revision string) (*store.RulesetEvents, error) {
+				return nil, nil
+			}
+			defer func() { s.WatchFn = nil }()
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", "/rulesets/a?watch&stream", nil))
+
+			require.Equal(t, http.StatusOK, w.Code)
+			require.Equal(t, "event: error\ndata: {\"error\":\"internal_error\"}\n\n", w.Body.String())
+		})
+
+		t.Run("Compacted", func(t *testing.T) {
+			s.WatchFn = func(ctx context.Context, prefix string, revision string) (*store.RulesetEvents, error) {
+				return nil, store.ErrRevisionCompacted
+			}
+			defer func() { s.WatchFn = nil }()
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", "/rulesets/a?watch&stream&revision=1", nil))
+
+			require.Equal(t, http.StatusOK, w.Code)
+			require.Equal(t, "event: error\ndata: {\"error\":\"revision compacted\"}\n\n", w.Body.String())
+		})
+
+		t.Run("Heartbeat", func(t *testing.T) {
+			h := NewHandler(context.Background(), s, Config{
+				WatchHeartbeat: 10 * time.Millisecond,
+				Logger:         &log,
+			})
+
+			s.WatchFn = func(ctx context.Context, prefix string, revision string) (*store.RulesetEvents, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			defer func() { s.WatchFn = nil }()
+
+			ts := httptest.NewServer(h)
+			// waits for the stream to notice the client went away.
+			defer ts.Close()
+
+			resp, err := http.Get(ts.URL + "/rulesets/?watch&stream")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			line, err := bufio.NewReader(resp.Body).ReadString('\n')
+			require.NoError(t, err)
+			require.Equal(t, ": heartbeat\n", line)
+		})
 	})
 
 	t.Run("Put", func(t *testing.T) {
@@ -306,7 +876,7 @@ func TestAPI(t *testing.T) {
 		call := func(t *testing.T, url string, code int, e *store.RulesetEntry, putErr error) {
 			t.Helper()
 
-			s.PutFn = func(context.Context, string) (*store.RulesetEntry, error) {
+			s.PutFn = func(context.Context, string, ...store.PutOption) (*store.RulesetEntry, error) {
 				return e, putErr
 			}
 			defer func() { s.PutFn = nil }()
@@ -337,6 +907,44 @@ func TestAPI(t *testing.T) {
 			call(t, "/rulesets/a", http.StatusOK, &e1, store.ErrNotModified)
 		})
 
+		t.Run("Audit", func(t *testing.T) {
+			s.PutFn = func(ctx context.Context, path string, opts ...store.PutOption) (*store.RulesetEntry, error) {
+				require.Equal(t, store.Audit{Author: "bob", Message: "some message"}, store.AuditFromContext(ctx))
+				return &e1, nil
+			}
+			defer func() { s.PutFn = nil }()
+
+			var buf bytes.Buffer
+			require.NoError(t, json.NewEncoder(&buf).Encode(r1))
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("PUT", "/rulesets/a", &buf)
+			r.Header.Set(api.AuthorHeader, "bob")
+			r.Header.Set(api.MessageHeader, "some message")
+			h.ServeHTTP(w, r)
+
+			require.Equal(t, http.StatusOK, w.Code)
+		})
+
+		t.Run("VersionMismatch", func(t *testing.T) {
+			s.PutFn = func(ctx context.Context, path string, opts ...store.PutOption) (*store.RulesetEntry, error) {
+				require.Equal(t, "old", store.NewPutOptions(opts...).ExpectedVersion)
+				return &e1, store.ErrVersionMismatch
+			}
+			defer func() { s.PutFn = nil }()
+
+			var buf bytes.Buffer
+			require.NoError(t, json.NewEncoder(&buf).Encode(r1))
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("PUT", "/rulesets/a", &buf)
+			r.Header.Set("If-Match", `"old"`)
+			h.ServeHTTP(w, r)
+
+			require.Equal(t, http.StatusPreconditionFailed, w.Code)
+			require.Equal(t, `"version"`, w.Header().Get("ETag"))
+		})
+
 		t.Run("EmptyPath", func(t *testing.T) {
 			call(t, "/rulesets/", http.StatusNotFound, &e1, nil)
 		})
@@ -353,21 +961,295 @@ func TestAPI(t *testing.T) {
 			call(t, "/rulesets/a", http.StatusBadRequest, nil, new(store.ValidationError))
 		})
 	})
+
+	t.Run("PutBatch", func(t *testing.T) {
+		r1, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(true)))
+
+		call := func(t *testing.T, body string, code int, batchErr error) {
+			t.Helper()
+			resetStore(s)
+
+			s.PutBatchFn = func(ctx context.Context, entries []store.RulesetEntry) ([]store.RulesetEntry, error) {
+				require.Equal(t, "bob", store.AuditFromContext(ctx).Author)
+				for i := range entries {
+					entries[i].Version = "v" + entries[i].Path
+				}
+				return entries, batchErr
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("PUT", "/rulesets/?batch", strings.NewReader(body))
+			r.Header.Set(api.AuthorHeader, "bob")
+			h.ServeHTTP(w, r)
+
+			require.Equal(t, code, w.Code)
+
+			if code == http.StatusOK {
+				var rss api.Rulesets
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&rss))
+				require.Len(t, rss.Rulesets, 2)
+				require.Equal(t, "a", rss.Rulesets[0].Path)
+				require.Equal(t, "vb", rss.Rulesets[1].Version)
+			}
+		}
+
+		var buf bytes.Buffer
+		require.NoError(t, json.NewEncoder(&buf).Encode(api.Rulesets{Rulesets: []api.Ruleset{
+			{Path: "a", Ruleset: r1},
+			{Path: "b", Ruleset: r1},
+		}}))
+		body := buf.String()
+
+		t.Run("OK", func(t *testing.T) {
+			call(t, body, http.StatusOK, nil)
+			require.Equal(t, 1, s.PutBatchCount)
+		})
+
+		t.Run("NotModified", func(t *testing.T) {
+			call(t, body, http.StatusOK, store.ErrNotModified)
+		})
+
+		t.Run("EmptyBatch", func(t *testing.T) {
+			call(t, `{"rulesets": []}`, http.StatusBadRequest, nil)
+			require.Zero(t, s.PutBatchCount)
+		})
+
+		t.Run("ValidationError", func(t *testing.T) {
+			call(t, body, http.StatusBadRequest, new(store.ValidationError))
+		})
+
+		t.Run("StoreError", func(t *testing.T) {
+			call(t, body, http.StatusInternalServerError, errors.New("some error"))
+		})
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		r1, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(true)))
+		e1 := store.RulesetEntry{
+			Path:    "a",
+			Version: "v1",
+			Ruleset: r1,
+		}
+
+		call := func(t *testing.T, url string, code int, e *store.RulesetEntry, rbErr error) {
+			t.Helper()
+			resetStore(s)
+
+			s.RollbackFn = func(ctx context.Context, path, version string) (*store.RulesetEntry, error) {
+				assert.Equal(t, "a", path)
+				assert.Equal(t, "v1", version)
+				assert.Equal(t, store.Audit{Author: "bob", Message: "revert"}, store.AuditFromContext(ctx))
+				return e, rbErr
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", url, nil)
+			r.Header.Set(api.AuthorHeader, "bob")
+			r.Header.Set(api.MessageHeader, "revert")
+			h.ServeHTTP(w, r)
+
+			require.Equal(t, code, w.Code)
+
+			if code == http.StatusOK {
+				var rs api.Ruleset
+				err := json.NewDecoder(w.Body).Decode(&rs)
+				require.NoError(t, err)
+				require.EqualValues(t, *e, rs)
+			}
+		}
+
+		t.Run("OK", func(t *testing.T) {
+			call(t, "/rulesets/a?rollback&version=v1", http.StatusOK, &e1, nil)
+			require.Equal(t, 1, s.RollbackCount)
+		})
+
+		t.Run("NotModified", func(t *testing.T) {
+			call(t, "/rulesets/a?rollback&version=v1", http.StatusOK, &e1, store.ErrNotModified)
+		})
+
+		t.Run("MissingVersion", func(t *testing.T) {
+			call(t, "/rulesets/a?rollback", http.StatusBadRequest, nil, nil)
+			require.Zero(t, s.RollbackCount)
+		})
+
+		t.Run("NotFound", func(t *testing.T) {
+			call(t, "/rulesets/a?rollback&version=v1", http.StatusNotFound, nil, store.ErrNotFound)
+		})
+
+		t.Run("StoreError", func(t *testing.T) {
+			call(t, "/rulesets/a?rollback&version=v1", http.StatusInternalServerError, nil, errors.New("some error"))
+		})
+	})
+
+	t.Run("Signatures", func(t *testing.T) {
+		sig := store.Signature{
+			ReturnType: "string",
+			ParamTypes: map[string]string{"a": "int64"},
+		}
+
+		t.Run("OK", func(t *testing.T) {
+			resetStore(s)
+			s.SignaturesFn = func(ctx context.Context, path string) ([]store.Signature, error) {
+				assert.Equal(t, "a", path)
+				return []store.Signature{sig}, nil
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "/rulesets/a?signature", nil)
+			h.ServeHTTP(w, r)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			var res api.Signatures
+			err := json.NewDecoder(w.Body).Decode(&res)
+			require.NoError(t, err)
+			require.Equal(t, "a", res.Path)
+			require.Equal(t, []api.Signature{api.Signature(sig)}, res.Signatures)
+		})
+
+		t.Run("NotFound", func(t *testing.T) {
+			resetStore(s)
+			s.SignaturesFn = func(ctx context.Context, path string) ([]store.Signature, error) {
+				return nil, store.ErrNotFound
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "/rulesets/a?signature", nil)
+			h.ServeHTTP(w, r)
+
+			require.Equal(t, http.StatusNotFound, w.Code)
+		})
+	})
+
+	t.Run("MigrateSignature", func(t *testing.T) {
+		sig := store.Signature{
+			ReturnType: "string",
+			ParamTypes: map[string]string{"a": "float64", "b": "bool"},
+			Optional:   []string{"b"},
+		}
+
+		call := func(t *testing.T, body string, code int, migErr error) {
+			t.Helper()
+			resetStore(s)
+
+			s.MigrateSignatureFn = func(ctx context.Context, path string, m *store.SignatureMigration) (*store.Signature, error) {
+				assert.Equal(t, "a", path)
+				assert.Equal(t, &store.SignatureMigration{
+					AddParams:   map[string]string{"b": "bool"},
+					WidenParams: map[string]string{"a": "float64"},
+				}, m)
+				assert.Equal(t, "bob", store.AuditFromContext(ctx).Author)
+				return &sig, migErr
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "/rulesets/a?signature", bytes.NewReader([]byte(body)))
+			r.Header.Set(api.AuthorHeader, "bob")
+			h.ServeHTTP(w, r)
+
+			require.Equal(t, code, w.Code)
+
+			if code == http.StatusOK {
+				var res api.Signature
+				err := json.NewDecoder(w.Body).Decode(&res)
+				require.NoError(t, err)
+				require.Equal(t, api.Signature(sig), res)
+			}
+		}
+
+		body := `{"addParams": {"b": "bool"}, "widenParams": {"a": "float64"}}`
+
+		t.Run("OK", func(t *testing.T) {
+			call(t, body, http.StatusOK, nil)
+			require.Equal(t, 1, s.MigrateSignatureCount)
+		})
+
+		t.Run("BadBody", func(t *testing.T) {
+			call(t, "{", http.StatusBadRequest, nil)
+			require.Zero(t, s.MigrateSignatureCount)
+		})
+
+		t.Run("ValidationError", func(t *testing.T) {
+			call(t, body, http.StatusBadRequest, new(store.ValidationError))
+		})
+
+		t.Run("NotFound", func(t *testing.T) {
+			call(t, body, http.StatusNotFound, store.ErrNotFound)
+		})
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		call := func(t *testing.T, url string, code int, hard bool, delErr error) {
+			t.Helper()
+			resetStore(s)
+
+			s.DeleteFn = func(ctx context.Context, path string, h bool) error {
+				assert.Equal(t, "a", path)
+				assert.Equal(t, hard, h)
+				assert.Equal(t, "bob", store.AuditFromContext(ctx).Author)
+				return delErr
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("DELETE", url, nil)
+			r.Header.Set(api.AuthorHeader, "bob")
+			h.ServeHTTP(w, r)
+
+			require.Equal(t, code, w.Code)
+		}
+
+		t.Run("Soft", func(t *testing.T) {
+			call(t, "/rulesets/a", http.StatusNoContent, false, nil)
+			require.Equal(t, 1, s.DeleteCount)
+		})
+
+		t.Run("Hard", func(t *testing.T) {
+			call(t, "/rulesets/a?hard", http.StatusNoContent, true, nil)
+		})
+
+		t.Run("EmptyPath", func(t *testing.T) {
+			call(t, "/rulesets/", http.StatusNotFound, false, nil)
+			require.Zero(t, s.DeleteCount)
+		})
+
+		t.Run("NotFound", func(t *testing.T) {
+			call(t, "/rulesets/a", http.StatusNotFound, false, store.ErrNotFound)
+		})
+
+		t.Run("StoreError", func(t *testing.T) {
+			call(t, "/rulesets/a", http.StatusInternalServerError, false, errors.New("some error"))
+		})
+	})
 }
 
 func resetStore(s *mockRulesetService) {
 	s.ListCount = 0
 	s.LatestCount = 0
 	s.OneByVersionCount = 0
+	s.VersionsCount = 0
+	s.HistoryCount = 0
+	s.RollbackCount = 0
+	s.SignaturesCount = 0
+	s.MigrateSignatureCount = 0
+	s.DeleteCount = 0
+	s.DeleteVersionsCount = 0
 	s.WatchCount = 0
 	s.PutCount = 0
+	s.PutBatchCount = 0
 	s.EvalCount = 0
 	s.EvalVersionCount = 0
 	s.ListFn = nil
 	s.LatestFn = nil
 	s.OneByVersionFn = nil
+	s.VersionsFn = nil
+	s.HistoryFn = nil
+	s.RollbackFn = nil
+	s.SignaturesFn = nil
+	s.MigrateSignatureFn = nil
+	s.DeleteFn = nil
+	s.DeleteVersionsFn = nil
 	s.WatchFn = nil
 	s.PutFn = nil
+	s.PutBatchFn = nil
 	s.EvalFn = nil
 	s.EvalVersionFn = nil
 }
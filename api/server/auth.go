@@ -0,0 +1,192 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	jwt "github.com/golang-jwt/jwt"
+	"github.com/pkg/errors"
+)
+
+// A Right allows a set of operations on the rulesets.
+type Right string
+
+// List of rights.
+const (
+	// RightRead allows fetching, listing and watching rulesets, their versions and signatures.
+	RightRead Right = "read"
+	// RightEval allows evaluating rulesets.
+	RightEval Right = "eval"
+	// RightWrite allows creating, deleting, rolling back rulesets and migrating their signatures.
+	RightWrite Right = "write"
+)
+
+// A Grant gives rights on the rulesets whose path is Prefix or is below it. Prefixes match whole
+// path segments: "pricing" matches "pricing" and "pricing/fees" but not "pricing-secret".
+// The rulesets of a namespace are matched using their path prefixed by the name of the namespace
+// and a colon, i.e. "name:path". Prefixes only match within a single namespace: a prefix without a colon
+// only grants rights on the rulesets of the default namespace, and the rights on the rulesets of a namespace
+// must be granted with a prefix starting with the full name of the namespace, e.g. "name:" for all of them.
+type Grant struct {
+	Prefix string
+	Rights []Right
+}
+
+// AuthConfig configures the authentication and authorization of the requests.
+// Clients authenticate with an "Authorization: Bearer <token>" header, the token being either
+// a static token or an HMAC signed JWT whose subject is read from the "sub" claim.
+type AuthConfig struct {
+	// Tokens maps static tokens to the subject they authenticate.
+	Tokens map[string]string
+	// JWTSecret is the secret used to validate the HMAC signature of JWTs.
+	// JWTs are rejected if empty.
+	JWTSecret []byte
+	// Grants maps each subject to the rights it was granted.
+	Grants map[string][]Grant
+}
+
+// Enabled reports whether the requests must be authenticated.
+func (c *AuthConfig) Enabled() bool {
+	return len(c.Tokens) > 0 || len(c.JWTSecret) > 0
+}
+
+// HTTP errors
+var (
+	errUnauthenticated = errors.New("unauthenticated")
+	errForbidden       = errors.New("forbidden")
+)
+
+type contextKey int
+
+const subjectKey contextKey = iota
+
+type auth struct {
+	cfg AuthConfig
+}
+
+// handler returns a middleware rejecting the requests that can't be authenticated
+// and storing the subject of the others in their context.
+func (a *auth) handler(s *service) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			subject, err := a.authenticate(r)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				s.writeError(w, r, errUnauthenticated, http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), subjectKey, subject)))
+		})
+	}
+}
+
+// authenticated returns a handler rejecting the requests that can't be authenticated before calling next,
+// for the handlers served outside of the API.
+func (a *auth) authenticated(next http.Handler) http.Handler {
+	return a.handler(new(service))(next)
+}
+
+// authenticate returns the subject authenticated by the bearer token of the request.
+func (a *auth) authenticate(r *http.Request) (string, error) {
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(h, "Bearer ") {
+		return "", errors.New("missing bearer token")
+	}
+
+	return a.cfg.Authenticate(strings.TrimSpace(strings.TrimPrefix(h, "Bearer ")))
+}
+
+// authorized reports whether the subject of the request was granted the right on the given resource.
+func (a *auth) authorized(r *http.Request, resource string, right Right) bool {
+	subject, _ := r.Context().Value(subjectKey).(string)
+
+	return a.cfg.Authorized(subject, resource, right)
+}
+
+// Authenticate returns the subject authenticated by the given static token or JWT.
+func (c *AuthConfig) Authenticate(token string) (string, error) {
+	for t, subject := range c.Tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			return subject, nil
+		}
+	}
+
+	if len(c.JWTSecret) == 0 {
+		return "", errors.New("unknown token")
+	}
+
+	var claims jwt.StandardClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+
+		return c.JWTSecret, nil
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "invalid token")
+	}
+
+	if claims.Subject == "" {
+		return "", errors.New("missing token subject")
+	}
+
+	return claims.Subject, nil
+}
+
+// Authorized reports whether the subject was granted the right on the given resource.
+// See Grant for the format of the resources.
+func (c *AuthConfig) Authorized(subject, resource string, right Right) bool {
+	namespace, path := splitResource(resource)
+
+	for _, g := range c.Grants[subject] {
+		ns, prefix := splitResource(g.Prefix)
+		if ns != namespace || !underPrefix(path, prefix) {
+			continue
+		}
+
+		for _, rg := range g.Rights {
+			if rg == right {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// authorize checks that the request is allowed to use the right on the given resource.
+// If not, it writes an error to the response and returns false.
+// All requests are authorized when authentication is disabled.
+func (s *service) authorize(w http.ResponseWriter, r *http.Request, resource string, right Right) bool {
+	if s.auth == nil || s.auth.authorized(r, resource, right) {
+		return true
+	}
+
+	s.writeError(w, r, errForbidden, http.StatusForbidden)
+	return false
+}
+
+// Resource returns the resource matched by the grants for the given path of a namespace, see Grant.
+// It is shared by the APIs checking the grants with AuthConfig.Authorized.
+func Resource(namespace, path string) string {
+	if namespace == "" {
+		return path
+	}
+
+	return namespace + ":" + path
+}
+
+// splitResource returns the namespace and the path of the given resource or grant prefix.
+// The namespace is empty for the resources of the default namespace.
+func splitResource(resource string) (namespace, path string) {
+	i := strings.Index(resource, ":")
+	if i < 0 {
+		return "", resource
+	}
+
+	return resource[:i], resource[i+1:]
+}
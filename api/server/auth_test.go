@@ -0,0 +1,193 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt"
+	"github.com/heetch/regula/api"
+	"github.com/heetch/regula/store/memory"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuth(t *testing.T) {
+	secret := []byte("secret")
+	ns := memory.NewNamespaceService()
+	require.NoError(t, ns.Create(context.Background(), "tenant"))
+
+	log := zerolog.New(ioutil.Discard)
+	h := NewHandler(context.Background(), memory.NewRulesetService(), Config{
+		Logger:     &log,
+		Namespaces: ns,
+		Auth: AuthConfig{
+			Tokens:    map[string]string{"admin-token": "admin", "reader-token": "reader", "ci-token": "ci"},
+			JWTSecret: secret,
+			Grants: map[string][]Grant{
+				"admin":  {{Prefix: "", Rights: []Right{RightRead, RightEval, RightWrite}}},
+				"reader": {{Prefix: "a/", Rights: []Right{RightRead}}},
+				"svc":    {{Prefix: "a/", Rights: []Right{RightEval}}, {Prefix: "tenant:", Rights: []Right{RightWrite}}},
+				"ci":     {{Prefix: "prod", Rights: []Right{RightRead, RightWrite}}},
+			},
+		},
+	})
+
+	sign := func(method jwt.SigningMethod, key interface{}, claims jwt.StandardClaims) string {
+		s, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		require.NoError(t, err)
+		return s
+	}
+
+	svcToken := sign(jwt.SigningMethodHS256, secret, jwt.StandardClaims{Subject: "svc"})
+	rs := `{"type":"string","rules":[{"expr":{"kind":"value","type":"bool","data":"true"},"result":{"kind":"value","type":"string","data":"a"}}]}`
+
+	tests := []struct {
+		name   string
+		token  string
+		method string
+		url    string
+		body   string
+		code   int
+	}{
+		{"NoToken", "", "GET", "/rulesets/?list", "", http.StatusUnauthorized},
+		{"UnknownToken", "foo", "GET", "/rulesets/?list", "", http.StatusUnauthorized},
+		{"AdminPut", "admin-token", "PUT", "/rulesets/a/b", rs, http.StatusOK},
+		{"AdminNamespaces", "admin-token", "GET", "/namespaces/", "", http.StatusOK},
+		{"ReaderList", "reader-token", "GET", "/rulesets/a/?list", "", http.StatusOK},
+		{"ReaderListAll", "reader-token", "GET", "/rulesets/?list", "", http.StatusForbidden},
+		{"ReaderEval", "reader-token", "GET", "/rulesets/a/b?eval", "", http.StatusForbidden},
+		{"ReaderPut", "reader-token", "PUT", "/rulesets/a/b", rs, http.StatusForbidden},
+		{"ReaderNamespace", "reader-token", "GET", "/namespaces/tenant/rulesets/a/?list", "", http.StatusForbidden},
+		{"AdminNamespacePut", "admin-token", "PUT", "/namespaces/tenant/rulesets/a/b", rs, http.StatusForbidden},
+		{"PrefixCreateNamespace", "ci-token", "PUT", "/namespaces/prod", "", http.StatusForbidden},
+		{"PrefixNamespaceList", "ci-token", "GET", "/namespaces/tenant/rulesets/?list", "", http.StatusForbidden},
+		{"PrefixPut", "ci-token", "PUT", "/rulesets/prod/a", rs, http.StatusOK},
+		{"PrefixSiblingPut", "ci-token", "PUT", "/rulesets/production/a", rs, http.StatusForbidden},
+		{"PrefixSiblingList", "ci-token", "GET", "/rulesets/production?list", "", http.StatusForbidden},
+		{"JWTEval", svcToken, "GET", "/rulesets/a/b?eval", "", http.StatusOK},
+		{"JWTEvalJSON", svcToken, "POST", "/rulesets/a/b/eval", `{"params": {}}`, http.StatusOK},
+		{"ReaderEvalJSON", "reader-token", "POST", "/rulesets/a/b/eval", `{"params": {}}`, http.StatusForbidden},
+		{"JWTEvalBatch", svcToken, "POST", "/rulesets/?eval", `{"paths": ["a/b"]}`, http.StatusOK},
+		{"JWTEvalBatchForbiddenPath", svcToken, "POST", "/rulesets/?eval", `{"paths": ["a/b", "b"]}`, http.StatusForbidden},
+		{"JWTEvalPrefix", svcToken, "POST", "/rulesets/?eval", `{}`, http.StatusForbidden},
+		{"JWTRead", svcToken, "GET", "/rulesets/a/?list", "", http.StatusForbidden},
+		{"JWTNamespacePut", svcToken, "PUT", "/namespaces/tenant/rulesets/a/b", rs, http.StatusOK},
+		{"JWTCreateNamespace", svcToken, "PUT", "/namespaces/other", "", http.StatusForbidden},
+		{"JWTBadSecret", sign(jwt.SigningMethodHS256, []byte("other"), jwt.StandardClaims{Subject: "svc"}), "GET", "/rulesets/a/b?eval", "", http.StatusUnauthorized},
+		{"JWTExpired", sign(jwt.SigningMethodHS256, secret, jwt.StandardClaims{Subject: "svc", ExpiresAt: time.Now().Add(-time.Minute).Unix()}), "GET", "/rulesets/a/b?eval", "", http.StatusUnauthorized},
+		{"JWTNoSubject", sign(jwt.SigningMethodHS256, secret, jwt.StandardClaims{}), "GET", "/rulesets/a/b?eval", "", http.StatusUnauthorized},
+		{"JWTNone", sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, jwt.StandardClaims{Subject: "svc"}), "GET", "/rulesets/a/b?eval", "", http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(test.method, test.url, strings.NewReader(test.body))
+			if test.token != "" {
+				r.Header.Set("Authorization", "Bearer "+test.token)
+			}
+			r.Header.Set(api.AuthorHeader, "someone")
+			h.ServeHTTP(w, r)
+
+			require.Equal(t, test.code, w.Code, w.Body.String())
+		})
+	}
+
+	t.Run("Author", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/rulesets/a/b?history", nil)
+		r.Header.Set("Authorization", "Bearer reader-token")
+		h.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		// the authenticated subject is recorded instead of the author header
+		var hs api.History
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&hs))
+		require.Len(t, hs.Entries, 1)
+		require.Equal(t, "admin", hs.Entries[0].Author)
+	})
+
+	t.Run("Actions", func(t *testing.T) {
+		call := func(method, url, body string, code int) *httptest.ResponseRecorder {
+			t.Helper()
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(method, url, strings.NewReader(body))
+			r.Header.Set("Authorization", "Bearer admin-token")
+			r.Header.Set(api.AuthorHeader, "someone")
+			h.ServeHTTP(w, r)
+			require.Equal(t, code, w.Code, w.Body.String())
+			return w
+		}
+
+		var first api.Ruleset
+		require.NoError(t, json.NewDecoder(call("PUT", "/rulesets/c", rs, http.StatusOK).Body).Decode(&first))
+		call("PUT", "/rulesets/c", strings.Replace(rs, `"data":"a"`, `"data":"b"`, 1), http.StatusOK)
+		call("POST", "/rulesets/c?rollback&version="+first.Version, "", http.StatusOK)
+		call("POST", "/rulesets/c?signature", `{"addParams": {"foo": "string"}}`, http.StatusOK)
+		call("DELETE", "/rulesets/c", "", http.StatusNoContent)
+
+		// the authenticated subject is recorded for every action
+		var hs api.History
+		require.NoError(t, json.NewDecoder(call("GET", "/rulesets/c?history", "", http.StatusOK).Body).Decode(&hs))
+		require.Len(t, hs.Entries, 5)
+		for _, e := range hs.Entries {
+			require.Equal(t, "admin", e.Author, e.Action)
+		}
+	})
+}
+
+func TestAuthorized(t *testing.T) {
+	cfg := AuthConfig{
+		Grants: map[string][]Grant{
+			"all":     {{Prefix: "", Rights: []Right{RightRead}}},
+			"p":       {{Prefix: "p", Rights: []Right{RightWrite}}},
+			"pricing": {{Prefix: "pricing", Rights: []Right{RightRead}}},
+			"prod":    {{Prefix: "prod:", Rights: []Right{RightWrite}}},
+			"prefix":  {{Prefix: "prod:a/", Rights: []Right{RightEval}}},
+		},
+	}
+
+	tests := []struct {
+		subject  string
+		resource string
+		right    Right
+		ok       bool
+	}{
+		{"all", "a/b", RightRead, true},
+		{"all", "", RightRead, true},
+		{"all", "prod:a/b", RightRead, false},
+		{"all", "a/b", RightWrite, false},
+		{"p", "p/a", RightWrite, true},
+		{"p", "p", RightWrite, true},
+		{"p", "prod/a", RightWrite, false},
+		{"p", "prod:", RightWrite, false},
+		{"p", "prod:a/b", RightWrite, false},
+		{"p", "p:a", RightWrite, false},
+		{"prod", "prod:", RightWrite, true},
+		{"prod", "prod:a/b", RightWrite, true},
+		{"prod", "production:a/b", RightWrite, false},
+		{"prod", "prod/a", RightWrite, false},
+		{"pricing", "pricing/fees", RightRead, true},
+		{"pricing", "pricing-secret/fees", RightRead, false},
+		{"pricing", "pricing-secret", RightRead, false},
+		{"prefix", "prod:a/b", RightEval, true},
+		{"prefix", "prod:a", RightEval, true},
+		{"prefix", "prod:ab/c", RightEval, false},
+		{"prefix", "prod:b", RightEval, false},
+		{"prefix", "a/b", RightEval, false},
+		{"unknown", "a/b", RightRead, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.subject+" "+test.resource, func(t *testing.T) {
+			require.Equal(t, test.ok, cfg.Authorized(test.subject, test.resource, test.right))
+		})
+	}
+}
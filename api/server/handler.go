@@ -16,7 +16,8 @@ import (
 
 // HTTP errors
 var (
-	errInternal = errors.New("internal_error")
+	errInternal          = errors.New("internal_error")
+	errNamespaceNotFound = errors.New("namespace not found")
 )
 
 // Config contains the API configuration.
@@ -24,12 +25,30 @@ type Config struct {
 	Logger       *zerolog.Logger
 	Timeout      time.Duration
 	WatchTimeout time.Duration
+	// WatchHeartbeat is the interval between two heartbeats sent on idle watch streams. Defaults to 15s.
+	WatchHeartbeat time.Duration
+	// Retention policy enforced in the background by the server, if enabled.
+	Retention store.RetentionPolicy
+	// PruneInterval is the interval between two enforcements of the retention policy.
+	PruneInterval time.Duration
+	// Namespaces, if set, serves the rulesets of every namespace under /namespaces/{name}/rulesets/.
+	Namespaces store.NamespaceService
+	// Auth, if enabled, requires the requests to be authenticated and authorized.
+	Auth AuthConfig
+	// Metrics, if set, records the metrics of the requests and of the evaluations.
+	// They are exposed on /metrics by the Server, which requires the requests to be authenticated if Auth is enabled.
+	Metrics *Metrics
 }
 
 // NewHandler creates an http handler to serve the rules engine API.
 func NewHandler(ctx context.Context, rsService store.RulesetService, cfg Config) http.Handler {
 	s := service{
-		rulesets: rsService,
+		rulesets: cfg.Metrics.instrument(rsService, ""),
+		metrics:  cfg.Metrics,
+	}
+
+	if cfg.Auth.Enabled() {
+		s.auth = &auth{cfg: cfg.Auth}
 	}
 
 	var logger zerolog.Logger
@@ -48,16 +67,31 @@ func NewHandler(ctx context.Context, rsService store.RulesetService, cfg Config)
 		cfg.WatchTimeout = 30 * time.Second
 	}
 
+	if cfg.WatchHeartbeat == 0 {
+		cfg.WatchHeartbeat = 15 * time.Second
+	}
+
 	rs := rulesetService{
-		service:      &s,
-		timeout:      cfg.Timeout,
-		watchTimeout: cfg.WatchTimeout,
+		service:        &s,
+		timeout:        cfg.Timeout,
+		watchTimeout:   cfg.WatchTimeout,
+		watchHeartbeat: cfg.WatchHeartbeat,
 	}
 
 	// router
 	mux := http.NewServeMux()
 	mux.Handle("/rulesets/", &rs)
 
+	if cfg.Namespaces != nil {
+		mux.Handle("/namespaces/", &namespaceService{
+			service:        &s,
+			namespaces:     cfg.Namespaces,
+			timeout:        cfg.Timeout,
+			watchTimeout:   cfg.WatchTimeout,
+			watchHeartbeat: cfg.WatchHeartbeat,
+		})
+	}
+
 	// middlewares
 	chain := []func(http.Handler) http.Handler{
 		hlog.NewHandler(logger),
@@ -73,10 +107,21 @@ func NewHandler(ctx context.Context, rsService store.RulesetService, cfg Config)
 		hlog.RemoteAddrHandler("ip"),
 		hlog.UserAgentHandler("user_agent"),
 		hlog.RefererHandler("referer"),
+	}
+
+	if cfg.Metrics != nil {
+		chain = append(chain, cfg.Metrics.handler)
+	}
+
+	if s.auth != nil {
+		chain = append(chain, s.auth.handler(&s))
+	}
+
+	chain = append(chain,
 		func(http.Handler) http.Handler {
 			return mux
 		},
-	}
+	)
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// playing the middleware chain
@@ -92,6 +137,8 @@ func NewHandler(ctx context.Context, rsService store.RulesetService, cfg Config)
 
 type service struct {
 	rulesets store.RulesetService
+	auth     *auth
+	metrics  *Metrics
 }
 
 // encodeJSON encodes v to w in JSON format.
@@ -0,0 +1,305 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/heetch/regula"
+	"github.com/heetch/regula/rule"
+	"github.com/heetch/regula/store"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/hlog"
+	"google.golang.org/grpc"
+)
+
+// otherPath is the label of the rulesets that don't have their own series.
+const otherPath = "other"
+
+// Metrics collects the Prometheus metrics of the server: the requests per route and per ruleset,
+// the outcome of the evaluations, the open watch connections and the latency of etcd.
+// To keep the number of series bounded, only the first rulesets found are labeled by their path,
+// the others being reported under the "other" path.
+type Metrics struct {
+	registry *prometheus.Registry
+	paths    pathLabels
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	rulesetRequests *prometheus.CounterVec
+	evals           *prometheus.CounterVec
+	evalDuration    *prometheus.HistogramVec
+	watches         *prometheus.GaugeVec
+	etcdDuration    *prometheus.HistogramVec
+}
+
+// NewMetrics creates the metrics of the server, registered along with the metrics of the Go runtime
+// and of the process. At most maxPaths ruleset paths are used as labels.
+func NewMetrics(maxPaths int) *Metrics {
+	m := Metrics{
+		registry: prometheus.NewRegistry(),
+		paths: pathLabels{
+			max:   maxPaths,
+			paths: make(map[string]struct{}),
+		},
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "regula",
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests, by route, method and status code.",
+		}, []string{"route", "method", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "regula",
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of the HTTP requests, by route and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		rulesetRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "regula",
+			Name:      "ruleset_requests_total",
+			Help:      "Number of HTTP requests targeting a single ruleset, by ruleset path and route.",
+		}, []string{"path", "route"}),
+		evals: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "regula",
+			Name:      "evaluations_total",
+			Help:      "Number of evaluations, by ruleset path and result (match, no_match, param_error, not_found or error).",
+		}, []string{"path", "result"}),
+		evalDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "regula",
+			Name:      "evaluation_duration_seconds",
+			Help:      "Duration of the evaluations, by ruleset path.",
+			Buckets:   []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"path"}),
+		watches: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "regula",
+			Name:      "watch_connections",
+			Help:      "Number of open watch connections, by route (watch or watch_stream).",
+		}, []string{"route"}),
+		etcdDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "regula",
+			Name:      "etcd_request_duration_seconds",
+			Help:      "Duration of the requests sent to etcd, by gRPC method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+	}
+
+	m.registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.rulesetRequests,
+		m.evals,
+		m.evalDuration,
+		m.watches,
+		m.etcdDuration,
+	)
+
+	return &m
+}
+
+// Handler returns an http handler exposing the metrics in the Prometheus format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// EtcdInterceptor returns a gRPC interceptor measuring the latency of the requests sent to etcd.
+// It must be passed to the etcd client using the DialOptions field of its configuration.
+func (m *Metrics) EtcdInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		m.etcdDuration.WithLabelValues(method[strings.LastIndex(method, "/")+1:]).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// handler records the metrics of the requests served by next.
+func (m *Metrics) handler(next http.Handler) http.Handler {
+	access := hlog.AccessHandler(func(r *http.Request, status, size int, duration time.Duration) {
+		route, path := routeOf(r)
+
+		m.requests.WithLabelValues(route, r.Method, strconv.Itoa(status)).Inc()
+		m.requestDuration.WithLabelValues(route, r.Method).Observe(duration.Seconds())
+		if path != "" {
+			// failed requests don't prove the ruleset exists, they can't allocate a new series.
+			m.rulesetRequests.WithLabelValues(m.paths.label(path, status < 400), route).Inc()
+		}
+	})
+
+	return access(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route, _ := routeOf(r); route == "watch" || route == "watch_stream" {
+			g := m.watches.WithLabelValues(route)
+			g.Inc()
+			defer g.Dec()
+		}
+
+		next.ServeHTTP(w, r)
+	}))
+}
+
+// instrument returns a service recording the evaluations made on the rulesets of the given namespace.
+// It returns rulesets as is if m is nil.
+func (m *Metrics) instrument(rulesets store.RulesetService, namespace string) store.RulesetService {
+	if m == nil {
+		return rulesets
+	}
+
+	return &instrumentedRulesets{
+		RulesetService: rulesets,
+		metrics:        m,
+		namespace:      namespace,
+	}
+}
+
+// observeEval records the outcome and the duration of an evaluation.
+func (m *Metrics) observeEval(path string, err error, duration time.Duration) {
+	var result string
+
+	switch err {
+	case nil:
+		result = "match"
+	case rule.ErrNoMatch:
+		result = "no_match"
+	case rule.ErrParamNotFound, rule.ErrParamTypeMismatch:
+		result = "param_error"
+	case regula.ErrRulesetNotFound:
+		result = "not_found"
+	default:
+		result = "error"
+		if _, ok := err.(*regula.ParamError); ok {
+			result = "param_error"
+		}
+	}
+
+	label := m.paths.label(path, result != "not_found" && result != "error")
+	m.evals.WithLabelValues(label, result).Inc()
+	m.evalDuration.WithLabelValues(label).Observe(duration.Seconds())
+}
+
+// instrumentedRulesets records the evaluations made on the rulesets of a namespace.
+type instrumentedRulesets struct {
+	store.RulesetService
+
+	metrics   *Metrics
+	namespace string
+}
+
+func (s *instrumentedRulesets) Eval(ctx context.Context, path string, params rule.Params) (*regula.EvalResult, error) {
+	start := time.Now()
+	res, err := s.RulesetService.Eval(ctx, path, params)
+	s.metrics.observeEval(Resource(s.namespace, path), err, time.Since(start))
+	return res, err
+}
+
+func (s *instrumentedRulesets) EvalVersion(ctx context.Context, path, version string, params rule.Params) (*regula.EvalResult, error) {
+	start := time.Now()
+	res, err := s.RulesetService.EvalVersion(ctx, path, version, params)
+	s.metrics.observeEval(Resource(s.namespace, path), err, time.Since(start))
+	return res, err
+}
+
+// pathLabels bounds the number of ruleset paths used as labels.
+type pathLabels struct {
+	mu    sync.Mutex
+	max   int
+	paths map[string]struct{}
+}
+
+// label returns the path if it already has its own series, or if admit is true and the limit
+// isn't reached yet. It returns otherPath otherwise.
+func (p *pathLabels) label(path string, admit bool) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.paths[path]; ok {
+		return path
+	}
+
+	if !admit || len(p.paths) >= p.max {
+		return otherPath
+	}
+
+	p.paths[path] = struct{}{}
+	return path
+}
+
+// routeOf returns the name of the route of the request, used as label,
+// and the path of the ruleset it targets, if any, prefixed by its namespace.
+func routeOf(r *http.Request) (route, path string) {
+	p := r.URL.Path
+
+	var namespace string
+	if strings.HasPrefix(p, "/namespaces/") {
+		parts := strings.SplitN(strings.TrimPrefix(p, "/namespaces/"), "/", 2)
+		if len(parts) < 2 || !strings.HasPrefix(parts[1], "rulesets/") {
+			return "namespaces", ""
+		}
+
+		namespace, p = parts[0], "/"+parts[1]
+	}
+
+	if !strings.HasPrefix(p, "/rulesets/") {
+		return otherPath, ""
+	}
+	path = strings.TrimPrefix(p, "/rulesets/")
+
+	q := r.URL.Query()
+	has := func(key string) bool {
+		_, ok := q[key]
+		return ok
+	}
+
+	switch r.Method {
+	case "GET":
+		switch {
+		case has("watch") && has("stream"):
+			return "watch_stream", ""
+		case has("watch"):
+			return "watch", ""
+		case has("list"):
+			return "list", ""
+		case has("eval"):
+			route = "eval"
+		case has("versions"):
+			route = "versions"
+		case has("history"):
+			route = "history"
+		case has("signature"):
+			route = "signatures"
+		case has("diff"):
+			route = "diff"
+		}
+	case "PUT":
+		route = "put"
+		if has("batch") {
+			return "put_batch", ""
+		}
+	case "POST":
+		switch {
+		case has("eval"):
+			return "eval_batch", ""
+		case strings.HasSuffix(path, "/eval") && r.URL.RawQuery == "":
+			route, path = "eval", strings.TrimSuffix(path, "/eval")
+		case has("rollback"):
+			route = "rollback"
+		case has("signature"):
+			route = "migrate_signature"
+		}
+	case "DELETE":
+		route = "delete"
+	}
+
+	if route == "" {
+		return otherPath, ""
+	}
+
+	if path == "" {
+		return route, ""
+	}
+
+	return route, Resource(namespace, path)
+}
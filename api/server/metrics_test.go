@@ -0,0 +1,164 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/heetch/regula"
+	"github.com/heetch/regula/rule"
+	"github.com/heetch/regula/store"
+	"github.com/heetch/regula/store/memory"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteOf(t *testing.T) {
+	tests := []struct {
+		method string
+		url    string
+		route  string
+		path   string
+	}{
+		{"GET", "/rulesets/a?list", "list", ""},
+		{"GET", "/rulesets/a?watch", "watch", ""},
+		{"GET", "/rulesets/a?watch&stream", "watch_stream", ""},
+		{"GET", "/rulesets/a/b?eval&foo=bar", "eval", "a/b"},
+		{"POST", "/rulesets/a/b/eval", "eval", "a/b"},
+		{"POST", "/rulesets/a?eval", "eval_batch", ""},
+		{"GET", "/rulesets/a/b?versions", "versions", "a/b"},
+		{"GET", "/rulesets/a/b?history", "history", "a/b"},
+		{"GET", "/rulesets/a/b?signature", "signatures", "a/b"},
+		{"GET", "/rulesets/a/b?diff&from=1", "diff", "a/b"},
+		{"PUT", "/rulesets/a/b", "put", "a/b"},
+		{"PUT", "/rulesets/?batch", "put_batch", ""},
+		{"POST", "/rulesets/a/b?rollback", "rollback", "a/b"},
+		{"POST", "/rulesets/a/b?signature", "migrate_signature", "a/b"},
+		{"DELETE", "/rulesets/a/b", "delete", "a/b"},
+		{"GET", "/rulesets/a/b", "other", ""},
+		{"GET", "/namespaces/", "namespaces", ""},
+		{"PUT", "/namespaces/tenant", "namespaces", ""},
+		{"GET", "/namespaces/tenant/rulesets/a/b?eval", "eval", "tenant:a/b"},
+		{"GET", "/namespaces/tenant/rulesets/?list", "list", ""},
+		{"GET", "/foo", "other", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.method+" "+test.url, func(t *testing.T) {
+			route, path := routeOf(httptest.NewRequest(test.method, test.url, nil))
+			require.Equal(t, test.route, route)
+			require.Equal(t, test.path, path)
+		})
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+	rulesets := memory.NewRulesetService()
+	for _, path := range []string{"a", "b"} {
+		rs, err := regula.NewStringRuleset(rule.New(rule.Eq(rule.StringParam("foo"), rule.StringValue("bar")), rule.StringValue("matched")))
+		require.NoError(t, err)
+		_, err = rulesets.Put(ctx, path, rs)
+		require.NoError(t, err)
+	}
+
+	// only one ruleset gets its own series.
+	m := NewMetrics(1)
+	log := zerolog.New(ioutil.Discard)
+	srv := New(rulesets, Config{
+		Logger:  &log,
+		Metrics: m,
+	})
+
+	call := func(method, url string, code int) {
+		t.Helper()
+
+		w := httptest.NewRecorder()
+		srv.Mux.ServeHTTP(w, httptest.NewRequest(method, url, nil))
+		require.Equal(t, code, w.Code)
+	}
+
+	// the first requests target unknown paths which must not allocate a series.
+	call("GET", "/rulesets/unknown?eval&foo=bar", http.StatusNotFound)
+	call("GET", "/rulesets/unknown?versions", http.StatusNotFound)
+	call("GET", "/rulesets/a?eval&foo=bar", http.StatusOK)
+	call("GET", "/rulesets/a?eval&foo=baz", http.StatusBadRequest)
+	call("GET", "/rulesets/a?eval", http.StatusBadRequest)
+	call("GET", "/rulesets/b?eval&foo=bar", http.StatusOK)
+	call("GET", "/rulesets/?list", http.StatusOK)
+
+	require.Equal(t, 1.0, testutil.ToFloat64(m.evals.WithLabelValues("a", "match")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.evals.WithLabelValues("a", "no_match")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.evals.WithLabelValues("a", "param_error")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.evals.WithLabelValues("other", "match")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.evals.WithLabelValues("other", "not_found")))
+
+	require.Equal(t, 3.0, testutil.ToFloat64(m.rulesetRequests.WithLabelValues("a", "eval")))
+	require.Equal(t, 2.0, testutil.ToFloat64(m.rulesetRequests.WithLabelValues("other", "eval")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.rulesetRequests.WithLabelValues("other", "versions")))
+
+	require.Equal(t, 2.0, testutil.ToFloat64(m.requests.WithLabelValues("eval", "GET", "200")))
+	require.Equal(t, 2.0, testutil.ToFloat64(m.requests.WithLabelValues("eval", "GET", "400")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues("eval", "GET", "404")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues("list", "GET", "200")))
+
+	w := httptest.NewRecorder()
+	srv.Mux.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	body := w.Body.String()
+	require.True(t, strings.Contains(body, `regula_evaluations_total{path="a",result="match"} 1`), body)
+	require.True(t, strings.Contains(body, `regula_http_requests_total{code="200",method="GET",route="list"} 1`), body)
+	require.True(t, strings.Contains(body, "go_goroutines"), body)
+}
+
+func TestMetricsAuth(t *testing.T) {
+	log := zerolog.New(ioutil.Discard)
+	srv := New(memory.NewRulesetService(), Config{
+		Logger:  &log,
+		Metrics: NewMetrics(10),
+		Auth: AuthConfig{
+			Tokens: map[string]string{"token": "prometheus"},
+		},
+	})
+
+	w := httptest.NewRecorder()
+	srv.Mux.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/metrics", nil)
+	r.Header.Set("Authorization", "Bearer token")
+	srv.Mux.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.True(t, strings.Contains(w.Body.String(), "go_goroutines"))
+}
+
+func TestMetricsWatchConnections(t *testing.T) {
+	m := NewMetrics(10)
+	log := zerolog.New(ioutil.Discard)
+
+	var open float64
+	rulesets := new(mockRulesetService)
+	rulesets.WatchFn = func(context.Context, string, string) (*store.RulesetEvents, error) {
+		open = testutil.ToFloat64(m.watches.WithLabelValues("watch"))
+		return &store.RulesetEvents{Revision: "rev"}, nil
+	}
+
+	h := NewHandler(context.Background(), rulesets, Config{
+		Logger:  &log,
+		Metrics: m,
+	})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/rulesets/?watch", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	require.Equal(t, 1.0, open)
+	require.Equal(t, 0.0, testutil.ToFloat64(m.watches.WithLabelValues("watch")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues("watch", "GET", "200")))
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/heetch/regula/api"
+	"github.com/heetch/regula/store"
+)
+
+type namespaceService struct {
+	*service
+
+	namespaces     store.NamespaceService
+	timeout        time.Duration
+	watchTimeout   time.Duration
+	watchHeartbeat time.Duration
+}
+
+// ServeHTTP serves the namespaces endpoints and forwards the requests made to /namespaces/{name}/rulesets/...
+// to a ruleset handler bound to the rulesets of the namespace.
+func (s *namespaceService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/namespaces")
+	path = strings.TrimPrefix(path, "/")
+
+	name, rest := path, ""
+	if i := strings.Index(path, "/"); i >= 0 {
+		name, rest = path[:i], path[i:]
+	}
+
+	if rest == "/rulesets" || strings.HasPrefix(rest, "/rulesets/") {
+		s.rulesets(w, r, name, rest)
+		return
+	}
+
+	if rest != "" && rest != "/" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+	defer cancel()
+	r = r.WithContext(ctx)
+
+	switch r.Method {
+	case "GET":
+		if name == "" {
+			if s.authorize(w, r, "", RightRead) {
+				s.list(w, r)
+			}
+			return
+		}
+	case "PUT":
+		if name != "" {
+			if s.authorize(w, r, Resource(name, ""), RightWrite) {
+				s.create(w, r, name)
+			}
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNotFound)
+}
+
+// list writes the namespaces to the http response.
+func (s *namespaceService) list(w http.ResponseWriter, r *http.Request) {
+	names, err := s.namespaces.List(r.Context())
+	if err != nil {
+		s.writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	nl := api.Namespaces{
+		Namespaces: make([]api.Namespace, len(names)),
+	}
+	for i := range names {
+		nl.Namespaces[i].Name = names[i]
+	}
+
+	s.encodeJSON(w, r, &nl, http.StatusOK)
+}
+
+// create creates a namespace. Creating an existing namespace is not an error.
+func (s *namespaceService) create(w http.ResponseWriter, r *http.Request, name string) {
+	err := s.namespaces.Create(r.Context(), name)
+	if err != nil && err != store.ErrNotModified {
+		if store.IsValidationError(err) {
+			s.writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+
+		s.writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	s.encodeJSON(w, r, &api.Namespace{Name: name}, http.StatusOK)
+}
+
+// rulesets serves a ruleset request made on the given namespace, the path being relative to the namespace.
+func (s *namespaceService) rulesets(w http.ResponseWriter, r *http.Request, name, path string) {
+	ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+	rulesets, err := s.namespaces.Rulesets(ctx, name)
+	cancel()
+	if err != nil {
+		if err == store.ErrNotFound {
+			s.writeError(w, r, errNamespaceNotFound, http.StatusNotFound)
+			return
+		}
+
+		s.writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	rs := rulesetService{
+		service:        &service{rulesets: s.metrics.instrument(rulesets, name), auth: s.auth, metrics: s.metrics},
+		namespace:      name,
+		timeout:        s.timeout,
+		watchTimeout:   s.watchTimeout,
+		watchHeartbeat: s.watchHeartbeat,
+	}
+
+	u := *r.URL
+	u.Path = path
+	u.RawPath = ""
+
+	nr := r.WithContext(r.Context())
+	nr.URL = &u
+
+	rs.ServeHTTP(w, nr)
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/heetch/regula/api"
+	"github.com/heetch/regula/store/memory"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespaces(t *testing.T) {
+	ns := memory.NewNamespaceService()
+	log := zerolog.New(ioutil.Discard)
+	h := NewHandler(context.Background(), new(mockRulesetService), Config{
+		Logger:     &log,
+		Namespaces: ns,
+	})
+
+	call := func(t *testing.T, method, url, body string, code int, v interface{}) {
+		t.Helper()
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, url, strings.NewReader(body))
+		h.ServeHTTP(w, r)
+
+		require.Equal(t, code, w.Code, w.Body.String())
+		if v != nil {
+			require.NoError(t, json.NewDecoder(w.Body).Decode(v))
+		}
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		for _, name := range []string{"b", "a", "a"} {
+			var n api.Namespace
+			call(t, "PUT", "/namespaces/"+name, "", http.StatusOK, &n)
+			require.Equal(t, name, n.Name)
+		}
+
+		call(t, "PUT", "/namespaces/Bad_Name", "", http.StatusBadRequest, nil)
+		call(t, "POST", "/namespaces/c", "", http.StatusNotFound, nil)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		var nl api.Namespaces
+		call(t, "GET", "/namespaces/", "", http.StatusOK, &nl)
+		require.Equal(t, []api.Namespace{{Name: "a"}, {Name: "b"}}, nl.Namespaces)
+	})
+
+	t.Run("Rulesets", func(t *testing.T) {
+		rs := `{"type":"string","rules":[{"expr":{"kind":"value","type":"bool","data":"true"},"result":{"kind":"value","type":"string","data":"a"}}]}`
+
+		var entry api.Ruleset
+		call(t, "PUT", "/namespaces/a/rulesets/path/to/rs", rs, http.StatusOK, &entry)
+		require.Equal(t, "path/to/rs", entry.Path)
+
+		var res api.EvalResult
+		call(t, "GET", "/namespaces/a/rulesets/path/to/rs?eval", "", http.StatusOK, &res)
+		require.Equal(t, entry.Version, res.Version)
+
+		var rl api.Rulesets
+		call(t, "GET", "/namespaces/a/rulesets/?list", "", http.StatusOK, &rl)
+		require.Len(t, rl.Rulesets, 1)
+
+		// namespaces are isolated
+		call(t, "GET", "/namespaces/b/rulesets/path/to/rs?eval", "", http.StatusNotFound, nil)
+		call(t, "GET", "/namespaces/b/rulesets/?list", "", http.StatusOK, &rl)
+		require.Empty(t, rl.Rulesets)
+
+		var aerr api.Error
+		call(t, "GET", "/namespaces/unknown/rulesets/path/to/rs?eval", "", http.StatusNotFound, &aerr)
+		require.Equal(t, "namespace not found", aerr.Err)
+
+		call(t, "GET", "/namespaces/a/other", "", http.StatusNotFound, nil)
+	})
+}
+
+func TestNamespacesDisabled(t *testing.T) {
+	log := zerolog.New(ioutil.Discard)
+	h := NewHandler(context.Background(), new(mockRulesetService), Config{
+		Logger: &log,
+	})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/namespaces/", nil))
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"encoding/json"
 	"strconv"
 
 	"github.com/heetch/regula/rule"
@@ -84,3 +85,120 @@ func (p params) EncodeValue(key string) (string, error) {
 
 	return v, nil
 }
+
+// jsonParams represents the parameters decoded from a JSON request body, numbers being decoded as json.Number.
+// Numbers and booleans keep their JSON type and are never read as strings. Strings are parsed like
+// the query string params when another type is expected, for clients that only know their string representation.
+// It implements the rule.Params interface.
+type jsonParams map[string]interface{}
+
+// GetString extracts a string parameter which corresponds to the given key.
+func (p jsonParams) GetString(key string) (string, error) {
+	v, ok := p[key]
+	if !ok {
+		return "", rule.ErrParamNotFound
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return "", rule.ErrParamTypeMismatch
+	}
+
+	return s, nil
+}
+
+// GetBool extracts a bool parameter which corresponds to the given key.
+func (p jsonParams) GetBool(key string) (bool, error) {
+	v, ok := p[key]
+	if !ok {
+		return false, rule.ErrParamNotFound
+	}
+
+	if s, ok := v.(string); ok {
+		return params{key: s}.GetBool(key)
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, rule.ErrParamTypeMismatch
+	}
+
+	return b, nil
+}
+
+// GetInt64 extracts an int64 parameter which corresponds to the given key.
+func (p jsonParams) GetInt64(key string) (int64, error) {
+	v, ok := p[key]
+	if !ok {
+		return 0, rule.ErrParamNotFound
+	}
+
+	if s, ok := v.(string); ok {
+		return params{key: s}.GetInt64(key)
+	}
+
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, rule.ErrParamTypeMismatch
+	}
+
+	i, err := n.Int64()
+	if err != nil {
+		return 0, rule.ErrParamTypeMismatch
+	}
+
+	return i, nil
+}
+
+// GetFloat64 extracts a float64 parameter which corresponds to the given key.
+func (p jsonParams) GetFloat64(key string) (float64, error) {
+	v, ok := p[key]
+	if !ok {
+		return 0, rule.ErrParamNotFound
+	}
+
+	if s, ok := v.(string); ok {
+		return params{key: s}.GetFloat64(key)
+	}
+
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, rule.ErrParamTypeMismatch
+	}
+
+	f, err := n.Float64()
+	if err != nil {
+		return 0, rule.ErrParamTypeMismatch
+	}
+
+	return f, nil
+}
+
+// Keys returns the list of all the keys.
+func (p jsonParams) Keys() []string {
+	keys := make([]string, 0, len(p))
+	for k := range p {
+		keys = append(keys, k)
+	}
+
+	return keys
+}
+
+// EncodeValue returns the string representation of a value.
+func (p jsonParams) EncodeValue(key string) (string, error) {
+	v, ok := p[key]
+	if !ok {
+		return "", rule.ErrParamNotFound
+	}
+
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case json.Number:
+		return t.String(), nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	}
+
+	return "", rule.ErrParamTypeMismatch
+}
@@ -1,6 +1,8 @@
 package server
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/heetch/regula/rule"
@@ -100,3 +102,66 @@ func TestGetFloat64(t *testing.T) {
 		require.Equal(t, err, rule.ErrParamTypeMismatch)
 	})
 }
+
+func TestJSONParams(t *testing.T) {
+	var p jsonParams
+	dec := json.NewDecoder(strings.NewReader(`{"string": "42", "text": "abc", "bool": true, "int64": 42, "float64": 4.2, "list": [1]}`))
+	dec.UseNumber()
+	require.NoError(t, dec.Decode(&p))
+
+	s, err := p.GetString("string")
+	require.NoError(t, err)
+	require.Equal(t, "42", s)
+
+	b, err := p.GetBool("bool")
+	require.NoError(t, err)
+	require.True(t, b)
+
+	i, err := p.GetInt64("int64")
+	require.NoError(t, err)
+	require.Equal(t, int64(42), i)
+
+	f, err := p.GetFloat64("float64")
+	require.NoError(t, err)
+	require.Equal(t, 4.2, f)
+
+	// integers can be read as floats, but not the other way around
+	f, err = p.GetFloat64("int64")
+	require.NoError(t, err)
+	require.Equal(t, 42.0, f)
+
+	_, err = p.GetInt64("float64")
+	require.Equal(t, rule.ErrParamTypeMismatch, err)
+
+	// strings are parsed according to the expected type
+	i, err = p.GetInt64("string")
+	require.NoError(t, err)
+	require.Equal(t, int64(42), i)
+
+	f, err = p.GetFloat64("string")
+	require.NoError(t, err)
+	require.Equal(t, 42.0, f)
+
+	_, err = p.GetInt64("text")
+	require.Equal(t, rule.ErrParamTypeMismatch, err)
+
+	_, err = p.GetBool("text")
+	require.Equal(t, rule.ErrParamTypeMismatch, err)
+
+	// but numbers are never read as strings
+	_, err = p.GetString("int64")
+	require.Equal(t, rule.ErrParamTypeMismatch, err)
+
+	_, err = p.GetBool("list")
+	require.Equal(t, rule.ErrParamTypeMismatch, err)
+
+	_, err = p.GetString("missing")
+	require.Equal(t, rule.ErrParamNotFound, err)
+
+	v, err := p.EncodeValue("float64")
+	require.NoError(t, err)
+	require.Equal(t, "4.2", v)
+
+	_, err = p.EncodeValue("list")
+	require.Equal(t, rule.ErrParamTypeMismatch, err)
+}
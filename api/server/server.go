@@ -17,12 +17,25 @@ type Server struct {
 	Mux    *http.ServeMux // Can be used to add handlers to the server.
 	logger zerolog.Logger
 	server *http.Server
+
+	service       store.RulesetService
+	namespaces    store.NamespaceService
+	retention     store.RetentionPolicy
+	pruneInterval time.Duration
 }
 
 // New creates a Server instance.
 func New(service store.RulesetService, cfg Config) *Server {
 	srv := Server{
-		Mux: http.NewServeMux(),
+		Mux:           http.NewServeMux(),
+		service:       service,
+		namespaces:    cfg.Namespaces,
+		retention:     cfg.Retention,
+		pruneInterval: cfg.PruneInterval,
+	}
+
+	if srv.pruneInterval == 0 {
+		srv.pruneInterval = time.Hour
 	}
 
 	if cfg.Logger == nil {
@@ -40,6 +53,17 @@ func New(service store.RulesetService, cfg Config) *Server {
 
 	srv.Mux.Handle("/", NewHandler(ctx, service, cfg))
 
+	if cfg.Metrics != nil {
+		h := cfg.Metrics.Handler()
+		// the metrics are labeled by the paths of the rulesets of every namespace.
+		if cfg.Auth.Enabled() {
+			a := auth{cfg: cfg.Auth}
+			h = a.authenticated(h)
+		}
+
+		srv.Mux.Handle("/metrics", h)
+	}
+
 	return &srv
 }
 
@@ -61,6 +85,10 @@ func (s *Server) Run(ctx context.Context, addr string) error {
 		}
 	}()
 
+	if s.retention.Enabled() {
+		go s.prune(ctx)
+	}
+
 	<-ctx.Done()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -73,3 +101,30 @@ func (s *Server) Run(ctx context.Context, addr string) error {
 
 	return err
 }
+
+// prune enforces the retention policy on the rulesets of the server and of its namespaces
+// every pruneInterval until the context is canceled.
+func (s *Server) prune(ctx context.Context) {
+	ticker := time.NewTicker(s.pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		n, err := store.Prune(ctx, s.service, "", &s.retention, time.Now())
+		if err == nil && s.namespaces != nil {
+			var nn int
+			nn, err = store.PruneNamespaces(ctx, s.namespaces, &s.retention, time.Now())
+			n += nn
+		}
+		if err != nil && ctx.Err() == nil {
+			s.logger.Error().Err(err).Int("pruned", n).Msg("failed to prune ruleset versions")
+		} else if n > 0 {
+			s.logger.Info().Int("pruned", n).Msg("pruned old ruleset versions")
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
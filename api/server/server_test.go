@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/heetch/regula/store"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerPrune(t *testing.T) {
+	s := new(mockRulesetService)
+	s.ListFn = func(context.Context, string, store.ListOptions) (*store.RulesetEntries, error) {
+		return &store.RulesetEntries{
+			Entries: []store.RulesetEntry{{Path: "a", Version: "3"}},
+		}, nil
+	}
+	s.LatestFn = func(context.Context, string) (*store.RulesetEntry, error) {
+		return &store.RulesetEntry{Path: "a", Version: "3"}, nil
+	}
+	s.VersionsFn = func(context.Context, string, int, string) (*store.RulesetVersions, error) {
+		return &store.RulesetVersions{
+			Path:     "a",
+			Versions: []store.RulesetVersion{{Version: "3"}, {Version: "2"}, {Version: "1"}},
+		}, nil
+	}
+
+	deleted := make(chan []string, 1)
+	s.DeleteVersionsFn = func(ctx context.Context, path string, versions ...string) error {
+		require.Equal(t, "a", path)
+		deleted <- versions
+		return nil
+	}
+
+	log := zerolog.New(ioutil.Discard)
+	srv := New(s, Config{
+		Logger:    &log,
+		Retention: store.RetentionPolicy{KeepLast: 2},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		srv.Run(ctx, "127.0.0.1:0")
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	select {
+	case versions := <-deleted:
+		require.Equal(t, []string{"1"}, versions)
+	case <-time.After(5 * time.Second):
+		t.Fatal("versions not pruned after 5 seconds")
+	}
+}
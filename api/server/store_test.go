@@ -12,27 +12,43 @@ import (
 var _ store.RulesetService = new(mockRulesetService)
 
 type mockRulesetService struct {
-	ListCount         int
-	ListFn            func(context.Context, string, int, string) (*store.RulesetEntries, error)
-	LatestCount       int
-	LatestFn          func(context.Context, string) (*store.RulesetEntry, error)
-	OneByVersionCount int
-	OneByVersionFn    func(context.Context, string, string) (*store.RulesetEntry, error)
-	WatchCount        int
-	WatchFn           func(context.Context, string, string) (*store.RulesetEvents, error)
-	PutCount          int
-	PutFn             func(context.Context, string) (*store.RulesetEntry, error)
-	EvalCount         int
-	EvalFn            func(ctx context.Context, path string, params rule.Params) (*regula.EvalResult, error)
-	EvalVersionCount  int
-	EvalVersionFn     func(ctx context.Context, path, version string, params rule.Params) (*regula.EvalResult, error)
-}
-
-func (s *mockRulesetService) List(ctx context.Context, prefix string, limit int, token string) (*store.RulesetEntries, error) {
+	ListCount             int
+	ListFn                func(context.Context, string, store.ListOptions) (*store.RulesetEntries, error)
+	LatestCount           int
+	LatestFn              func(context.Context, string) (*store.RulesetEntry, error)
+	OneByVersionCount     int
+	OneByVersionFn        func(context.Context, string, string) (*store.RulesetEntry, error)
+	VersionsCount         int
+	VersionsFn            func(context.Context, string, int, string) (*store.RulesetVersions, error)
+	HistoryCount          int
+	HistoryFn             func(context.Context, string, int, string) (*store.AuditEntries, error)
+	RollbackCount         int
+	RollbackFn            func(context.Context, string, string) (*store.RulesetEntry, error)
+	SignaturesCount       int
+	SignaturesFn          func(context.Context, string) ([]store.Signature, error)
+	MigrateSignatureCount int
+	MigrateSignatureFn    func(context.Context, string, *store.SignatureMigration) (*store.Signature, error)
+	DeleteCount           int
+	DeleteFn              func(context.Context, string, bool) error
+	DeleteVersionsCount   int
+	DeleteVersionsFn      func(context.Context, string, ...string) error
+	WatchCount            int
+	WatchFn               func(context.Context, string, string) (*store.RulesetEvents, error)
+	PutCount              int
+	PutFn                 func(context.Context, string, ...store.PutOption) (*store.RulesetEntry, error)
+	PutBatchCount         int
+	PutBatchFn            func(context.Context, []store.RulesetEntry) ([]store.RulesetEntry, error)
+	EvalCount             int
+	EvalFn                func(ctx context.Context, path string, params rule.Params) (*regula.EvalResult, error)
+	EvalVersionCount      int
+	EvalVersionFn         func(ctx context.Context, path, version string, params rule.Params) (*regula.EvalResult, error)
+}
+
+func (s *mockRulesetService) List(ctx context.Context, prefix string, opt store.ListOptions) (*store.RulesetEntries, error) {
 	s.ListCount++
 
 	if s.ListFn != nil {
-		return s.ListFn(ctx, prefix, limit, token)
+		return s.ListFn(ctx, prefix, opt)
 	}
 
 	return nil, nil
@@ -56,6 +72,69 @@ func (s *mockRulesetService) OneByVersion(ctx context.Context, path, version str
 	return nil, nil
 }
 
+func (s *mockRulesetService) Versions(ctx context.Context, path string, limit int, token string) (*store.RulesetVersions, error) {
+	s.VersionsCount++
+
+	if s.VersionsFn != nil {
+		return s.VersionsFn(ctx, path, limit, token)
+	}
+	return nil, nil
+}
+
+func (s *mockRulesetService) History(ctx context.Context, path string, limit int, token string) (*store.AuditEntries, error) {
+	s.HistoryCount++
+
+	if s.HistoryFn != nil {
+		return s.HistoryFn(ctx, path, limit, token)
+	}
+	return nil, nil
+}
+
+func (s *mockRulesetService) Rollback(ctx context.Context, path, version string) (*store.RulesetEntry, error) {
+	s.RollbackCount++
+
+	if s.RollbackFn != nil {
+		return s.RollbackFn(ctx, path, version)
+	}
+	return nil, nil
+}
+
+func (s *mockRulesetService) Signatures(ctx context.Context, path string) ([]store.Signature, error) {
+	s.SignaturesCount++
+
+	if s.SignaturesFn != nil {
+		return s.SignaturesFn(ctx, path)
+	}
+	return nil, nil
+}
+
+func (s *mockRulesetService) MigrateSignature(ctx context.Context, path string, m *store.SignatureMigration) (*store.Signature, error) {
+	s.MigrateSignatureCount++
+
+	if s.MigrateSignatureFn != nil {
+		return s.MigrateSignatureFn(ctx, path, m)
+	}
+	return nil, nil
+}
+
+func (s *mockRulesetService) Delete(ctx context.Context, path string, hard bool) error {
+	s.DeleteCount++
+
+	if s.DeleteFn != nil {
+		return s.DeleteFn(ctx, path, hard)
+	}
+	return nil
+}
+
+func (s *mockRulesetService) DeleteVersions(ctx context.Context, path string, versions ...string) error {
+	s.DeleteVersionsCount++
+
+	if s.DeleteVersionsFn != nil {
+		return s.DeleteVersionsFn(ctx, path, versions...)
+	}
+	return nil
+}
+
 func (s *mockRulesetService) Watch(ctx context.Context, prefix, revision string) (*store.RulesetEvents, error) {
 	s.WatchCount++
 
@@ -66,11 +145,20 @@ func (s *mockRulesetService) Watch(ctx context.Context, prefix, revision string)
 	return nil, nil
 }
 
-func (s *mockRulesetService) Put(ctx context.Context, path string, ruleset *regula.Ruleset) (*store.RulesetEntry, error) {
+func (s *mockRulesetService) Put(ctx context.Context, path string, ruleset *regula.Ruleset, opts ...store.PutOption) (*store.RulesetEntry, error) {
 	s.PutCount++
 
 	if s.PutFn != nil {
-		return s.PutFn(ctx, path)
+		return s.PutFn(ctx, path, opts...)
+	}
+	return nil, nil
+}
+
+func (s *mockRulesetService) PutBatch(ctx context.Context, entries []store.RulesetEntry) ([]store.RulesetEntry, error) {
+	s.PutBatchCount++
+
+	if s.PutBatchFn != nil {
+		return s.PutBatchFn(ctx, entries)
 	}
 	return nil, nil
 }
@@ -3,15 +3,65 @@ package api
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/heetch/regula"
+	"github.com/heetch/regula/diff"
 	"github.com/heetch/regula/rule"
 )
 
 // EvalResult is the response sent to the client after an eval.
 type EvalResult struct {
-	Value   *rule.Value `json:"value"`
-	Version string      `json:"version"`
+	Value       *rule.Value  `json:"value"`
+	Version     string       `json:"version"`
+	Explanation *Explanation `json:"explanation,omitempty"`
+}
+
+// Explanation describes how the result of an evaluation was produced.
+type Explanation struct {
+	// Index of the rule that matched, omitted if the evaluator couldn't report it
+	Rule *int `json:"rule,omitempty"`
+}
+
+// EvalRequest is the body of a typed evaluation. Unlike query string params,
+// the params keep their JSON type: strings, numbers and booleans. Strings are still
+// parsed when the ruleset expects a number or a boolean.
+// If Version is empty, the latest version of the ruleset is evaluated.
+// If Explain is true, the result contains an explanation of the evaluation.
+type EvalRequest struct {
+	Params  map[string]interface{} `json:"params"`
+	Version string                 `json:"version,omitempty"`
+	Explain bool                   `json:"explain,omitempty"`
+}
+
+// EvalBatchRequest is the body of a batch evaluation. Every ruleset is evaluated with every set of params.
+// If Paths is empty, all the rulesets under the prefix of the request are evaluated.
+// Like in EvalRequest, the params keep their JSON type.
+// Batches of more than MaxEvalBatchSize evaluations, i.e. rulesets times sets of params, are rejected.
+type EvalBatchRequest struct {
+	Paths  []string                 `json:"paths,omitempty"`
+	Params []map[string]interface{} `json:"params"`
+}
+
+// MaxEvalBatchSize is the maximum number of evaluations of a batch.
+const MaxEvalBatchSize = 1000
+
+// EvalBatchResult is the result of the evaluation of a ruleset with one of the sets of params of a batch.
+// Params is the index of the set of params in the request and Rule the index of the rule that matched,
+// if the evaluation succeeded.
+type EvalBatchResult struct {
+	Path    string      `json:"path"`
+	Params  int         `json:"params"`
+	Value   *rule.Value `json:"value,omitempty"`
+	Version string      `json:"version,omitempty"`
+	Rule    *int        `json:"rule,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// EvalBatchResults is the response sent to the client after a batch evaluation,
+// ordered by path, then by set of params.
+type EvalBatchResults struct {
+	Results []EvalBatchResult `json:"results"`
 }
 
 // Error is a generic error response.
@@ -45,9 +95,77 @@ type Rulesets struct {
 	Continue string    `json:"continue,omitempty"`
 }
 
+// RulesetVersion describes a version of a ruleset.
+type RulesetVersion struct {
+	Version   string    `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// RulesetVersions holds the list of versions of a ruleset, newest first.
+type RulesetVersions struct {
+	Path     string           `json:"path"`
+	Versions []RulesetVersion `json:"versions"`
+	Continue string           `json:"continue,omitempty"`
+}
+
+// AuditEntry records a change made to a ruleset.
+type AuditEntry struct {
+	Path            string    `json:"path"`
+	Action          string    `json:"action"`
+	Version         string    `json:"version"`
+	PreviousVersion string    `json:"previousVersion,omitempty"`
+	Author          string    `json:"author,omitempty"`
+	Message         string    `json:"message,omitempty"`
+	Time            time.Time `json:"time"`
+}
+
+// History holds the changes made to a ruleset, newest first.
+type History struct {
+	Path     string       `json:"path"`
+	Entries  []AuditEntry `json:"entries"`
+	Continue string       `json:"continue,omitempty"`
+}
+
+// Headers used to describe the author and the reason of a change.
+// The author is ignored if the request is authenticated, the authenticated subject being used instead.
+const (
+	AuthorHeader  = "X-Regula-Author"
+	MessageHeader = "X-Regula-Message"
+)
+
+// Signature describes the return type and the params of the rulesets stored on a path.
+type Signature struct {
+	ReturnType string            `json:"returnType"`
+	ParamTypes map[string]string `json:"paramTypes"`
+	Optional   []string          `json:"optional,omitempty"`
+	Deprecated []string          `json:"deprecated,omitempty"`
+}
+
+// Signatures holds the history of the signatures of a ruleset, the current one last.
+type Signatures struct {
+	Path       string      `json:"path"`
+	Signatures []Signature `json:"signatures"`
+}
+
+// SignatureMigration describes the changes to apply to the signature of a ruleset.
+type SignatureMigration struct {
+	AddParams       map[string]string `json:"addParams,omitempty"`
+	WidenParams     map[string]string `json:"widenParams,omitempty"`
+	DeprecateParams []string          `json:"deprecateParams,omitempty"`
+}
+
+// RulesetDiff holds the differences between two versions of a ruleset.
+type RulesetDiff struct {
+	Path    string        `json:"path"`
+	From    string        `json:"from"`
+	To      string        `json:"to"`
+	Changes *diff.Ruleset `json:"changes"`
+}
+
 // List of possible events executed against a ruleset.
 const (
-	PutEvent = "PUT"
+	PutEvent    = "PUT"
+	DeleteEvent = "DELETE"
 )
 
 // Event describes an event occured on a ruleset.
@@ -64,3 +182,13 @@ type Events struct {
 	Revision string  `json:"revision,omitempty"`
 	Timeout  bool    `json:"timeout,omitempty"`
 }
+
+// Namespace describes a namespace, holding rulesets isolated from the ones of the other namespaces.
+type Namespace struct {
+	Name string `json:"name"`
+}
+
+// Namespaces holds a list of namespaces.
+type Namespaces struct {
+	Namespaces []Namespace `json:"namespaces"`
+}
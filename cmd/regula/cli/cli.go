@@ -15,20 +15,56 @@ import (
 	"github.com/heetch/confita"
 	"github.com/heetch/confita/backend/env"
 	"github.com/heetch/regula/api/server"
+	"github.com/heetch/regula/store"
 	isatty "github.com/mattn/go-isatty"
 	"github.com/rs/zerolog"
 )
 
 // Config holds the server configuration.
 type Config struct {
-	Etcd struct {
+	// Store selects the store backend: etcd, memory, dir or bolt.
+	Store string `config:"store"`
+	// Cache keeps the rulesets read from the store in memory.
+	Cache bool `config:"cache"`
+	Etcd  struct {
 		Endpoints []string `config:"etcd-endpoints"`
 		Namespace string   `config:"etcd-namespace"`
 	}
+	Dir struct {
+		Path     string        `config:"dir-path"`
+		Interval time.Duration `config:"dir-interval"`
+	}
+	Bolt struct {
+		Path string `config:"bolt-path"`
+	}
 	Server struct {
-		Address      string        `config:"addr"`
+		Address string `config:"addr"`
+		// GRPCAddress enables the gRPC API when not empty.
+		GRPCAddress  string        `config:"grpc-addr"`
 		Timeout      time.Duration `config:"server-timeout"`
 		WatchTimeout time.Duration `config:"server-watch-timeout"`
+		// WatchHeartbeat is the interval between two heartbeats sent on idle watch streams.
+		WatchHeartbeat time.Duration `config:"server-watch-heartbeat"`
+		PruneInterval  time.Duration `config:"server-prune-interval"`
+	}
+	Retention struct {
+		KeepLast int           `config:"retention-keep-last"`
+		KeepFor  time.Duration `config:"retention-keep-for"`
+		// Pinned versions, formatted as path@version.
+		Pinned []string `config:"retention-pinned"`
+	}
+	Auth struct {
+		// Static tokens, formatted as subject:token.
+		Tokens []string `config:"auth-tokens"`
+		// Secret validating the HMAC signature of JWTs.
+		JWTSecret string `config:"auth-jwt-secret"`
+		// Rights granted to the subjects, formatted as subject:rights:prefix,
+		// rights being separated by a plus sign (e.g. ci:read+eval:payments/).
+		Grants []string `config:"auth-grants"`
+	}
+	Metrics struct {
+		// Maximum number of ruleset paths used as metric labels.
+		MaxPaths int `config:"metrics-max-paths"`
 	}
 	LogLevel string `config:"log-level"`
 }
@@ -39,13 +75,29 @@ type Config struct {
 func LoadConfig(args []string) (*Config, error) {
 	var cfg Config
 	flag := stdflag.NewFlagSet("", stdflag.ContinueOnError)
+	flag.StringVar(&cfg.Store, "store", "etcd", "store backend to use (etcd, memory, dir or bolt)")
+	flag.BoolVar(&cfg.Cache, "cache", true, "keep the rulesets in memory, watching the store for changes")
 	flag.StringVar(&cfg.Etcd.Namespace, "etcd-namespace", "", "etcd namespace to use")
 	flag.StringVar(&cfg.LogLevel, "log-level", zerolog.DebugLevel.String(), "debug level")
 	cfg.Etcd.Endpoints = []string{"127.0.0.1:2379"}
 	flag.Var(commaSeparatedFlag{&cfg.Etcd.Endpoints}, "etcd-endpoints", "comma separated etcd endpoints")
+	flag.StringVar(&cfg.Dir.Path, "dir-path", "rulesets", "directory holding the ruleset files when using the dir store")
+	flag.DurationVar(&cfg.Dir.Interval, "dir-interval", time.Second, "interval between two scans of the directory when using the dir store")
+	flag.StringVar(&cfg.Bolt.Path, "bolt-path", "regula.db", "database file when using the bolt store")
 	flag.StringVar(&cfg.Server.Address, "addr", "0.0.0.0:5331", "server address to listen on")
+	flag.StringVar(&cfg.Server.GRPCAddress, "grpc-addr", "", "address the gRPC API listens on, disabled if empty")
 	flag.DurationVar(&cfg.Server.Timeout, "server-timeout", 5*time.Second, "server timeout (TODO)")
 	flag.DurationVar(&cfg.Server.WatchTimeout, "server-watch-timeout", 30*time.Second, "server watch timeout (TODO)")
+	flag.DurationVar(&cfg.Server.WatchHeartbeat, "server-watch-heartbeat", 15*time.Second, "interval between two heartbeats sent on idle watch streams")
+	flag.DurationVar(&cfg.Server.PruneInterval, "server-prune-interval", time.Hour, "interval between two enforcements of the retention policy by the server")
+	flag.IntVar(&cfg.Retention.KeepLast, "retention-keep-last", 0, "number of newest versions of each ruleset to keep when pruning")
+	flag.DurationVar(&cfg.Retention.KeepFor, "retention-keep-for", 0, "age under which versions are kept when pruning")
+	flag.Var(commaSeparatedFlag{&cfg.Retention.Pinned}, "retention-pinned", "comma separated versions to keep forever when pruning, formatted as path@version")
+	flag.Var(commaSeparatedFlag{&cfg.Auth.Tokens}, "auth-tokens", "comma separated static tokens accepted by the server, formatted as subject:token")
+	flag.StringVar(&cfg.Auth.JWTSecret, "auth-jwt-secret", "", "secret validating the HMAC signature of the JWTs accepted by the server")
+	flag.Var(commaSeparatedFlag{&cfg.Auth.Grants}, "auth-grants", "comma separated rights granted to the subjects, formatted as subject:read+eval+write:prefix")
+
+	flag.IntVar(&cfg.Metrics.MaxPaths, "metrics-max-paths", 100, "maximum number of ruleset paths used as labels of the metrics exposed on /metrics, which requires authentication if enabled, the others being reported as 'other'")
 
 	err := confita.NewLoader(env.NewBackend()).Load(context.Background(), &cfg)
 	if err != nil {
@@ -54,12 +106,100 @@ func LoadConfig(args []string) (*Config, error) {
 	if err := flag.Parse(args[1:]); err != nil {
 		return nil, err
 	}
+	if cfg.Retention.KeepLast < 0 || cfg.Retention.KeepFor < 0 {
+		return nil, fmt.Errorf("retention rules must not be negative")
+	}
+	if cfg.Metrics.MaxPaths < 0 {
+		return nil, fmt.Errorf("metrics-max-paths must not be negative")
+	}
+	if _, err := cfg.RetentionPolicy(); err != nil {
+		return nil, err
+	}
+	if _, err := cfg.AuthConfig(); err != nil {
+		return nil, err
+	}
+	switch cfg.Store {
+	case "etcd":
+	case "memory", "dir", "bolt":
+		return &cfg, nil
+	default:
+		return nil, fmt.Errorf("unsupported store '%s' (use etcd, memory, dir or bolt)", cfg.Store)
+	}
 	if cfg.Etcd.Namespace == "" {
 		return nil, fmt.Errorf("etcdnamespace is required (use the -etc-namespace flag to set it)")
 	}
 	return &cfg, nil
 }
 
+// RetentionPolicy returns the retention policy described by the configuration.
+func (c *Config) RetentionPolicy() (store.RetentionPolicy, error) {
+	p := store.RetentionPolicy{
+		KeepLast: c.Retention.KeepLast,
+		KeepFor:  c.Retention.KeepFor,
+	}
+
+	for _, pin := range c.Retention.Pinned {
+		i := strings.LastIndex(pin, "@")
+		if i <= 0 || i == len(pin)-1 {
+			return p, fmt.Errorf("invalid pinned version '%s' (use path@version)", pin)
+		}
+
+		if p.Pinned == nil {
+			p.Pinned = make(map[string][]string)
+		}
+		p.Pinned[pin[:i]] = append(p.Pinned[pin[:i]], pin[i+1:])
+	}
+
+	return p, nil
+}
+
+// AuthConfig returns the authentication configuration of the server.
+func (c *Config) AuthConfig() (server.AuthConfig, error) {
+	a := server.AuthConfig{
+		JWTSecret: []byte(c.Auth.JWTSecret),
+	}
+
+	for _, t := range c.Auth.Tokens {
+		i := strings.Index(t, ":")
+		if i <= 0 || i == len(t)-1 {
+			return a, fmt.Errorf("invalid token '%s' (use subject:token)", t)
+		}
+
+		if a.Tokens == nil {
+			a.Tokens = make(map[string]string)
+		}
+		a.Tokens[t[i+1:]] = t[:i]
+	}
+
+	for _, g := range c.Auth.Grants {
+		parts := strings.SplitN(g, ":", 3)
+		if len(parts) != 3 || parts[0] == "" {
+			return a, fmt.Errorf("invalid grant '%s' (use subject:rights:prefix)", g)
+		}
+
+		grant := server.Grant{Prefix: parts[2]}
+		for _, r := range strings.Split(parts[1], "+") {
+			switch right := server.Right(r); right {
+			case server.RightRead, server.RightEval, server.RightWrite:
+				grant.Rights = append(grant.Rights, right)
+			default:
+				return a, fmt.Errorf("invalid right '%s' in grant '%s' (use read, eval or write)", r, g)
+			}
+		}
+
+		if a.Grants == nil {
+			a.Grants = make(map[string][]server.Grant)
+		}
+		a.Grants[parts[0]] = append(a.Grants[parts[0]], grant)
+	}
+
+	if len(a.Grants) > 0 && !a.Enabled() {
+		return a, fmt.Errorf("auth grants require tokens or a jwt secret")
+	}
+
+	return a, nil
+}
+
 type commaSeparatedFlag struct {
 	parts *[]string
 }
@@ -1,19 +1,37 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"net"
 	"os"
 	"time"
 
 	"github.com/coreos/etcd/clientv3"
+	"github.com/heetch/regula/api/rpc"
 	"github.com/heetch/regula/api/server"
 	"github.com/heetch/regula/cmd/regula/cli"
+	"github.com/heetch/regula/store"
+	"github.com/heetch/regula/store/bolt"
+	"github.com/heetch/regula/store/cache"
+	"github.com/heetch/regula/store/dir"
 	"github.com/heetch/regula/store/etcd"
+	"github.com/heetch/regula/store/memory"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
 )
 
 func main() {
-	cfg, err := cli.LoadConfig(os.Args)
+	args := os.Args
+
+	// "regula prune" enforces the retention policy once and exits.
+	prune := len(args) > 1 && args[1] == "prune"
+	if prune {
+		args = append(args[:1:1], args[2:]...)
+	}
+
+	cfg, err := cli.LoadConfig(args)
 	if err != nil {
 		if err == flag.ErrHelp {
 			os.Exit(0)
@@ -24,26 +42,128 @@ func main() {
 
 	logger := cli.CreateLogger(cfg.LogLevel, os.Stderr)
 
-	etcdCli, err := clientv3.New(clientv3.Config{
-		Endpoints:   cfg.Etcd.Endpoints,
-		DialTimeout: 5 * time.Second,
-	})
-	if err != nil {
-		logger.Fatal().Err(err).Msg("Failed to connect to etcd cluster")
+	// the policy and the auth configuration were validated by LoadConfig.
+	policy, _ := cfg.RetentionPolicy()
+	auth, _ := cfg.AuthConfig()
+
+	metrics := server.NewMetrics(cfg.Metrics.MaxPaths)
+
+	service, namespaces, closeService := newService(cfg, logger, metrics)
+	defer closeService()
+
+	if prune {
+		if !policy.Enabled() {
+			fmt.Fprintln(os.Stderr, "regula: no retention rule configured (use the -retention-keep-last or -retention-keep-for flags)")
+			os.Exit(2)
+		}
+
+		n, err := store.Prune(context.Background(), service, "", &policy, time.Now())
+		if err != nil {
+			logger.Fatal().Err(err).Int("pruned", n).Msg("Failed to prune ruleset versions")
+		}
+
+		if namespaces != nil {
+			nn, err := store.PruneNamespaces(context.Background(), namespaces, &policy, time.Now())
+			n += nn
+			if err != nil {
+				logger.Fatal().Err(err).Int("pruned", n).Msg("Failed to prune ruleset versions")
+			}
+		}
+
+		logger.Info().Int("pruned", n).Msg("Pruned old ruleset versions")
+		return
 	}
-	defer etcdCli.Close()
 
-	service := etcd.RulesetService{
-		Client:    etcdCli,
-		Namespace: cfg.Etcd.Namespace,
-		Logger:    logger.With().Str("service", "etcd").Logger(),
+	if cfg.Cache {
+		cached := cache.NewRulesetService(service, logger.With().Str("service", "cache").Logger())
+		defer cached.Close()
+
+		service = cached
+
+		if namespaces != nil {
+			cachedNamespaces := cache.NewNamespaceService(namespaces, logger.With().Str("service", "cache").Logger())
+			defer cachedNamespaces.Close()
+
+			namespaces = cachedNamespaces
+		}
 	}
 
-	srv := server.New(&service, server.Config{
-		Logger:       &logger,
-		Timeout:      cfg.Server.Timeout,
-		WatchTimeout: cfg.Server.WatchTimeout,
+	srv := server.New(service, server.Config{
+		Logger:         &logger,
+		Timeout:        cfg.Server.Timeout,
+		WatchTimeout:   cfg.Server.WatchTimeout,
+		WatchHeartbeat: cfg.Server.WatchHeartbeat,
+		Retention:      policy,
+		Namespaces:     namespaces,
+		Auth:           auth,
+		PruneInterval:  cfg.Server.PruneInterval,
+		Metrics:        metrics,
 	})
 
+	if cfg.Server.GRPCAddress != "" {
+		l, err := net.Listen("tcp", cfg.Server.GRPCAddress)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to listen for gRPC requests")
+		}
+
+		grpcLogger := logger.With().Str("service", "grpc").Logger()
+		grpcSrv := rpc.NewServer(service, rpc.Config{
+			Logger:     &grpcLogger,
+			Namespaces: namespaces,
+			Auth:       auth,
+		})
+		defer grpcSrv.Stop()
+
+		go func() {
+			if err := grpcSrv.Serve(l); err != nil {
+				logger.Error().Err(err).Msg("gRPC server stopped")
+			}
+		}()
+	}
+
 	cli.RunServer(srv, cfg.Server.Address)
 }
+
+// newService creates the store selected by the configuration and a function releasing its resources.
+// The namespace service is nil if the store doesn't support namespaces.
+// The latency of etcd is recorded by the given metrics.
+func newService(cfg *cli.Config, logger zerolog.Logger, metrics *server.Metrics) (store.RulesetService, store.NamespaceService, func()) {
+	switch cfg.Store {
+	case "memory":
+		return memory.NewRulesetService(), memory.NewNamespaceService(), func() {}
+	case "dir":
+		service, err := dir.NewRulesetService(cfg.Dir.Path, cfg.Dir.Interval, logger.With().Str("service", "dir").Logger())
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to load rulesets directory")
+		}
+
+		return service, nil, func() {}
+	case "bolt":
+		service, err := bolt.NewRulesetService(cfg.Bolt.Path, logger.With().Str("service", "bolt").Logger())
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to open bolt database")
+		}
+
+		return service, nil, func() { service.Close() }
+	default:
+		etcdCli, err := clientv3.New(clientv3.Config{
+			Endpoints:   cfg.Etcd.Endpoints,
+			DialTimeout: 5 * time.Second,
+			DialOptions: []grpc.DialOption{grpc.WithUnaryInterceptor(metrics.EtcdInterceptor())},
+		})
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to connect to etcd cluster")
+		}
+
+		etcdLogger := logger.With().Str("service", "etcd").Logger()
+
+		return &etcd.RulesetService{
+			Client:    etcdCli,
+			Namespace: cfg.Etcd.Namespace,
+			Logger:    etcdLogger,
+		}, &etcd.NamespaceService{
+			Client: etcdCli,
+			Logger: etcdLogger,
+		}, func() { etcdCli.Close() }
+	}
+}
@@ -0,0 +1,388 @@
+// Package diff computes the structural differences between two rulesets,
+// rule by rule and expression node by expression node, along with the changes
+// made to their tests and schema.
+package diff
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/heetch/regula"
+	"github.com/heetch/regula/rule"
+)
+
+// List of possible changes.
+const (
+	Added   = "added"
+	Removed = "removed"
+	Moved   = "moved"
+	Changed = "changed"
+)
+
+// Ruleset holds the differences between two rulesets.
+type Ruleset struct {
+	Type   *TypeChange `json:"type,omitempty"`
+	Rules  []Rule      `json:"rules,omitempty"`
+	Tests  []Test      `json:"tests,omitempty"`
+	Schema []Param     `json:"schema,omitempty"`
+}
+
+// Empty reports whether the rulesets are identical.
+func (r *Ruleset) Empty() bool {
+	return r.Type == nil && len(r.Rules) == 0 && len(r.Tests) == 0 && len(r.Schema) == 0
+}
+
+// TypeChange describes a change of the return type of a ruleset.
+type TypeChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Rule describes a change made to a rule.
+// From and To are the positions of the rule in the old and new rulesets,
+// From is -1 if the rule was added and To is -1 if it was removed.
+type Rule struct {
+	Change string        `json:"change"`
+	From   int           `json:"from"`
+	To     int           `json:"to"`
+	Rule   *rule.Rule    `json:"rule,omitempty"`   // added or removed rule
+	Result *ResultChange `json:"result,omitempty"` // set if the result of a changed rule differs
+	Expr   []Node        `json:"expr,omitempty"`   // changes made to the expression of a changed rule
+}
+
+// Test describes a test case that was added or removed. The order of the test cases doesn't matter,
+// a modified test case is reported as removed and added.
+// From and To are the positions of the test case in the old and new rulesets,
+// From is -1 if the test case was added and To is -1 if it was removed.
+type Test struct {
+	Change string           `json:"change"`
+	From   int              `json:"from"`
+	To     int              `json:"to"`
+	Test   *regula.TestCase `json:"test"`
+}
+
+// Param describes a change made to the schema of a param, identified by its name.
+// From is nil if the param was added and To is nil if it was removed.
+type Param struct {
+	Change string            `json:"change"`
+	Name   string            `json:"name"`
+	From   *regula.ParamSpec `json:"from,omitempty"`
+	To     *regula.ParamSpec `json:"to,omitempty"`
+}
+
+// ResultChange describes a change of the result of a rule.
+type ResultChange struct {
+	From *rule.Value `json:"from"`
+	To   *rule.Value `json:"to"`
+}
+
+// Node describes a change made to a node of an expression tree.
+// Path is the position of the node in the tree, as the list of operand indexes
+// leading to it from the root. From is nil if the node was added and To is nil if it was removed.
+type Node struct {
+	Change string `json:"change"`
+	Path   []int  `json:"path"`
+	From   *Expr  `json:"from,omitempty"`
+	To     *Expr  `json:"to,omitempty"`
+}
+
+// Expr summarizes an expression node. Operators only have a kind,
+// params have a type and a name and values have a type and data.
+type Expr struct {
+	Kind string `json:"kind"`
+	Type string `json:"type,omitempty"`
+	Name string `json:"name,omitempty"`
+	Data string `json:"data,omitempty"`
+}
+
+// Rulesets compares two rulesets and returns their differences.
+// Rules present in both rulesets at the same relative position are not reported.
+// Rules that only changed position are reported as moved, rules that can be paired
+// with a rule of the other ruleset are reported as changed, the others as added or removed.
+func Rulesets(from, to *regula.Ruleset) *Ruleset {
+	var d Ruleset
+
+	if from.Type != to.Type {
+		d.Type = &TypeChange{From: from.Type, To: to.Type}
+	}
+
+	fromKeys := ruleKeys(from.Rules)
+	toKeys := ruleKeys(to.Rules)
+
+	fromLeft, toLeft := unmatched(fromKeys, toKeys)
+
+	// rules that only changed position
+	for j := 0; j < len(toLeft); j++ {
+		for i := range fromLeft {
+			if fromKeys[fromLeft[i]] != toKeys[toLeft[j]] {
+				continue
+			}
+
+			d.Rules = append(d.Rules, Rule{Change: Moved, From: fromLeft[i], To: toLeft[j]})
+			fromLeft = remove(fromLeft, i)
+			toLeft = remove(toLeft, j)
+			j--
+			break
+		}
+	}
+
+	// rules whose result changed are paired first, then the remaining rules are paired in order
+	for j := 0; j < len(toLeft); j++ {
+		for i := range fromLeft {
+			if exprKey(from.Rules[fromLeft[i]]) != exprKey(to.Rules[toLeft[j]]) {
+				continue
+			}
+
+			d.Rules = append(d.Rules, changed(from.Rules, to.Rules, fromLeft[i], toLeft[j]))
+			fromLeft = remove(fromLeft, i)
+			toLeft = remove(toLeft, j)
+			j--
+			break
+		}
+	}
+
+	for len(fromLeft) > 0 && len(toLeft) > 0 {
+		d.Rules = append(d.Rules, changed(from.Rules, to.Rules, fromLeft[0], toLeft[0]))
+		fromLeft = fromLeft[1:]
+		toLeft = toLeft[1:]
+	}
+
+	for _, i := range fromLeft {
+		d.Rules = append(d.Rules, Rule{Change: Removed, From: i, To: -1, Rule: from.Rules[i]})
+	}
+
+	for _, j := range toLeft {
+		d.Rules = append(d.Rules, Rule{Change: Added, From: -1, To: j, Rule: to.Rules[j]})
+	}
+
+	sort.SliceStable(d.Rules, func(i, j int) bool {
+		return position(&d.Rules[i]) < position(&d.Rules[j])
+	})
+
+	d.Tests = tests(from.Tests, to.Tests)
+	d.Schema = schema(from.Schema, to.Schema)
+
+	return &d
+}
+
+// tests returns the test cases that are only present in one of the lists.
+func tests(from, to []*regula.TestCase) []Test {
+	fromKeys := testKeys(from)
+	toKeys := testKeys(to)
+
+	var changes []Test
+
+	matched := make([]bool, len(to))
+	for i := range from {
+		found := false
+		for j := range to {
+			if !matched[j] && fromKeys[i] == toKeys[j] {
+				matched[j] = true
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			changes = append(changes, Test{Change: Removed, From: i, To: -1, Test: from[i]})
+		}
+	}
+
+	for j := range to {
+		if !matched[j] {
+			changes = append(changes, Test{Change: Added, From: -1, To: j, Test: to[j]})
+		}
+	}
+
+	return changes
+}
+
+// schema compares the params specs by name. Added and changed params are reported in the order
+// of the new schema, followed by the removed ones.
+func schema(from, to []*regula.ParamSpec) []Param {
+	var changes []Param
+
+	for _, t := range to {
+		f := findParam(from, t.Name)
+		switch {
+		case f == nil:
+			changes = append(changes, Param{Change: Added, Name: t.Name, To: t})
+		case jsonKey(f) != jsonKey(t):
+			changes = append(changes, Param{Change: Changed, Name: t.Name, From: f, To: t})
+		}
+	}
+
+	for _, f := range from {
+		if findParam(to, f.Name) == nil {
+			changes = append(changes, Param{Change: Removed, Name: f.Name, From: f})
+		}
+	}
+
+	return changes
+}
+
+func findParam(specs []*regula.ParamSpec, name string) *regula.ParamSpec {
+	for _, s := range specs {
+		if s.Name == name {
+			return s
+		}
+	}
+
+	return nil
+}
+
+func changed(from, to []*rule.Rule, i, j int) Rule {
+	r := Rule{
+		Change: Changed,
+		From:   i,
+		To:     j,
+		Expr:   Exprs(from[i].Expr, to[j].Expr),
+	}
+
+	if fr, tr := from[i].Result, to[j].Result; fr == nil || tr == nil || *fr != *tr {
+		r.Result = &ResultChange{From: fr, To: tr}
+	}
+
+	return r
+}
+
+// position returns the index used to sort the changes, removed rules are reported
+// where they were in the old ruleset.
+func position(r *Rule) int {
+	if r.To >= 0 {
+		return r.To
+	}
+
+	return r.From
+}
+
+// unmatched computes the longest common subsequence of both lists of keys and returns
+// the indexes of the keys that are not part of it.
+func unmatched(from, to []string) (fromLeft, toLeft []int) {
+	lcs := make([][]int, len(from)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(to)+1)
+	}
+
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			switch {
+			case from[i] == to[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(from) && j < len(to) {
+		switch {
+		case from[i] == to[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			fromLeft = append(fromLeft, i)
+			i++
+		default:
+			toLeft = append(toLeft, j)
+			j++
+		}
+	}
+
+	for ; i < len(from); i++ {
+		fromLeft = append(fromLeft, i)
+	}
+
+	for ; j < len(to); j++ {
+		toLeft = append(toLeft, j)
+	}
+
+	return fromLeft, toLeft
+}
+
+func remove(l []int, i int) []int {
+	return append(l[:i], l[i+1:]...)
+}
+
+func ruleKeys(rules []*rule.Rule) []string {
+	keys := make([]string, len(rules))
+	for i, r := range rules {
+		keys[i] = jsonKey(r)
+	}
+
+	return keys
+}
+
+func testKeys(tcs []*regula.TestCase) []string {
+	keys := make([]string, len(tcs))
+	for i, tc := range tcs {
+		keys[i] = jsonKey(tc)
+	}
+
+	return keys
+}
+
+func jsonKey(v interface{}) string {
+	raw, _ := json.Marshal(v)
+	return string(raw)
+}
+
+func exprKey(r *rule.Rule) string {
+	raw, _ := json.Marshal(r.Expr)
+	return string(raw)
+}
+
+// Exprs compares two expression trees node by node and returns the list of changes.
+// Operands of operators of the same kind are compared one by one, if the kinds differ the
+// whole node is reported as changed.
+func Exprs(from, to rule.Expr) []Node {
+	return diffNodes(newNode(from), newNode(to), []int{})
+}
+
+// node is a generic representation of an expression, built from its JSON representation.
+type node struct {
+	Expr
+	Operands []node `json:"operands"`
+}
+
+func newNode(e rule.Expr) node {
+	var n node
+
+	raw, err := json.Marshal(e)
+	if err == nil {
+		_ = json.Unmarshal(raw, &n)
+	}
+
+	return n
+}
+
+func diffNodes(from, to node, path []int) []Node {
+	if from.Expr != to.Expr {
+		f, t := from.Expr, to.Expr
+		return []Node{{Change: Changed, Path: path, From: &f, To: &t}}
+	}
+
+	var changes []Node
+
+	for i := 0; i < len(from.Operands) || i < len(to.Operands); i++ {
+		p := make([]int, len(path)+1)
+		copy(p, path)
+		p[len(path)] = i
+
+		switch {
+		case i >= len(to.Operands):
+			f := from.Operands[i].Expr
+			changes = append(changes, Node{Change: Removed, Path: p, From: &f})
+		case i >= len(from.Operands):
+			t := to.Operands[i].Expr
+			changes = append(changes, Node{Change: Added, Path: p, To: &t})
+		default:
+			changes = append(changes, diffNodes(from.Operands[i], to.Operands[i], p)...)
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,193 @@
+package diff_test
+
+import (
+	"testing"
+
+	"github.com/heetch/regula"
+	"github.com/heetch/regula/diff"
+	"github.com/heetch/regula/rule"
+	"github.com/stretchr/testify/require"
+)
+
+func cityRule(city, result string) *rule.Rule {
+	return rule.New(rule.Eq(rule.StringParam("city"), rule.StringValue(city)), rule.StringValue(result))
+}
+
+func TestRulesets(t *testing.T) {
+	t.Run("Identical", func(t *testing.T) {
+		r1, _ := regula.NewStringRuleset(cityRule("paris", "a"), cityRule("milan", "b"))
+		r2, _ := regula.NewStringRuleset(cityRule("paris", "a"), cityRule("milan", "b"))
+
+		d := diff.Rulesets(r1, r2)
+		require.True(t, d.Empty())
+	})
+
+	t.Run("Type", func(t *testing.T) {
+		r1, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("true")))
+		r2, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(true)))
+
+		d := diff.Rulesets(r1, r2)
+		require.Equal(t, &diff.TypeChange{From: "string", To: "bool"}, d.Type)
+		require.Len(t, d.Rules, 1)
+		require.Equal(t, diff.Changed, d.Rules[0].Change)
+		require.Equal(t, &diff.ResultChange{From: rule.StringValue("true"), To: rule.BoolValue(true)}, d.Rules[0].Result)
+		require.Empty(t, d.Rules[0].Expr)
+	})
+
+	t.Run("AddedRemoved", func(t *testing.T) {
+		r1, _ := regula.NewStringRuleset(cityRule("paris", "a"), cityRule("milan", "b"))
+		r2, _ := regula.NewStringRuleset(cityRule("paris", "a"), cityRule("milan", "b"), cityRule("rome", "c"))
+
+		d := diff.Rulesets(r1, r2)
+		require.Equal(t, []diff.Rule{
+			{Change: diff.Added, From: -1, To: 2, Rule: cityRule("rome", "c")},
+		}, d.Rules)
+
+		d = diff.Rulesets(r2, r1)
+		require.Equal(t, []diff.Rule{
+			{Change: diff.Removed, From: 2, To: -1, Rule: cityRule("rome", "c")},
+		}, d.Rules)
+	})
+
+	t.Run("Moved", func(t *testing.T) {
+		r1, _ := regula.NewStringRuleset(cityRule("paris", "a"), cityRule("milan", "b"), cityRule("rome", "c"))
+		r2, _ := regula.NewStringRuleset(cityRule("rome", "c"), cityRule("paris", "a"), cityRule("milan", "b"))
+
+		d := diff.Rulesets(r1, r2)
+		require.Equal(t, []diff.Rule{
+			{Change: diff.Moved, From: 2, To: 0},
+		}, d.Rules)
+	})
+
+	t.Run("Changed", func(t *testing.T) {
+		r1, _ := regula.NewStringRuleset(cityRule("paris", "a"), cityRule("milan", "b"), cityRule("rome", "c"))
+		r2, _ := regula.NewStringRuleset(cityRule("paris", "a"), cityRule("milan", "x"), cityRule("lyon", "c"))
+
+		d := diff.Rulesets(r1, r2)
+		require.Equal(t, []diff.Rule{
+			{Change: diff.Changed, From: 1, To: 1, Result: &diff.ResultChange{From: rule.StringValue("b"), To: rule.StringValue("x")}},
+			{Change: diff.Changed, From: 2, To: 2, Expr: []diff.Node{
+				{
+					Change: diff.Changed,
+					Path:   []int{1},
+					From:   &diff.Expr{Kind: "value", Type: "string", Data: "rome"},
+					To:     &diff.Expr{Kind: "value", Type: "string", Data: "lyon"},
+				},
+			}},
+		}, d.Rules)
+	})
+}
+
+func TestRulesetsTests(t *testing.T) {
+	tc := func(city, result string) *regula.TestCase {
+		return &regula.TestCase{Params: regula.Params{"city": city}, Result: rule.StringValue(result)}
+	}
+
+	t.Run("Identical", func(t *testing.T) {
+		r1, _ := regula.NewStringRuleset(cityRule("paris", "a"))
+		r1.Tests = []*regula.TestCase{tc("paris", "a"), tc("milan", "b")}
+		r2, _ := regula.NewStringRuleset(cityRule("paris", "a"))
+		r2.Tests = []*regula.TestCase{tc("milan", "b"), tc("paris", "a")}
+
+		d := diff.Rulesets(r1, r2)
+		require.Empty(t, d.Tests)
+		require.True(t, d.Empty())
+	})
+
+	t.Run("AddedRemoved", func(t *testing.T) {
+		r1, _ := regula.NewStringRuleset(cityRule("paris", "a"))
+		r1.Tests = []*regula.TestCase{tc("paris", "a"), tc("milan", "b")}
+		r2, _ := regula.NewStringRuleset(cityRule("paris", "a"))
+		r2.Tests = []*regula.TestCase{tc("paris", "a"), tc("milan", "c"), {Params: regula.Params{"city": "rome"}, NoMatch: true}}
+
+		d := diff.Rulesets(r1, r2)
+		require.False(t, d.Empty())
+		require.Empty(t, d.Rules)
+		require.Equal(t, []diff.Test{
+			{Change: diff.Removed, From: 1, To: -1, Test: tc("milan", "b")},
+			{Change: diff.Added, From: -1, To: 1, Test: tc("milan", "c")},
+			{Change: diff.Added, From: -1, To: 2, Test: r2.Tests[2]},
+		}, d.Tests)
+	})
+}
+
+func TestRulesetsSchema(t *testing.T) {
+	min := 0.0
+
+	t.Run("Identical", func(t *testing.T) {
+		r1, _ := regula.NewStringRuleset(cityRule("paris", "a"))
+		r1.Schema = []*regula.ParamSpec{{Name: "city", Type: "string"}}
+		r2, _ := regula.NewStringRuleset(cityRule("paris", "a"))
+		r2.Schema = []*regula.ParamSpec{{Name: "city", Type: "string"}}
+
+		d := diff.Rulesets(r1, r2)
+		require.Empty(t, d.Schema)
+		require.True(t, d.Empty())
+	})
+
+	t.Run("Changes", func(t *testing.T) {
+		r1, _ := regula.NewStringRuleset(cityRule("paris", "a"))
+		r1.Schema = []*regula.ParamSpec{
+			{Name: "city", Type: "string"},
+			{Name: "age", Type: "int64"},
+			{Name: "vip", Type: "bool"},
+		}
+		r2, _ := regula.NewStringRuleset(cityRule("paris", "a"))
+		r2.Schema = []*regula.ParamSpec{
+			{Name: "city", Type: "string", Enum: []string{"paris", "milan"}},
+			{Name: "age", Type: "int64"},
+			{Name: "score", Type: "float64", Min: &min},
+		}
+
+		d := diff.Rulesets(r1, r2)
+		require.False(t, d.Empty())
+		require.Empty(t, d.Rules)
+		require.Equal(t, []diff.Param{
+			{Change: diff.Changed, Name: "city", From: r1.Schema[0], To: r2.Schema[0]},
+			{Change: diff.Added, Name: "score", To: r2.Schema[2]},
+			{Change: diff.Removed, Name: "vip", From: r1.Schema[2]},
+		}, d.Schema)
+	})
+}
+
+func TestExprs(t *testing.T) {
+	t.Run("Identical", func(t *testing.T) {
+		e1 := rule.And(rule.BoolParam("a"), rule.Eq(rule.StringParam("b"), rule.StringValue("c")))
+		e2 := rule.And(rule.BoolParam("a"), rule.Eq(rule.StringParam("b"), rule.StringValue("c")))
+
+		require.Empty(t, diff.Exprs(e1, e2))
+	})
+
+	t.Run("Operands", func(t *testing.T) {
+		e1 := rule.And(rule.BoolParam("a"), rule.Eq(rule.StringParam("b"), rule.StringValue("c")))
+		e2 := rule.And(rule.BoolParam("x"), rule.Eq(rule.StringParam("b"), rule.StringValue("c"), rule.StringValue("d")))
+
+		require.Equal(t, []diff.Node{
+			{
+				Change: diff.Changed,
+				Path:   []int{0},
+				From:   &diff.Expr{Kind: "param", Type: "bool", Name: "a"},
+				To:     &diff.Expr{Kind: "param", Type: "bool", Name: "x"},
+			},
+			{
+				Change: diff.Added,
+				Path:   []int{1, 2},
+				To:     &diff.Expr{Kind: "value", Type: "string", Data: "d"},
+			},
+		}, diff.Exprs(e1, e2))
+	})
+
+	t.Run("Kind", func(t *testing.T) {
+		e1 := rule.And(rule.BoolParam("a"), rule.BoolParam("b"))
+		e2 := rule.Or(rule.BoolParam("a"), rule.BoolParam("b"))
+
+		require.Equal(t, []diff.Node{
+			{
+				Change: diff.Changed,
+				Path:   []int{},
+				From:   &diff.Expr{Kind: "and"},
+				To:     &diff.Expr{Kind: "or"},
+			},
+		}, diff.Exprs(e1, e2))
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/heetch/confita"
 	"github.com/heetch/confita/backend"
@@ -17,32 +18,45 @@ import (
 // It is safe for concurrent use.
 type Engine struct {
 	evaluator Evaluator
+	observers []Observer
 }
 
 // NewEngine creates an Engine using the given evaluator.
-func NewEngine(evaluator Evaluator) *Engine {
+// The given observers are notified of every evaluation made by the engine.
+func NewEngine(evaluator Evaluator, observers ...Observer) *Engine {
 	return &Engine{
 		evaluator: evaluator,
+		observers: observers,
 	}
 }
 
-// Get evaluates a ruleset and returns the result.
-func (e *Engine) get(ctx context.Context, typ, path string, params rule.Params, opts ...Option) (*EvalResult, error) {
-	var cfg engineConfig
-	for _, opt := range opts {
-		opt(&cfg)
-	}
+// eval evaluates the given version of a ruleset, or its latest version if empty, and notifies the observers.
+func (e *Engine) eval(ctx context.Context, path, version string, params rule.Params) (*EvalResult, error) {
+	start := time.Now()
 
 	var (
 		result *EvalResult
 		err    error
 	)
 
-	if cfg.Version != "" {
-		result, err = e.evaluator.EvalVersion(ctx, path, cfg.Version, params)
+	if version != "" {
+		result, err = e.evaluator.EvalVersion(ctx, path, version, params)
 	} else {
 		result, err = e.evaluator.Eval(ctx, path, params)
 	}
+
+	observe(ctx, e.observers, path, version, params, result, err, start)
+	return result, err
+}
+
+// Get evaluates a ruleset and returns the result.
+func (e *Engine) get(ctx context.Context, typ, path string, params rule.Params, opts ...Option) (*EvalResult, error) {
+	var cfg engineConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	result, err := e.eval(ctx, path, cfg.Version, params)
 	if err != nil {
 		if err == ErrRulesetNotFound || err == rule.ErrNoMatch {
 			return nil, err
@@ -104,7 +118,7 @@ func (e *Engine) GetFloat64(ctx context.Context, path string, params rule.Params
 // tagged with the "ruleset" struct tag.
 func (e *Engine) LoadStruct(ctx context.Context, to interface{}, params rule.Params) error {
 	b := backend.Func("regula", func(ctx context.Context, path string) ([]byte, error) {
-		res, err := e.evaluator.Eval(ctx, path, params)
+		res, err := e.eval(ctx, path, "", params)
 		if err != nil {
 			if err == ErrRulesetNotFound {
 				return nil, backend.ErrNotFound
@@ -122,6 +136,48 @@ func (e *Engine) LoadStruct(ctx context.Context, to interface{}, params rule.Par
 	return l.Load(ctx, to)
 }
 
+// EvalBatch evaluates every given ruleset with every given set of params and returns the results
+// ordered by path, then by set of params. If no params are given, the rulesets are evaluated with empty params.
+// Evaluation errors are reported per result, the returned error is only set if the batch itself failed.
+// If the evaluator implements BatchEvaluator, the whole batch is delegated to it in a single call,
+// in which case the observers are notified of every result with the duration of the whole batch.
+func (e *Engine) EvalBatch(ctx context.Context, paths []string, params ...rule.Params) ([]BatchResult, error) {
+	if len(params) == 0 {
+		params = []rule.Params{Params{}}
+	}
+
+	if be, ok := e.evaluator.(BatchEvaluator); ok {
+		start := time.Now()
+		results, err := be.EvalBatch(ctx, paths, params)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range results {
+			if r.Params >= 0 && r.Params < len(params) {
+				observe(ctx, e.observers, r.Path, "", params[r.Params], r.Result, r.Err, start)
+			}
+		}
+
+		return results, nil
+	}
+
+	results := make([]BatchResult, 0, len(paths)*len(params))
+	for _, path := range paths {
+		for i, p := range params {
+			res, err := e.eval(ctx, path, "", p)
+			results = append(results, BatchResult{
+				Path:   path,
+				Params: i,
+				Result: res,
+				Err:    err,
+			})
+		}
+	}
+
+	return results, nil
+}
+
 type engineConfig struct {
 	Version string
 }
@@ -147,19 +203,44 @@ type Evaluator interface {
 	EvalVersion(ctx context.Context, path string, version string, params rule.Params) (*EvalResult, error)
 }
 
+// A BatchEvaluator is an Evaluator able to evaluate several rulesets at once, e.g. in a single network round trip.
+type BatchEvaluator interface {
+	Evaluator
+
+	// EvalBatch evaluates every ruleset with every set of params and returns the results
+	// ordered by path, then by set of params. Evaluation errors must be reported per result,
+	// using ErrRulesetNotFound if no ruleset is found for a given path.
+	EvalBatch(ctx context.Context, paths []string, params []rule.Params) ([]BatchResult, error)
+}
+
+// BatchResult is the outcome of the evaluation of a ruleset with one of the sets of params of a batch.
+type BatchResult struct {
+	// Path of the ruleset
+	Path string
+	// Index of the set of params used for the evaluation
+	Params int
+	// Result of the evaluation, nil if it failed
+	Result *EvalResult
+	// Error returned by the evaluation
+	Err error
+}
+
 // EvalResult is the product of an evaluation. It contains the value generated as long as some metadata.
 type EvalResult struct {
 	// Result of the evaluation
 	Value *rule.Value
 	// Version of the ruleset that generated this value
 	Version string
+	// Index of the rule that matched, nil if the evaluator couldn't report it
+	Rule *int
 }
 
 // RulesetBuffer can hold a group of rulesets in memory and can be used as an evaluator.
 // It is safe for concurrent use.
 type RulesetBuffer struct {
-	rw       sync.RWMutex
-	rulesets map[string][]*rulesetInfo
+	rw        sync.RWMutex
+	rulesets  map[string][]*rulesetInfo
+	observers []Observer
 }
 
 // NewRulesetBuffer creates a ready to use RulesetBuffer.
@@ -174,14 +255,50 @@ type rulesetInfo struct {
 	r             *Ruleset
 }
 
+// Observe registers observers notified of every evaluation made by the buffer.
+func (b *RulesetBuffer) Observe(observers ...Observer) {
+	b.rw.Lock()
+	b.observers = append(b.observers, observers...)
+	b.rw.Unlock()
+}
+
 // Add adds the given ruleset version to a list for a specific path.
-// The last added ruleset is treated as the latest version.
+// The last added ruleset is treated as the latest version. Adding a version
+// that already exists makes it the latest version, e.g. after a rollback.
 func (b *RulesetBuffer) Add(path, version string, r *Ruleset) {
 	b.rw.Lock()
-	b.rulesets[path] = append(b.rulesets[path], &rulesetInfo{path, version, r})
+	l := b.rulesets[path]
+	for i := range l {
+		if l[i].version == version {
+			l = append(l[:i], l[i+1:]...)
+			break
+		}
+	}
+	b.rulesets[path] = append(l, &rulesetInfo{path, version, r})
 	b.rw.Unlock()
 }
 
+// Remove removes the given ruleset version. The path is removed when its last version is.
+func (b *RulesetBuffer) Remove(path, version string) {
+	b.rw.Lock()
+	defer b.rw.Unlock()
+
+	l := b.rulesets[path]
+	for i := range l {
+		if l[i].version == version {
+			l = append(l[:i], l[i+1:]...)
+			break
+		}
+	}
+
+	if len(l) == 0 {
+		delete(b.rulesets, path)
+		return
+	}
+
+	b.rulesets[path] = l
+}
+
 // Latest returns the latest version of a ruleset.
 func (b *RulesetBuffer) Latest(path string) (*Ruleset, string, error) {
 	b.rw.RLock()
@@ -210,24 +327,7 @@ func (b *RulesetBuffer) GetVersion(path, version string) (*Ruleset, error) {
 
 // Eval evaluates the latest added ruleset or returns ErrRulesetNotFound if not found.
 func (b *RulesetBuffer) Eval(ctx context.Context, path string, params rule.Params) (*EvalResult, error) {
-	b.rw.RLock()
-	defer b.rw.RUnlock()
-
-	l, ok := b.rulesets[path]
-	if !ok || len(l) == 0 {
-		return nil, ErrRulesetNotFound
-	}
-
-	ri := l[len(l)-1]
-	v, err := ri.r.Eval(params)
-	if err != nil {
-		return nil, err
-	}
-
-	return &EvalResult{
-		Value:   v,
-		Version: ri.version,
-	}, nil
+	return b.eval(ctx, path, "", params)
 }
 
 func (b *RulesetBuffer) getVersion(path, version string) (*rulesetInfo, error) {
@@ -247,15 +347,42 @@ func (b *RulesetBuffer) getVersion(path, version string) (*rulesetInfo, error) {
 
 // EvalVersion evaluates the selected ruleset version or returns ErrRulesetNotFound if not found.
 func (b *RulesetBuffer) EvalVersion(ctx context.Context, path, version string, params rule.Params) (*EvalResult, error) {
+	return b.eval(ctx, path, version, params)
+}
+
+// eval evaluates the given version of a ruleset, or its latest version if empty.
+// The observers are notified once the lock is released, so that they can use the buffer.
+func (b *RulesetBuffer) eval(ctx context.Context, path, version string, params rule.Params) (*EvalResult, error) {
+	start := time.Now()
+
 	b.rw.RLock()
-	defer b.rw.RUnlock()
+	res, err := b.evalLocked(path, version, params)
+	observers := b.observers
+	b.rw.RUnlock()
 
-	ri, err := b.getVersion(path, version)
-	if err != nil {
-		return nil, err
+	observe(ctx, observers, path, version, params, res, err, start)
+	return res, err
+}
+
+func (b *RulesetBuffer) evalLocked(path, version string, params rule.Params) (*EvalResult, error) {
+	var ri *rulesetInfo
+
+	if version != "" {
+		var err error
+		ri, err = b.getVersion(path, version)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		l, ok := b.rulesets[path]
+		if !ok || len(l) == 0 {
+			return nil, ErrRulesetNotFound
+		}
+
+		ri = l[len(l)-1]
 	}
 
-	v, err := ri.r.Eval(params)
+	v, i, err := ri.r.EvalRule(params)
 	if err != nil {
 		return nil, err
 	}
@@ -263,5 +390,6 @@ func (b *RulesetBuffer) EvalVersion(ctx context.Context, path, version string, p
 	return &EvalResult{
 		Value:   v,
 		Version: ri.version,
+		Rule:    &i,
 	}, nil
 }
@@ -116,6 +116,30 @@ func TestEngine(t *testing.T) {
 		require.Equal(t, regula.ErrRulesetNotFound, err)
 	})
 
+	t.Run("EvalBatch", func(t *testing.T) {
+		res, err := e.EvalBatch(ctx, []string{"match-string-a", "not-found"}, regula.Params{"foo": "bar"}, regula.Params{"foo": "baz"})
+		require.NoError(t, err)
+		require.Len(t, res, 4)
+
+		require.Equal(t, "match-string-a", res[0].Path)
+		require.Equal(t, 0, res[0].Params)
+		require.NoError(t, res[0].Err)
+		require.Equal(t, "matched a v2", res[0].Result.Value.Data)
+
+		require.Equal(t, 1, res[1].Params)
+		require.Equal(t, rule.ErrNoMatch, res[1].Err)
+
+		require.Equal(t, "not-found", res[2].Path)
+		require.Equal(t, regula.ErrRulesetNotFound, res[2].Err)
+		require.Nil(t, res[3].Result)
+
+		// without params, every ruleset is evaluated once
+		res, err = e.EvalBatch(ctx, []string{"match-string-b", "match-bool"})
+		require.NoError(t, err)
+		require.Len(t, res, 2)
+		require.Equal(t, "true", res[1].Result.Value.Data)
+	})
+
 	t.Run("StructLoading", func(t *testing.T) {
 		to := struct {
 			StringA  string        `ruleset:"match-string-a"`
@@ -160,3 +184,44 @@ func TestEngine(t *testing.T) {
 		require.Error(t, err)
 	})
 }
+
+func TestRulesetBuffer(t *testing.T) {
+	buf := regula.NewRulesetBuffer()
+
+	r1, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("a")))
+	r2, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("b")))
+
+	buf.Add("a", "1", r1)
+	buf.Add("a", "2", r2)
+
+	rs, version, err := buf.Latest("a")
+	require.NoError(t, err)
+	require.Equal(t, "2", version)
+	require.Equal(t, r2, rs)
+
+	// adding an existing version makes it the latest
+	buf.Add("a", "1", r1)
+
+	rs, version, err = buf.Latest("a")
+	require.NoError(t, err)
+	require.Equal(t, "1", version)
+	require.Equal(t, r1, rs)
+
+	rs, err = buf.GetVersion("a", "2")
+	require.NoError(t, err)
+	require.Equal(t, r2, rs)
+
+	buf.Remove("a", "1")
+
+	_, version, err = buf.Latest("a")
+	require.NoError(t, err)
+	require.Equal(t, "2", version)
+
+	_, err = buf.GetVersion("a", "1")
+	require.Equal(t, regula.ErrRulesetNotFound, err)
+
+	buf.Remove("a", "2")
+
+	_, _, err = buf.Latest("a")
+	require.Equal(t, regula.ErrRulesetNotFound, err)
+}
@@ -128,6 +128,27 @@ func ExampleEngine() {
 	// 5b4cbdf307bb5346a6c42ac3
 }
 
+func ExampleRuleHits() {
+	hits := regula.NewRuleHits()
+
+	// log every decision and count the rules that matched.
+	engine := regula.NewEngine(ev, hits, regula.ObserverFunc(func(ctx context.Context, e *regula.Evaluation) {
+		log.Printf("path=%s version=%s rule=%d err=%v duration=%s", e.Path, e.Version, e.Rule, e.Err, e.Duration)
+	}))
+
+	_, _, err := engine.GetString(context.Background(), "/a/b/c", regula.Params{
+		"product-id": "1234",
+		"user-id":    "5678",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, h := range hits.All() {
+		fmt.Printf("%s@%s rule %d matched %d times\n", h.Path, h.Version, h.Rule, h.Hits)
+	}
+}
+
 func ExampleEngine_GetBool() {
 	engine := regula.NewEngine(ev)
 
@@ -0,0 +1,162 @@
+package regula
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/heetch/regula/rule"
+)
+
+// An Observer is notified of every evaluation made by an Engine or a RulesetBuffer,
+// e.g. to record decision logs or metrics.
+// Observers are called synchronously once the evaluation is done and must be safe for concurrent use.
+type Observer interface {
+	ObserveEval(ctx context.Context, ev *Evaluation)
+}
+
+// ObserverFunc is an adapter allowing the use of ordinary functions as observers.
+type ObserverFunc func(ctx context.Context, ev *Evaluation)
+
+// ObserveEval calls fn(ctx, ev).
+func (fn ObserverFunc) ObserveEval(ctx context.Context, ev *Evaluation) {
+	fn(ctx, ev)
+}
+
+// Evaluation describes an evaluation passed to the observers.
+type Evaluation struct {
+	// Path of the ruleset
+	Path string
+	// Version of the ruleset that was evaluated, or the requested version if the evaluation failed,
+	// which is empty when the latest version was requested
+	Version string
+	// Params used for the evaluation
+	Params rule.Params
+	// Index of the rule that matched, nil if none did or if the evaluator couldn't report it
+	Rule *int
+	// Result of the evaluation, nil if it failed
+	Result *EvalResult
+	// Error returned by the evaluation
+	Err error
+	// Duration of the evaluation
+	Duration time.Duration
+}
+
+// observe notifies the observers of the evaluation of the ruleset stored on the given path,
+// started at the given time.
+func observe(ctx context.Context, observers []Observer, path, version string, params rule.Params, res *EvalResult, err error, start time.Time) {
+	if len(observers) == 0 {
+		return
+	}
+
+	ev := Evaluation{
+		Path:     path,
+		Version:  version,
+		Params:   params,
+		Result:   res,
+		Err:      err,
+		Duration: time.Since(start),
+	}
+
+	if res != nil {
+		ev.Version = res.Version
+		ev.Rule = res.Rule
+	}
+
+	for _, o := range observers {
+		o.ObserveEval(ctx, &ev)
+	}
+}
+
+// RuleHits is an Observer counting how many times each rule of each ruleset version matched.
+// Rules that never match in production are often dead and can be detected using the Unmatched method.
+// It is safe for concurrent use.
+type RuleHits struct {
+	mu   sync.Mutex
+	hits map[ruleKey]int64
+}
+
+type ruleKey struct {
+	path, version string
+	rule          int
+}
+
+// RuleHit holds the number of times a rule matched.
+type RuleHit struct {
+	Path    string
+	Version string
+	// Index of the rule in the ruleset
+	Rule int
+	Hits int64
+}
+
+// NewRuleHits creates a ready to use RuleHits.
+func NewRuleHits() *RuleHits {
+	return &RuleHits{
+		hits: make(map[ruleKey]int64),
+	}
+}
+
+// ObserveEval counts the rule that matched, if any.
+func (h *RuleHits) ObserveEval(ctx context.Context, ev *Evaluation) {
+	if ev.Rule == nil {
+		return
+	}
+
+	h.mu.Lock()
+	h.hits[ruleKey{ev.Path, ev.Version, *ev.Rule}]++
+	h.mu.Unlock()
+}
+
+// Hits returns the number of times the rule at the given index of the ruleset version matched.
+func (h *RuleHits) Hits(path, version string, rule int) int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.hits[ruleKey{path, version, rule}]
+}
+
+// All returns the counters of the rules that matched at least once, ordered by path, version and rule.
+func (h *RuleHits) All() []RuleHit {
+	h.mu.Lock()
+	hits := make([]RuleHit, 0, len(h.hits))
+	for k, n := range h.hits {
+		hits = append(hits, RuleHit{Path: k.path, Version: k.version, Rule: k.rule, Hits: n})
+	}
+	h.mu.Unlock()
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Path != hits[j].Path {
+			return hits[i].Path < hits[j].Path
+		}
+		if hits[i].Version != hits[j].Version {
+			return hits[i].Version < hits[j].Version
+		}
+		return hits[i].Rule < hits[j].Rule
+	})
+
+	return hits
+}
+
+// Unmatched returns the indexes of the rules of the given ruleset version that never matched.
+func (h *RuleHits) Unmatched(path, version string, rs *Ruleset) []int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var rules []int
+	for i := range rs.Rules {
+		if h.hits[ruleKey{path, version, i}] == 0 {
+			rules = append(rules, i)
+		}
+	}
+
+	return rules
+}
+
+// Reset sets all the counters to zero.
+func (h *RuleHits) Reset() {
+	h.mu.Lock()
+	h.hits = make(map[ruleKey]int64)
+	h.mu.Unlock()
+}
@@ -0,0 +1,236 @@
+package regula_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/heetch/regula"
+	"github.com/heetch/regula/rule"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder is an observer recording the evaluations.
+type recorder struct {
+	mu  sync.Mutex
+	evs []regula.Evaluation
+}
+
+func (r *recorder) ObserveEval(ctx context.Context, ev *regula.Evaluation) {
+	r.mu.Lock()
+	r.evs = append(r.evs, *ev)
+	r.mu.Unlock()
+}
+
+// batchEvaluator delegates the evaluations of a batch to a RulesetBuffer.
+type batchEvaluator struct {
+	*regula.RulesetBuffer
+	calls int
+}
+
+func (b *batchEvaluator) EvalBatch(ctx context.Context, paths []string, params []rule.Params) ([]regula.BatchResult, error) {
+	b.calls++
+	return regula.NewEngine(b.RulesetBuffer).EvalBatch(ctx, paths, params...)
+}
+
+// unknownRuleEvaluator is an evaluator that doesn't report the rule that matched.
+type unknownRuleEvaluator struct {
+	*regula.RulesetBuffer
+}
+
+func (u *unknownRuleEvaluator) Eval(ctx context.Context, path string, params rule.Params) (*regula.EvalResult, error) {
+	res, err := u.RulesetBuffer.Eval(ctx, path, params)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Rule = nil
+	return res, nil
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func newObservedBuffer(t *testing.T) *regula.RulesetBuffer {
+	buf := regula.NewRulesetBuffer()
+
+	for _, version := range []string{"1", "2"} {
+		rs, err := regula.NewStringRuleset(
+			rule.New(rule.Eq(rule.StringParam("foo"), rule.StringValue("bar")), rule.StringValue("bar")),
+			rule.New(rule.Eq(rule.StringParam("foo"), rule.StringValue("baz")), rule.StringValue("baz")),
+			rule.New(rule.Eq(rule.StringParam("foo"), rule.StringValue("qux")), rule.StringValue("qux")),
+		)
+		require.NoError(t, err)
+		buf.Add("a", version, rs)
+	}
+
+	return buf
+}
+
+func TestEngineObservers(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Get", func(t *testing.T) {
+		var r recorder
+		e := regula.NewEngine(newObservedBuffer(t), &r)
+
+		params := regula.Params{"foo": "baz"}
+		_, _, err := e.GetString(ctx, "a", params)
+		require.NoError(t, err)
+
+		_, _, err = e.GetString(ctx, "a", regula.Params{"foo": "bar"}, regula.Version("1"))
+		require.NoError(t, err)
+
+		_, _, err = e.GetString(ctx, "a", regula.Params{"foo": "nope"})
+		require.Equal(t, rule.ErrNoMatch, err)
+
+		_, _, err = e.GetString(ctx, "b", nil, regula.Version("1"))
+		require.Equal(t, regula.ErrRulesetNotFound, err)
+
+		// evaluations succeeding with the wrong type are observed as successful.
+		_, _, err = e.GetBool(ctx, "a", params)
+		require.Equal(t, regula.ErrTypeMismatch, err)
+
+		require.Len(t, r.evs, 5)
+
+		require.Equal(t, "a", r.evs[0].Path)
+		require.Equal(t, "2", r.evs[0].Version)
+		require.Equal(t, params, r.evs[0].Params)
+		require.Equal(t, intPtr(1), r.evs[0].Rule)
+		require.Equal(t, "baz", r.evs[0].Result.Value.Data)
+		require.NoError(t, r.evs[0].Err)
+
+		require.Equal(t, "1", r.evs[1].Version)
+		require.Equal(t, intPtr(0), r.evs[1].Rule)
+
+		// the version is unknown when the latest one was requested and the evaluation failed.
+		require.Equal(t, "", r.evs[2].Version)
+		require.Nil(t, r.evs[2].Rule)
+		require.Nil(t, r.evs[2].Result)
+		require.Equal(t, rule.ErrNoMatch, r.evs[2].Err)
+
+		// the requested version is reported when the evaluation fails.
+		require.Equal(t, "b", r.evs[3].Path)
+		require.Equal(t, "1", r.evs[3].Version)
+		require.Equal(t, regula.ErrRulesetNotFound, r.evs[3].Err)
+
+		require.NoError(t, r.evs[4].Err)
+	})
+
+	t.Run("LoadStruct", func(t *testing.T) {
+		var r recorder
+		e := regula.NewEngine(newObservedBuffer(t), &r)
+
+		to := struct {
+			A string `ruleset:"a"`
+		}{}
+		require.NoError(t, e.LoadStruct(ctx, &to, regula.Params{"foo": "qux"}))
+		require.Equal(t, "qux", to.A)
+
+		require.Len(t, r.evs, 1)
+		require.Equal(t, intPtr(2), r.evs[0].Rule)
+	})
+
+	t.Run("EvalBatch", func(t *testing.T) {
+		var r recorder
+		e := regula.NewEngine(newObservedBuffer(t), &r)
+
+		_, err := e.EvalBatch(ctx, []string{"a", "b"}, regula.Params{"foo": "bar"}, regula.Params{"foo": "baz"})
+		require.NoError(t, err)
+		require.Len(t, r.evs, 4)
+		require.Equal(t, intPtr(0), r.evs[0].Rule)
+		require.Equal(t, intPtr(1), r.evs[1].Rule)
+		require.Equal(t, regula.ErrRulesetNotFound, r.evs[2].Err)
+
+		// batches delegated to the evaluator are observed once per result.
+		var rb recorder
+		be := batchEvaluator{RulesetBuffer: newObservedBuffer(t)}
+		e = regula.NewEngine(&be, &rb)
+
+		_, err = e.EvalBatch(ctx, []string{"a", "b"}, regula.Params{"foo": "bar"}, regula.Params{"foo": "baz"})
+		require.NoError(t, err)
+		require.Equal(t, 1, be.calls)
+		require.Equal(t, r.evs[0].Params, rb.evs[0].Params)
+		require.Len(t, rb.evs, 4)
+		for i := range rb.evs {
+			require.Equal(t, r.evs[i].Path, rb.evs[i].Path)
+			require.Equal(t, r.evs[i].Rule, rb.evs[i].Rule)
+			require.Equal(t, r.evs[i].Err, rb.evs[i].Err)
+		}
+	})
+}
+
+func TestRulesetBufferObservers(t *testing.T) {
+	ctx := context.Background()
+	buf := newObservedBuffer(t)
+
+	var r recorder
+	// observers can use the buffer.
+	var latest string
+	buf.Observe(&r, regula.ObserverFunc(func(ctx context.Context, ev *regula.Evaluation) {
+		_, latest, _ = buf.Latest(ev.Path)
+	}))
+
+	_, err := buf.Eval(ctx, "a", regula.Params{"foo": "qux"})
+	require.NoError(t, err)
+	_, err = buf.EvalVersion(ctx, "a", "1", regula.Params{"foo": "bar"})
+	require.NoError(t, err)
+	_, err = buf.EvalVersion(ctx, "a", "3", regula.Params{"foo": "bar"})
+	require.Equal(t, regula.ErrRulesetNotFound, err)
+
+	require.Len(t, r.evs, 3)
+	require.Equal(t, "2", r.evs[0].Version)
+	require.Equal(t, intPtr(2), r.evs[0].Rule)
+	require.Equal(t, "1", r.evs[1].Version)
+	require.Equal(t, intPtr(0), r.evs[1].Rule)
+	require.Equal(t, "3", r.evs[2].Version)
+	require.Nil(t, r.evs[2].Rule)
+	require.Equal(t, "2", latest)
+}
+
+func TestRuleHits(t *testing.T) {
+	ctx := context.Background()
+	buf := newObservedBuffer(t)
+	hits := regula.NewRuleHits()
+	buf.Observe(hits)
+
+	for _, foo := range []string{"bar", "bar", "baz", "nope"} {
+		buf.Eval(ctx, "a", regula.Params{"foo": foo})
+	}
+	buf.EvalVersion(ctx, "a", "1", regula.Params{"foo": "qux"})
+
+	require.EqualValues(t, 2, hits.Hits("a", "2", 0))
+	require.EqualValues(t, 1, hits.Hits("a", "2", 1))
+	require.EqualValues(t, 0, hits.Hits("a", "2", 2))
+
+	require.Equal(t, []regula.RuleHit{
+		{Path: "a", Version: "1", Rule: 2, Hits: 1},
+		{Path: "a", Version: "2", Rule: 0, Hits: 2},
+		{Path: "a", Version: "2", Rule: 1, Hits: 1},
+	}, hits.All())
+
+	rs, version, err := buf.Latest("a")
+	require.NoError(t, err)
+	require.Equal(t, []int{2}, hits.Unmatched("a", version, rs))
+
+	hits.Reset()
+	require.Empty(t, hits.All())
+	require.Equal(t, []int{0, 1, 2}, hits.Unmatched("a", version, rs))
+}
+
+func TestRuleHitsUnknownRule(t *testing.T) {
+	ctx := context.Background()
+	hits := regula.NewRuleHits()
+	var r recorder
+	e := regula.NewEngine(&unknownRuleEvaluator{newObservedBuffer(t)}, hits, &r)
+
+	_, _, err := e.GetString(ctx, "a", regula.Params{"foo": "bar"})
+	require.NoError(t, err)
+
+	// the first rule isn't counted when the evaluator doesn't report it.
+	require.Len(t, r.evs, 1)
+	require.Nil(t, r.evs[0].Rule)
+	require.Empty(t, hits.All())
+	require.EqualValues(t, 0, hits.Hits("a", "2", 0))
+}
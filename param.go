@@ -100,3 +100,53 @@ func (p Params) EncodeValue(key string) (string, error) {
 		return "", errors.Errorf("type %t is not supported", t)
 	}
 }
+
+// OptionalParams wraps the given params so that reading one of the missing optional params
+// returns rule.ErrNoMatch, which makes the rule being evaluated not match instead of failing.
+func OptionalParams(params rule.Params, optional []string) rule.Params {
+	if len(optional) == 0 {
+		return params
+	}
+
+	return &optionalParams{Params: params, optional: optional}
+}
+
+type optionalParams struct {
+	rule.Params
+
+	optional []string
+}
+
+func (p *optionalParams) err(key string, err error) error {
+	if err != rule.ErrParamNotFound {
+		return err
+	}
+
+	for _, o := range p.optional {
+		if o == key {
+			return rule.ErrNoMatch
+		}
+	}
+
+	return err
+}
+
+func (p *optionalParams) GetString(key string) (string, error) {
+	v, err := p.Params.GetString(key)
+	return v, p.err(key, err)
+}
+
+func (p *optionalParams) GetBool(key string) (bool, error) {
+	v, err := p.Params.GetBool(key)
+	return v, p.err(key, err)
+}
+
+func (p *optionalParams) GetInt64(key string) (int64, error) {
+	v, err := p.Params.GetInt64(key)
+	return v, p.err(key, err)
+}
+
+func (p *optionalParams) GetFloat64(key string) (float64, error) {
+	v, err := p.Params.GetFloat64(key)
+	return v, p.err(key, err)
+}
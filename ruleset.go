@@ -8,9 +8,13 @@ import (
 )
 
 // A Ruleset is list of rules that must return the same type.
+// It can also carry a list of test cases describing its expected behaviour
+// and a schema declaring the params it expects.
 type Ruleset struct {
-	Rules []*rule.Rule `json:"rules"`
-	Type  string       `json:"type"`
+	Rules  []*rule.Rule `json:"rules"`
+	Type   string       `json:"type"`
+	Tests  []*TestCase  `json:"tests,omitempty"`
+	Schema []*ParamSpec `json:"schema,omitempty"`
 }
 
 // NewStringRuleset creates a ruleset which rules all return a string otherwise
@@ -53,15 +57,34 @@ func newRuleset(typ string, rules ...*rule.Rule) (*Ruleset, error) {
 
 // Eval evaluates every rule of the ruleset until one matches.
 // It returns rule.ErrNoMatch if no rule matches the given context.
+// If the ruleset has a schema, the params are checked first and a *ParamError is returned
+// if they don't satisfy it.
 func (r *Ruleset) Eval(params rule.Params) (*rule.Value, error) {
-	for _, rl := range r.Rules {
+	v, _, err := r.EvalRule(params)
+	return v, err
+}
+
+// EvalRule evaluates the ruleset like Eval and also returns the index of the rule that matched,
+// or -1 if none did.
+func (r *Ruleset) EvalRule(params rule.Params) (*rule.Value, int, error) {
+	params, err := r.checkParams(params)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	for i, rl := range r.Rules {
 		res, err := rl.Eval(params)
-		if err != rule.ErrNoMatch {
-			return res, err
+		if err == rule.ErrNoMatch {
+			continue
 		}
+		if err != nil {
+			return nil, -1, err
+		}
+
+		return res, i, nil
 	}
 
-	return nil, rule.ErrNoMatch
+	return nil, -1, rule.ErrNoMatch
 }
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
@@ -75,6 +98,21 @@ func (r *Ruleset) UnmarshalJSON(data []byte) error {
 		return errors.New("unsupported ruleset type")
 	}
 
+	params := r.Params()
+	for _, spec := range r.Schema {
+		if spec != nil {
+			params = append(params, rule.Param{Name: spec.Name, Type: spec.Type})
+		}
+	}
+
+	for _, tc := range r.Tests {
+		if tc == nil {
+			return errors.New("invalid empty test case")
+		}
+
+		tc.coerce(params)
+	}
+
 	return r.validate()
 }
 
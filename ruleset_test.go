@@ -19,6 +19,11 @@ func TestRulesetEval(t *testing.T) {
 		res, err := r.Eval(nil)
 		require.NoError(t, err)
 		require.Equal(t, "second", res.Data)
+
+		res, i, err := r.EvalRule(nil)
+		require.NoError(t, err)
+		require.Equal(t, "second", res.Data)
+		require.Equal(t, 1, i)
 	})
 
 	t.Run("Match bool", func(t *testing.T) {
@@ -50,6 +55,10 @@ func TestRulesetEval(t *testing.T) {
 
 		_, err = r.Eval(nil)
 		require.Equal(t, rule.ErrNoMatch, err)
+
+		_, i, err := r.EvalRule(nil)
+		require.Equal(t, rule.ErrNoMatch, err)
+		require.Equal(t, -1, i)
 	})
 
 	t.Run("Default", func(t *testing.T) {
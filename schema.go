@@ -0,0 +1,171 @@
+package regula
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/heetch/regula/rule"
+)
+
+// ParamSpec declares a parameter of a ruleset, its type and the constraints
+// the values sent during evaluation must satisfy.
+type ParamSpec struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	// Enum lists the allowed values of a string param.
+	Enum []string `json:"enum,omitempty"`
+	// Min and Max are the inclusive bounds of an int64 or float64 param.
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+	// Optional params may be omitted, rules reading a missing optional param don't match.
+	Optional bool `json:"optional,omitempty"`
+}
+
+// ParamError is returned when a param doesn't satisfy the schema of a ruleset.
+type ParamError struct {
+	Name   string
+	Reason string
+}
+
+func (p *ParamError) Error() string {
+	return fmt.Sprintf("invalid param '%s': %s", p.Name, p.Reason)
+}
+
+// ValidateSchema makes sure the schema of the ruleset is well formed and that
+// all the params used by the rules are declared with the right type.
+// Rulesets without schema are always valid.
+func (r *Ruleset) ValidateSchema() error {
+	if len(r.Schema) == 0 {
+		return nil
+	}
+
+	specs := make(map[string]*ParamSpec, len(r.Schema))
+	for _, spec := range r.Schema {
+		if spec == nil || spec.Name == "" {
+			return &ParamError{Reason: "missing param name"}
+		}
+
+		if _, ok := specs[spec.Name]; ok {
+			return &ParamError{Name: spec.Name, Reason: "declared more than once"}
+		}
+		specs[spec.Name] = spec
+
+		if spec.Type != "string" && spec.Type != "bool" && spec.Type != "int64" && spec.Type != "float64" {
+			return &ParamError{Name: spec.Name, Reason: fmt.Sprintf("unsupported type '%s'", spec.Type)}
+		}
+
+		if len(spec.Enum) > 0 && spec.Type != "string" {
+			return &ParamError{Name: spec.Name, Reason: "enum is only supported by string params"}
+		}
+
+		if (spec.Min != nil || spec.Max != nil) && spec.Type != "int64" && spec.Type != "float64" {
+			return &ParamError{Name: spec.Name, Reason: "min and max are only supported by numeric params"}
+		}
+
+		if spec.Min != nil && spec.Max != nil && *spec.Min > *spec.Max {
+			return &ParamError{Name: spec.Name, Reason: "min is greater than max"}
+		}
+	}
+
+	for _, p := range r.Params() {
+		spec, ok := specs[p.Name]
+		if !ok {
+			return &ParamError{Name: p.Name, Reason: "not declared in the schema"}
+		}
+
+		if spec.Type != p.Type {
+			return &ParamError{Name: p.Name, Reason: fmt.Sprintf("declared as %s but used as %s", spec.Type, p.Type)}
+		}
+	}
+
+	return nil
+}
+
+// checkParams makes sure the given params satisfy the schema. It returns the params to use for the evaluation
+// of the rules, in which missing optional params make the rules not match.
+func (r *Ruleset) checkParams(params rule.Params) (rule.Params, error) {
+	if len(r.Schema) == 0 {
+		return params, nil
+	}
+
+	if params == nil {
+		params = Params{}
+	}
+
+	keys := make(map[string]bool)
+	for _, k := range params.Keys() {
+		keys[k] = true
+	}
+
+	var optional []string
+	for _, spec := range r.Schema {
+		if !keys[spec.Name] {
+			if !spec.Optional {
+				return nil, &ParamError{Name: spec.Name, Reason: "missing required param"}
+			}
+
+			optional = append(optional, spec.Name)
+			continue
+		}
+
+		err := spec.check(params)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return OptionalParams(params, optional), nil
+}
+
+func (spec *ParamSpec) check(params rule.Params) error {
+	var (
+		err error
+		num float64
+	)
+
+	switch spec.Type {
+	case "string":
+		var s string
+		s, err = params.GetString(spec.Name)
+		if err == nil && len(spec.Enum) > 0 && !inEnum(spec.Enum, s) {
+			return &ParamError{Name: spec.Name, Reason: fmt.Sprintf("'%s' is not one of the allowed values", s)}
+		}
+	case "bool":
+		_, err = params.GetBool(spec.Name)
+	case "int64":
+		var i int64
+		i, err = params.GetInt64(spec.Name)
+		num = float64(i)
+	case "float64":
+		num, err = params.GetFloat64(spec.Name)
+	}
+
+	if err != nil {
+		if err == rule.ErrParamTypeMismatch {
+			return &ParamError{Name: spec.Name, Reason: fmt.Sprintf("must be of type %s", spec.Type)}
+		}
+
+		return err
+	}
+
+	if spec.Min != nil && num < *spec.Min {
+		return &ParamError{Name: spec.Name, Reason: "must be greater than or equal to " + strconv.FormatFloat(*spec.Min, 'f', -1, 64)}
+	}
+
+	if spec.Max != nil && num > *spec.Max {
+		return &ParamError{Name: spec.Name, Reason: "must be less than or equal to " + strconv.FormatFloat(*spec.Max, 'f', -1, 64)}
+	}
+
+	return nil
+}
+
+func inEnum(enum []string, s string) bool {
+	for i := range enum {
+		if enum[i] == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,108 @@
+package regula_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/heetch/regula"
+	"github.com/heetch/regula/rule"
+	"github.com/stretchr/testify/require"
+)
+
+func float(f float64) *float64 {
+	return &f
+}
+
+func TestValidateSchema(t *testing.T) {
+	rs, err := regula.NewStringRuleset(
+		rule.New(rule.Eq(rule.StringParam("city"), rule.StringValue("paris")), rule.StringValue("a")),
+		rule.New(rule.GT(rule.Int64Param("age"), rule.Int64Value(18)), rule.StringValue("b")),
+	)
+	require.NoError(t, err)
+
+	t.Run("NoSchema", func(t *testing.T) {
+		require.NoError(t, rs.ValidateSchema())
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		rs.Schema = []*regula.ParamSpec{
+			{Name: "city", Type: "string", Description: "city of the user", Enum: []string{"paris", "milan"}},
+			{Name: "age", Type: "int64", Min: float(0), Max: float(150)},
+			{Name: "vip", Type: "bool", Optional: true},
+		}
+		require.NoError(t, rs.ValidateSchema())
+	})
+
+	t.Run("NOK", func(t *testing.T) {
+		schemas := [][]*regula.ParamSpec{
+			{{Name: "city", Type: "string"}},
+			{{Name: "city", Type: "string"}, {Name: "age", Type: "float64"}},
+			{{Name: "city", Type: "string"}, {Name: "city", Type: "string"}, {Name: "age", Type: "int64"}},
+			{{Name: "city", Type: "string"}, {Name: "age", Type: "int64"}, {Name: "other", Type: "duration"}},
+			{{Name: "city", Type: "string", Min: float(1)}, {Name: "age", Type: "int64"}},
+			{{Name: "city", Type: "string"}, {Name: "age", Type: "int64", Enum: []string{"1"}}},
+			{{Name: "city", Type: "string"}, {Name: "age", Type: "int64", Min: float(10), Max: float(1)}},
+			{{Name: "city", Type: "string"}, {Name: "age", Type: "int64"}, nil},
+		}
+
+		for _, schema := range schemas {
+			rs.Schema = schema
+			err := rs.ValidateSchema()
+			require.IsType(t, new(regula.ParamError), err)
+		}
+	})
+}
+
+func TestSchemaEval(t *testing.T) {
+	rs, err := regula.NewStringRuleset(
+		rule.New(rule.BoolParam("vip"), rule.StringValue("vip")),
+		rule.New(rule.Eq(rule.StringParam("city"), rule.StringValue("paris")), rule.StringValue("a")),
+		rule.New(rule.GT(rule.Int64Param("age"), rule.Int64Value(18)), rule.StringValue("b")),
+	)
+	require.NoError(t, err)
+	rs.Schema = []*regula.ParamSpec{
+		{Name: "city", Type: "string", Enum: []string{"paris", "milan"}},
+		{Name: "age", Type: "int64", Min: float(0), Max: float(150)},
+		{Name: "vip", Type: "bool", Optional: true},
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		v, err := rs.Eval(regula.Params{"city": "milan", "age": int64(20)})
+		require.NoError(t, err)
+		require.Equal(t, rule.StringValue("b"), v)
+
+		v, err = rs.Eval(regula.Params{"city": "milan", "age": int64(20), "vip": true})
+		require.NoError(t, err)
+		require.Equal(t, rule.StringValue("vip"), v)
+	})
+
+	t.Run("NOK", func(t *testing.T) {
+		tests := []struct {
+			params regula.Params
+			name   string
+		}{
+			{regula.Params{"age": int64(20)}, "city"},
+			{regula.Params{"city": "rome", "age": int64(20)}, "city"},
+			{regula.Params{"city": "paris", "age": int64(-1)}, "age"},
+			{regula.Params{"city": "paris", "age": int64(151)}, "age"},
+			{regula.Params{"city": "paris", "age": "20"}, "age"},
+			{nil, "city"},
+		}
+
+		for _, test := range tests {
+			_, err := rs.Eval(test.params)
+			require.IsType(t, new(regula.ParamError), err)
+			require.Equal(t, test.name, err.(*regula.ParamError).Name)
+		}
+	})
+
+	t.Run("EncDec", func(t *testing.T) {
+		raw, err := json.Marshal(rs)
+		require.NoError(t, err)
+
+		var res regula.Ruleset
+		err = json.Unmarshal(raw, &res)
+		require.NoError(t, err)
+		require.Equal(t, rs, &res)
+	})
+}
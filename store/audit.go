@@ -0,0 +1,77 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Audit describes who made a change and why.
+type Audit struct {
+	Author  string
+	Message string
+}
+
+type auditKey struct{}
+
+// WithAudit returns a copy of ctx carrying the given audit information.
+// It is recorded by the RulesetService methods changing a ruleset.
+func WithAudit(ctx context.Context, a Audit) context.Context {
+	return context.WithValue(ctx, auditKey{}, a)
+}
+
+// AuditFromContext returns the audit information carried by ctx, if any.
+func AuditFromContext(ctx context.Context) Audit {
+	a, _ := ctx.Value(auditKey{}).(Audit)
+	return a
+}
+
+// List of actions recorded in the history of the rulesets.
+const (
+	// AuditPut records the creation of a version.
+	AuditPut = "put"
+	// AuditRollback records a rollback to a previous version.
+	AuditRollback = "rollback"
+	// AuditMigrateSignature records a signature migration.
+	AuditMigrateSignature = "migrate-signature"
+	// AuditDelete records a soft delete.
+	AuditDelete = "delete"
+	// AuditHardDelete records a hard delete.
+	AuditHardDelete = "hard-delete"
+)
+
+// AuditEntry records a change made to a ruleset.
+type AuditEntry struct {
+	Path string
+	// Action is the kind of change, e.g. AuditPut.
+	Action string
+	// Version is the latest version after the change, empty if there is none.
+	Version string
+	// PreviousVersion is the version that was the latest one before the change, empty if there was none.
+	PreviousVersion string
+	Author          string
+	Message         string
+	Time            time.Time
+}
+
+// NewAuditEntry returns the entry recording the given action, which changed the latest version of the ruleset
+// from previous to version, using the audit information carried by ctx.
+func NewAuditEntry(ctx context.Context, action, path, version, previous string) AuditEntry {
+	a := AuditFromContext(ctx)
+
+	return AuditEntry{
+		Path:            path,
+		Action:          action,
+		Version:         version,
+		PreviousVersion: previous,
+		Author:          a.Author,
+		Message:         a.Message,
+		Time:            time.Now().UTC(),
+	}
+}
+
+// AuditEntries holds the history of the changes made to a ruleset, newest first.
+type AuditEntries struct {
+	Path     string
+	Entries  []AuditEntry
+	Continue string // token of the next page, if any
+}
@@ -0,0 +1,1106 @@
+// Package bolt provides an implementation of store.RulesetService backed by a bbolt database.
+// All the data is kept in a single file which makes it suitable for single node deployments.
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/heetch/regula"
+	"github.com/heetch/regula/rule"
+	"github.com/heetch/regula/store"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/segmentio/ksuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Buckets used to store the data.
+// Rulesets entries are stored by path and version, separated by a null byte,
+// so that the versions of a path are sorted and don't mix with the ones of its sub paths.
+var (
+	entriesBucket           = []byte("entries")
+	deletedBucket           = []byte("deleted")
+	latestBucket            = []byte("latest")
+	checksumsBucket         = []byte("checksums")
+	signaturesBucket        = []byte("signatures")
+	signaturesHistoryBucket = []byte("signatures-history")
+	eventsBucket            = []byte("events")
+	auditBucket             = []byte("audit")
+	metaBucket              = []byte("meta")
+
+	revisionKey  = []byte("revision")
+	compactedKey = []byte("compacted")
+)
+
+// RulesetService manages the rulesets stored in a bbolt database.
+// The events of the last EventsRetention revisions are kept in the database for the watchers,
+// the older ones are deleted.
+type RulesetService struct {
+	// EventsRetention is the number of revisions whose events are kept for the watchers.
+	// Defaults to store.DefaultEventsRetention.
+	EventsRetention int64
+
+	db     *bolt.DB
+	logger zerolog.Logger
+
+	mu sync.Mutex
+	// closed and replaced after every write to wake up the watchers.
+	changed chan struct{}
+}
+
+// NewRulesetService opens or creates the database stored in the given file.
+// The database is locked until the service is closed.
+func NewRulesetService(filename string, logger zerolog.Logger) (*RulesetService, error) {
+	db, err := bolt.Open(filename, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open database %s", filename)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, b := range [][]byte{entriesBucket, deletedBucket, latestBucket, checksumsBucket, signaturesBucket, signaturesHistoryBucket, eventsBucket, auditBucket, metaBucket} {
+			_, err := tx.CreateBucketIfNotExists(b)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "failed to create buckets")
+	}
+
+	return &RulesetService{
+		EventsRetention: store.DefaultEventsRetention,
+		db:              db,
+		logger:          logger,
+		changed:         make(chan struct{}),
+	}, nil
+}
+
+// Close the database.
+func (s *RulesetService) Close() error {
+	return s.db.Close()
+}
+
+// List returns the rulesets entries under the given prefix.
+// If opt.LatestOnly is true, only the latest version of each ruleset is returned
+// by walking the latest bucket instead of the entries one.
+func (s *RulesetService) List(ctx context.Context, prefix string, opt store.ListOptions) (*store.RulesetEntries, error) {
+	limit := opt.Limit
+	if limit != 0 {
+		limit = store.NormalizeLimit(limit)
+	}
+
+	var lastKey []byte
+	if opt.ContinueToken != "" {
+		k, err := base64.URLEncoding.DecodeString(opt.ContinueToken)
+		if err != nil {
+			return nil, store.ErrInvalidContinueToken
+		}
+
+		lastKey = k
+	}
+
+	bucket := entriesBucket
+	if opt.LatestOnly {
+		bucket = latestBucket
+	}
+
+	var entries store.RulesetEntries
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		entries.Revision = strconv.FormatUint(getRevision(tx), 10)
+
+		c := tx.Bucket(bucket).Cursor()
+
+		k, v := c.Seek([]byte(prefix))
+		if lastKey != nil {
+			k, v = c.Seek(lastKey)
+			if bytes.Equal(k, lastKey) {
+				k, v = c.Next()
+			}
+		}
+
+		for ; k != nil && bytes.HasPrefix(k, []byte(prefix)); k, v = c.Next() {
+			if limit > 0 && len(entries.Entries) == limit {
+				entries.Continue = base64.URLEncoding.EncodeToString(lastKey)
+				break
+			}
+
+			if opt.LatestOnly {
+				// the latest bucket maps paths to versions.
+				v = tx.Bucket(entriesBucket).Get(key(string(k), string(v)))
+			}
+
+			var entry store.RulesetEntry
+			err := json.Unmarshal(v, &entry)
+			if err != nil {
+				s.logger.Debug().Err(err).Bytes("entry", k).Msg("list: unmarshalling failed")
+				return errors.Wrap(err, "failed to unmarshal entry")
+			}
+
+			entries.Entries = append(entries.Entries, entry)
+			lastKey = append(lastKey[:0], k...)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// if a prefix is provided it must always return results
+	// otherwise it doesn't exist.
+	if len(entries.Entries) == 0 && prefix != "" {
+		return nil, store.ErrNotFound
+	}
+
+	return &entries, nil
+}
+
+// Latest returns the latest version of the ruleset entry which corresponds to the given path.
+// It returns store.ErrNotFound if the path doesn't exist.
+func (s *RulesetService) Latest(ctx context.Context, path string) (*store.RulesetEntry, error) {
+	var entry *store.RulesetEntry
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		version := tx.Bucket(latestBucket).Get([]byte(path))
+		if version == nil {
+			return store.ErrNotFound
+		}
+
+		var err error
+		entry, err = s.entry(tx, path, string(version))
+		return err
+	})
+
+	return entry, err
+}
+
+// OneByVersion returns the ruleset entry which corresponds to the given path at the given version.
+// It returns store.ErrNotFound if the path or the version doesn't exist.
+func (s *RulesetService) OneByVersion(ctx context.Context, path, version string) (*store.RulesetEntry, error) {
+	var entry *store.RulesetEntry
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		entry, err = s.entry(tx, path, version)
+		return err
+	})
+
+	return entry, err
+}
+
+func (s *RulesetService) entry(tx *bolt.Tx, path, version string) (*store.RulesetEntry, error) {
+	v := tx.Bucket(entriesBucket).Get(key(path, version))
+	if v == nil {
+		return nil, store.ErrNotFound
+	}
+
+	var entry store.RulesetEntry
+	err := json.Unmarshal(v, &entry)
+	if err != nil {
+		s.logger.Debug().Err(err).Str("path", path).Str("version", version).Msg("entry: unmarshalling failed")
+		return nil, errors.Wrap(err, "failed to unmarshal entry")
+	}
+
+	return &entry, nil
+}
+
+// Versions returns the versions of the ruleset stored on the given path, newest first.
+// It returns store.ErrNotFound if the path doesn't exist.
+func (s *RulesetService) Versions(ctx context.Context, path string, limit int, continueToken string) (*store.RulesetVersions, error) {
+	limit = store.NormalizeLimit(limit)
+
+	// sequence numbers are zero padded, iterating backwards returns the newest first.
+	start := key(path, "\xff")
+	if continueToken != "" {
+		lastSeq, err := base64.URLEncoding.DecodeString(continueToken)
+		if err != nil {
+			return nil, store.ErrInvalidContinueToken
+		}
+
+		start = key(path, string(lastSeq))
+	}
+
+	versions := store.RulesetVersions{
+		Path: path,
+	}
+
+	prefix := key(path, "")
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(entriesBucket).Cursor()
+
+		k, _ := c.Seek(start)
+		if k == nil {
+			k, _ = c.Last()
+		}
+		// Seek positions the cursor on the first key greater than or equal to start.
+		for k != nil && bytes.Compare(k, start) >= 0 {
+			k, _ = c.Prev()
+		}
+
+		for ; k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Prev() {
+			if len(versions.Versions) == limit {
+				last := versions.Versions[limit-1].Version
+				versions.Continue = base64.URLEncoding.EncodeToString([]byte(last))
+				break
+			}
+
+			version := string(k[len(prefix):])
+			id, err := ksuid.Parse(version)
+			if err != nil {
+				return errors.Wrapf(err, "failed to parse version: %s", version)
+			}
+
+			versions.Versions = append(versions.Versions, store.RulesetVersion{
+				Version:   version,
+				CreatedAt: id.Time(),
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(versions.Versions) == 0 && continueToken == "" {
+		return nil, store.ErrNotFound
+	}
+
+	return &versions, nil
+}
+
+// History returns the changes made to the ruleset stored on the given path, newest first.
+// The entries are stored by path and by a sequence number of the audit bucket. The history survives deletes.
+// It returns store.ErrNotFound if nothing was recorded for the path.
+func (s *RulesetService) History(ctx context.Context, path string, limit int, continueToken string) (*store.AuditEntries, error) {
+	limit = store.NormalizeLimit(limit)
+
+	// sequence numbers are zero padded, iterating backwards returns the newest first.
+	start := key(path, "\xff")
+	if continueToken != "" {
+		lastSeq, err := base64.URLEncoding.DecodeString(continueToken)
+		if err != nil {
+			return nil, store.ErrInvalidContinueToken
+		}
+
+		start = key(path, string(lastSeq))
+	}
+
+	history := store.AuditEntries{
+		Path: path,
+	}
+
+	prefix := key(path, "")
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(auditBucket).Cursor()
+
+		k, v := c.Seek(start)
+		if k == nil {
+			k, v = c.Last()
+		}
+		// Seek positions the cursor on the first key greater than or equal to start.
+		for k != nil && bytes.Compare(k, start) >= 0 {
+			k, v = c.Prev()
+		}
+
+		var last []byte
+		for ; k != nil && bytes.HasPrefix(k, prefix); k, v = c.Prev() {
+			if len(history.Entries) == limit {
+				history.Continue = base64.URLEncoding.EncodeToString(last[len(prefix):])
+				break
+			}
+			last = k
+
+			var entry store.AuditEntry
+			err := json.Unmarshal(v, &entry)
+			if err != nil {
+				return errors.Wrapf(err, "failed to decode audit entry: %s", k)
+			}
+
+			history.Entries = append(history.Entries, entry)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(history.Entries) == 0 && continueToken == "" {
+		return nil, store.ErrNotFound
+	}
+
+	return &history, nil
+}
+
+// Put adds a version of the given ruleset using a ksuid, in a single transaction.
+// The ruleset is validated like in the etcd implementation and a store.ValidationError
+// is returned if it is invalid. If the ruleset didn't change, the latest entry is returned
+// with store.ErrNotModified.
+func (s *RulesetService) Put(ctx context.Context, path string, ruleset *regula.Ruleset, opts ...store.PutOption) (*store.RulesetEntry, error) {
+	sig, err := store.ValidateRuleset(path, ruleset)
+	if err != nil {
+		return nil, err
+	}
+
+	cs, err := checksum(ruleset)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := store.NewPutOptions(opts...).ExpectedVersion
+
+	var entry *store.RulesetEntry
+
+	err = s.update(func(tx *bolt.Tx) error {
+		var err error
+		entry, err = s.put(ctx, tx, path, ruleset, sig, cs, expected)
+		if err != nil {
+			return err
+		}
+
+		return s.notify(tx, store.RulesetEvent{
+			Type:    store.RulesetPutEvent,
+			Path:    path,
+			Version: entry.Version,
+			Ruleset: ruleset,
+		})
+	})
+	if err != nil {
+		if err == store.ErrNotModified || err == store.ErrVersionMismatch {
+			return entry, err
+		}
+
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// PutBatch stores a version of every given ruleset in a single transaction, producing a single revision.
+// If one of the rulesets is invalid, a store.ValidationError is returned and nothing is stored.
+// Unchanged rulesets are left untouched.
+func (s *RulesetService) PutBatch(ctx context.Context, entries []store.RulesetEntry) ([]store.RulesetEntry, error) {
+	sigs, err := store.ValidateBatch(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	css := make([][]byte, len(entries))
+	for i, e := range entries {
+		css[i], err = checksum(e.Ruleset)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	res := make([]store.RulesetEntry, len(entries))
+
+	err = s.update(func(tx *bolt.Tx) error {
+		var events []store.RulesetEvent
+
+		for i, e := range entries {
+			entry, err := s.put(ctx, tx, e.Path, e.Ruleset, sigs[i], css[i], "")
+			if err != nil && err != store.ErrNotModified {
+				return err
+			}
+			res[i] = *entry
+
+			if err == nil {
+				events = append(events, store.RulesetEvent{
+					Type:    store.RulesetPutEvent,
+					Path:    e.Path,
+					Version: entry.Version,
+					Ruleset: entry.Ruleset,
+				})
+			}
+		}
+
+		if len(events) == 0 {
+			return store.ErrNotModified
+		}
+
+		return s.notify(tx, events...)
+	})
+	if err != nil {
+		if err == store.ErrNotModified {
+			return res, err
+		}
+
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// put creates a new version of the ruleset within the given transaction.
+// If expected is not empty, it must be the latest version of the ruleset.
+// If the ruleset didn't change, the latest entry is returned with store.ErrNotModified.
+func (s *RulesetService) put(ctx context.Context, tx *bolt.Tx, path string, ruleset *regula.Ruleset, sig *store.Signature, cs []byte, expected string) (*store.RulesetEntry, error) {
+	latest := tx.Bucket(latestBucket).Get([]byte(path))
+
+	// make sure the ruleset didn't change since the caller read it
+	if expected != "" && expected != string(latest) {
+		if latest == nil {
+			return nil, store.ErrVersionMismatch
+		}
+
+		entry, err := s.entry(tx, path, string(latest))
+		if err != nil {
+			return nil, err
+		}
+
+		return entry, store.ErrVersionMismatch
+	}
+
+	// if nothing changed return latest ruleset
+	if latest != nil && bytes.Equal(tx.Bucket(checksumsBucket).Get([]byte(path)), cs) {
+		entry, err := s.entry(tx, path, string(latest))
+		if err != nil {
+			return nil, err
+		}
+
+		return entry, store.ErrNotModified
+	}
+
+	// make sure signature didn't change
+	cur, err := s.signature(tx, path)
+	if err != nil && err != store.ErrNotFound {
+		return nil, err
+	}
+	if cur != nil {
+		err = cur.MatchWith(sig)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		err = putJSON(tx.Bucket(signaturesBucket), []byte(path), sig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	k, err := ksuid.NewRandom()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate ruleset version")
+	}
+
+	entry := store.RulesetEntry{
+		Path:    path,
+		Version: k.String(),
+		Ruleset: ruleset,
+	}
+
+	err = putJSON(tx.Bucket(entriesBucket), key(path, entry.Version), &entry)
+	if err != nil {
+		return nil, err
+	}
+
+	err = audit(tx, store.NewAuditEntry(ctx, store.AuditPut, path, entry.Version, string(latest)))
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Bucket(latestBucket).Put([]byte(path), []byte(entry.Version))
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Bucket(checksumsBucket).Put([]byte(path), cs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// Rollback makes the given version the latest version of the ruleset stored on the given path.
+// It returns store.ErrNotFound if the version doesn't exist and store.ErrNotModified if it is already the latest.
+func (s *RulesetService) Rollback(ctx context.Context, path, version string) (*store.RulesetEntry, error) {
+	var entry *store.RulesetEntry
+
+	err := s.update(func(tx *bolt.Tx) error {
+		var err error
+		entry, err = s.entry(tx, path, version)
+		if err != nil {
+			return err
+		}
+
+		latest := string(tx.Bucket(latestBucket).Get([]byte(path)))
+		if latest == version {
+			return store.ErrNotModified
+		}
+
+		cs, err := checksum(entry.Ruleset)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Bucket(latestBucket).Put([]byte(path), []byte(version))
+		if err != nil {
+			return err
+		}
+
+		err = tx.Bucket(checksumsBucket).Put([]byte(path), cs)
+		if err != nil {
+			return err
+		}
+
+		err = audit(tx, store.NewAuditEntry(ctx, store.AuditRollback, path, version, latest))
+		if err != nil {
+			return err
+		}
+
+		return s.notify(tx, store.RulesetEvent{
+			Type:    store.RulesetPutEvent,
+			Path:    path,
+			Version: version,
+			Ruleset: entry.Ruleset,
+		})
+	})
+	if err != nil {
+		if err == store.ErrNotModified {
+			return entry, err
+		}
+
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// Signatures returns the history of the signatures of the ruleset stored on the given path, the current one last.
+// It returns store.ErrNotFound if the path has no signature.
+func (s *RulesetService) Signatures(ctx context.Context, path string) ([]store.Signature, error) {
+	var sigs []store.Signature
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		sig, err := s.signature(tx, path)
+		if err != nil {
+			return err
+		}
+
+		// the history is only created by the first migration
+		v := tx.Bucket(signaturesHistoryBucket).Get([]byte(path))
+		if v == nil {
+			sigs = []store.Signature{*sig}
+			return nil
+		}
+
+		err = json.Unmarshal(v, &sigs)
+		if err != nil {
+			s.logger.Debug().Err(err).Str("path", path).Msg("signatures: unmarshalling failed")
+			return errors.Wrap(err, "failed to unmarshal signatures history")
+		}
+
+		return nil
+	})
+
+	return sigs, err
+}
+
+func (s *RulesetService) signature(tx *bolt.Tx, path string) (*store.Signature, error) {
+	v := tx.Bucket(signaturesBucket).Get([]byte(path))
+	if v == nil {
+		return nil, store.ErrNotFound
+	}
+
+	var sig store.Signature
+	err := json.Unmarshal(v, &sig)
+	if err != nil {
+		s.logger.Debug().Err(err).Str("path", path).Msg("signature: unmarshalling failed")
+		return nil, errors.Wrap(err, "failed to unmarshal signature")
+	}
+
+	return &sig, nil
+}
+
+// MigrateSignature applies the given migration to the signature of the ruleset stored on the given path
+// and records the new signature in the history.
+// All the existing versions of the ruleset must remain evaluable with the new signature, otherwise a store.ValidationError is returned.
+// It returns store.ErrNotFound if the path has no signature.
+func (s *RulesetService) MigrateSignature(ctx context.Context, path string, m *store.SignatureMigration) (*store.Signature, error) {
+	params := make([]rule.Param, 0, len(m.AddParams))
+	for name, tp := range m.AddParams {
+		params = append(params, rule.Param{Name: name, Type: tp})
+	}
+	err := store.ValidateParamNames(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var sig *store.Signature
+
+	err = s.update(func(tx *bolt.Tx) error {
+		cur, err := s.signature(tx, path)
+		if err != nil {
+			return err
+		}
+
+		sig, err = cur.Migrate(m)
+		if err != nil {
+			return err
+		}
+
+		prefix := key(path, "")
+		c := tx.Bucket(entriesBucket).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var entry store.RulesetEntry
+			err = json.Unmarshal(v, &entry)
+			if err != nil {
+				return errors.Wrap(err, "failed to unmarshal entry")
+			}
+
+			err = sig.Evaluable(entry.Ruleset)
+			if err != nil {
+				return &store.ValidationError{
+					Field:  "version",
+					Value:  entry.Version,
+					Reason: err.Error(),
+				}
+			}
+		}
+
+		var history []store.Signature
+		if v := tx.Bucket(signaturesHistoryBucket).Get([]byte(path)); v != nil {
+			err = json.Unmarshal(v, &history)
+			if err != nil {
+				return errors.Wrap(err, "failed to unmarshal signatures history")
+			}
+		} else {
+			history = append(history, *cur)
+		}
+		history = append(history, *sig)
+
+		err = putJSON(tx.Bucket(signaturesHistoryBucket), []byte(path), history)
+		if err != nil {
+			return err
+		}
+
+		latest := string(tx.Bucket(latestBucket).Get([]byte(path)))
+		err = audit(tx, store.NewAuditEntry(ctx, store.AuditMigrateSignature, path, latest, latest))
+		if err != nil {
+			return err
+		}
+
+		return putJSON(tx.Bucket(signaturesBucket), []byte(path), sig)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sig, nil
+}
+
+// Delete removes the ruleset stored on the given path.
+// A soft delete keeps the versions aside and preserves the signature, a hard delete removes everything but the history.
+// Every removed version generates a delete event. It returns store.ErrNotFound if the path doesn't exist.
+func (s *RulesetService) Delete(ctx context.Context, path string, hard bool) error {
+	return s.update(func(tx *bolt.Tx) error {
+		prefix := key(path, "")
+		latest := tx.Bucket(latestBucket).Get([]byte(path))
+		// the value is only valid until the latest version is removed.
+		previous := string(latest)
+
+		if latest == nil {
+			if !hard {
+				return store.ErrNotFound
+			}
+
+			k, _ := tx.Bucket(deletedBucket).Cursor().Seek(prefix)
+			if k == nil || !bytes.HasPrefix(k, prefix) {
+				return store.ErrNotFound
+			}
+		}
+
+		var events []store.RulesetEvent
+
+		entries := tx.Bucket(entriesBucket)
+		deleted := tx.Bucket(deletedBucket)
+
+		// keys are collected first because a bucket can't be modified while iterating over it.
+		var keys [][]byte
+		c := entries.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			keys = append(keys, append([]byte(nil), k...))
+		}
+
+		for _, k := range keys {
+			if !hard {
+				err := deleted.Put(k, entries.Get(k))
+				if err != nil {
+					return err
+				}
+			}
+
+			err := entries.Delete(k)
+			if err != nil {
+				return err
+			}
+
+			events = append(events, store.RulesetEvent{
+				Type:    store.RulesetDeleteEvent,
+				Path:    path,
+				Version: string(k[len(prefix):]),
+			})
+		}
+
+		action := store.AuditDelete
+		if hard {
+			action = store.AuditHardDelete
+
+			keys = keys[:0]
+			c := deleted.Cursor()
+			for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+				keys = append(keys, append([]byte(nil), k...))
+			}
+
+			for _, k := range keys {
+				err := deleted.Delete(k)
+				if err != nil {
+					return err
+				}
+			}
+
+			for _, b := range [][]byte{signaturesBucket, signaturesHistoryBucket} {
+				err := tx.Bucket(b).Delete([]byte(path))
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, b := range [][]byte{latestBucket, checksumsBucket} {
+			err := tx.Bucket(b).Delete([]byte(path))
+			if err != nil {
+				return err
+			}
+		}
+
+		err := audit(tx, store.NewAuditEntry(ctx, action, path, "", previous))
+		if err != nil {
+			return err
+		}
+
+		return s.notify(tx, events...)
+	})
+}
+
+// DeleteVersions removes the given versions of the ruleset stored on the given path.
+// Every removed version generates a delete event. It returns store.ErrNotFound if the path doesn't exist
+// and store.ErrLatestVersion, without removing anything, if one of the versions is the latest one.
+func (s *RulesetService) DeleteVersions(ctx context.Context, path string, versions ...string) error {
+	return s.update(func(tx *bolt.Tx) error {
+		latest := tx.Bucket(latestBucket).Get([]byte(path))
+		if latest == nil {
+			return store.ErrNotFound
+		}
+
+		for _, v := range versions {
+			if v == string(latest) {
+				return store.ErrLatestVersion
+			}
+		}
+
+		var events []store.RulesetEvent
+
+		entries := tx.Bucket(entriesBucket)
+		for _, v := range versions {
+			k := key(path, v)
+			if entries.Get(k) == nil {
+				continue
+			}
+
+			err := entries.Delete(k)
+			if err != nil {
+				return err
+			}
+
+			events = append(events, store.RulesetEvent{
+				Type:    store.RulesetDeleteEvent,
+				Path:    path,
+				Version: v,
+			})
+		}
+
+		if len(events) == 0 {
+			return nil
+		}
+
+		return s.notify(tx, events...)
+	})
+}
+
+// Watch the given prefix for anything new.
+// If revision is empty, it waits for the changes occurring after the call, otherwise it returns
+// the changes that occurred after the given revision, waiting for them if necessary.
+// It returns store.ErrRevisionCompacted if the events following the revision were deleted
+// or if the revision is unknown.
+func (s *RulesetService) Watch(ctx context.Context, prefix string, revision string) (*store.RulesetEvents, error) {
+	rev, _ := strconv.ParseUint(revision, 10, 64)
+	if rev == 0 {
+		err := s.db.View(func(tx *bolt.Tx) error {
+			rev = getRevision(tx)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		s.mu.Lock()
+		changed := s.changed
+		s.mu.Unlock()
+
+		var events store.RulesetEvents
+
+		err := s.db.View(func(tx *bolt.Tx) error {
+			current := getRevision(tx)
+			if rev < getUint64(tx, compactedKey) || rev > current {
+				return store.ErrRevisionCompacted
+			}
+			events.Revision = strconv.FormatUint(current, 10)
+
+			start := make([]byte, 8)
+			binary.BigEndian.PutUint64(start, rev+1)
+
+			c := tx.Bucket(eventsBucket).Cursor()
+			for k, v := c.Seek(start); k != nil; k, v = c.Next() {
+				var ev store.RulesetEvent
+				err := json.Unmarshal(v, &ev)
+				if err != nil {
+					s.logger.Debug().Err(err).Msg("watch: unmarshalling failed")
+					return errors.Wrap(err, "failed to unmarshal event")
+				}
+
+				if strings.HasPrefix(ev.Path, prefix) {
+					events.Events = append(events.Events, ev)
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(events.Events) > 0 {
+			return &events, nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Eval evaluates a ruleset given a path and a set of parameters. It implements the regula.Evaluator interface.
+func (s *RulesetService) Eval(ctx context.Context, path string, params rule.Params) (*regula.EvalResult, error) {
+	return s.eval(path, "", params)
+}
+
+// EvalVersion evaluates a ruleset given a path and a set of parameters. It implements the regula.Evaluator interface.
+func (s *RulesetService) EvalVersion(ctx context.Context, path, version string, params rule.Params) (*regula.EvalResult, error) {
+	if version == "" {
+		return nil, regula.ErrRulesetNotFound
+	}
+
+	return s.eval(path, version, params)
+}
+
+func (s *RulesetService) eval(path, version string, params rule.Params) (*regula.EvalResult, error) {
+	var (
+		entry *store.RulesetEntry
+		sig   *store.Signature
+	)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := version
+		if v == "" {
+			v = string(tx.Bucket(latestBucket).Get([]byte(path)))
+		}
+
+		var err error
+		entry, err = s.entry(tx, path, v)
+		if err != nil {
+			return err
+		}
+
+		sig, err = s.signature(tx, path)
+		if err == store.ErrNotFound {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, regula.ErrRulesetNotFound
+		}
+
+		return nil, err
+	}
+
+	if sig != nil {
+		params = sig.Params(params)
+	}
+
+	v, i, err := entry.Ruleset.EvalRule(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &regula.EvalResult{
+		Value:   v,
+		Version: entry.Version,
+		Rule:    &i,
+	}, nil
+}
+
+// update runs fn in a read-write transaction and wakes up the watchers once it is committed.
+func (s *RulesetService) update(fn func(tx *bolt.Tx) error) error {
+	err := s.db.Update(fn)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	close(s.changed)
+	s.changed = make(chan struct{})
+	s.mu.Unlock()
+
+	return nil
+}
+
+// notify records the given events under a new revision and deletes the events of the revisions
+// that aren't retained anymore.
+func (s *RulesetService) notify(tx *bolt.Tx, events ...store.RulesetEvent) error {
+	rev := getRevision(tx) + 1
+
+	err := putUint64(tx, revisionKey, rev)
+	if err != nil {
+		return err
+	}
+
+	for i, ev := range events {
+		// events are stored by revision and by position within the revision.
+		k := make([]byte, 12)
+		binary.BigEndian.PutUint64(k, rev)
+		binary.BigEndian.PutUint32(k[8:], uint32(i))
+
+		err = putJSON(tx.Bucket(eventsBucket), k, &ev)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.compact(tx, rev)
+}
+
+// compact deletes the events of the revisions older than the last EventsRetention ones
+// and records the last revision whose events were deleted.
+func (s *RulesetService) compact(tx *bolt.Tx, rev uint64) error {
+	if s.EventsRetention <= 0 || rev <= uint64(s.EventsRetention) {
+		return nil
+	}
+
+	compacted := rev - uint64(s.EventsRetention)
+	if compacted <= getUint64(tx, compactedKey) {
+		return nil
+	}
+
+	end := make([]byte, 8)
+	binary.BigEndian.PutUint64(end, compacted+1)
+
+	// keys can't be deleted while iterating with a cursor, collect them first.
+	var keys [][]byte
+	c := tx.Bucket(eventsBucket).Cursor()
+	for k, _ := c.First(); k != nil && bytes.Compare(k, end) < 0; k, _ = c.Next() {
+		keys = append(keys, append([]byte(nil), k...))
+	}
+
+	for _, k := range keys {
+		err := tx.Bucket(eventsBucket).Delete(k)
+		if err != nil {
+			return err
+		}
+	}
+
+	return putUint64(tx, compactedKey, compacted)
+}
+
+// getRevision returns the current revision of the database.
+func getRevision(tx *bolt.Tx) uint64 {
+	return getUint64(tx, revisionKey)
+}
+
+// getUint64 returns the number stored under the given key of the meta bucket, zero if there is none.
+func getUint64(tx *bolt.Tx, key []byte) uint64 {
+	v := tx.Bucket(metaBucket).Get(key)
+	if v == nil {
+		return 0
+	}
+
+	return binary.BigEndian.Uint64(v)
+}
+
+// putUint64 stores the given number under the given key of the meta bucket.
+func putUint64(tx *bolt.Tx, key []byte, n uint64) error {
+	raw := make([]byte, 8)
+	binary.BigEndian.PutUint64(raw, n)
+	return tx.Bucket(metaBucket).Put(key, raw)
+}
+
+// audit records the given entry in the audit bucket, under the next sequence number of the bucket.
+func audit(tx *bolt.Tx, entry store.AuditEntry) error {
+	b := tx.Bucket(auditBucket)
+	seq, err := b.NextSequence()
+	if err != nil {
+		return err
+	}
+
+	return putJSON(b, key(entry.Path, fmt.Sprintf("%020d", seq)), &entry)
+}
+
+func putJSON(b *bolt.Bucket, k []byte, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode value")
+	}
+
+	return b.Put(k, raw)
+}
+
+func key(path, version string) []byte {
+	return []byte(path + "\x00" + version)
+}
+
+// checksum of a ruleset used to detect changes.
+func checksum(rs *regula.Ruleset) ([]byte, error) {
+	h := md5.New()
+	err := json.NewEncoder(h).Encode(rs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate checksum")
+	}
+
+	return h.Sum(nil), nil
+}
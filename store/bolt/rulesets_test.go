@@ -0,0 +1,109 @@
+package bolt_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/heetch/regula"
+	"github.com/heetch/regula/rule"
+	"github.com/heetch/regula/store"
+	"github.com/heetch/regula/store/bolt"
+	"github.com/heetch/regula/store/storetest"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ store.RulesetService = new(bolt.RulesetService)
+	_ regula.Evaluator     = new(bolt.RulesetService)
+)
+
+func newBoltRulesetService(t *testing.T) (*bolt.RulesetService, string, func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "regula-bolt-tests")
+	require.NoError(t, err)
+
+	filename := filepath.Join(dir, "regula.db")
+	s, err := bolt.NewRulesetService(filename, zerolog.Nop())
+	require.NoError(t, err)
+
+	return s, filename, func() {
+		s.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (store.RulesetService, func()) {
+		s, _, cleanup := newBoltRulesetService(t)
+		return s, cleanup
+	})
+}
+
+func TestReopen(t *testing.T) {
+	s, filename, cleanup := newBoltRulesetService(t)
+	defer cleanup()
+
+	rs, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(true)))
+	entry, err := s.Put(context.Background(), "a", rs)
+	require.NoError(t, err)
+
+	list, err := s.List(context.Background(), "", store.ListOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "1", list.Revision)
+
+	require.NoError(t, s.Close())
+
+	s, err = bolt.NewRulesetService(filename, zerolog.Nop())
+	require.NoError(t, err)
+
+	latest, err := s.Latest(context.Background(), "a")
+	require.NoError(t, err)
+	require.Equal(t, entry, latest)
+
+	_, err = s.Put(context.Background(), "a", rs)
+	require.Equal(t, store.ErrNotModified, err)
+
+	// revisions continue after a restart so that watchers can resume
+	rs, _ = regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(false)))
+	_, err = s.Put(context.Background(), "a", rs)
+	require.NoError(t, err)
+
+	events, err := s.Watch(context.Background(), "a", list.Revision)
+	require.NoError(t, err)
+	require.Equal(t, "2", events.Revision)
+	require.Len(t, events.Events, 1)
+	require.Equal(t, rs, events.Events[0].Ruleset)
+}
+
+func TestEventsRetention(t *testing.T) {
+	s, filename, cleanup := newBoltRulesetService(t)
+	defer cleanup()
+	s.EventsRetention = 2
+
+	storetest.RunEventsRetention(t, s, 2)
+
+	// the compacted revision is kept after a restart, whatever the new retention.
+	list, err := s.List(context.Background(), "", store.ListOptions{})
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	s, err = bolt.NewRulesetService(filename, zerolog.Nop())
+	require.NoError(t, err)
+	defer s.Close()
+
+	rev, err := strconv.ParseInt(list.Revision, 10, 64)
+	require.NoError(t, err)
+
+	_, err = s.Watch(context.Background(), "", strconv.FormatInt(rev-3, 10))
+	require.Equal(t, store.ErrRevisionCompacted, err)
+
+	events, err := s.Watch(context.Background(), "", strconv.FormatInt(rev-2, 10))
+	require.NoError(t, err)
+	require.Len(t, events.Events, 2)
+}
@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"context"
+	"sync"
+
+	"github.com/heetch/regula/store"
+	"github.com/rs/zerolog"
+)
+
+// NamespaceService decorates a store.NamespaceService so that the rulesets of every namespace are cached
+// by a RulesetService, created the first time the namespace is used.
+type NamespaceService struct {
+	store.NamespaceService
+
+	logger zerolog.Logger
+
+	mu       sync.Mutex
+	rulesets map[string]*RulesetService
+	closed   bool
+}
+
+// NewNamespaceService wraps the given service. The watchers of the namespaces are stopped by Close.
+func NewNamespaceService(s store.NamespaceService, logger zerolog.Logger) *NamespaceService {
+	return &NamespaceService{
+		NamespaceService: s,
+		logger:           logger,
+		rulesets:         make(map[string]*RulesetService),
+	}
+}
+
+// Rulesets returns the cached service managing the rulesets of the given namespace.
+// It returns store.ErrNotFound if the namespace doesn't exist.
+func (s *NamespaceService) Rulesets(ctx context.Context, name string) (store.RulesetService, error) {
+	s.mu.Lock()
+	rs, ok := s.rulesets[name]
+	s.mu.Unlock()
+	if ok {
+		return rs, nil
+	}
+
+	// namespaces can't be removed, once found a namespace can be cached forever.
+	decorated, err := s.NamespaceService.Rulesets(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// another call may have cached it in the meantime
+	if rs, ok := s.rulesets[name]; ok {
+		return rs, nil
+	}
+
+	// the decorated service is returned as is once closed to avoid leaking watchers.
+	if s.closed {
+		return decorated, nil
+	}
+
+	rs = NewRulesetService(decorated, s.logger.With().Str("namespace", name).Logger())
+	s.rulesets[name] = rs
+
+	return rs, nil
+}
+
+// Close stops watching the namespaces. It doesn't close the decorated service.
+func (s *NamespaceService) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, rs := range s.rulesets {
+		rs.Close()
+	}
+
+	s.closed = true
+}
@@ -0,0 +1,37 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/heetch/regula/store"
+	"github.com/heetch/regula/store/cache"
+	"github.com/heetch/regula/store/memory"
+	"github.com/heetch/regula/store/storetest"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+var _ store.NamespaceService = new(cache.NamespaceService)
+
+func TestNamespaces(t *testing.T) {
+	storetest.RunNamespaces(t, func(t *testing.T) (store.NamespaceService, func()) {
+		s := cache.NewNamespaceService(memory.NewNamespaceService(), zerolog.Nop())
+		return s, s.Close
+	})
+
+	t.Run("Cached", func(t *testing.T) {
+		s := cache.NewNamespaceService(memory.NewNamespaceService(), zerolog.Nop())
+		defer s.Close()
+
+		require.NoError(t, s.Create(context.Background(), "a"))
+
+		rs, err := s.Rulesets(context.Background(), "a")
+		require.NoError(t, err)
+		require.IsType(t, new(cache.RulesetService), rs)
+
+		again, err := s.Rulesets(context.Background(), "a")
+		require.NoError(t, err)
+		require.True(t, rs == again)
+	})
+}
@@ -0,0 +1,377 @@
+// Package cache provides a store.RulesetService decorator keeping the decoded rulesets in memory.
+// The cache is kept fresh by watching the decorated service: every event invalidates the cached data
+// of its path, which is reloaded from the decorated service on the next read.
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/heetch/regula"
+	"github.com/heetch/regula/rule"
+	"github.com/heetch/regula/store"
+	"github.com/rs/zerolog"
+)
+
+// RulesetService caches the latest version, the versions and the signature of the rulesets
+// read through Latest, OneByVersion, Eval and EvalVersion.
+// The other methods are forwarded to the decorated service.
+// The returned entries are shared with the cache and must not be modified.
+type RulesetService struct {
+	store.RulesetService
+
+	logger zerolog.Logger
+	cancel func()
+	done   chan struct{}
+
+	mu       sync.RWMutex
+	rulesets map[string]*rulesetData
+	// incremented every time the whole cache is dropped.
+	epoch int
+}
+
+// rulesetData holds the cached data of a path.
+type rulesetData struct {
+	// incremented every time the path is invalidated, to prevent
+	// a read that started before the invalidation from filling the cache.
+	generation int
+	latest     *store.RulesetEntry
+	versions   map[string]*store.RulesetEntry
+	signature  *store.Signature
+}
+
+// NewRulesetService wraps the given service and starts watching it for changes.
+// The watcher is stopped by Close.
+func NewRulesetService(s store.RulesetService, logger zerolog.Logger) *RulesetService {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	c := RulesetService{
+		RulesetService: s,
+		logger:         logger,
+		cancel:         cancel,
+		done:           make(chan struct{}),
+		rulesets:       make(map[string]*rulesetData),
+	}
+
+	go c.watch(ctx)
+
+	return &c
+}
+
+// Close stops watching the decorated service. It doesn't close the decorated service.
+func (s *RulesetService) Close() {
+	s.cancel()
+	<-s.done
+}
+
+// watch invalidates the cache every time a ruleset changes.
+// The whole cache is dropped every time the watch starts from the current revision,
+// i.e. when the service is created and after a failure.
+func (s *RulesetService) watch(ctx context.Context) {
+	defer close(s.done)
+
+	var revision string
+	for {
+		if revision == "" {
+			entries, err := s.RulesetService.List(ctx, "", store.ListOptions{Limit: 1, LatestOnly: true})
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+
+				s.logger.Error().Err(err).Msg("cache: failed to get the current revision")
+				if !sleep(ctx, time.Second) {
+					return
+				}
+				continue
+			}
+
+			revision = entries.Revision
+
+			// the data cached before the revision was known may be outdated.
+			s.invalidateAll()
+		}
+
+		events, err := s.RulesetService.Watch(ctx, "", revision)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			s.logger.Error().Err(err).Msg("cache: watch failed, starting over")
+			revision = ""
+			if !sleep(ctx, time.Second) {
+				return
+			}
+			continue
+		}
+
+		for _, ev := range events.Events {
+			s.invalidate(ev.Path)
+		}
+
+		revision = events.Revision
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// invalidate drops the cached data of the given path.
+func (s *RulesetService) invalidate(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var gen int
+	if rs, ok := s.rulesets[path]; ok {
+		gen = rs.generation
+	}
+
+	s.rulesets[path] = &rulesetData{generation: gen + 1}
+}
+
+func (s *RulesetService) invalidateAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rulesets = make(map[string]*rulesetData)
+	s.epoch++
+}
+
+// generation identifies the state of the cache of a path at a given time.
+type generation struct {
+	epoch int
+	gen   int
+}
+
+// generation returns the current generation of the path, to be passed to fill.
+func (s *RulesetService) generation(path string) generation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	g := generation{epoch: s.epoch}
+	if rs, ok := s.rulesets[path]; ok {
+		g.gen = rs.generation
+	}
+
+	return g
+}
+
+// fill calls fn to store the data of the path loaded by the caller, unless the path was invalidated
+// since the given generation was read.
+func (s *RulesetService) fill(path string, g generation, fn func(rs *rulesetData)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rs, ok := s.rulesets[path]
+	if !ok {
+		rs = new(rulesetData)
+	}
+
+	if s.epoch != g.epoch || rs.generation != g.gen {
+		return
+	}
+
+	s.rulesets[path] = rs
+	fn(rs)
+}
+
+// Latest returns the latest version of the ruleset entry which corresponds to the given path.
+// It returns store.ErrNotFound if the path doesn't exist.
+func (s *RulesetService) Latest(ctx context.Context, path string) (*store.RulesetEntry, error) {
+	s.mu.RLock()
+	rs, ok := s.rulesets[path]
+	if ok && rs.latest != nil {
+		entry := *rs.latest
+		s.mu.RUnlock()
+		return &entry, nil
+	}
+	s.mu.RUnlock()
+
+	gen := s.generation(path)
+
+	entry, err := s.RulesetService.Latest(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	s.fill(path, gen, func(rs *rulesetData) {
+		rs.latest = entry
+		rs.addVersion(entry)
+	})
+
+	cp := *entry
+	return &cp, nil
+}
+
+// OneByVersion returns the ruleset entry which corresponds to the given path at the given version.
+// It returns store.ErrNotFound if the path or the version doesn't exist.
+func (s *RulesetService) OneByVersion(ctx context.Context, path, version string) (*store.RulesetEntry, error) {
+	s.mu.RLock()
+	rs, ok := s.rulesets[path]
+	if ok && rs.versions[version] != nil {
+		entry := *rs.versions[version]
+		s.mu.RUnlock()
+		return &entry, nil
+	}
+	s.mu.RUnlock()
+
+	gen := s.generation(path)
+
+	entry, err := s.RulesetService.OneByVersion(ctx, path, version)
+	if err != nil {
+		return nil, err
+	}
+
+	s.fill(path, gen, func(rs *rulesetData) {
+		rs.addVersion(entry)
+	})
+
+	cp := *entry
+	return &cp, nil
+}
+
+func (rs *rulesetData) addVersion(entry *store.RulesetEntry) {
+	if rs.versions == nil {
+		rs.versions = make(map[string]*store.RulesetEntry)
+	}
+
+	rs.versions[entry.Version] = entry
+}
+
+// signature returns the signature of the path, or nil if the path has none.
+func (s *RulesetService) signature(ctx context.Context, path string) (*store.Signature, error) {
+	s.mu.RLock()
+	rs, ok := s.rulesets[path]
+	if ok && rs.signature != nil {
+		sig := rs.signature
+		s.mu.RUnlock()
+		return sig, nil
+	}
+	s.mu.RUnlock()
+
+	gen := s.generation(path)
+
+	sigs, err := s.RulesetService.Signatures(ctx, path)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	sig := &sigs[len(sigs)-1]
+
+	// migrations don't generate events, but a cached signature can't be outdated in a way
+	// that changes the result of an evaluation: migrations only add optional params, which
+	// are used by new versions, and every new version invalidates the cache.
+	s.fill(path, gen, func(rs *rulesetData) {
+		rs.signature = sig
+	})
+
+	return sig, nil
+}
+
+// Eval evaluates the latest version of a ruleset given a path and a set of parameters,
+// without calling the decorated service once the ruleset is cached. It implements the regula.Evaluator interface.
+func (s *RulesetService) Eval(ctx context.Context, path string, params rule.Params) (*regula.EvalResult, error) {
+	entry, err := s.Latest(ctx, path)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, regula.ErrRulesetNotFound
+		}
+
+		return nil, err
+	}
+
+	return s.eval(ctx, entry, params)
+}
+
+// EvalVersion evaluates a version of a ruleset given a path and a set of parameters,
+// without calling the decorated service once the version is cached. It implements the regula.Evaluator interface.
+func (s *RulesetService) EvalVersion(ctx context.Context, path, version string, params rule.Params) (*regula.EvalResult, error) {
+	entry, err := s.OneByVersion(ctx, path, version)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, regula.ErrRulesetNotFound
+		}
+
+		return nil, err
+	}
+
+	return s.eval(ctx, entry, params)
+}
+
+func (s *RulesetService) eval(ctx context.Context, entry *store.RulesetEntry, params rule.Params) (*regula.EvalResult, error) {
+	sig, err := s.signature(ctx, entry.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if sig != nil {
+		params = sig.Params(params)
+	}
+
+	v, i, err := entry.Ruleset.EvalRule(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &regula.EvalResult{
+		Value:   v,
+		Version: entry.Version,
+		Rule:    &i,
+	}, nil
+}
+
+// Put stores a ruleset version using the decorated service and invalidates the cached data of the path.
+func (s *RulesetService) Put(ctx context.Context, path string, ruleset *regula.Ruleset, opts ...store.PutOption) (*store.RulesetEntry, error) {
+	defer s.invalidate(path)
+
+	return s.RulesetService.Put(ctx, path, ruleset, opts...)
+}
+
+// PutBatch stores several rulesets using the decorated service and invalidates the cached data of their paths.
+func (s *RulesetService) PutBatch(ctx context.Context, entries []store.RulesetEntry) ([]store.RulesetEntry, error) {
+	for _, e := range entries {
+		defer s.invalidate(e.Path)
+	}
+
+	return s.RulesetService.PutBatch(ctx, entries)
+}
+
+// Rollback rolls back a ruleset using the decorated service and invalidates the cached data of the path.
+func (s *RulesetService) Rollback(ctx context.Context, path, version string) (*store.RulesetEntry, error) {
+	defer s.invalidate(path)
+
+	return s.RulesetService.Rollback(ctx, path, version)
+}
+
+// MigrateSignature migrates a signature using the decorated service and invalidates the cached data of the path.
+func (s *RulesetService) MigrateSignature(ctx context.Context, path string, m *store.SignatureMigration) (*store.Signature, error) {
+	defer s.invalidate(path)
+
+	return s.RulesetService.MigrateSignature(ctx, path, m)
+}
+
+// Delete removes a ruleset using the decorated service and invalidates the cached data of the path.
+func (s *RulesetService) Delete(ctx context.Context, path string, hard bool) error {
+	defer s.invalidate(path)
+
+	return s.RulesetService.Delete(ctx, path, hard)
+}
+
+// DeleteVersions removes versions of a ruleset using the decorated service and invalidates the cached data of the path.
+func (s *RulesetService) DeleteVersions(ctx context.Context, path string, versions ...string) error {
+	defer s.invalidate(path)
+
+	return s.RulesetService.DeleteVersions(ctx, path, versions...)
+}
@@ -0,0 +1,115 @@
+package cache_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/heetch/regula"
+	"github.com/heetch/regula/rule"
+	"github.com/heetch/regula/store"
+	"github.com/heetch/regula/store/cache"
+	"github.com/heetch/regula/store/memory"
+	"github.com/heetch/regula/store/storetest"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ store.RulesetService = new(cache.RulesetService)
+	_ regula.Evaluator     = new(cache.RulesetService)
+)
+
+// countingService counts the reads reaching the decorated service.
+type countingService struct {
+	store.RulesetService
+
+	latest int32
+}
+
+func (s *countingService) Latest(ctx context.Context, path string) (*store.RulesetEntry, error) {
+	atomic.AddInt32(&s.latest, 1)
+	return s.RulesetService.Latest(ctx, path)
+}
+
+func (s *countingService) count() int {
+	return int(atomic.LoadInt32(&s.latest))
+}
+
+// eventually fails the test if fn doesn't return true within 5 seconds.
+func eventually(t *testing.T, fn func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !fn() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met after 5 seconds")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (store.RulesetService, func()) {
+		s := cache.NewRulesetService(memory.NewRulesetService(), zerolog.Nop())
+		return s, s.Close
+	})
+}
+
+func TestCache(t *testing.T) {
+	m := memory.NewRulesetService()
+	counter := countingService{RulesetService: m}
+	s := cache.NewRulesetService(&counter, zerolog.Nop())
+	defer s.Close()
+
+	rs, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("a")))
+	_, err := s.Put(context.Background(), "a", rs)
+	require.NoError(t, err)
+
+	t.Run("Eval", func(t *testing.T) {
+		// once cached, evaluations don't reach the decorated service anymore
+		eventually(t, func() bool {
+			before := counter.count()
+			for i := 0; i < 3; i++ {
+				res, err := s.Eval(context.Background(), "a", nil)
+				require.NoError(t, err)
+				require.Equal(t, rule.StringValue("a"), res.Value)
+			}
+
+			return counter.count() <= before+1
+		})
+	})
+
+	t.Run("Put", func(t *testing.T) {
+		rs, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("b")))
+		_, err := s.Put(context.Background(), "a", rs)
+		require.NoError(t, err)
+
+		// writes made through the cache are visible immediately
+		res, err := s.Eval(context.Background(), "a", nil)
+		require.NoError(t, err)
+		require.Equal(t, rule.StringValue("b"), res.Value)
+	})
+
+	t.Run("Watch", func(t *testing.T) {
+		// writes made by others are detected by watching the decorated service
+		rs, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("c")))
+		_, err := m.Put(context.Background(), "a", rs)
+		require.NoError(t, err)
+
+		eventually(t, func() bool {
+			res, err := s.Eval(context.Background(), "a", nil)
+			return err == nil && res.Value.Equal(rule.StringValue("c"))
+		})
+
+		err = m.Delete(context.Background(), "a", false)
+		require.NoError(t, err)
+
+		eventually(t, func() bool {
+			_, err := s.Eval(context.Background(), "a", nil)
+			return err == regula.ErrRulesetNotFound
+		})
+	})
+}
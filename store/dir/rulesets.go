@@ -0,0 +1,1136 @@
+// Package dir provides an implementation of store.RulesetService storing the rulesets as JSON files
+// in a directory tree. The path of a ruleset is derived from the name of its file, relative to the root
+// directory and without the .json extension: rulesets/pricing/base.json holds the ruleset pricing/base.
+//
+// Versions are content hashes, putting the same ruleset twice always produces the same version.
+// Changes made to the files by other programs, like git, are detected by polling the directory.
+// The files only hold the latest version of each ruleset, the history of the versions and the signatures
+// are kept in memory and rebuilt from the files when the service starts.
+package dir
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/heetch/regula"
+	"github.com/heetch/regula/rule"
+	"github.com/heetch/regula/store"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+const ext = ".json"
+
+// RulesetService manages the rulesets stored in a directory.
+// The events are kept in memory for the watchers during the last EventsRetention revisions.
+// Revisions start from the time the service was created, so that the revisions of a previous
+// process are known to be compacted.
+type RulesetService struct {
+	// EventsRetention is the number of revisions whose events are kept for the watchers.
+	// Defaults to store.DefaultEventsRetention.
+	EventsRetention int64
+
+	root     string
+	interval time.Duration
+	logger   zerolog.Logger
+
+	mu       sync.Mutex
+	lastScan time.Time
+	rulesets map[string]*rulesetData
+	// state of the files the last time they were read, by ruleset path.
+	files map[string]fileState
+	// first revision of the service, the events of the previous ones are unknown.
+	base     int64
+	revision int64
+	events   []event
+}
+
+// NewRulesetService creates the root directory if it doesn't exist and loads the rulesets it contains.
+// The directory is scanned for changes at most once per interval.
+func NewRulesetService(root string, interval time.Duration, logger zerolog.Logger) (*RulesetService, error) {
+	err := os.MkdirAll(root, 0755)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create directory %s", root)
+	}
+
+	base := time.Now().UnixNano()
+
+	s := RulesetService{
+		EventsRetention: store.DefaultEventsRetention,
+		root:            root,
+		interval:        interval,
+		logger:          logger,
+		rulesets:        make(map[string]*rulesetData),
+		files:           make(map[string]fileState),
+		base:            base,
+		revision:        base,
+	}
+
+	err = s.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// rulesetData holds all the data known about a path.
+type rulesetData struct {
+	versions  []version // in creation order
+	deleted   []version // soft deleted versions
+	latest    string
+	signature *store.Signature
+	history   []store.Signature
+	audit     []store.AuditEntry // in creation order
+}
+
+type version struct {
+	store.RulesetEntry
+
+	createdAt time.Time
+}
+
+func (r *rulesetData) version(v string) (int, bool) {
+	for i := range r.versions {
+		if r.versions[i].Version == v {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+type event struct {
+	store.RulesetEvent
+
+	revision int64
+}
+
+// List returns the rulesets entries under the given prefix.
+// If opt.LatestOnly is true, only the latest version of each ruleset is returned.
+func (s *RulesetService) List(ctx context.Context, prefix string, opt store.ListOptions) (*store.RulesetEntries, error) {
+	limit := opt.Limit
+	if limit != 0 {
+		limit = store.NormalizeLimit(limit)
+	}
+
+	var lastKey string
+	if opt.ContinueToken != "" {
+		k, err := base64.URLEncoding.DecodeString(opt.ContinueToken)
+		if err != nil {
+			return nil, store.ErrInvalidContinueToken
+		}
+
+		lastKey = string(k)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sync()
+
+	// entries are sorted by path, then by version.
+	var entries []store.RulesetEntry
+	for path, rs := range s.rulesets {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+
+		for _, v := range rs.versions {
+			if opt.LatestOnly && v.Version != rs.latest {
+				continue
+			}
+
+			if lastKey == "" || key(v.Path, v.Version) > lastKey {
+				e, err := copyEntry(v.RulesetEntry)
+				if err != nil {
+					return nil, err
+				}
+
+				entries = append(entries, *e)
+			}
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return key(entries[i].Path, entries[i].Version) < key(entries[j].Path, entries[j].Version)
+	})
+
+	// if a prefix is provided it must always return results
+	// otherwise it doesn't exist.
+	if len(entries) == 0 && prefix != "" {
+		return nil, store.ErrNotFound
+	}
+
+	res := store.RulesetEntries{
+		Revision: strconv.FormatInt(s.revision, 10),
+	}
+
+	if limit == 0 || len(entries) <= limit {
+		res.Entries = entries
+		return &res, nil
+	}
+
+	res.Entries = entries[:limit]
+	last := res.Entries[limit-1]
+	res.Continue = base64.URLEncoding.EncodeToString([]byte(key(last.Path, last.Version)))
+
+	return &res, nil
+}
+
+// Latest returns the latest version of the ruleset entry which corresponds to the given path.
+// It returns store.ErrNotFound if the path doesn't exist.
+func (s *RulesetService) Latest(ctx context.Context, path string) (*store.RulesetEntry, error) {
+	entry, _, err := s.find(path, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return copyEntry(entry)
+}
+
+// OneByVersion returns the ruleset entry which corresponds to the given path at the given version.
+// It returns store.ErrNotFound if the path or the version doesn't exist.
+func (s *RulesetService) OneByVersion(ctx context.Context, path, version string) (*store.RulesetEntry, error) {
+	entry, _, err := s.find(path, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return copyEntry(entry)
+}
+
+// find returns the known entry of the given version, or of the latest one if version is empty,
+// along with the signature of the path. The returned entry must not be modified.
+func (s *RulesetService) find(path, v string) (store.RulesetEntry, *store.Signature, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sync()
+
+	rs, ok := s.rulesets[path]
+	if !ok {
+		return store.RulesetEntry{}, nil, store.ErrNotFound
+	}
+
+	if v == "" {
+		v = rs.latest
+	}
+
+	i, ok := rs.version(v)
+	if !ok {
+		return store.RulesetEntry{}, nil, store.ErrNotFound
+	}
+
+	return rs.versions[i].RulesetEntry, rs.signature, nil
+}
+
+// Versions returns the versions of the ruleset stored on the given path, newest first.
+// It returns store.ErrNotFound if the path doesn't exist.
+func (s *RulesetService) Versions(ctx context.Context, path string, limit int, continueToken string) (*store.RulesetVersions, error) {
+	limit = store.NormalizeLimit(limit)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sync()
+
+	rs, ok := s.rulesets[path]
+	if !ok || len(rs.versions) == 0 {
+		return nil, store.ErrNotFound
+	}
+
+	start := len(rs.versions) - 1
+	if continueToken != "" {
+		lastVersion, err := base64.URLEncoding.DecodeString(continueToken)
+		if err != nil {
+			return nil, store.ErrInvalidContinueToken
+		}
+
+		i, ok := rs.version(string(lastVersion))
+		if !ok {
+			return nil, store.ErrInvalidContinueToken
+		}
+
+		start = i - 1
+	}
+
+	versions := store.RulesetVersions{
+		Path: path,
+	}
+
+	for i := start; i >= 0; i-- {
+		versions.Versions = append(versions.Versions, store.RulesetVersion{
+			Version:   rs.versions[i].Version,
+			CreatedAt: rs.versions[i].createdAt,
+		})
+
+		if len(versions.Versions) == limit {
+			if i > 0 {
+				versions.Continue = base64.URLEncoding.EncodeToString([]byte(rs.versions[i].Version))
+			}
+			break
+		}
+	}
+
+	return &versions, nil
+}
+
+// History returns the changes made to the ruleset stored on the given path, newest first.
+// Like the versions, the history is only kept in memory and changes made to the files by other programs
+// are not recorded. It returns store.ErrNotFound if nothing was recorded for the path.
+func (s *RulesetService) History(ctx context.Context, path string, limit int, continueToken string) (*store.AuditEntries, error) {
+	limit = store.NormalizeLimit(limit)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rs, ok := s.rulesets[path]
+	if !ok || len(rs.audit) == 0 {
+		return nil, store.ErrNotFound
+	}
+
+	// entries are only appended, the token holds the position of the last returned one.
+	end := len(rs.audit)
+	if continueToken != "" {
+		raw, err := base64.URLEncoding.DecodeString(continueToken)
+		if err != nil {
+			return nil, store.ErrInvalidContinueToken
+		}
+
+		end, err = strconv.Atoi(string(raw))
+		if err != nil || end < 0 || end > len(rs.audit) {
+			return nil, store.ErrInvalidContinueToken
+		}
+	}
+
+	history := store.AuditEntries{
+		Path: path,
+	}
+
+	for i := end - 1; i >= 0; i-- {
+		history.Entries = append(history.Entries, rs.audit[i])
+
+		if len(history.Entries) == limit {
+			if i > 0 {
+				history.Continue = base64.URLEncoding.EncodeToString([]byte(strconv.Itoa(i)))
+			}
+			break
+		}
+	}
+
+	return &history, nil
+}
+
+// Put writes the given ruleset to the file corresponding to the path. The version is the hash of its content.
+// The ruleset is validated like in the etcd implementation and a store.ValidationError
+// is returned if it is invalid. If the ruleset didn't change, the latest entry is returned
+// with store.ErrNotModified.
+func (s *RulesetService) Put(ctx context.Context, path string, ruleset *regula.Ruleset, opts ...store.PutOption) (*store.RulesetEntry, error) {
+	sig, err := store.ValidateRuleset(path, ruleset)
+	if err != nil {
+		return nil, err
+	}
+
+	// the ruleset is copied to prevent the caller from modifying the stored version.
+	cp, err := copyRuleset(ruleset)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := hash(cp)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sync()
+
+	rs, ok := s.rulesets[path]
+	if !ok {
+		rs = new(rulesetData)
+	}
+
+	// make sure the ruleset didn't change since the caller read it
+	if ev := store.NewPutOptions(opts...).ExpectedVersion; ev != "" && ev != rs.latest {
+		if rs.latest == "" {
+			return nil, store.ErrVersionMismatch
+		}
+
+		i, _ := rs.version(rs.latest)
+		entry, err := copyEntry(rs.versions[i].RulesetEntry)
+		if err != nil {
+			return nil, err
+		}
+
+		return entry, store.ErrVersionMismatch
+	}
+
+	// if nothing changed return latest ruleset
+	if rs.latest == v {
+		i, _ := rs.version(v)
+		entry, err := copyEntry(rs.versions[i].RulesetEntry)
+		if err != nil {
+			return nil, err
+		}
+
+		return entry, store.ErrNotModified
+	}
+
+	// make sure signature didn't change
+	if rs.signature != nil {
+		err = rs.signature.MatchWith(sig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.write(path, cp)
+	if err != nil {
+		return nil, err
+	}
+
+	if rs.signature == nil {
+		rs.signature = sig
+	}
+	s.rulesets[path] = rs
+
+	rs.audit = append(rs.audit, store.NewAuditEntry(ctx, store.AuditPut, path, v, rs.latest))
+	entry := s.add(rs, path, v, cp, time.Now())
+
+	s.notify(store.RulesetEvent{
+		Type:    store.RulesetPutEvent,
+		Path:    path,
+		Version: entry.Version,
+		Ruleset: entry.Ruleset,
+	})
+
+	return copyEntry(entry)
+}
+
+// PutBatch writes the files of the given rulesets and notifies the watchers under a single revision.
+// All the rulesets are checked before writing anything, if one of them is invalid a store.ValidationError
+// is returned and nothing is written. Unchanged rulesets are left untouched.
+// The files are written one after the other though, a failing write leaves the previous ones in place.
+func (s *RulesetService) PutBatch(ctx context.Context, entries []store.RulesetEntry) ([]store.RulesetEntry, error) {
+	sigs, err := store.ValidateBatch(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	cps := make([]*regula.Ruleset, len(entries))
+	versions := make([]string, len(entries))
+	for i, e := range entries {
+		// the rulesets are copied to prevent the caller from modifying the stored versions.
+		cps[i], err = copyRuleset(e.Ruleset)
+		if err != nil {
+			return nil, err
+		}
+
+		versions[i], err = hash(cps[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sync()
+
+	res := make([]store.RulesetEntry, len(entries))
+	changed := make([]bool, len(entries))
+	for i, e := range entries {
+		rs, ok := s.rulesets[e.Path]
+		if ok && rs.latest == versions[i] {
+			j, _ := rs.version(versions[i])
+			res[i] = rs.versions[j].RulesetEntry
+			continue
+		}
+
+		if ok && rs.signature != nil {
+			err = rs.signature.MatchWith(sigs[i])
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		changed[i] = true
+	}
+
+	var events []store.RulesetEvent
+	for i, e := range entries {
+		if !changed[i] {
+			continue
+		}
+
+		err = s.write(e.Path, cps[i])
+		if err != nil {
+			break
+		}
+
+		rs, ok := s.rulesets[e.Path]
+		if !ok {
+			rs = new(rulesetData)
+			s.rulesets[e.Path] = rs
+		}
+		if rs.signature == nil {
+			rs.signature = sigs[i]
+		}
+
+		rs.audit = append(rs.audit, store.NewAuditEntry(ctx, store.AuditPut, e.Path, versions[i], rs.latest))
+		res[i] = s.add(rs, e.Path, versions[i], cps[i], time.Now())
+
+		events = append(events, store.RulesetEvent{
+			Type:    store.RulesetPutEvent,
+			Path:    e.Path,
+			Version: res[i].Version,
+			Ruleset: res[i].Ruleset,
+		})
+	}
+
+	if len(events) > 0 {
+		s.notify(events...)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range res {
+		cp, err := copyEntry(res[i])
+		if err != nil {
+			return nil, err
+		}
+		res[i] = *cp
+	}
+
+	if len(events) == 0 {
+		return res, store.ErrNotModified
+	}
+
+	return res, nil
+}
+
+// add makes the given version the latest version of the path.
+// Versions are content hashes, a known version is reused instead of being added twice.
+func (s *RulesetService) add(rs *rulesetData, path, v string, ruleset *regula.Ruleset, createdAt time.Time) store.RulesetEntry {
+	rs.latest = v
+
+	i, ok := rs.version(v)
+	if ok {
+		return rs.versions[i].RulesetEntry
+	}
+
+	entry := store.RulesetEntry{
+		Path:    path,
+		Version: v,
+		Ruleset: ruleset,
+	}
+
+	rs.versions = append(rs.versions, version{RulesetEntry: entry, createdAt: createdAt})
+	return entry
+}
+
+// Rollback writes the given version to the file of the ruleset, making it the latest version.
+// It returns store.ErrNotFound if the version doesn't exist and store.ErrNotModified if it is already the latest.
+func (s *RulesetService) Rollback(ctx context.Context, path, v string) (*store.RulesetEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sync()
+
+	rs, ok := s.rulesets[path]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+
+	i, ok := rs.version(v)
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+
+	entry := rs.versions[i].RulesetEntry
+	if rs.latest == v {
+		cp, err := copyEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+
+		return cp, store.ErrNotModified
+	}
+
+	err := s.write(path, entry.Ruleset)
+	if err != nil {
+		return nil, err
+	}
+
+	rs.audit = append(rs.audit, store.NewAuditEntry(ctx, store.AuditRollback, path, v, rs.latest))
+	rs.latest = v
+
+	s.notify(store.RulesetEvent{
+		Type:    store.RulesetPutEvent,
+		Path:    path,
+		Version: entry.Version,
+		Ruleset: entry.Ruleset,
+	})
+
+	return copyEntry(entry)
+}
+
+// Signatures returns the history of the signatures of the ruleset stored on the given path, the current one last.
+// It returns store.ErrNotFound if the path has no signature.
+func (s *RulesetService) Signatures(ctx context.Context, path string) ([]store.Signature, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sync()
+
+	rs, ok := s.rulesets[path]
+	if !ok || rs.signature == nil {
+		return nil, store.ErrNotFound
+	}
+
+	// the history is only created by the first migration
+	if len(rs.history) == 0 {
+		return []store.Signature{*rs.signature}, nil
+	}
+
+	return append([]store.Signature(nil), rs.history...), nil
+}
+
+// MigrateSignature applies the given migration to the signature of the ruleset stored on the given path
+// and records the new signature in the history.
+// All the known versions of the ruleset must remain evaluable with the new signature, otherwise a store.ValidationError is returned.
+// It returns store.ErrNotFound if the path has no signature.
+func (s *RulesetService) MigrateSignature(ctx context.Context, path string, m *store.SignatureMigration) (*store.Signature, error) {
+	params := make([]rule.Param, 0, len(m.AddParams))
+	for name, tp := range m.AddParams {
+		params = append(params, rule.Param{Name: name, Type: tp})
+	}
+	err := store.ValidateParamNames(params)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sync()
+
+	rs, ok := s.rulesets[path]
+	if !ok || rs.signature == nil {
+		return nil, store.ErrNotFound
+	}
+
+	sig, err := rs.signature.Migrate(m)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range rs.versions {
+		err = sig.Evaluable(v.Ruleset)
+		if err != nil {
+			return nil, &store.ValidationError{
+				Field:  "version",
+				Value:  v.Version,
+				Reason: err.Error(),
+			}
+		}
+	}
+
+	if len(rs.history) == 0 {
+		rs.history = append(rs.history, *rs.signature)
+	}
+	rs.history = append(rs.history, *sig)
+	rs.signature = sig
+	rs.audit = append(rs.audit, store.NewAuditEntry(ctx, store.AuditMigrateSignature, path, rs.latest, rs.latest))
+
+	cp := *sig
+	return &cp, nil
+}
+
+// Delete removes the file of the ruleset stored on the given path.
+// A soft delete preserves the signature, a hard delete also forgets the signatures of the path but not its history.
+// Every removed version generates a delete event. It returns store.ErrNotFound if the path doesn't exist.
+func (s *RulesetService) Delete(ctx context.Context, path string, hard bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sync()
+
+	rs, ok := s.rulesets[path]
+	if !ok || (rs.latest == "" && !hard) || (len(rs.versions) == 0 && len(rs.deleted) == 0) {
+		return store.ErrNotFound
+	}
+
+	if rs.latest != "" {
+		err := os.Remove(s.filename(path))
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "failed to remove ruleset file of %s", path)
+		}
+		delete(s.files, path)
+	}
+
+	action := store.AuditDelete
+	if hard {
+		action = store.AuditHardDelete
+	}
+	rs.audit = append(rs.audit, store.NewAuditEntry(ctx, action, path, "", rs.latest))
+	s.remove(path, hard)
+
+	return nil
+}
+
+// remove forgets the versions of the given path and notifies the watchers.
+func (s *RulesetService) remove(path string, hard bool) {
+	rs := s.rulesets[path]
+
+	events := make([]store.RulesetEvent, len(rs.versions))
+	for i, v := range rs.versions {
+		events[i] = store.RulesetEvent{
+			Type:    store.RulesetDeleteEvent,
+			Path:    path,
+			Version: v.Version,
+		}
+	}
+
+	if hard {
+		rs.deleted = nil
+		rs.signature = nil
+		rs.history = nil
+	} else {
+		rs.deleted = append(rs.deleted, rs.versions...)
+	}
+	rs.versions = nil
+	rs.latest = ""
+
+	s.notify(events...)
+}
+
+// DeleteVersions forgets the given versions of the ruleset stored on the given path.
+// The file only holds the latest version, which can't be removed, so it is left untouched.
+// Every removed version generates a delete event. It returns store.ErrNotFound if the path doesn't exist
+// and store.ErrLatestVersion, without removing anything, if one of the versions is the latest one.
+func (s *RulesetService) DeleteVersions(ctx context.Context, path string, versions ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sync()
+
+	rs, ok := s.rulesets[path]
+	if !ok || rs.latest == "" {
+		return store.ErrNotFound
+	}
+
+	remove := make(map[string]bool, len(versions))
+	for _, v := range versions {
+		if v == rs.latest {
+			return store.ErrLatestVersion
+		}
+
+		remove[v] = true
+	}
+
+	var events []store.RulesetEvent
+	kept := rs.versions[:0]
+	for _, v := range rs.versions {
+		if !remove[v.Version] {
+			kept = append(kept, v)
+			continue
+		}
+
+		events = append(events, store.RulesetEvent{
+			Type:    store.RulesetDeleteEvent,
+			Path:    path,
+			Version: v.Version,
+		})
+	}
+	rs.versions = kept
+
+	if len(events) > 0 {
+		s.notify(events...)
+	}
+
+	return nil
+}
+
+// Watch the given prefix for anything new.
+// If revision is empty, it waits for the changes occurring after the call, otherwise it returns
+// the changes that occurred after the given revision, waiting for them if necessary.
+// Changes made to the files by other programs are detected by polling the directory.
+// It returns store.ErrRevisionCompacted if the events following the revision were dropped
+// or if the revision is unknown, e.g. if it was returned before the service restarted.
+func (s *RulesetService) Watch(ctx context.Context, prefix string, revision string) (*store.RulesetEvents, error) {
+	s.mu.Lock()
+	s.sync()
+
+	rev, _ := strconv.ParseInt(revision, 10, 64)
+	if rev <= 0 {
+		rev = s.revision
+	}
+	s.mu.Unlock()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.mu.Lock()
+		s.sync()
+
+		if rev < s.compactedRevision() || rev > s.revision {
+			s.mu.Unlock()
+			return nil, store.ErrRevisionCompacted
+		}
+
+		var events []store.RulesetEvent
+		i := sort.Search(len(s.events), func(i int) bool { return s.events[i].revision > rev })
+		for _, ev := range s.events[i:] {
+			if strings.HasPrefix(ev.Path, prefix) {
+				events = append(events, ev.RulesetEvent)
+			}
+		}
+
+		for i := range events {
+			if events[i].Ruleset == nil {
+				continue
+			}
+
+			cp, err := copyRuleset(events[i].Ruleset)
+			if err != nil {
+				s.mu.Unlock()
+				return nil, err
+			}
+
+			events[i].Ruleset = cp
+		}
+
+		revision := s.revision
+		s.mu.Unlock()
+
+		if len(events) > 0 {
+			return &store.RulesetEvents{
+				Events:   events,
+				Revision: strconv.FormatInt(revision, 10),
+			}, nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Eval evaluates a ruleset given a path and a set of parameters. It implements the regula.Evaluator interface.
+func (s *RulesetService) Eval(ctx context.Context, path string, params rule.Params) (*regula.EvalResult, error) {
+	return s.eval(path, "", params)
+}
+
+// EvalVersion evaluates a ruleset given a path and a set of parameters. It implements the regula.Evaluator interface.
+func (s *RulesetService) EvalVersion(ctx context.Context, path, version string, params rule.Params) (*regula.EvalResult, error) {
+	if version == "" {
+		return nil, regula.ErrRulesetNotFound
+	}
+
+	return s.eval(path, version, params)
+}
+
+func (s *RulesetService) eval(path, version string, params rule.Params) (*regula.EvalResult, error) {
+	re, sig, err := s.find(path, version)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, regula.ErrRulesetNotFound
+		}
+
+		return nil, err
+	}
+
+	if sig != nil {
+		params = sig.Params(params)
+	}
+
+	v, i, err := re.Ruleset.EvalRule(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &regula.EvalResult{
+		Value:   v,
+		Version: re.Version,
+		Rule:    &i,
+	}, nil
+}
+
+// sync scans the directory if the last scan is older than the interval.
+// Errors are logged, the known rulesets are kept as is until the next scan.
+// It must be called with the lock held.
+func (s *RulesetService) sync() {
+	if time.Since(s.lastScan) < s.interval {
+		return
+	}
+
+	err := s.scan()
+	if err != nil {
+		s.logger.Error().Err(err).Str("dir", s.root).Msg("failed to scan directory")
+	}
+}
+
+// scan reads the files that changed since the last scan and records the corresponding events.
+// Invalid files are ignored. A removed file soft deletes its ruleset.
+// It must be called with the lock held.
+func (s *RulesetService) scan() error {
+	s.lastScan = time.Now()
+
+	seen := make(map[string]bool)
+	var events []store.RulesetEvent
+
+	err := filepath.Walk(s.root, func(name string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// hidden files and directories are ignored, they hold the temporary files created by Put.
+		if name != s.root && strings.HasPrefix(fi.Name(), ".") {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if fi.IsDir() || filepath.Ext(name) != ext {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.root, name)
+		if err != nil {
+			return err
+		}
+		path := filepath.ToSlash(strings.TrimSuffix(rel, ext))
+		seen[path] = true
+
+		state := fileState{modTime: fi.ModTime(), size: fi.Size()}
+		if s.files[path] == state {
+			return nil
+		}
+		s.files[path] = state
+
+		ev, err := s.load(path, name, fi.ModTime())
+		if err != nil {
+			s.logger.Warn().Err(err).Str("file", name).Msg("ignoring invalid ruleset file")
+			return nil
+		}
+
+		if ev != nil {
+			events = append(events, *ev)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to read directory %s", s.root)
+	}
+
+	if len(events) > 0 {
+		s.notify(events...)
+	}
+
+	for path, rs := range s.rulesets {
+		if rs.latest != "" && !seen[path] {
+			delete(s.files, path)
+			s.remove(path, false)
+		}
+	}
+
+	return nil
+}
+
+// load reads the file of the given path and makes its content the latest version of the ruleset.
+// It returns nil if the content didn't change.
+func (s *RulesetService) load(path, name string, modTime time.Time) (*store.RulesetEvent, error) {
+	raw, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var ruleset regula.Ruleset
+	err = json.Unmarshal(raw, &ruleset)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode ruleset")
+	}
+
+	sig, err := store.ValidateRuleset(path, &ruleset)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := hash(&ruleset)
+	if err != nil {
+		return nil, err
+	}
+
+	rs, ok := s.rulesets[path]
+	if !ok {
+		rs = new(rulesetData)
+	}
+
+	if rs.latest == v {
+		return nil, nil
+	}
+
+	if rs.signature != nil {
+		err = rs.signature.MatchWith(sig)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		rs.signature = sig
+	}
+	s.rulesets[path] = rs
+
+	entry := s.add(rs, path, v, &ruleset, modTime)
+
+	return &store.RulesetEvent{
+		Type:    store.RulesetPutEvent,
+		Path:    path,
+		Version: entry.Version,
+		Ruleset: entry.Ruleset,
+	}, nil
+}
+
+// write replaces the file of the given path atomically.
+// It must be called with the lock held.
+func (s *RulesetService) write(path string, ruleset *regula.Ruleset) error {
+	raw, err := json.MarshalIndent(ruleset, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode ruleset")
+	}
+
+	name := s.filename(path)
+	err = os.MkdirAll(filepath.Dir(name), 0755)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create directory of %s", path)
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(name), ".regula-")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temporary file")
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(append(raw, '\n'))
+	if err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to write temporary file")
+	}
+
+	err = os.Rename(f.Name(), name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to write ruleset file of %s", path)
+	}
+
+	fi, err := os.Stat(name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read ruleset file of %s", path)
+	}
+
+	// the file is up to date, it doesn't need to be read by the next scan.
+	s.files[path] = fileState{modTime: fi.ModTime(), size: fi.Size()}
+
+	return nil
+}
+
+func (s *RulesetService) filename(path string) string {
+	return filepath.Join(s.root, filepath.FromSlash(path)+ext)
+}
+
+// notify records the given events under a new revision and drops the events of the revisions
+// that aren't retained anymore.
+// It must be called with the lock held.
+func (s *RulesetService) notify(events ...store.RulesetEvent) {
+	s.revision++
+	for _, ev := range events {
+		s.events = append(s.events, event{RulesetEvent: ev, revision: s.revision})
+	}
+
+	compacted := s.compactedRevision()
+	i := sort.Search(len(s.events), func(i int) bool { return s.events[i].revision > compacted })
+	s.events = s.events[i:]
+}
+
+// compactedRevision returns the last revision whose events are unknown.
+// It must be called with the lock held.
+func (s *RulesetService) compactedRevision() int64 {
+	if s.revision-s.base <= s.EventsRetention {
+		return s.base
+	}
+
+	return s.revision - s.EventsRetention
+}
+
+// key used to sort the entries by path, then by version.
+func key(path, version string) string {
+	return path + "\x00" + version
+}
+
+// hash of the content of a ruleset, used as version.
+// Rulesets are encoded before being hashed so that the formatting of the files doesn't matter.
+func hash(rs *regula.Ruleset) (string, error) {
+	raw, err := json.Marshal(rs)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encode ruleset")
+	}
+
+	h := sha1.Sum(raw)
+	return hex.EncodeToString(h[:]), nil
+}
+
+// copyRuleset returns a deep copy of the given ruleset.
+func copyRuleset(rs *regula.Ruleset) (*regula.Ruleset, error) {
+	raw, err := json.Marshal(rs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode ruleset")
+	}
+
+	var cp regula.Ruleset
+	err = json.Unmarshal(raw, &cp)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode ruleset")
+	}
+
+	return &cp, nil
+}
+
+// copyEntry returns a copy of the given entry so that the stored ruleset can't be modified by the caller.
+func copyEntry(e store.RulesetEntry) (*store.RulesetEntry, error) {
+	rs, err := copyRuleset(e.Ruleset)
+	if err != nil {
+		return nil, err
+	}
+
+	e.Ruleset = rs
+	return &e, nil
+}
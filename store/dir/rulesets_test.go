@@ -0,0 +1,163 @@
+package dir_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/heetch/regula"
+	"github.com/heetch/regula/rule"
+	"github.com/heetch/regula/store"
+	"github.com/heetch/regula/store/dir"
+	"github.com/heetch/regula/store/storetest"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ store.RulesetService = new(dir.RulesetService)
+	_ regula.Evaluator     = new(dir.RulesetService)
+)
+
+func newDirRulesetService(t *testing.T) (*dir.RulesetService, string, func()) {
+	t.Helper()
+
+	root, err := ioutil.TempDir("", "regula-dir-tests")
+	require.NoError(t, err)
+
+	s, err := dir.NewRulesetService(root, 10*time.Millisecond, zerolog.Nop())
+	require.NoError(t, err)
+
+	return s, root, func() {
+		os.RemoveAll(root)
+	}
+}
+
+func writeRuleset(t *testing.T, name string, rs *regula.Ruleset) {
+	t.Helper()
+
+	raw, err := json.Marshal(rs)
+	require.NoError(t, err)
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(name), 0755))
+	require.NoError(t, ioutil.WriteFile(name, raw, 0644))
+}
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (store.RulesetService, func()) {
+		s, _, cleanup := newDirRulesetService(t)
+		return s, cleanup
+	})
+}
+
+func TestFiles(t *testing.T) {
+	s, root, cleanup := newDirRulesetService(t)
+	defer cleanup()
+
+	rs, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(true)))
+
+	t.Run("Put", func(t *testing.T) {
+		entry, err := s.Put(context.Background(), "pricing/base", rs)
+		require.NoError(t, err)
+
+		raw, err := ioutil.ReadFile(filepath.Join(root, "pricing", "base.json"))
+		require.NoError(t, err)
+
+		var got regula.Ruleset
+		require.NoError(t, json.Unmarshal(raw, &got))
+		require.Equal(t, rs, &got)
+
+		// versions only depend on the content of the rulesets
+		other, err := dir.NewRulesetService(root, time.Millisecond, zerolog.Nop())
+		require.NoError(t, err)
+
+		latest, err := other.Latest(context.Background(), "pricing/base")
+		require.NoError(t, err)
+		require.Equal(t, entry.Version, latest.Version)
+	})
+
+	t.Run("Changes", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		list, err := s.List(ctx, "", store.ListOptions{})
+		require.NoError(t, err)
+
+		writeRuleset(t, filepath.Join(root, "pricing", "extra.json"), rs)
+
+		events, err := s.Watch(ctx, "pricing", list.Revision)
+		require.NoError(t, err)
+		require.Len(t, events.Events, 1)
+		require.Equal(t, store.RulesetPutEvent, events.Events[0].Type)
+		require.Equal(t, "pricing/extra", events.Events[0].Path)
+		require.Equal(t, rs, events.Events[0].Ruleset)
+
+		res, err := s.Eval(ctx, "pricing/extra", nil)
+		require.NoError(t, err)
+		require.Equal(t, rule.BoolValue(true), res.Value)
+
+		require.NoError(t, os.Remove(filepath.Join(root, "pricing", "extra.json")))
+
+		events, err = s.Watch(ctx, "pricing", events.Revision)
+		require.NoError(t, err)
+		require.Len(t, events.Events, 1)
+		require.Equal(t, store.RulesetDeleteEvent, events.Events[0].Type)
+		require.Equal(t, "pricing/extra", events.Events[0].Path)
+
+		_, err = s.Latest(ctx, "pricing/extra")
+		require.Equal(t, store.ErrNotFound, err)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(root, "broken.json"), []byte("{"), 0644))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(root, "README.md"), []byte("rulesets"), 0644))
+
+		// a file whose signature doesn't match the known one is ignored
+		bad, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("a")))
+		writeRuleset(t, filepath.Join(root, "pricing", "base.json"), bad)
+
+		time.Sleep(20 * time.Millisecond)
+
+		_, err := s.Latest(context.Background(), "broken")
+		require.Equal(t, store.ErrNotFound, err)
+
+		entry, err := s.Latest(context.Background(), "pricing/base")
+		require.NoError(t, err)
+		require.Equal(t, rs, entry.Ruleset)
+	})
+}
+
+func TestEventsRetention(t *testing.T) {
+	s, _, cleanup := newDirRulesetService(t)
+	defer cleanup()
+	s.EventsRetention = 2
+
+	storetest.RunEventsRetention(t, s, 2)
+}
+
+func TestRestart(t *testing.T) {
+	s, root, cleanup := newDirRulesetService(t)
+	defer cleanup()
+
+	rs, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(true)))
+	_, err := s.Put(context.Background(), "a", rs)
+	require.NoError(t, err)
+
+	list, err := s.List(context.Background(), "", store.ListOptions{})
+	require.NoError(t, err)
+
+	other, err := dir.NewRulesetService(root, time.Millisecond, zerolog.Nop())
+	require.NoError(t, err)
+	for _, path := range []string{"b", "c", "d"} {
+		_, err = other.Put(context.Background(), path, rs)
+		require.NoError(t, err)
+	}
+
+	// the changes made before the restart are unknown
+	_, err = other.Watch(context.Background(), "", list.Revision)
+	require.Equal(t, store.ErrRevisionCompacted, err)
+}
@@ -0,0 +1,92 @@
+package etcd
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/heetch/regula/store"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// NamespaceService manages namespaces using etcd.
+// The rulesets of a namespace are stored by a RulesetService using the name of the namespace,
+// relative to Root, as its etcd namespace. The namespaces themselves are registered under Root/_namespaces,
+// which can't conflict with a namespace since underscores are not allowed in their names.
+type NamespaceService struct {
+	Client *clientv3.Client
+	Logger zerolog.Logger
+	// Root is the etcd key under which the namespaces are stored, empty for the root of the key space.
+	Root string
+}
+
+// List returns the names of the namespaces, sorted.
+func (s *NamespaceService) List(ctx context.Context) ([]string, error) {
+	prefix := s.namespacesPath("") + "/"
+
+	resp, err := s.Client.KV.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch namespaces")
+	}
+
+	names := make([]string, len(resp.Kvs))
+	for i, kv := range resp.Kvs {
+		names[i] = strings.TrimPrefix(string(kv.Key), prefix)
+	}
+
+	return names, nil
+}
+
+// Create registers the given namespace. It returns a store.ValidationError if the name is invalid
+// and store.ErrNotModified if the namespace already exists.
+func (s *NamespaceService) Create(ctx context.Context, name string) error {
+	err := store.ValidateNamespace(name)
+	if err != nil {
+		return err
+	}
+
+	key := s.namespacesPath(name)
+
+	resp, err := s.Client.KV.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, name)).
+		Commit()
+	if err != nil {
+		return errors.Wrapf(err, "failed to create namespace: %s", name)
+	}
+
+	if !resp.Succeeded {
+		return store.ErrNotModified
+	}
+
+	return nil
+}
+
+// Rulesets returns the service managing the rulesets of the given namespace.
+// It returns store.ErrNotFound if the namespace doesn't exist.
+func (s *NamespaceService) Rulesets(ctx context.Context, name string) (store.RulesetService, error) {
+	if store.ValidateNamespace(name) != nil {
+		return nil, store.ErrNotFound
+	}
+
+	resp, err := s.Client.KV.Get(ctx, s.namespacesPath(name), clientv3.WithKeysOnly())
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch namespace: %s", name)
+	}
+
+	if resp.Count == 0 {
+		return nil, store.ErrNotFound
+	}
+
+	return &RulesetService{
+		Client:    s.Client,
+		Logger:    s.Logger.With().Str("namespace", name).Logger(),
+		Namespace: path.Join(s.Root, name),
+	}, nil
+}
+
+func (s *NamespaceService) namespacesPath(name string) string {
+	return path.Join(s.Root, "_namespaces", name)
+}
@@ -0,0 +1,36 @@
+package etcd_test
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/heetch/regula/store"
+	"github.com/heetch/regula/store/etcd"
+	"github.com/heetch/regula/store/storetest"
+	"github.com/stretchr/testify/require"
+)
+
+var _ store.NamespaceService = new(etcd.NamespaceService)
+
+func TestNamespaces(t *testing.T) {
+	storetest.RunNamespaces(t, func(t *testing.T) (store.NamespaceService, func()) {
+		cli, err := clientv3.New(clientv3.Config{
+			Endpoints:   endpoints,
+			DialTimeout: dialTimeout,
+		})
+		require.NoError(t, err)
+
+		s := etcd.NamespaceService{
+			Client: cli,
+			Root:   fmt.Sprintf("regula-namespaces-tests-%d", rand.Int()),
+		}
+
+		return &s, func() {
+			cli.Delete(context.Background(), s.Root+"/", clientv3.WithPrefix())
+			cli.Close()
+		}
+	})
+}
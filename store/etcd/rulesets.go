@@ -5,10 +5,9 @@ import (
 	"crypto/md5"
 	"encoding/base64"
 	"encoding/json"
-	"fmt"
 	"path"
-	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/coreos/etcd/clientv3"
 	"github.com/coreos/etcd/clientv3/concurrency"
@@ -28,16 +27,23 @@ type RulesetService struct {
 	Namespace string
 }
 
-// List returns all the rulesets entries under the given prefix.
-func (s *RulesetService) List(ctx context.Context, prefix string, limit int, continueToken string) (*store.RulesetEntries, error) {
+// List returns the rulesets entries under the given prefix.
+// If opt.LatestOnly is true, only the latest version of each ruleset is returned.
+func (s *RulesetService) List(ctx context.Context, prefix string, opt store.ListOptions) (*store.RulesetEntries, error) {
 	options := make([]clientv3.OpOption, 0, 2)
 
 	var key string
 
-	if limit < 0 || limit > 100 {
-		limit = 50 // TODO(asdine): make this configurable in future releases.
+	limit := opt.Limit
+	if limit != 0 {
+		limit = store.NormalizeLimit(limit)
 	}
 
+	if opt.LatestOnly {
+		return s.listLatest(ctx, prefix, limit, opt.ContinueToken)
+	}
+
+	continueToken := opt.ContinueToken
 	if continueToken != "" {
 		lastPath, err := base64.URLEncoding.DecodeString(continueToken)
 		if err != nil {
@@ -89,6 +95,86 @@ func (s *RulesetService) List(ctx context.Context, prefix string, limit int, con
 	return &entries, nil
 }
 
+// listLatest returns the latest version of the rulesets stored under the given prefix
+// by reading the latest pointers, then the entries they point to at the same revision.
+func (s *RulesetService) listLatest(ctx context.Context, prefix string, limit int, continueToken string) (*store.RulesetEntries, error) {
+	options := []clientv3.OpOption{clientv3.WithLimit(int64(limit))}
+
+	key := s.latestRulesetPath(prefix)
+	if continueToken != "" {
+		lastPath, err := base64.URLEncoding.DecodeString(continueToken)
+		if err != nil {
+			return nil, store.ErrInvalidContinueToken
+		}
+
+		options = append(options, clientv3.WithRange(clientv3.GetPrefixRangeEnd(key)))
+		key = s.latestRulesetPath(string(lastPath))
+	} else {
+		options = append(options, clientv3.WithPrefix())
+	}
+
+	resp, err := s.Client.KV.Get(ctx, key, options...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch latest entries")
+	}
+
+	// if a prefix is provided it must always return results
+	// otherwise it doesn't exist.
+	if resp.Count == 0 && prefix != "" {
+		return nil, store.ErrNotFound
+	}
+
+	var entries store.RulesetEntries
+	entries.Revision = strconv.FormatInt(resp.Header.Revision, 10)
+	entries.Entries = make([]store.RulesetEntry, 0, len(resp.Kvs))
+
+	// the entries are fetched in batches to stay below the maximum number of operations per transaction.
+	for kvs := resp.Kvs; len(kvs) > 0; {
+		n := len(kvs)
+		if n > 100 {
+			n = 100
+		}
+
+		ops := make([]clientv3.Op, n)
+		for i, kv := range kvs[:n] {
+			ops[i] = clientv3.OpGet(string(kv.Value), clientv3.WithRev(resp.Header.Revision))
+		}
+		kvs = kvs[n:]
+
+		tresp, err := s.Client.KV.Txn(ctx).Then(ops...).Commit()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to fetch latest entries")
+		}
+
+		for _, r := range tresp.Responses {
+			rr := r.GetResponseRange()
+			if len(rr.Kvs) == 0 {
+				continue
+			}
+
+			var entry store.RulesetEntry
+			err = json.Unmarshal(rr.Kvs[0].Value, &entry)
+			if err != nil {
+				s.Logger.Debug().Err(err).Bytes("entry", rr.Kvs[0].Value).Msg("list: unmarshalling failed")
+				return nil, errors.Wrap(err, "failed to unmarshal entry")
+			}
+
+			entries.Entries = append(entries.Entries, entry)
+		}
+	}
+
+	if len(resp.Kvs) < limit || !resp.More {
+		return &entries, nil
+	}
+
+	lastPath := strings.TrimPrefix(string(resp.Kvs[len(resp.Kvs)-1].Key), s.latestRulesetPath("")+"/")
+
+	// we want to start immediately after the last key
+	entries.Continue = base64.URLEncoding.EncodeToString([]byte(lastPath + "\x00"))
+
+	return &entries, nil
+}
+
 // Latest returns the latest version of the ruleset entry which corresponds to the given path.
 // It returns store.ErrNotFound if the path doesn't exist or if it's not a ruleset.
 func (s *RulesetService) Latest(ctx context.Context, path string) (*store.RulesetEntry, error) {
@@ -148,217 +234,889 @@ func (s *RulesetService) OneByVersion(ctx context.Context, path, version string)
 	return &entry, nil
 }
 
-// Put adds a version of the given ruleset using an uuid.
-func (s *RulesetService) Put(ctx context.Context, path string, ruleset *regula.Ruleset) (*store.RulesetEntry, error) {
-	sig, err := validateRuleset(path, ruleset)
-	if err != nil {
-		return nil, err
+// Versions returns the versions of the ruleset stored on the given path, newest first.
+// Versions are ordered by the revision that created them since ksuids aren't ordered within the same second,
+// their creation time is extracted from them though.
+// It returns store.ErrNotFound if the path doesn't exist or if it's not a ruleset.
+func (s *RulesetService) Versions(ctx context.Context, path string, limit int, continueToken string) (*store.RulesetVersions, error) {
+	if path == "" {
+		return nil, store.ErrNotFound
 	}
 
-	var entry store.RulesetEntry
+	limit = store.NormalizeLimit(limit)
 
-	txfn := func(stm concurrency.STM) error {
-		// generate a checksum from the ruleset for comparison purpose
-		h := md5.New()
-		err = json.NewEncoder(h).Encode(ruleset)
+	// the trailing slash prevents matching paths starting with the same characters,
+	// keys of sub paths still need to be filtered out though.
+	prefix := s.rulesetsPath(path, "") + "/"
+
+	// entries are rewritten by rollbacks, only their create revision is stable.
+	// The token holds the create revision of the last returned version.
+	var maxRev int64
+	if continueToken != "" {
+		raw, err := base64.URLEncoding.DecodeString(continueToken)
 		if err != nil {
-			return errors.Wrap(err, "failed to generate checksum")
+			return nil, store.ErrInvalidContinueToken
+		}
+
+		rev, err := strconv.ParseInt(string(raw), 10, 64)
+		if err != nil || rev <= 1 {
+			return nil, store.ErrInvalidContinueToken
+		}
+
+		maxRev = rev - 1
+	}
+
+	versions := store.RulesetVersions{
+		Path: path,
+	}
+
+	for {
+		opts := []clientv3.OpOption{
+			clientv3.WithPrefix(),
+			clientv3.WithKeysOnly(),
+			clientv3.WithSort(clientv3.SortByCreateRevision, clientv3.SortDescend),
+			clientv3.WithLimit(int64(limit)),
 		}
-		checksum := string(h.Sum(nil))
+		if maxRev > 0 {
+			opts = append(opts, clientv3.WithMaxCreateRev(maxRev))
+		}
+
+		resp, err := s.Client.KV.Get(ctx, prefix, opts...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to fetch versions: %s", path)
+		}
+
+		for i, kv := range resp.Kvs {
+			maxRev = kv.CreateRevision - 1
 
-		// if nothing changed return latest ruleset
-		if stm.Get(s.checksumsPath(path)) == checksum {
-			v := stm.Get(stm.Get(s.latestRulesetPath(path)))
+			version := strings.TrimPrefix(string(kv.Key), prefix)
+			if strings.Contains(version, "/") {
+				// the key belongs to a sub path.
+				continue
+			}
 
-			err = json.Unmarshal([]byte(v), &entry)
+			k, err := ksuid.Parse(version)
 			if err != nil {
-				s.Logger.Debug().Err(err).Str("entry", v).Msg("put: entry unmarshalling failed")
-				return errors.Wrap(err, "failed to unmarshal entry")
+				return nil, errors.Wrapf(err, "failed to parse version: %s", version)
 			}
 
-			return store.ErrNotModified
+			versions.Versions = append(versions.Versions, store.RulesetVersion{
+				Version:   version,
+				CreatedAt: k.Time(),
+			})
+
+			if len(versions.Versions) == limit {
+				if i < len(resp.Kvs)-1 || resp.More {
+					versions.Continue = base64.URLEncoding.EncodeToString([]byte(strconv.FormatInt(kv.CreateRevision, 10)))
+				}
+
+				return &versions, nil
+			}
 		}
 
-		// make sure signature didn't change
-		rawSig := stm.Get(s.signaturesPath(path))
-		if rawSig != "" {
-			var curSig signature
-			err := json.Unmarshal([]byte(rawSig), &curSig)
-			if err != nil {
-				s.Logger.Debug().Err(err).Str("signature", rawSig).Msg("put: signature unmarshalling failed")
-				return errors.Wrap(err, "failed to decode ruleset signature")
+		if !resp.More {
+			break
+		}
+	}
+
+	if len(versions.Versions) == 0 && continueToken == "" {
+		return nil, store.ErrNotFound
+	}
+
+	return &versions, nil
+}
+
+// History returns the changes made to the ruleset stored on the given path, newest first.
+// The entries are stored under unique keys and returned in the order of their etcd revision.
+// The history survives deletes.
+// It returns store.ErrNotFound if nothing was recorded for the path.
+func (s *RulesetService) History(ctx context.Context, path string, limit int, continueToken string) (*store.AuditEntries, error) {
+	if path == "" {
+		return nil, store.ErrNotFound
+	}
+
+	limit = store.NormalizeLimit(limit)
+
+	// the trailing slash prevents matching paths starting with the same characters,
+	// keys of sub paths still need to be filtered out though.
+	prefix := s.auditPath(path, "") + "/"
+
+	// audit keys are never modified, their mod revision orders the changes.
+	// The token holds the revision of the last returned entry.
+	var maxRev int64
+	if continueToken != "" {
+		raw, err := base64.URLEncoding.DecodeString(continueToken)
+		if err != nil {
+			return nil, store.ErrInvalidContinueToken
+		}
+
+		rev, err := strconv.ParseInt(string(raw), 10, 64)
+		if err != nil || rev <= 1 {
+			return nil, store.ErrInvalidContinueToken
+		}
+
+		maxRev = rev - 1
+	}
+
+	history := store.AuditEntries{
+		Path: path,
+	}
+
+	for {
+		opts := []clientv3.OpOption{
+			clientv3.WithPrefix(),
+			clientv3.WithSort(clientv3.SortByModRevision, clientv3.SortDescend),
+			clientv3.WithLimit(int64(limit)),
+		}
+		if maxRev > 0 {
+			opts = append(opts, clientv3.WithMaxModRev(maxRev))
+		}
+
+		resp, err := s.Client.KV.Get(ctx, prefix, opts...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to fetch history: %s", path)
+		}
+
+		for i, kv := range resp.Kvs {
+			maxRev = kv.ModRevision - 1
+
+			if strings.Contains(strings.TrimPrefix(string(kv.Key), prefix), "/") {
+				// the key belongs to a sub path.
+				continue
 			}
 
-			err = curSig.matchWith(sig)
+			var entry store.AuditEntry
+			err := json.Unmarshal(kv.Value, &entry)
 			if err != nil {
-				return err
+				s.Logger.Debug().Err(err).Bytes("entry", kv.Value).Msg("history: entry unmarshalling failed")
+				return nil, errors.Wrap(err, "failed to unmarshal audit entry")
+			}
+
+			history.Entries = append(history.Entries, entry)
+
+			if len(history.Entries) == limit {
+				if i < len(resp.Kvs)-1 || resp.More {
+					history.Continue = base64.URLEncoding.EncodeToString([]byte(strconv.FormatInt(kv.ModRevision, 10)))
+				}
+
+				return &history, nil
 			}
 		}
 
-		// if no signature found, create one
-		if rawSig == "" {
-			v, err := json.Marshal(&sig)
+		if !resp.More {
+			break
+		}
+	}
+
+	if len(history.Entries) == 0 && continueToken == "" {
+		return nil, store.ErrNotFound
+	}
+
+	return &history, nil
+}
+
+// Put adds a version of the given ruleset using an uuid.
+// The rules are validated against the schema of the ruleset, if any, and its test cases are run
+// before the version is created. If any of these checks fails a store.ValidationError is returned.
+func (s *RulesetService) Put(ctx context.Context, path string, ruleset *regula.Ruleset, opts ...store.PutOption) (*store.RulesetEntry, error) {
+	sig, err := store.ValidateRuleset(path, ruleset)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := store.NewPutOptions(opts...).ExpectedVersion
+
+	var entry store.RulesetEntry
+
+	txfn := func(stm concurrency.STM) error {
+		return s.put(ctx, stm, path, ruleset, sig, expected, &entry)
+	}
+
+	_, err = concurrency.NewSTM(s.Client, txfn, concurrency.WithAbortContext(ctx))
+	if err != nil && err != store.ErrNotModified && err != store.ErrVersionMismatch && !store.IsValidationError(err) {
+		return nil, errors.Wrap(err, "failed to put ruleset")
+	}
+
+	if err == store.ErrVersionMismatch && entry.Version == "" {
+		return nil, err
+	}
+
+	return &entry, err
+}
+
+// PutBatch stores a version of every given ruleset in a single transaction, producing a single revision
+// and thus a single watch response. Unchanged rulesets are left untouched.
+// If one of the rulesets is invalid, a store.ValidationError is returned and nothing is stored.
+func (s *RulesetService) PutBatch(ctx context.Context, entries []store.RulesetEntry) ([]store.RulesetEntry, error) {
+	sigs, err := store.ValidateBatch(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		res     []store.RulesetEntry
+		changed bool
+	)
+
+	txfn := func(stm concurrency.STM) error {
+		// the function is called again if the transaction is retried.
+		res = make([]store.RulesetEntry, len(entries))
+		changed = false
+
+		for i, e := range entries {
+			err := s.put(ctx, stm, e.Path, e.Ruleset, sigs[i], "", &res[i])
+			if err == store.ErrNotModified {
+				continue
+			}
 			if err != nil {
-				return errors.Wrap(err, "failed to encode updated signature")
+				return err
 			}
 
-			stm.Put(s.signaturesPath(path), string(v))
+			changed = true
+		}
+
+		return nil
+	}
+
+	_, err = concurrency.NewSTM(s.Client, txfn, concurrency.WithAbortContext(ctx))
+	if err != nil {
+		if store.IsValidationError(err) {
+			return nil, err
+		}
+
+		return nil, errors.Wrap(err, "failed to put rulesets")
+	}
+
+	if !changed {
+		return res, store.ErrNotModified
+	}
+
+	return res, nil
+}
+
+// put creates a new version of the ruleset within the given transaction and stores it in entry.
+// If expected is not empty, it must be the latest version of the ruleset.
+// If the ruleset didn't change, the latest entry is stored in entry and store.ErrNotModified is returned.
+func (s *RulesetService) put(ctx context.Context, stm concurrency.STM, path string, ruleset *regula.Ruleset, sig *store.Signature, expected string, entry *store.RulesetEntry) error {
+	// generate a checksum from the ruleset for comparison purpose
+	h := md5.New()
+	err := json.NewEncoder(h).Encode(ruleset)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate checksum")
+	}
+	checksum := string(h.Sum(nil))
+
+	latest := s.latestVersion(stm, path)
+
+	// make sure the ruleset didn't change since the caller read it
+	if expected != "" && expected != latest {
+		if latest == "" {
+			return store.ErrVersionMismatch
+		}
+
+		raw := stm.Get(s.rulesetsPath(path, latest))
+		err = json.Unmarshal([]byte(raw), entry)
+		if err != nil {
+			s.Logger.Debug().Err(err).Str("entry", raw).Msg("put: entry unmarshalling failed")
+			return errors.Wrap(err, "failed to unmarshal entry")
 		}
 
-		// update checksum
-		stm.Put(s.checksumsPath(path), checksum)
+		return store.ErrVersionMismatch
+	}
+
+	// if nothing changed return latest ruleset
+	if stm.Get(s.checksumsPath(path)) == checksum {
+		v := stm.Get(stm.Get(s.latestRulesetPath(path)))
+
+		err = json.Unmarshal([]byte(v), entry)
+		if err != nil {
+			s.Logger.Debug().Err(err).Str("entry", v).Msg("put: entry unmarshalling failed")
+			return errors.Wrap(err, "failed to unmarshal entry")
+		}
+
+		return store.ErrNotModified
+	}
 
-		// create a new ruleset version
-		k, err := ksuid.NewRandom()
+	// make sure signature didn't change
+	rawSig := stm.Get(s.signaturesPath(path))
+	if rawSig != "" {
+		var curSig store.Signature
+		err := json.Unmarshal([]byte(rawSig), &curSig)
 		if err != nil {
-			return errors.Wrap(err, "failed to generate ruleset version")
+			s.Logger.Debug().Err(err).Str("signature", rawSig).Msg("put: signature unmarshalling failed")
+			return errors.Wrap(err, "failed to decode ruleset signature")
 		}
-		version := k.String()
 
-		re := store.RulesetEntry{
-			Path:    path,
-			Version: version,
-			Ruleset: ruleset,
+		err = curSig.MatchWith(sig)
+		if err != nil {
+			return err
 		}
+	}
 
-		raw, err := json.Marshal(&re)
+	// if no signature found, create one
+	if rawSig == "" {
+		v, err := json.Marshal(&sig)
 		if err != nil {
-			return errors.Wrap(err, "failed to encode entry")
+			return errors.Wrap(err, "failed to encode updated signature")
 		}
 
-		stm.Put(s.rulesetsPath(path, version), string(raw))
+		stm.Put(s.signaturesPath(path), string(v))
+	}
+
+	// update checksum
+	stm.Put(s.checksumsPath(path), checksum)
+
+	// create a new ruleset version
+	k, err := ksuid.NewRandom()
+	if err != nil {
+		return errors.Wrap(err, "failed to generate ruleset version")
+	}
+	version := k.String()
+
+	re := store.RulesetEntry{
+		Path:    path,
+		Version: version,
+		Ruleset: ruleset,
+	}
+
+	raw, err := json.Marshal(&re)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode entry")
+	}
+
+	stm.Put(s.rulesetsPath(path, version), string(raw))
+
+	// record the change
+	err = s.audit(stm, store.NewAuditEntry(ctx, store.AuditPut, path, version, latest))
+	if err != nil {
+		return err
+	}
+
+	// update the pointer to the latest ruleset
+	stm.Put(s.latestRulesetPath(path), s.rulesetsPath(path, version))
+
+	*entry = re
+	return nil
+}
+
+// Rollback makes the given version the latest version of the ruleset stored on the given path.
+// The entry of the version is rewritten as is so that watchers are notified of the change.
+// It returns store.ErrNotFound if the version doesn't exist or if the ruleset is being deleted,
+// and store.ErrNotModified if it is already the latest.
+func (s *RulesetService) Rollback(ctx context.Context, path, version string) (*store.RulesetEntry, error) {
+	if path == "" || version == "" {
+		return nil, store.ErrNotFound
+	}
+
+	var entry store.RulesetEntry
+
+	txfn := func(stm concurrency.STM) error {
+		key := s.rulesetsPath(path, version)
+
+		// the version may be about to be removed by Delete
+		if stm.Get(s.tombstonesPath(path)) != "" {
+			return store.ErrNotFound
+		}
+
+		v := stm.Get(key)
+		if v == "" {
+			return store.ErrNotFound
+		}
+
+		err := json.Unmarshal([]byte(v), &entry)
+		if err != nil {
+			s.Logger.Debug().Err(err).Str("entry", v).Msg("rollback: entry unmarshalling failed")
+			return errors.Wrap(err, "failed to unmarshal entry")
+		}
+
+		latest := s.latestVersion(stm, path)
+		if latest == version {
+			return store.ErrNotModified
+		}
+
+		// the checksum must match the latest ruleset for put to detect changes correctly
+		h := md5.New()
+		err = json.NewEncoder(h).Encode(entry.Ruleset)
+		if err != nil {
+			return errors.Wrap(err, "failed to generate checksum")
+		}
+		stm.Put(s.checksumsPath(path), string(h.Sum(nil)))
+
+		// rewrite the entry to generate a new event
+		stm.Put(key, v)
 
 		// update the pointer to the latest ruleset
-		stm.Put(s.latestRulesetPath(path), s.rulesetsPath(path, version))
+		stm.Put(s.latestRulesetPath(path), key)
 
-		entry = re
-		return nil
+		return s.audit(stm, store.NewAuditEntry(ctx, store.AuditRollback, path, version, latest))
 	}
 
-	_, err = concurrency.NewSTM(s.Client, txfn, concurrency.WithAbortContext(ctx))
-	if err != nil && err != store.ErrNotModified && !store.IsValidationError(err) {
-		return nil, errors.Wrap(err, "failed to put ruleset")
+	_, err := concurrency.NewSTM(s.Client, txfn, concurrency.WithAbortContext(ctx))
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, err
+		}
+
+		if err == store.ErrNotModified {
+			return &entry, err
+		}
+
+		return nil, errors.Wrap(err, "failed to rollback ruleset")
 	}
 
-	return &entry, err
+	return &entry, nil
 }
 
-type signature struct {
-	ReturnType string
-	ParamTypes map[string]string
+// Signatures returns the history of the signatures of the ruleset stored on the given path, the current one last.
+// It returns store.ErrNotFound if the path has no signature.
+func (s *RulesetService) Signatures(ctx context.Context, path string) ([]store.Signature, error) {
+	if path == "" {
+		return nil, store.ErrNotFound
+	}
+
+	resp, err := s.Client.KV.Txn(ctx).Then(
+		clientv3.OpGet(s.signaturesPath(path)),
+		clientv3.OpGet(s.signaturesHistoryPath(path)),
+	).Commit()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch signatures: %s", path)
+	}
+
+	cur, history := resp.Responses[0].GetResponseRange(), resp.Responses[1].GetResponseRange()
+	if cur.Count == 0 {
+		return nil, store.ErrNotFound
+	}
+
+	// the history is only created by the first migration
+	if history.Count == 0 {
+		var sig store.Signature
+		err = json.Unmarshal(cur.Kvs[0].Value, &sig)
+		if err != nil {
+			s.Logger.Debug().Err(err).Bytes("signature", cur.Kvs[0].Value).Msg("signatures: unmarshalling failed")
+			return nil, errors.Wrap(err, "failed to decode ruleset signature")
+		}
+
+		return []store.Signature{sig}, nil
+	}
+
+	var sigs []store.Signature
+	err = json.Unmarshal(history.Kvs[0].Value, &sigs)
+	if err != nil {
+		s.Logger.Debug().Err(err).Bytes("history", history.Kvs[0].Value).Msg("signatures: unmarshalling failed")
+		return nil, errors.Wrap(err, "failed to decode signatures history")
+	}
+
+	return sigs, nil
 }
 
-func newSignature(rs *regula.Ruleset) *signature {
-	pt := make(map[string]string)
-	for _, p := range rs.Params() {
-		pt[p.Name] = p.Type
+// MigrateSignature applies the given migration to the signature of the ruleset stored on the given path
+// and records the new signature in the history.
+// All the existing versions of the ruleset must remain evaluable with the new signature, otherwise a store.ValidationError is returned.
+// It returns store.ErrNotFound if the path has no signature.
+//
+// Rulesets can have more versions than a single transaction can read: the versions are checked in chunks
+// at the revision of the signature and the new signature is only committed if neither the signature nor the latest
+// version pointer, which is updated by every put, changed since then. Otherwise the migration is retried.
+func (s *RulesetService) MigrateSignature(ctx context.Context, path string, m *store.SignatureMigration) (*store.Signature, error) {
+	if path == "" {
+		return nil, store.ErrNotFound
+	}
+
+	params := make([]rule.Param, 0, len(m.AddParams))
+	for name, tp := range m.AddParams {
+		params = append(params, rule.Param{Name: name, Type: tp})
+	}
+	err := store.ValidateParamNames(params)
+	if err != nil {
+		return nil, err
 	}
 
-	return &signature{
-		ParamTypes: pt,
-		ReturnType: rs.Type,
+	for {
+		sig, ok, err := s.migrateSignature(ctx, path, m)
+		if err != nil || ok {
+			return sig, err
+		}
 	}
 }
 
-func (s *signature) matchWith(other *signature) error {
-	if s.ReturnType != other.ReturnType {
-		return &store.ValidationError{
-			Field:  "return type",
-			Value:  other.ReturnType,
-			Reason: fmt.Sprintf("signature mismatch: return type must be of type %s", s.ReturnType),
+// migrateSignature makes one attempt at migrating the signature of the ruleset stored on the given path.
+// It returns false if the ruleset changed while the versions were checked.
+func (s *RulesetService) migrateSignature(ctx context.Context, path string, m *store.SignatureMigration) (*store.Signature, bool, error) {
+	resp, err := s.Client.KV.Get(ctx, s.signaturesPath(path))
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "failed to fetch signature: %s", path)
+	}
+	if resp.Count == 0 {
+		return nil, false, store.ErrNotFound
+	}
+	rev, sigRev := resp.Header.Revision, resp.Kvs[0].ModRevision
+
+	var curSig store.Signature
+	err = json.Unmarshal(resp.Kvs[0].Value, &curSig)
+	if err != nil {
+		s.Logger.Debug().Err(err).Bytes("signature", resp.Kvs[0].Value).Msg("migrate-signature: signature unmarshalling failed")
+		return nil, false, errors.Wrap(err, "failed to decode ruleset signature")
+	}
+
+	sig, err := curSig.Migrate(m)
+	if err != nil {
+		return nil, false, err
+	}
+
+	err = s.evaluable(ctx, path, sig, rev)
+	if err != nil {
+		return nil, false, err
+	}
+
+	resp, err = s.Client.KV.Get(ctx, s.latestRulesetPath(path), clientv3.WithRev(rev))
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "failed to fetch latest version: %s", path)
+	}
+	var latest string
+	var latestRev int64
+	if resp.Count > 0 {
+		latest = strings.TrimPrefix(string(resp.Kvs[0].Value), s.rulesetsPath(path, "")+"/")
+		latestRev = resp.Kvs[0].ModRevision
+	}
+
+	resp, err = s.Client.KV.Get(ctx, s.signaturesHistoryPath(path), clientv3.WithRev(rev))
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "failed to fetch signatures history: %s", path)
+	}
+	var history []store.Signature
+	if resp.Count > 0 {
+		err = json.Unmarshal(resp.Kvs[0].Value, &history)
+		if err != nil {
+			s.Logger.Debug().Err(err).Bytes("history", resp.Kvs[0].Value).Msg("migrate-signature: history unmarshalling failed")
+			return nil, false, errors.Wrap(err, "failed to decode signatures history")
 		}
+	} else {
+		history = append(history, curSig)
 	}
+	history = append(history, *sig)
 
-	for name, tp := range other.ParamTypes {
-		stp, ok := s.ParamTypes[name]
-		if !ok {
-			return &store.ValidationError{
-				Field:  "param",
-				Value:  name,
-				Reason: "signature mismatch: unknown parameter",
-			}
+	rawSig, err := json.Marshal(sig)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to encode updated signature")
+	}
+
+	rawHistory, err := json.Marshal(history)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to encode signatures history")
+	}
+
+	auditKey, rawAudit, err := s.auditEntry(store.NewAuditEntry(ctx, store.AuditMigrateSignature, path, latest, latest))
+	if err != nil {
+		return nil, false, err
+	}
+
+	// the mod revision of a missing key is 0.
+	txn, err := s.Client.KV.Txn(ctx).
+		If(
+			clientv3.Compare(clientv3.ModRevision(s.signaturesPath(path)), "=", sigRev),
+			clientv3.Compare(clientv3.ModRevision(s.latestRulesetPath(path)), "=", latestRev),
+		).
+		Then(
+			clientv3.OpPut(s.signaturesPath(path), string(rawSig)),
+			clientv3.OpPut(s.signaturesHistoryPath(path), string(rawHistory)),
+			clientv3.OpPut(auditKey, rawAudit),
+		).
+		Commit()
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to migrate signature")
+	}
+
+	return sig, txn.Succeeded, nil
+}
+
+// evaluable checks that all the versions of the ruleset stored on the given path at the given revision
+// can be evaluated with the signature, reading them deleteChunkSize at a time.
+func (s *RulesetService) evaluable(ctx context.Context, path string, sig *store.Signature, rev int64) error {
+	prefix := s.rulesetsPath(path, "") + "/"
+	from, end := prefix, clientv3.GetPrefixRangeEnd(prefix)
+
+	for {
+		resp, err := s.Client.KV.Get(ctx, from,
+			clientv3.WithRange(end),
+			clientv3.WithRev(rev),
+			clientv3.WithLimit(deleteChunkSize),
+		)
+		if err != nil {
+			return errors.Wrapf(err, "failed to fetch versions: %s", path)
 		}
 
-		if tp != stp {
-			return &store.ValidationError{
-				Field:  "param type",
-				Value:  tp,
-				Reason: fmt.Sprintf("signature mismatch: param must be of type %s", stp),
+		for _, kv := range resp.Kvs {
+			from = string(kv.Key) + "\x00"
+
+			version := strings.TrimPrefix(string(kv.Key), prefix)
+			if strings.Contains(version, "/") {
+				// the key belongs to a sub path.
+				continue
+			}
+
+			var entry store.RulesetEntry
+			err = json.Unmarshal(kv.Value, &entry)
+			if err != nil {
+				s.Logger.Debug().Err(err).Bytes("entry", kv.Value).Msg("migrate-signature: entry unmarshalling failed")
+				return errors.Wrap(err, "failed to unmarshal entry")
+			}
+
+			err = sig.Evaluable(entry.Ruleset)
+			if err != nil {
+				return &store.ValidationError{
+					Field:  "version",
+					Value:  version,
+					Reason: err.Error(),
+				}
 			}
 		}
+
+		if !resp.More {
+			return nil
+		}
 	}
+}
 
-	return nil
+// signature returns the current signature of the ruleset stored on the given path.
+func (s *RulesetService) signature(ctx context.Context, path string) (*store.Signature, error) {
+	resp, err := s.Client.KV.Get(ctx, s.signaturesPath(path))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch signature: %s", path)
+	}
+
+	var sig store.Signature
+	if resp.Count == 0 {
+		return &sig, nil
+	}
+
+	err = json.Unmarshal(resp.Kvs[0].Value, &sig)
+	if err != nil {
+		s.Logger.Debug().Err(err).Bytes("signature", resp.Kvs[0].Value).Msg("signature: unmarshalling failed")
+		return nil, errors.Wrap(err, "failed to decode ruleset signature")
+	}
+
+	return &sig, nil
+}
+
+// deleteChunkSize is the number of versions removed per transaction by Delete, or read per request
+// by MigrateSignature, to stay below the maximum number of operations per etcd transaction.
+const deleteChunkSize = 32
+
+// tombstone marks a ruleset whose versions are being removed by Delete.
+type tombstone struct {
+	Hard bool `json:"hard"`
 }
 
-func validateRuleset(path string, rs *regula.Ruleset) (*signature, error) {
-	err := validateRulesetName(path)
+// Delete removes the ruleset stored on the given path.
+// A soft delete moves all the versions of the ruleset out of the entries and removes its latest version
+// pointer and its checksum, the signature is kept so that a new ruleset created on the same path remains compatible.
+// A hard delete removes all the versions, including the soft deleted ones, the checksum and the signature.
+// Both record the deletion in the history of the ruleset, which is never removed.
+// Every removed version generates a delete event. It returns store.ErrNotFound if the path doesn't exist.
+//
+// Rulesets can have more versions than a single transaction can hold: the deletion starts by writing a tombstone
+// and removing the latest version pointer in one transaction, the versions that existed at that revision are then
+// removed in chunks and the tombstone is removed last. Versions created in the meantime belong to a new ruleset
+// and are kept. If the deletion is interrupted, the next call to Delete on the same path finishes it first.
+func (s *RulesetService) Delete(ctx context.Context, path string, hard bool) error {
+	if path == "" {
+		return store.ErrNotFound
+	}
+
+	err := s.resumeDelete(ctx, path)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	sig := newSignature(rs)
+	txfn := func(stm concurrency.STM) error {
+		// the latest pointer is updated by every put and rollback,
+		// reading it makes sure the transaction is retried if the ruleset changes.
+		// The signature is only removed by hard deletes, soft deleted rulesets can still be hard deleted.
+		latest := s.latestVersion(stm, path)
+		if latest == "" && (!hard || stm.Get(s.signaturesPath(path)) == "") {
+			return store.ErrNotFound
+		}
 
-	for _, r := range rs.Rules {
-		params := r.Params()
-		err = validateParamNames(params)
+		raw, err := json.Marshal(&tombstone{Hard: hard})
 		if err != nil {
-			return nil, err
+			return errors.Wrap(err, "failed to encode tombstone")
+		}
+		stm.Put(s.tombstonesPath(path), string(raw))
+
+		stm.Del(s.latestRulesetPath(path))
+		stm.Del(s.checksumsPath(path))
+
+		action := store.AuditDelete
+		if hard {
+			action = store.AuditHardDelete
+			stm.Del(s.signaturesPath(path))
+			stm.Del(s.signaturesHistoryPath(path))
+		}
+
+		return s.audit(stm, store.NewAuditEntry(ctx, action, path, "", latest))
+	}
+
+	resp, err := concurrency.NewSTM(s.Client, txfn, concurrency.WithAbortContext(ctx))
+	if err != nil {
+		if err == store.ErrNotFound {
+			return err
 		}
+
+		return errors.Wrap(err, "failed to delete ruleset")
+	}
+
+	return s.purge(ctx, path, hard, resp.Header.Revision)
+}
+
+// resumeDelete finishes the deletion of the ruleset stored on the given path if it was interrupted.
+func (s *RulesetService) resumeDelete(ctx context.Context, path string) error {
+	resp, err := s.Client.KV.Get(ctx, s.tombstonesPath(path))
+	if err != nil {
+		return errors.Wrapf(err, "failed to fetch tombstone: %s", path)
+	}
+
+	if resp.Count == 0 {
+		return nil
 	}
 
-	return sig, nil
+	var t tombstone
+	err = json.Unmarshal(resp.Kvs[0].Value, &t)
+	if err != nil {
+		s.Logger.Debug().Err(err).Bytes("tombstone", resp.Kvs[0].Value).Msg("delete: tombstone unmarshalling failed")
+		return errors.Wrap(err, "failed to decode tombstone")
+	}
+
+	return s.purge(ctx, path, t.Hard, resp.Kvs[0].ModRevision)
 }
 
-// regex used to validate ruleset names.
-var rgxRuleset = regexp.MustCompile(`^[a-z]+(?:[a-z0-9-\/]?[a-z0-9])*$`)
+// purge removes, in chunks, the versions of the ruleset stored on the given path that were created
+// before the tombstone written at the given revision, then removes the tombstone.
+// Soft deleted versions are moved aside, unless hard is true in which case the versions deleted
+// by previous soft deletes are removed as well.
+func (s *RulesetService) purge(ctx context.Context, path string, hard bool, rev int64) error {
+	err := s.purgeVersions(ctx, path, s.rulesetsPath(path, ""), rev, !hard)
+	if err != nil {
+		return err
+	}
 
-func validateRulesetName(path string) error {
-	if !rgxRuleset.MatchString(path) {
-		return &store.ValidationError{
-			Field:  "path",
-			Value:  path,
-			Reason: "invalid format",
+	if hard {
+		err = s.purgeVersions(ctx, path, s.deletedRulesetsPath(path, ""), rev, false)
+		if err != nil {
+			return err
 		}
 	}
 
+	// the tombstone is only removed if no other deletion replaced it.
+	_, err = s.Client.KV.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(s.tombstonesPath(path)), "=", rev)).
+		Then(clientv3.OpDelete(s.tombstonesPath(path))).
+		Commit()
+	if err != nil {
+		return errors.Wrapf(err, "failed to remove tombstone: %s", path)
+	}
+
 	return nil
 }
 
-// regex used to validate parameters name.
-var rgxParam = regexp.MustCompile(`^[a-z]+(?:[a-z0-9-]?[a-z0-9])*$`)
+// purgeVersions removes the versions stored under the given key that were created at or before the given revision,
+// deleteChunkSize versions per transaction. If keep is true, the versions are moved to the deleted rulesets.
+func (s *RulesetService) purgeVersions(ctx context.Context, path, key string, rev int64, keep bool) error {
+	prefix := key + "/"
+	from, end := prefix, clientv3.GetPrefixRangeEnd(prefix)
 
-// list of reserved words that shouldn't be used as parameters.
-var reservedWords = []string{
-	"version",
-	"list",
-	"eval",
-	"watch",
-	"revision",
-}
+	for {
+		resp, err := s.Client.KV.Get(ctx, from,
+			clientv3.WithRange(end),
+			clientv3.WithKeysOnly(),
+			clientv3.WithMaxCreateRev(rev),
+			clientv3.WithLimit(deleteChunkSize),
+		)
+		if err != nil {
+			return errors.Wrapf(err, "failed to fetch versions: %s", key)
+		}
+
+		versions := make([]string, 0, len(resp.Kvs))
+		for _, kv := range resp.Kvs {
+			from = string(kv.Key) + "\x00"
 
-func validateParamNames(params []rule.Param) error {
-	for i := range params {
-		if !rgxParam.MatchString(params[i].Name) {
-			return &store.ValidationError{
-				Field:  "param",
-				Value:  params[i].Name,
-				Reason: "invalid format",
+			v := strings.TrimPrefix(string(kv.Key), prefix)
+			if strings.Contains(v, "/") {
+				// the key belongs to a sub path.
+				continue
 			}
+
+			versions = append(versions, v)
 		}
 
-		for _, w := range reservedWords {
-			if params[i].Name == w {
-				return &store.ValidationError{
-					Field:  "param",
-					Value:  params[i].Name,
-					Reason: "forbidden value",
+		if len(versions) > 0 {
+			txfn := func(stm concurrency.STM) error {
+				for _, v := range versions {
+					k := prefix + v
+
+					// the versions are read within the transaction, they may have been removed since they were listed.
+					raw := stm.Get(k)
+					if raw == "" {
+						continue
+					}
+
+					if keep {
+						stm.Put(s.deletedRulesetsPath(path, v), raw)
+					}
+					stm.Del(k)
 				}
+
+				return nil
+			}
+
+			_, err = concurrency.NewSTM(s.Client, txfn, concurrency.WithAbortContext(ctx))
+			if err != nil {
+				return errors.Wrap(err, "failed to delete ruleset versions")
 			}
 		}
+
+		if !resp.More {
+			return nil
+		}
 	}
+}
 
-	return nil
+// DeleteVersions removes the given versions of the ruleset stored on the given path.
+// Every removed version generates a delete event. It returns store.ErrNotFound if the path doesn't exist
+// and store.ErrLatestVersion, without removing anything, if one of the versions is the latest one.
+// All the versions are removed in a single transaction, callers must keep their number reasonable.
+func (s *RulesetService) DeleteVersions(ctx context.Context, path string, versions ...string) error {
+	if path == "" {
+		return store.ErrNotFound
+	}
+
+	txfn := func(stm concurrency.STM) error {
+		latest := stm.Get(s.latestRulesetPath(path))
+		if latest == "" {
+			return store.ErrNotFound
+		}
+
+		for _, v := range versions {
+			if s.rulesetsPath(path, v) == latest {
+				return store.ErrLatestVersion
+			}
+		}
+
+		for _, v := range versions {
+			key := s.rulesetsPath(path, v)
+			if stm.Get(key) != "" {
+				stm.Del(key)
+			}
+		}
+
+		return nil
+	}
+
+	_, err := concurrency.NewSTM(s.Client, txfn, concurrency.WithAbortContext(ctx))
+	if err != nil && err != store.ErrNotFound && err != store.ErrLatestVersion {
+		return errors.Wrap(err, "failed to delete ruleset versions")
+	}
+
+	return err
 }
 
 // Watch the given prefix for anything new.
@@ -376,6 +1134,10 @@ func (s *RulesetService) Watch(ctx context.Context, prefix string, revision stri
 	for {
 		select {
 		case wresp := <-wc:
+			if wresp.CompactRevision != 0 {
+				return nil, store.ErrRevisionCompacted
+			}
+
 			if err := wresp.Err(); err != nil {
 				return nil, errors.Wrapf(err, "failed to watch prefix: '%s'", prefix)
 			}
@@ -389,6 +1151,13 @@ func (s *RulesetService) Watch(ctx context.Context, prefix string, revision stri
 				switch ev.Type {
 				case mvccpb.PUT:
 					events[i].Type = store.RulesetPutEvent
+				case mvccpb.DELETE:
+					// deleted keys have no value, the path and version are extracted from the key.
+					events[i].Type = store.RulesetDeleteEvent
+					key := strings.TrimPrefix(string(ev.Kv.Key), s.rulesetsPath("", "")+"/")
+					events[i].Path, events[i].Version = path.Split(key)
+					events[i].Path = strings.TrimSuffix(events[i].Path, "/")
+					continue
 				default:
 					s.Logger.Debug().Str("type", string(ev.Type)).Msg("watch: ignoring event type")
 					continue
@@ -427,7 +1196,12 @@ func (s *RulesetService) Eval(ctx context.Context, path string, params rule.Para
 		return nil, err
 	}
 
-	v, err := re.Ruleset.Eval(params)
+	sig, err := s.signature(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	v, i, err := re.Ruleset.EvalRule(sig.Params(params))
 	if err != nil {
 		return nil, err
 	}
@@ -435,6 +1209,7 @@ func (s *RulesetService) Eval(ctx context.Context, path string, params rule.Para
 	return &regula.EvalResult{
 		Value:   v,
 		Version: re.Version,
+		Rule:    &i,
 	}, nil
 }
 
@@ -449,7 +1224,12 @@ func (s *RulesetService) EvalVersion(ctx context.Context, path, version string,
 		return nil, err
 	}
 
-	v, err := re.Ruleset.Eval(params)
+	sig, err := s.signature(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	v, i, err := re.Ruleset.EvalRule(sig.Params(params))
 	if err != nil {
 		return nil, err
 	}
@@ -457,6 +1237,7 @@ func (s *RulesetService) EvalVersion(ctx context.Context, path, version string,
 	return &regula.EvalResult{
 		Value:   v,
 		Version: re.Version,
+		Rule:    &i,
 	}, nil
 }
 
@@ -464,6 +1245,10 @@ func (s *RulesetService) rulesetsPath(p, v string) string {
 	return path.Join(s.Namespace, "rulesets", "entries", p, v)
 }
 
+func (s *RulesetService) deletedRulesetsPath(p, v string) string {
+	return path.Join(s.Namespace, "rulesets", "deleted", p, v)
+}
+
 func (s *RulesetService) checksumsPath(p string) string {
 	return path.Join(s.Namespace, "rulesets", "checksums", p)
 }
@@ -472,6 +1257,50 @@ func (s *RulesetService) signaturesPath(p string) string {
 	return path.Join(s.Namespace, "rulesets", "signatures", p)
 }
 
+func (s *RulesetService) signaturesHistoryPath(p string) string {
+	return path.Join(s.Namespace, "rulesets", "signatures-history", p)
+}
+
+// latestVersion returns the latest version of the ruleset stored on the given path, empty if there is none.
+func (s *RulesetService) latestVersion(stm concurrency.STM, path string) string {
+	// the latest pointer holds the key of the latest version
+	return strings.TrimPrefix(stm.Get(s.latestRulesetPath(path)), s.rulesetsPath(path, "")+"/")
+}
+
+// audit records the given entry in the history of its ruleset.
+func (s *RulesetService) audit(stm concurrency.STM, entry store.AuditEntry) error {
+	k, raw, err := s.auditEntry(entry)
+	if err != nil {
+		return err
+	}
+
+	stm.Put(k, raw)
+	return nil
+}
+
+// auditEntry returns the key, a new ksuid, and the encoded value of the given audit entry.
+func (s *RulesetService) auditEntry(entry store.AuditEntry) (string, string, error) {
+	k, err := ksuid.NewRandom()
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to generate audit key")
+	}
+
+	raw, err := json.Marshal(&entry)
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to encode audit entry")
+	}
+
+	return s.auditPath(entry.Path, k.String()), string(raw), nil
+}
+
+func (s *RulesetService) auditPath(p, v string) string {
+	return path.Join(s.Namespace, "rulesets", "audit", p, v)
+}
+
+func (s *RulesetService) tombstonesPath(p string) string {
+	return path.Join(s.Namespace, "rulesets", "tombstones", p)
+}
+
 func (s *RulesetService) latestRulesetPath(p string) string {
 	return path.Join(s.Namespace, "rulesets", "latest", p)
 }
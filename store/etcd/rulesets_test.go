@@ -5,8 +5,8 @@ import (
 	"fmt"
 	"math/rand"
 	ppath "path"
+	"strconv"
 	"strings"
-	"sync"
 	"testing"
 	"time"
 
@@ -15,6 +15,7 @@ import (
 	"github.com/heetch/regula/rule"
 	"github.com/heetch/regula/store"
 	"github.com/heetch/regula/store/etcd"
+	"github.com/heetch/regula/store/storetest"
 	"github.com/stretchr/testify/require"
 )
 
@@ -77,7 +78,7 @@ func TestList(t *testing.T) {
 
 		paths := []string{"a/1", "a", "b", "c"}
 
-		entries, err := s.List(context.Background(), "", 0, "")
+		entries, err := s.List(context.Background(), "", store.ListOptions{})
 		require.NoError(t, err)
 		require.Len(t, entries.Entries, len(paths))
 		for i, e := range entries.Entries {
@@ -94,7 +95,7 @@ func TestList(t *testing.T) {
 
 		paths := []string{"x/1", "x", "x/2", "xx"}
 
-		entries, err := s.List(context.Background(), "x", 0, "")
+		entries, err := s.List(context.Background(), "x", store.ListOptions{})
 		require.NoError(t, err)
 		require.Len(t, entries.Entries, len(paths))
 		for i, e := range entries.Entries {
@@ -104,7 +105,7 @@ func TestList(t *testing.T) {
 	})
 
 	t.Run("NotFound", func(t *testing.T) {
-		_, err := s.List(context.Background(), "doesntexist", 0, "")
+		_, err := s.List(context.Background(), "doesntexist", store.ListOptions{})
 		require.Equal(t, err, store.ErrNotFound)
 	})
 
@@ -115,7 +116,7 @@ func TestList(t *testing.T) {
 		createRuleset(t, s, "y/2", rs)
 		createRuleset(t, s, "y/3", rs)
 
-		entries, err := s.List(context.Background(), "y", 2, "")
+		entries, err := s.List(context.Background(), "y", store.ListOptions{Limit: 2})
 		require.NoError(t, err)
 		require.Len(t, entries.Entries, 2)
 		require.Equal(t, "y/1", entries.Entries[0].Path)
@@ -123,20 +124,20 @@ func TestList(t *testing.T) {
 		require.NotEmpty(t, entries.Continue)
 
 		token := entries.Continue
-		entries, err = s.List(context.Background(), "y", 2, entries.Continue)
+		entries, err = s.List(context.Background(), "y", store.ListOptions{Limit: 2, ContinueToken: entries.Continue})
 		require.NoError(t, err)
 		require.Len(t, entries.Entries, 2)
 		require.Equal(t, "y/2", entries.Entries[0].Path)
 		require.Equal(t, "y/3", entries.Entries[1].Path)
 		require.NotEmpty(t, entries.Continue)
 
-		entries, err = s.List(context.Background(), "y", 2, entries.Continue)
+		entries, err = s.List(context.Background(), "y", store.ListOptions{Limit: 2, ContinueToken: entries.Continue})
 		require.NoError(t, err)
 		require.Len(t, entries.Entries, 1)
 		require.Equal(t, "yy", entries.Entries[0].Path)
 		require.Empty(t, entries.Continue)
 
-		entries, err = s.List(context.Background(), "y", 3, token)
+		entries, err = s.List(context.Background(), "y", store.ListOptions{Limit: 3, ContinueToken: token})
 		require.NoError(t, err)
 		require.Len(t, entries.Entries, 3)
 		require.Equal(t, "y/2", entries.Entries[0].Path)
@@ -144,439 +145,193 @@ func TestList(t *testing.T) {
 		require.Equal(t, "yy", entries.Entries[2].Path)
 		require.Empty(t, entries.Continue)
 
-		entries, err = s.List(context.Background(), "y", 3, "some token")
+		entries, err = s.List(context.Background(), "y", store.ListOptions{Limit: 3, ContinueToken: "some token"})
 		require.Equal(t, store.ErrInvalidContinueToken, err)
 
-		entries, err = s.List(context.Background(), "y", -10, "")
+		entries, err = s.List(context.Background(), "y", store.ListOptions{Limit: -10})
 		require.NoError(t, err)
 		require.Len(t, entries.Entries, 5)
 	})
 }
 
-func TestLatest(t *testing.T) {
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (store.RulesetService, func()) {
+		return newEtcdRulesetService(t)
+	})
+}
+
+func TestPut(t *testing.T) {
 	t.Parallel()
 
 	s, cleanup := newEtcdRulesetService(t)
 	defer cleanup()
 
-	oldRse, _ := regula.NewStringRuleset(
-		rule.New(
-			rule.True(),
-			rule.StringValue("a"),
-		),
-	)
-
-	newRse, _ := regula.NewStringRuleset(
+	path := "a"
+	rs, _ := regula.NewBoolRuleset(
 		rule.New(
 			rule.True(),
-			rule.StringValue("b"),
+			rule.BoolValue(true),
 		),
 	)
 
-	createRuleset(t, s, "a", oldRse)
-	// sleep 1 second because ksuid doesn't guarantee the order within the same second since it's based on a 32 bits timestamp (second).
-	time.Sleep(time.Second)
-	createRuleset(t, s, "a", newRse)
-
-	rs, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(true)))
-	rs2, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(false)))
-	createRuleset(t, s, "b", rs)
-	createRuleset(t, s, "c", rs)
-	createRuleset(t, s, "abc", rs)
-	createRuleset(t, s, "abcd", rs)
-	createRuleset(t, s, "abcd/e", rs2)
-
-	t.Run("OK - several versions of a ruleset", func(t *testing.T) {
-		path := "a"
-
-		entry, err := s.Latest(context.Background(), path)
-		require.NoError(t, err)
-		require.Equal(t, path, entry.Path)
-		require.Equal(t, newRse, entry.Ruleset)
-	})
-
-	t.Run("OK - only one version of a ruleset", func(t *testing.T) {
-		path := "b"
-
-		entry, err := s.Latest(context.Background(), path)
-		require.NoError(t, err)
-		require.Equal(t, path, entry.Path)
-		require.Equal(t, rs, entry.Ruleset)
-	})
-
-	t.Run("NOK - path doesn't exist", func(t *testing.T) {
-		path := "aa"
-
-		_, err := s.Latest(context.Background(), path)
-		require.Equal(t, err, store.ErrNotFound)
-	})
+	entry, err := s.Put(context.Background(), path, rs)
+	require.NoError(t, err)
 
-	t.Run("NOK - empty path", func(t *testing.T) {
-		path := ""
+	// verify ruleset creation
+	resp, err := s.Client.Get(context.Background(), ppath.Join(s.Namespace, "rulesets", "entries", path), clientv3.WithPrefix())
+	require.NoError(t, err)
+	require.EqualValues(t, 1, resp.Count)
+	// verify if the path contains the right ruleset version
+	require.Equal(t, entry.Version, strings.TrimPrefix(string(resp.Kvs[0].Key), ppath.Join(s.Namespace, "rulesets", "entries", "a")+"/"))
 
-		_, err := s.Latest(context.Background(), path)
-		require.Equal(t, err, store.ErrNotFound)
-	})
+	// verify checksum creation
+	resp, err = s.Client.Get(context.Background(), ppath.Join(s.Namespace, "rulesets", "checksums", path), clientv3.WithPrefix())
+	require.NoError(t, err)
+	require.EqualValues(t, 1, resp.Count)
 
-	t.Run("NOK - path exists but it's not a ruleset", func(t *testing.T) {
-		path := "ab"
+	// verify latest pointer creation
+	resp, err = s.Client.Get(context.Background(), ppath.Join(s.Namespace, "rulesets", "latest", path), clientv3.WithPrefix())
+	require.NoError(t, err)
+	require.EqualValues(t, 1, resp.Count)
 
-		_, err := s.Latest(context.Background(), path)
-		require.Error(t, err)
-		require.Equal(t, err, store.ErrNotFound)
-	})
+	// putting a ruleset that was the latest before a rollback creates a new version
+	rs2, _ := regula.NewBoolRuleset(
+		rule.New(
+			rule.True(),
+			rule.BoolValue(false),
+		),
+	)
+	entry2, err := s.Put(context.Background(), path, rs2)
+	require.NoError(t, err)
 
-	t.Run("OK - ruleset with sub ruleset", func(t *testing.T) {
-		entry, err := s.Latest(context.Background(), "abcd")
-		require.NoError(t, err)
-		require.Equal(t, rs, entry.Ruleset)
+	_, err = s.Rollback(context.Background(), path, entry.Version)
+	require.NoError(t, err)
 
-		entry, err = s.Latest(context.Background(), "abcd/e")
-		require.NoError(t, err)
-		require.Equal(t, rs2, entry.Ruleset)
-	})
+	entry3, err := s.Put(context.Background(), path, rs2)
+	require.NoError(t, err)
+	require.NotEqual(t, entry2.Version, entry3.Version)
 }
 
-func TestOneByVersion(t *testing.T) {
+func TestVersions(t *testing.T) {
 	t.Parallel()
 
 	s, cleanup := newEtcdRulesetService(t)
 	defer cleanup()
 
-	oldRse, _ := regula.NewStringRuleset(
-		rule.New(
-			rule.True(),
-			rule.StringValue("a"),
-		),
-	)
-
-	newRse, _ := regula.NewStringRuleset(
-		rule.New(
-			rule.True(),
-			rule.StringValue("b"),
-		),
-	)
+	// versions created within the same second are returned in creation order, even after a rollback.
+	var versions []string
+	for i := 0; i < 10; i++ {
+		rs, _ := regula.NewInt64Ruleset(rule.New(rule.True(), rule.Int64Value(int64(i))))
+		versions = append([]string{createRuleset(t, s, "a", rs).Version}, versions...)
+	}
 
-	createRuleset(t, s, "a", oldRse)
-	entry, err := s.Latest(context.Background(), "a")
+	_, err := s.Rollback(context.Background(), "a", versions[5])
 	require.NoError(t, err)
-	version := entry.Version
-
-	createRuleset(t, s, "a", newRse)
 
-	rs, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(true)))
-	createRuleset(t, s, "abc", rs)
-
-	t.Run("OK", func(t *testing.T) {
-		path := "a"
-
-		entry, err := s.OneByVersion(context.Background(), path, version)
+	var got []string
+	var token string
+	for {
+		vs, err := s.Versions(context.Background(), "a", 3, token)
 		require.NoError(t, err)
-		require.Equal(t, path, entry.Path)
-		require.Equal(t, version, entry.Version)
-		require.Equal(t, oldRse, entry.Ruleset)
-	})
 
-	t.Run("NOK", func(t *testing.T) {
-		paths := []string{
-			"a",  // doesn't exist
-			"ab", // exists but not a ruleset
-			"",   // empty path
+		for _, v := range vs.Versions {
+			got = append(got, v.Version)
 		}
 
-		for _, path := range paths {
-			_, err := s.OneByVersion(context.Background(), path, "123version")
-			require.Equal(t, err, store.ErrNotFound)
+		if vs.Continue == "" {
+			break
 		}
-	})
+		token = vs.Continue
+	}
+	require.Equal(t, versions, got)
 }
 
-func TestPut(t *testing.T) {
+func TestDelete(t *testing.T) {
 	t.Parallel()
 
 	s, cleanup := newEtcdRulesetService(t)
 	defer cleanup()
 
-	t.Run("OK", func(t *testing.T) {
-		path := "a"
-		rs, _ := regula.NewBoolRuleset(
-			rule.New(
-				rule.True(),
-				rule.BoolValue(true),
-			),
-		)
+	countKeys := func(t *testing.T, kind, path string) int64 {
+		t.Helper()
 
-		entry, err := s.Put(context.Background(), path, rs)
+		resp, err := s.Client.Get(context.Background(), ppath.Join(s.Namespace, "rulesets", kind, path), clientv3.WithPrefix(), clientv3.WithCountOnly())
 		require.NoError(t, err)
-		require.Equal(t, path, entry.Path)
-		require.NotEmpty(t, entry.Version)
-		require.Equal(t, rs, entry.Ruleset)
+		return resp.Count
+	}
 
-		// verify ruleset creation
-		resp, err := s.Client.Get(context.Background(), ppath.Join(s.Namespace, "rulesets", "entries", path), clientv3.WithPrefix())
-		require.NoError(t, err)
-		require.EqualValues(t, 1, resp.Count)
-		// verify if the path contains the right ruleset version
-		require.Equal(t, entry.Version, strings.TrimPrefix(string(resp.Kvs[0].Key), ppath.Join(s.Namespace, "rulesets", "entries", "a")+"/"))
+	r1, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("a")))
+	r2, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("b")))
 
-		// verify checksum creation
-		resp, err = s.Client.Get(context.Background(), ppath.Join(s.Namespace, "rulesets", "checksums", path), clientv3.WithPrefix())
-		require.NoError(t, err)
-		require.EqualValues(t, 1, resp.Count)
+	createRuleset(t, s, "a", r1)
+	createRuleset(t, s, "a", r2)
 
-		// verify latest pointer creation
-		resp, err = s.Client.Get(context.Background(), ppath.Join(s.Namespace, "rulesets", "latest", path), clientv3.WithPrefix())
-		require.NoError(t, err)
-		require.EqualValues(t, 1, resp.Count)
-
-		// create new version with same ruleset
-		entry2, err := s.Put(context.Background(), path, rs)
-		require.Equal(t, err, store.ErrNotModified)
-		require.Equal(t, entry, entry2)
-
-		// create new version with different ruleset
-		rs, _ = regula.NewBoolRuleset(
-			rule.New(
-				rule.True(),
-				rule.BoolValue(false),
-			),
-		)
-		entry2, err = s.Put(context.Background(), path, rs)
+	t.Run("Soft", func(t *testing.T) {
+		err := s.Delete(context.Background(), "a", false)
 		require.NoError(t, err)
-		require.NotEqual(t, entry.Version, entry2.Version)
-	})
 
-	t.Run("Signatures", func(t *testing.T) {
-		path := "b"
-		rs1, err := regula.NewBoolRuleset(
-			rule.New(
-				rule.Eq(
-					rule.StringParam("a"),
-					rule.BoolParam("b"),
-					rule.Int64Param("c"),
-				),
-				rule.BoolValue(true),
-			),
-		)
+		require.EqualValues(t, 0, countKeys(t, "entries", "a"))
+		require.EqualValues(t, 2, countKeys(t, "deleted", "a"))
+		require.EqualValues(t, 0, countKeys(t, "latest", "a"))
+		resp, err := s.Client.Get(context.Background(), ppath.Join(s.Namespace, "rulesets", "checksums", "a"))
 		require.NoError(t, err)
+		require.Zero(t, resp.Count)
+		require.EqualValues(t, 1, countKeys(t, "signatures", "a"))
+	})
 
-		_, err = s.Put(context.Background(), path, rs1)
+	t.Run("Hard", func(t *testing.T) {
+		err := s.Delete(context.Background(), "a", true)
 		require.NoError(t, err)
 
-		// same params, different return type
-		rs2, err := regula.NewStringRuleset(
-			rule.New(
-				rule.Eq(
-					rule.StringParam("a"),
-					rule.BoolParam("b"),
-					rule.Int64Param("c"),
-				),
-				rule.StringValue("true"),
-			),
-		)
-		require.NoError(t, err)
+		require.EqualValues(t, 0, countKeys(t, "deleted", "a"))
+		require.EqualValues(t, 0, countKeys(t, "signatures", "a"))
+		require.EqualValues(t, 0, countKeys(t, "tombstones", "a"))
+	})
 
-		_, err = s.Put(context.Background(), path, rs2)
-		require.True(t, store.IsValidationError(err))
-
-		// adding new params
-		rs3, err := regula.NewBoolRuleset(
-			rule.New(
-				rule.Eq(
-					rule.StringParam("a"),
-					rule.BoolParam("b"),
-					rule.Int64Param("c"),
-					rule.BoolParam("d"),
-				),
-				rule.BoolValue(true),
-			),
-		)
-		require.NoError(t, err)
+	t.Run("ManyVersions", func(t *testing.T) {
+		// more versions than a transaction can hold
+		for i := 0; i < 70; i++ {
+			rs, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue(strconv.Itoa(i))))
+			createRuleset(t, s, "b", rs)
+		}
 
-		_, err = s.Put(context.Background(), path, rs3)
-		require.True(t, store.IsValidationError(err))
-
-		// changing param types
-		rs4, err := regula.NewBoolRuleset(
-			rule.New(
-				rule.Eq(
-					rule.StringParam("a"),
-					rule.StringParam("b"),
-					rule.Int64Param("c"),
-					rule.BoolParam("d"),
-				),
-				rule.BoolValue(true),
-			),
-		)
+		err := s.Delete(context.Background(), "b", false)
 		require.NoError(t, err)
+		require.EqualValues(t, 0, countKeys(t, "entries", "b"))
+		require.EqualValues(t, 70, countKeys(t, "deleted", "b"))
+		require.EqualValues(t, 0, countKeys(t, "tombstones", "b"))
 
-		_, err = s.Put(context.Background(), path, rs4)
-		require.True(t, store.IsValidationError(err))
-
-		// adding new rule with different param types
-		rs5, err := regula.NewBoolRuleset(
-			rule.New(
-				rule.Eq(
-					rule.StringParam("a"),
-					rule.StringParam("b"),
-					rule.Int64Param("c"),
-					rule.BoolParam("d"),
-				),
-				rule.BoolValue(true),
-			),
-			rule.New(
-				rule.Eq(
-					rule.StringParam("a"),
-					rule.StringParam("b"),
-					rule.Int64Param("c"),
-					rule.BoolParam("d"),
-				),
-				rule.BoolValue(true),
-			),
-		)
-
-		_, err = s.Put(context.Background(), path, rs5)
-		require.True(t, store.IsValidationError(err))
-
-		// adding new rule with correct param types but less
-		rs6, _ := regula.NewBoolRuleset(
-			rule.New(
-				rule.Eq(
-					rule.StringParam("a"),
-					rule.BoolParam("b"),
-				),
-				rule.BoolValue(true),
-			),
-			rule.New(
-				rule.Eq(
-					rule.StringParam("a"),
-					rule.BoolParam("b"),
-				),
-				rule.BoolValue(true),
-			),
-		)
-
-		_, err = s.Put(context.Background(), path, rs6)
+		err = s.Delete(context.Background(), "b", true)
 		require.NoError(t, err)
+		require.EqualValues(t, 0, countKeys(t, "deleted", "b"))
 	})
-}
-
-func TestWatch(t *testing.T) {
-	t.Parallel()
-
-	s, cleanup := newEtcdRulesetService(t)
-	defer cleanup()
 
-	var wg sync.WaitGroup
+	t.Run("Resume", func(t *testing.T) {
+		createRuleset(t, s, "c", r1)
+		e2 := createRuleset(t, s, "c", r2)
 
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-
-		time.Sleep(time.Second)
-
-		rs, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(true)))
-
-		createRuleset(t, s, "aa", rs)
-		createRuleset(t, s, "ab", rs)
-		createRuleset(t, s, "a/1", rs)
-	}()
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	events, err := s.Watch(ctx, "a", "")
-	require.NoError(t, err)
-	require.Len(t, events.Events, 1)
-	require.NotEmpty(t, events.Revision)
-	require.Equal(t, "aa", events.Events[0].Path)
-	require.Equal(t, store.RulesetPutEvent, events.Events[0].Type)
-
-	wg.Wait()
-
-	events, err = s.Watch(ctx, "a", events.Revision)
-	require.NoError(t, err)
-	require.Len(t, events.Events, 2)
-	require.NotEmpty(t, events.Revision)
-	require.Equal(t, store.RulesetPutEvent, events.Events[0].Type)
-	require.Equal(t, "ab", events.Events[0].Path)
-	require.Equal(t, store.RulesetPutEvent, events.Events[1].Type)
-	require.Equal(t, "a/1", events.Events[1].Path)
-}
-
-func TestEval(t *testing.T) {
-	t.Parallel()
-
-	s, cleanup := newEtcdRulesetService(t)
-	defer cleanup()
-
-	rs, _ := regula.NewBoolRuleset(
-		rule.New(
-			rule.Eq(
-				rule.StringParam("id"),
-				rule.StringValue("123"),
-			),
-			rule.BoolValue(true),
-		),
-	)
-
-	entry := createRuleset(t, s, "a", rs)
-
-	t.Run("OK", func(t *testing.T) {
-		res, err := s.Eval(context.Background(), "a", regula.Params{
-			"id": "123",
-		})
+		// simulate a soft delete interrupted after the tombstone was written
+		_, err := s.Client.Txn(context.Background()).Then(
+			clientv3.OpPut(ppath.Join(s.Namespace, "rulesets", "tombstones", "c"), `{"hard":false}`),
+			clientv3.OpDelete(ppath.Join(s.Namespace, "rulesets", "latest", "c")),
+		).Commit()
 		require.NoError(t, err)
-		require.Equal(t, entry.Version, res.Version)
-		require.Equal(t, rule.BoolValue(true), res.Value)
-	})
-
-	t.Run("NotFound", func(t *testing.T) {
-		_, err := s.Eval(context.Background(), "notexists", regula.Params{
-			"id": "123",
-		})
-		require.Equal(t, regula.ErrRulesetNotFound, err)
-	})
-}
-
-func TestEvalVersion(t *testing.T) {
-	t.Parallel()
 
-	s, cleanup := newEtcdRulesetService(t)
-	defer cleanup()
+		// versions can't be rolled back to while the ruleset is being deleted
+		_, err = s.Rollback(context.Background(), "c", e2.Version)
+		require.Equal(t, store.ErrNotFound, err)
 
-	rs, _ := regula.NewBoolRuleset(
-		rule.New(
-			rule.Eq(
-				rule.StringParam("id"),
-				rule.StringValue("123"),
-			),
-			rule.BoolValue(true),
-		),
-	)
-
-	entry := createRuleset(t, s, "a", rs)
-
-	t.Run("OK", func(t *testing.T) {
-		res, err := s.EvalVersion(context.Background(), "a", entry.Version, regula.Params{
-			"id": "123",
-		})
+		// a new ruleset can be created on the path meanwhile
+		e3 := createRuleset(t, s, "c", r1)
+		latest, err := s.Latest(context.Background(), "c")
 		require.NoError(t, err)
-		require.Equal(t, entry.Version, res.Version)
-		require.Equal(t, rule.BoolValue(true), res.Value)
-	})
+		require.Equal(t, e3.Version, latest.Version)
 
-	t.Run("NotFound", func(t *testing.T) {
-		_, err := s.EvalVersion(context.Background(), "b", entry.Version, regula.Params{
-			"id": "123",
-		})
-		require.Equal(t, regula.ErrRulesetNotFound, err)
-	})
-
-	t.Run("BadVersion", func(t *testing.T) {
-		_, err := s.EvalVersion(context.Background(), "a", "someversion", regula.Params{
-			"id": "123",
-		})
-		require.Equal(t, regula.ErrRulesetNotFound, err)
+		// the interrupted deletion is finished before deleting the new ruleset
+		err = s.Delete(context.Background(), "c", false)
+		require.NoError(t, err)
+		require.EqualValues(t, 0, countKeys(t, "entries", "c"))
+		require.EqualValues(t, 3, countKeys(t, "deleted", "c"))
+		require.EqualValues(t, 0, countKeys(t, "tombstones", "c"))
 	})
 }
@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/heetch/regula/store"
+)
+
+// NamespaceService manages namespaces in memory, each one holding its own RulesetService.
+type NamespaceService struct {
+	mu         sync.RWMutex
+	namespaces map[string]*RulesetService
+}
+
+// NewNamespaceService creates a ready to use NamespaceService.
+func NewNamespaceService() *NamespaceService {
+	return &NamespaceService{
+		namespaces: make(map[string]*RulesetService),
+	}
+}
+
+// List returns the names of the namespaces, sorted.
+func (s *NamespaceService) List(ctx context.Context) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.namespaces))
+	for name := range s.namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Create creates the given namespace. It returns a store.ValidationError if the name is invalid
+// and store.ErrNotModified if the namespace already exists.
+func (s *NamespaceService) Create(ctx context.Context, name string) error {
+	err := store.ValidateNamespace(name)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.namespaces[name]; ok {
+		return store.ErrNotModified
+	}
+
+	s.namespaces[name] = NewRulesetService()
+
+	return nil
+}
+
+// Rulesets returns the service managing the rulesets of the given namespace.
+// It returns store.ErrNotFound if the namespace doesn't exist.
+func (s *NamespaceService) Rulesets(ctx context.Context, name string) (store.RulesetService, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rs, ok := s.namespaces[name]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+
+	return rs, nil
+}
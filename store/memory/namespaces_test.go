@@ -0,0 +1,17 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/heetch/regula/store"
+	"github.com/heetch/regula/store/memory"
+	"github.com/heetch/regula/store/storetest"
+)
+
+var _ store.NamespaceService = new(memory.NamespaceService)
+
+func TestNamespaces(t *testing.T) {
+	storetest.RunNamespaces(t, func(t *testing.T) (store.NamespaceService, func()) {
+		return memory.NewNamespaceService(), func() {}
+	})
+}
@@ -0,0 +1,855 @@
+// Package memory provides an in-memory implementation of store.RulesetService.
+// It is meant to be used in tests and to run Regula locally without etcd.
+package memory
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/heetch/regula"
+	"github.com/heetch/regula/rule"
+	"github.com/heetch/regula/store"
+	"github.com/pkg/errors"
+	"github.com/segmentio/ksuid"
+)
+
+// RulesetService manages the rulesets in memory.
+// Every change increments the revision of the service and is recorded so that watchers
+// can be notified of the events that occurred since any of the last EventsRetention revisions.
+type RulesetService struct {
+	// EventsRetention is the number of revisions whose events are kept for the watchers.
+	// Defaults to store.DefaultEventsRetention.
+	EventsRetention int64
+
+	mu       sync.RWMutex
+	rulesets map[string]*rulesetData
+	revision int64
+	events   []event
+	// closed and replaced every time the revision changes to wake up the watchers.
+	changed chan struct{}
+}
+
+// NewRulesetService creates a ready to use RulesetService.
+func NewRulesetService() *RulesetService {
+	return &RulesetService{
+		EventsRetention: store.DefaultEventsRetention,
+		rulesets:        make(map[string]*rulesetData),
+		changed:         make(chan struct{}),
+	}
+}
+
+// rulesetData holds all the data stored on a path.
+type rulesetData struct {
+	versions  []store.RulesetEntry // in creation order
+	deleted   []store.RulesetEntry // soft deleted versions
+	latest    string
+	checksum  string
+	signature *store.Signature
+	history   []store.Signature
+	audit     []store.AuditEntry // in creation order
+}
+
+func (r *rulesetData) version(version string) (int, bool) {
+	for i := range r.versions {
+		if r.versions[i].Version == version {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+type event struct {
+	store.RulesetEvent
+
+	revision int64
+}
+
+// List returns the rulesets entries under the given prefix.
+// If opt.LatestOnly is true, only the latest version of each ruleset is returned.
+func (s *RulesetService) List(ctx context.Context, prefix string, opt store.ListOptions) (*store.RulesetEntries, error) {
+	limit := opt.Limit
+	if limit != 0 {
+		limit = store.NormalizeLimit(limit)
+	}
+
+	var lastKey string
+	if opt.ContinueToken != "" {
+		k, err := base64.URLEncoding.DecodeString(opt.ContinueToken)
+		if err != nil {
+			return nil, store.ErrInvalidContinueToken
+		}
+
+		lastKey = string(k)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// entries are sorted by path, then by version.
+	var entries []store.RulesetEntry
+	for path, rs := range s.rulesets {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+
+		for _, e := range rs.versions {
+			if opt.LatestOnly && e.Version != rs.latest {
+				continue
+			}
+
+			if lastKey == "" || key(e.Path, e.Version) > lastKey {
+				cp, err := copyEntry(e)
+				if err != nil {
+					return nil, err
+				}
+
+				entries = append(entries, *cp)
+			}
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return key(entries[i].Path, entries[i].Version) < key(entries[j].Path, entries[j].Version)
+	})
+
+	// if a prefix is provided it must always return results
+	// otherwise it doesn't exist.
+	if len(entries) == 0 && prefix != "" {
+		return nil, store.ErrNotFound
+	}
+
+	res := store.RulesetEntries{
+		Revision: strconv.FormatInt(s.revision, 10),
+	}
+
+	if limit == 0 || len(entries) <= limit {
+		res.Entries = entries
+		return &res, nil
+	}
+
+	res.Entries = entries[:limit]
+	last := res.Entries[limit-1]
+	res.Continue = base64.URLEncoding.EncodeToString([]byte(key(last.Path, last.Version)))
+
+	return &res, nil
+}
+
+// Latest returns the latest version of the ruleset entry which corresponds to the given path.
+// It returns store.ErrNotFound if the path doesn't exist.
+func (s *RulesetService) Latest(ctx context.Context, path string) (*store.RulesetEntry, error) {
+	entry, _, err := s.find(path, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return copyEntry(entry)
+}
+
+// OneByVersion returns the ruleset entry which corresponds to the given path at the given version.
+// It returns store.ErrNotFound if the path or the version doesn't exist.
+func (s *RulesetService) OneByVersion(ctx context.Context, path, version string) (*store.RulesetEntry, error) {
+	entry, _, err := s.find(path, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return copyEntry(entry)
+}
+
+// find returns the stored entry of the given version, or of the latest one if version is empty,
+// along with the signature of the path. The returned entry must not be modified.
+func (s *RulesetService) find(path, version string) (store.RulesetEntry, *store.Signature, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rs, ok := s.rulesets[path]
+	if !ok {
+		return store.RulesetEntry{}, nil, store.ErrNotFound
+	}
+
+	if version == "" {
+		version = rs.latest
+	}
+
+	i, ok := rs.version(version)
+	if !ok {
+		return store.RulesetEntry{}, nil, store.ErrNotFound
+	}
+
+	return rs.versions[i], rs.signature, nil
+}
+
+// Versions returns the versions of the ruleset stored on the given path, newest first.
+// It returns store.ErrNotFound if the path doesn't exist.
+func (s *RulesetService) Versions(ctx context.Context, path string, limit int, continueToken string) (*store.RulesetVersions, error) {
+	limit = store.NormalizeLimit(limit)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rs, ok := s.rulesets[path]
+	if !ok || len(rs.versions) == 0 {
+		return nil, store.ErrNotFound
+	}
+
+	// versions are ksuids, they are returned in descending order like the keys of the etcd implementation.
+	sorted := make([]string, len(rs.versions))
+	for i := range rs.versions {
+		sorted[i] = rs.versions[i].Version
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(sorted)))
+
+	start := 0
+	if continueToken != "" {
+		lastVersion, err := base64.URLEncoding.DecodeString(continueToken)
+		if err != nil {
+			return nil, store.ErrInvalidContinueToken
+		}
+
+		start = sort.Search(len(sorted), func(i int) bool {
+			return sorted[i] < string(lastVersion)
+		})
+	}
+
+	versions := store.RulesetVersions{
+		Path: path,
+	}
+
+	for i := start; i < len(sorted); i++ {
+		k, err := ksuid.Parse(sorted[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse version: %s", sorted[i])
+		}
+
+		versions.Versions = append(versions.Versions, store.RulesetVersion{
+			Version:   sorted[i],
+			CreatedAt: k.Time(),
+		})
+
+		if len(versions.Versions) == limit {
+			if i < len(sorted)-1 {
+				versions.Continue = base64.URLEncoding.EncodeToString([]byte(sorted[i]))
+			}
+			break
+		}
+	}
+
+	return &versions, nil
+}
+
+// History returns the changes made to the ruleset stored on the given path, newest first.
+// The history survives deletes. It returns store.ErrNotFound if nothing was recorded for the path.
+func (s *RulesetService) History(ctx context.Context, path string, limit int, continueToken string) (*store.AuditEntries, error) {
+	limit = store.NormalizeLimit(limit)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rs, ok := s.rulesets[path]
+	if !ok || len(rs.audit) == 0 {
+		return nil, store.ErrNotFound
+	}
+
+	// entries are only appended, the token holds the position of the last returned one.
+	end := len(rs.audit)
+	if continueToken != "" {
+		raw, err := base64.URLEncoding.DecodeString(continueToken)
+		if err != nil {
+			return nil, store.ErrInvalidContinueToken
+		}
+
+		end, err = strconv.Atoi(string(raw))
+		if err != nil || end < 0 || end > len(rs.audit) {
+			return nil, store.ErrInvalidContinueToken
+		}
+	}
+
+	history := store.AuditEntries{
+		Path: path,
+	}
+
+	for i := end - 1; i >= 0; i-- {
+		history.Entries = append(history.Entries, rs.audit[i])
+
+		if len(history.Entries) == limit {
+			if i > 0 {
+				history.Continue = base64.URLEncoding.EncodeToString([]byte(strconv.Itoa(i)))
+			}
+			break
+		}
+	}
+
+	return &history, nil
+}
+
+// Put adds a version of the given ruleset using a ksuid.
+// The ruleset is validated like in the etcd implementation and a store.ValidationError
+// is returned if it is invalid. If the ruleset didn't change, the latest entry is returned
+// with store.ErrNotModified.
+func (s *RulesetService) Put(ctx context.Context, path string, ruleset *regula.Ruleset, opts ...store.PutOption) (*store.RulesetEntry, error) {
+	sig, err := store.ValidateRuleset(path, ruleset)
+	if err != nil {
+		return nil, err
+	}
+
+	// the ruleset is copied to prevent the caller from modifying the stored version.
+	cp, err := copyRuleset(ruleset)
+	if err != nil {
+		return nil, err
+	}
+
+	cs, err := checksum(cp)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rs, ok := s.rulesets[path]
+	if !ok {
+		rs = new(rulesetData)
+		s.rulesets[path] = rs
+	}
+
+	// make sure the ruleset didn't change since the caller read it
+	if v := store.NewPutOptions(opts...).ExpectedVersion; v != "" && v != rs.latest {
+		if rs.latest == "" {
+			return nil, store.ErrVersionMismatch
+		}
+
+		i, _ := rs.version(rs.latest)
+		entry, err := copyEntry(rs.versions[i])
+		if err != nil {
+			return nil, err
+		}
+
+		return entry, store.ErrVersionMismatch
+	}
+
+	// if nothing changed return latest ruleset
+	if rs.latest != "" && rs.checksum == cs {
+		i, _ := rs.version(rs.latest)
+		entry, err := copyEntry(rs.versions[i])
+		if err != nil {
+			return nil, err
+		}
+
+		return entry, store.ErrNotModified
+	}
+
+	// make sure signature didn't change
+	if rs.signature != nil {
+		err = rs.signature.MatchWith(sig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	k, err := ksuid.NewRandom()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate ruleset version")
+	}
+
+	entry := add(ctx, rs, path, k.String(), cp, sig, cs)
+
+	s.notify(store.RulesetEvent{
+		Type:    store.RulesetPutEvent,
+		Path:    path,
+		Version: entry.Version,
+		Ruleset: entry.Ruleset,
+	})
+
+	return copyEntry(entry)
+}
+
+// PutBatch stores a version of every given ruleset under a single revision.
+// All the rulesets are checked before storing anything, if one of them is invalid
+// a store.ValidationError is returned and nothing is stored. Unchanged rulesets are left untouched.
+func (s *RulesetService) PutBatch(ctx context.Context, entries []store.RulesetEntry) ([]store.RulesetEntry, error) {
+	sigs, err := store.ValidateBatch(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	cps := make([]*regula.Ruleset, len(entries))
+	css := make([]string, len(entries))
+	for i, e := range entries {
+		// the rulesets are copied to prevent the caller from modifying the stored versions.
+		cps[i], err = copyRuleset(e.Ruleset)
+		if err != nil {
+			return nil, err
+		}
+
+		css[i], err = checksum(cps[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// versions are generated while checking the rulesets, nothing must fail once the first one is stored.
+	versions := make([]string, len(entries))
+	res := make([]store.RulesetEntry, len(entries))
+	for i, e := range entries {
+		rs, ok := s.rulesets[e.Path]
+		if ok && rs.latest != "" && rs.checksum == css[i] {
+			j, _ := rs.version(rs.latest)
+			res[i] = rs.versions[j]
+			continue
+		}
+
+		if ok && rs.signature != nil {
+			err = rs.signature.MatchWith(sigs[i])
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		k, err := ksuid.NewRandom()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to generate ruleset version")
+		}
+		versions[i] = k.String()
+	}
+
+	var events []store.RulesetEvent
+	for i, e := range entries {
+		if versions[i] == "" {
+			continue
+		}
+
+		rs, ok := s.rulesets[e.Path]
+		if !ok {
+			rs = new(rulesetData)
+			s.rulesets[e.Path] = rs
+		}
+
+		res[i] = add(ctx, rs, e.Path, versions[i], cps[i], sigs[i], css[i])
+		events = append(events, store.RulesetEvent{
+			Type:    store.RulesetPutEvent,
+			Path:    e.Path,
+			Version: res[i].Version,
+			Ruleset: res[i].Ruleset,
+		})
+	}
+
+	if len(events) > 0 {
+		s.notify(events...)
+	}
+
+	for i := range res {
+		cp, err := copyEntry(res[i])
+		if err != nil {
+			return nil, err
+		}
+		res[i] = *cp
+	}
+
+	if len(events) == 0 {
+		return res, store.ErrNotModified
+	}
+
+	return res, nil
+}
+
+// add makes the given version the latest version of the ruleset, recording the change in its history.
+// The signature is only used if the ruleset doesn't have one yet.
+func add(ctx context.Context, rs *rulesetData, path, version string, ruleset *regula.Ruleset, sig *store.Signature, cs string) store.RulesetEntry {
+	if rs.signature == nil {
+		rs.signature = sig
+	}
+
+	entry := store.RulesetEntry{
+		Path:    path,
+		Version: version,
+		Ruleset: ruleset,
+	}
+
+	rs.audit = append(rs.audit, store.NewAuditEntry(ctx, store.AuditPut, path, entry.Version, rs.latest))
+	rs.versions = append(rs.versions, entry)
+	rs.latest = entry.Version
+	rs.checksum = cs
+
+	return entry
+}
+
+// Rollback makes the given version the latest version of the ruleset stored on the given path.
+// It returns store.ErrNotFound if the version doesn't exist and store.ErrNotModified if it is already the latest.
+func (s *RulesetService) Rollback(ctx context.Context, path, version string) (*store.RulesetEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rs, ok := s.rulesets[path]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+
+	i, ok := rs.version(version)
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+
+	entry := rs.versions[i]
+	if rs.latest == version {
+		cp, err := copyEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+
+		return cp, store.ErrNotModified
+	}
+
+	cs, err := checksum(entry.Ruleset)
+	if err != nil {
+		return nil, err
+	}
+
+	rs.audit = append(rs.audit, store.NewAuditEntry(ctx, store.AuditRollback, path, version, rs.latest))
+	rs.latest = version
+	rs.checksum = cs
+
+	s.notify(store.RulesetEvent{
+		Type:    store.RulesetPutEvent,
+		Path:    path,
+		Version: entry.Version,
+		Ruleset: entry.Ruleset,
+	})
+
+	return copyEntry(entry)
+}
+
+// Signatures returns the history of the signatures of the ruleset stored on the given path, the current one last.
+// It returns store.ErrNotFound if the path has no signature.
+func (s *RulesetService) Signatures(ctx context.Context, path string) ([]store.Signature, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rs, ok := s.rulesets[path]
+	if !ok || rs.signature == nil {
+		return nil, store.ErrNotFound
+	}
+
+	// the history is only created by the first migration
+	if len(rs.history) == 0 {
+		return []store.Signature{*rs.signature}, nil
+	}
+
+	return append([]store.Signature(nil), rs.history...), nil
+}
+
+// MigrateSignature applies the given migration to the signature of the ruleset stored on the given path
+// and records the new signature in the history.
+// All the existing versions of the ruleset must remain evaluable with the new signature, otherwise a store.ValidationError is returned.
+// It returns store.ErrNotFound if the path has no signature.
+func (s *RulesetService) MigrateSignature(ctx context.Context, path string, m *store.SignatureMigration) (*store.Signature, error) {
+	params := make([]rule.Param, 0, len(m.AddParams))
+	for name, tp := range m.AddParams {
+		params = append(params, rule.Param{Name: name, Type: tp})
+	}
+	err := store.ValidateParamNames(params)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rs, ok := s.rulesets[path]
+	if !ok || rs.signature == nil {
+		return nil, store.ErrNotFound
+	}
+
+	sig, err := rs.signature.Migrate(m)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range rs.versions {
+		err = sig.Evaluable(e.Ruleset)
+		if err != nil {
+			return nil, &store.ValidationError{
+				Field:  "version",
+				Value:  e.Version,
+				Reason: err.Error(),
+			}
+		}
+	}
+
+	if len(rs.history) == 0 {
+		rs.history = append(rs.history, *rs.signature)
+	}
+	rs.history = append(rs.history, *sig)
+	rs.signature = sig
+	rs.audit = append(rs.audit, store.NewAuditEntry(ctx, store.AuditMigrateSignature, path, rs.latest, rs.latest))
+
+	cp := *sig
+	return &cp, nil
+}
+
+// Delete removes the ruleset stored on the given path.
+// A soft delete keeps the versions aside and preserves the signature, a hard delete removes everything but the history.
+// Every removed version generates a delete event. It returns store.ErrNotFound if the path doesn't exist.
+func (s *RulesetService) Delete(ctx context.Context, path string, hard bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rs, ok := s.rulesets[path]
+	if !ok || (rs.latest == "" && !hard) || (len(rs.versions) == 0 && len(rs.deleted) == 0) {
+		return store.ErrNotFound
+	}
+
+	events := make([]store.RulesetEvent, len(rs.versions))
+	for i, e := range rs.versions {
+		events[i] = store.RulesetEvent{
+			Type:    store.RulesetDeleteEvent,
+			Path:    path,
+			Version: e.Version,
+		}
+	}
+
+	action := store.AuditDelete
+	if hard {
+		action = store.AuditHardDelete
+		rs.deleted = nil
+		rs.signature = nil
+		rs.history = nil
+	} else {
+		rs.deleted = append(rs.deleted, rs.versions...)
+	}
+
+	rs.audit = append(rs.audit, store.NewAuditEntry(ctx, action, path, "", rs.latest))
+	rs.versions = nil
+	rs.latest = ""
+	rs.checksum = ""
+
+	s.notify(events...)
+
+	return nil
+}
+
+// DeleteVersions removes the given versions of the ruleset stored on the given path.
+// Every removed version generates a delete event. It returns store.ErrNotFound if the path doesn't exist
+// and store.ErrLatestVersion, without removing anything, if one of the versions is the latest one.
+func (s *RulesetService) DeleteVersions(ctx context.Context, path string, versions ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rs, ok := s.rulesets[path]
+	if !ok || rs.latest == "" {
+		return store.ErrNotFound
+	}
+
+	remove := make(map[string]bool, len(versions))
+	for _, v := range versions {
+		if v == rs.latest {
+			return store.ErrLatestVersion
+		}
+
+		remove[v] = true
+	}
+
+	var events []store.RulesetEvent
+	kept := rs.versions[:0]
+	for _, e := range rs.versions {
+		if !remove[e.Version] {
+			kept = append(kept, e)
+			continue
+		}
+
+		events = append(events, store.RulesetEvent{
+			Type:    store.RulesetDeleteEvent,
+			Path:    path,
+			Version: e.Version,
+		})
+	}
+	rs.versions = kept
+
+	if len(events) > 0 {
+		s.notify(events...)
+	}
+
+	return nil
+}
+
+// Watch the given prefix for anything new.
+// If revision is empty, it waits for the changes occurring after the call, otherwise it returns
+// the changes that occurred after the given revision, waiting for them if necessary.
+// It returns store.ErrRevisionCompacted if the events following the revision were dropped
+// or if the revision is unknown.
+func (s *RulesetService) Watch(ctx context.Context, prefix string, revision string) (*store.RulesetEvents, error) {
+	s.mu.RLock()
+
+	rev, _ := strconv.ParseInt(revision, 10, 64)
+	if rev <= 0 {
+		rev = s.revision
+	}
+
+	for {
+		if rev < s.compactedRevision() || rev > s.revision {
+			s.mu.RUnlock()
+			return nil, store.ErrRevisionCompacted
+		}
+
+		var events []store.RulesetEvent
+		i := sort.Search(len(s.events), func(i int) bool { return s.events[i].revision > rev })
+		for _, ev := range s.events[i:] {
+			if strings.HasPrefix(ev.Path, prefix) {
+				events = append(events, ev.RulesetEvent)
+			}
+		}
+
+		for i := range events {
+			if events[i].Ruleset == nil {
+				continue
+			}
+
+			cp, err := copyRuleset(events[i].Ruleset)
+			if err != nil {
+				s.mu.RUnlock()
+				return nil, err
+			}
+
+			events[i].Ruleset = cp
+		}
+
+		if len(events) > 0 {
+			res := store.RulesetEvents{
+				Events:   events,
+				Revision: strconv.FormatInt(s.revision, 10),
+			}
+			s.mu.RUnlock()
+			return &res, nil
+		}
+
+		changed := s.changed
+		s.mu.RUnlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		s.mu.RLock()
+	}
+}
+
+// Eval evaluates a ruleset given a path and a set of parameters. It implements the regula.Evaluator interface.
+func (s *RulesetService) Eval(ctx context.Context, path string, params rule.Params) (*regula.EvalResult, error) {
+	return s.eval(path, "", params)
+}
+
+// EvalVersion evaluates a ruleset given a path and a set of parameters. It implements the regula.Evaluator interface.
+func (s *RulesetService) EvalVersion(ctx context.Context, path, version string, params rule.Params) (*regula.EvalResult, error) {
+	if version == "" {
+		return nil, regula.ErrRulesetNotFound
+	}
+
+	return s.eval(path, version, params)
+}
+
+func (s *RulesetService) eval(path, version string, params rule.Params) (*regula.EvalResult, error) {
+	re, sig, err := s.find(path, version)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, regula.ErrRulesetNotFound
+		}
+
+		return nil, err
+	}
+
+	if sig != nil {
+		params = sig.Params(params)
+	}
+
+	v, i, err := re.Ruleset.EvalRule(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &regula.EvalResult{
+		Value:   v,
+		Version: re.Version,
+		Rule:    &i,
+	}, nil
+}
+
+// notify records the given events under a new revision, drops the events of the revisions
+// that aren't retained anymore and wakes up the watchers.
+// It must be called with the lock held.
+func (s *RulesetService) notify(events ...store.RulesetEvent) {
+	s.revision++
+	for _, ev := range events {
+		s.events = append(s.events, event{RulesetEvent: ev, revision: s.revision})
+	}
+
+	compacted := s.compactedRevision()
+	i := sort.Search(len(s.events), func(i int) bool { return s.events[i].revision > compacted })
+	s.events = s.events[i:]
+
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// compactedRevision returns the last revision whose events were dropped, zero if none were.
+// It must be called with the lock held.
+func (s *RulesetService) compactedRevision() int64 {
+	if s.revision <= s.EventsRetention {
+		return 0
+	}
+
+	return s.revision - s.EventsRetention
+}
+
+// key used to sort the entries by path, then by version.
+func key(path, version string) string {
+	return path + "\x00" + version
+}
+
+// copyRuleset returns a deep copy of the given ruleset.
+func copyRuleset(rs *regula.Ruleset) (*regula.Ruleset, error) {
+	raw, err := json.Marshal(rs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode ruleset")
+	}
+
+	var cp regula.Ruleset
+	err = json.Unmarshal(raw, &cp)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode ruleset")
+	}
+
+	return &cp, nil
+}
+
+// copyEntry returns a copy of the given entry so that the stored ruleset can't be modified by the caller.
+func copyEntry(e store.RulesetEntry) (*store.RulesetEntry, error) {
+	rs, err := copyRuleset(e.Ruleset)
+	if err != nil {
+		return nil, err
+	}
+
+	e.Ruleset = rs
+	return &e, nil
+}
+
+// checksum of a ruleset used to detect changes.
+func checksum(rs *regula.Ruleset) (string, error) {
+	h := md5.New()
+	err := json.NewEncoder(h).Encode(rs)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to generate checksum")
+	}
+
+	return string(h.Sum(nil)), nil
+}
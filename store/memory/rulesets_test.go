@@ -0,0 +1,73 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/heetch/regula"
+	"github.com/heetch/regula/rule"
+	"github.com/heetch/regula/store"
+	"github.com/heetch/regula/store/memory"
+	"github.com/heetch/regula/store/storetest"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ store.RulesetService = new(memory.RulesetService)
+	_ regula.Evaluator     = new(memory.RulesetService)
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (store.RulesetService, func()) {
+		return memory.NewRulesetService(), func() {}
+	})
+}
+
+func TestPutCopy(t *testing.T) {
+	s := memory.NewRulesetService()
+
+	rs, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("a")))
+	e, err := s.Put(context.Background(), "a", rs)
+	require.NoError(t, err)
+
+	// modifying the ruleset after the put must not modify the stored version
+	rs.Rules[0].Result = rule.StringValue("b")
+
+	e, err = s.OneByVersion(context.Background(), "a", e.Version)
+	require.NoError(t, err)
+	require.Equal(t, rule.StringValue("a"), e.Ruleset.Rules[0].Result)
+
+	// neither does modifying a returned entry
+	e.Ruleset.Rules[0].Result = rule.StringValue("c")
+
+	e, err = s.Latest(context.Background(), "a")
+	require.NoError(t, err)
+	require.Equal(t, rule.StringValue("a"), e.Ruleset.Rules[0].Result)
+}
+
+func TestRevision(t *testing.T) {
+	s := memory.NewRulesetService()
+
+	rs, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(true)))
+	for _, path := range []string{"aa", "b", "ab"} {
+		_, err := s.Put(context.Background(), path, rs)
+		require.NoError(t, err)
+	}
+
+	entries, err := s.List(context.Background(), "", store.ListOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "3", entries.Revision)
+
+	events, err := s.Watch(context.Background(), "a", "1")
+	require.NoError(t, err)
+	require.Equal(t, "3", events.Revision)
+	require.Len(t, events.Events, 1)
+	require.Equal(t, "ab", events.Events[0].Path)
+}
+
+func TestEventsRetention(t *testing.T) {
+	s := memory.NewRulesetService()
+	s.EventsRetention = 2
+
+	storetest.RunEventsRetention(t, s, 2)
+}
@@ -0,0 +1,15 @@
+package store
+
+import "context"
+
+// NamespaceService manages namespaces. Every namespace holds a set of rulesets
+// isolated from the ones of the other namespaces.
+type NamespaceService interface {
+	// List returns the names of the namespaces, sorted.
+	List(ctx context.Context) ([]string, error)
+	// Create creates a namespace. It returns ErrNotModified if the namespace already exists.
+	Create(ctx context.Context, name string) error
+	// Rulesets returns the service managing the rulesets of the given namespace.
+	// It returns ErrNotFound if the namespace doesn't exist.
+	Rulesets(ctx context.Context, name string) (RulesetService, error)
+}
@@ -0,0 +1,193 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// maximum number of versions removed by a single call to DeleteVersions.
+const pruneBatchSize = 50
+
+// RetentionPolicy decides which versions of a ruleset are kept when pruning.
+// A version is kept if it is one of the KeepLast newest versions, if it was created
+// less than KeepFor ago, if it is pinned, or if it is the latest version of the ruleset.
+// A zero value rule doesn't keep anything by itself, a policy with no rule at all is disabled.
+type RetentionPolicy struct {
+	// KeepLast is the number of newest versions to keep.
+	KeepLast int
+	// KeepFor is the age under which versions are kept.
+	KeepFor time.Duration
+	// Pinned holds the versions to keep forever, by path.
+	Pinned map[string][]string
+}
+
+// Enabled indicates if the policy has at least one rule, i.e. if it can remove anything.
+func (p *RetentionPolicy) Enabled() bool {
+	return p.KeepLast > 0 || p.KeepFor > 0
+}
+
+// Expired returns the versions that can be removed according to the policy.
+// The versions must be sorted from newest to oldest, as returned by RulesetService.Versions,
+// and latest is the current latest version of the ruleset, which is never returned.
+func (p *RetentionPolicy) Expired(path string, versions []RulesetVersion, latest string, now time.Time) []string {
+	if !p.Enabled() {
+		return nil
+	}
+
+	var expired []string
+	for i, v := range versions {
+		switch {
+		case v.Version == latest:
+		case p.KeepLast > 0 && i < p.KeepLast:
+		case p.KeepFor > 0 && now.Sub(v.CreatedAt) < p.KeepFor:
+		case contains(p.Pinned[path], v.Version):
+		default:
+			expired = append(expired, v.Version)
+		}
+	}
+
+	return expired
+}
+
+// Prune removes the versions of the rulesets stored under the given prefix that are expired
+// according to the policy. It returns the number of removed versions.
+func Prune(ctx context.Context, s RulesetService, prefix string, p *RetentionPolicy, now time.Time) (int, error) {
+	if !p.Enabled() {
+		return 0, nil
+	}
+
+	paths, err := listPaths(ctx, s, prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	for _, path := range paths {
+		n, err := prunePath(ctx, s, path, p, now)
+		if err != nil {
+			return count, err
+		}
+
+		count += n
+	}
+
+	return count, nil
+}
+
+// PruneNamespaces removes the versions of the rulesets of every namespace that are expired according to the policy.
+// It returns the number of removed versions.
+func PruneNamespaces(ctx context.Context, ns NamespaceService, p *RetentionPolicy, now time.Time) (int, error) {
+	if !p.Enabled() {
+		return 0, nil
+	}
+
+	names, err := ns.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	for _, name := range names {
+		s, err := ns.Rulesets(ctx, name)
+		if err != nil {
+			return count, err
+		}
+
+		n, err := Prune(ctx, s, "", p, now)
+		count += n
+		if err != nil {
+			return count, err
+		}
+	}
+
+	return count, nil
+}
+
+// listPaths returns the paths of the rulesets stored under the given prefix.
+func listPaths(ctx context.Context, s RulesetService, prefix string) ([]string, error) {
+	var paths []string
+	var token string
+
+	for {
+		entries, err := s.List(ctx, prefix, ListOptions{Limit: MaxLimit, ContinueToken: token, LatestOnly: true})
+		if err != nil {
+			if err == ErrNotFound {
+				return nil, nil
+			}
+
+			return nil, err
+		}
+
+		for _, e := range entries.Entries {
+			paths = append(paths, e.Path)
+		}
+
+		if entries.Continue == "" {
+			return paths, nil
+		}
+
+		token = entries.Continue
+	}
+}
+
+func prunePath(ctx context.Context, s RulesetService, path string, p *RetentionPolicy, now time.Time) (int, error) {
+	latest, err := s.Latest(ctx, path)
+	if err != nil {
+		if err == ErrNotFound {
+			// deleted in the meantime
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	var versions []RulesetVersion
+	var token string
+	for {
+		page, err := s.Versions(ctx, path, MaxLimit, token)
+		if err != nil {
+			if err == ErrNotFound {
+				return 0, nil
+			}
+
+			return 0, err
+		}
+
+		versions = append(versions, page.Versions...)
+
+		if page.Continue == "" {
+			break
+		}
+
+		token = page.Continue
+	}
+
+	expired := p.Expired(path, versions, latest.Version, now)
+	if len(expired) == 0 {
+		return 0, nil
+	}
+
+	var count int
+	for len(expired) > 0 {
+		// versions are removed in small batches to keep the transactions of the stores small.
+		n := len(expired)
+		if n > pruneBatchSize {
+			n = pruneBatchSize
+		}
+
+		err = s.DeleteVersions(ctx, path, expired[:n]...)
+		if err != nil {
+			if err == ErrNotFound || err == ErrLatestVersion {
+				// deleted or rolled back in the meantime, the next run will take care of it
+				return count, nil
+			}
+
+			return count, err
+		}
+
+		count += n
+		expired = expired[n:]
+	}
+
+	return count, nil
+}
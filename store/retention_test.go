@@ -0,0 +1,83 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/heetch/regula"
+	"github.com/heetch/regula/rule"
+	"github.com/heetch/regula/store"
+	"github.com/heetch/regula/store/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetentionPolicyExpired(t *testing.T) {
+	now := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// newest first, one version per day
+	var versions []store.RulesetVersion
+	for _, v := range []string{"e", "d", "c", "b", "a"} {
+		versions = append(versions, store.RulesetVersion{
+			Version:   v,
+			CreatedAt: now.Add(-time.Duration(len(versions)+1) * 24 * time.Hour),
+		})
+	}
+
+	tests := []struct {
+		name     string
+		policy   store.RetentionPolicy
+		latest   string
+		expected []string
+	}{
+		{"Disabled", store.RetentionPolicy{}, "e", nil},
+		{"KeepLast", store.RetentionPolicy{KeepLast: 2}, "e", []string{"c", "b", "a"}},
+		{"KeepFor", store.RetentionPolicy{KeepFor: 50 * time.Hour}, "e", []string{"c", "b", "a"}},
+		{"Both", store.RetentionPolicy{KeepLast: 3, KeepFor: 25 * time.Hour}, "e", []string{"b", "a"}},
+		{"Latest", store.RetentionPolicy{KeepLast: 1}, "b", []string{"d", "c", "a"}},
+		{"Pinned", store.RetentionPolicy{KeepLast: 1, Pinned: map[string][]string{"p": {"c"}, "q": {"d"}}}, "e", []string{"d", "b", "a"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, test.policy.Expired("p", versions, test.latest, now))
+		})
+	}
+
+	t.Run("StoreOrder", func(t *testing.T) {
+		// the order of the store is kept, even if the creation times disagree with it.
+		sec := now.Add(-time.Hour)
+		unordered := []store.RulesetVersion{
+			{Version: "d", CreatedAt: sec},
+			{Version: "c", CreatedAt: sec.Add(time.Second)},
+			{Version: "b", CreatedAt: sec},
+			{Version: "a", CreatedAt: sec.Add(time.Second)},
+		}
+
+		p := store.RetentionPolicy{KeepLast: 2}
+		require.Equal(t, []string{"b", "a"}, p.Expired("p", unordered, "d", now))
+	})
+}
+
+func TestPruneNamespaces(t *testing.T) {
+	ns := memory.NewNamespaceService()
+
+	for _, name := range []string{"a", "b"} {
+		require.NoError(t, ns.Create(context.Background(), name))
+		s, err := ns.Rulesets(context.Background(), name)
+		require.NoError(t, err)
+
+		for _, v := range []string{"1", "2"} {
+			rs, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue(v)))
+			_, err = s.Put(context.Background(), "rules", rs)
+			require.NoError(t, err)
+		}
+	}
+
+	// versions created in the same second have no defined order, only their age is used
+	// by moving the clock forward.
+	now := time.Now().Add(time.Hour)
+	n, err := store.PruneNamespaces(context.Background(), ns, &store.RetentionPolicy{KeepFor: time.Minute}, now)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+}
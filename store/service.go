@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/heetch/regula"
 	"github.com/heetch/regula/rule"
@@ -14,6 +15,9 @@ var (
 	ErrNotFound             = errors.New("not found")
 	ErrNotModified          = errors.New("not modified")
 	ErrInvalidContinueToken = errors.New("invalid continue token")
+	ErrLatestVersion        = errors.New("latest version can't be deleted")
+	ErrVersionMismatch      = errors.New("version mismatch")
+	ErrRevisionCompacted    = errors.New("revision compacted")
 )
 
 // ValidationError gives informations about the reason of failed validation.
@@ -35,22 +39,106 @@ func IsValidationError(err error) bool {
 
 // RulesetService manages rulesets.
 type RulesetService interface {
-	// List returns all the rulesets entries under the given prefix.
-	List(ctx context.Context, prefix string, limit int, continueToken string) (*RulesetEntries, error)
+	// List returns the rulesets entries under the given prefix, sorted by path then by version.
+	List(ctx context.Context, prefix string, opt ListOptions) (*RulesetEntries, error)
 	// Latest returns the latest version of the ruleset entry which corresponds to the given path.
 	Latest(ctx context.Context, path string) (*RulesetEntry, error)
 	// OneByVersion returns the ruleset entry which corresponds to the given path at the given version.
 	OneByVersion(ctx context.Context, path, version string) (*RulesetEntry, error)
+	// Versions returns the versions of the ruleset stored on the given path, newest first.
+	Versions(ctx context.Context, path string, limit int, continueToken string) (*RulesetVersions, error)
+	// History returns the changes made to the ruleset stored on the given path, newest first.
+	History(ctx context.Context, path string, limit int, continueToken string) (*AuditEntries, error)
+	// Rollback makes the given version the latest version of the ruleset stored on the given path.
+	Rollback(ctx context.Context, path, version string) (*RulesetEntry, error)
+	// Signatures returns the history of the signatures of the ruleset stored on the given path, the current one last.
+	Signatures(ctx context.Context, path string) ([]Signature, error)
+	// MigrateSignature applies the given migration to the signature of the ruleset stored on the given path.
+	MigrateSignature(ctx context.Context, path string, m *SignatureMigration) (*Signature, error)
+	// Delete removes the ruleset stored on the given path. If hard is false, the versions of the ruleset
+	// are kept aside and its signature is preserved, otherwise everything but the history is removed.
+	Delete(ctx context.Context, path string, hard bool) error
+	// DeleteVersions removes the given versions of the ruleset stored on the given path.
+	// Unknown versions are ignored. It returns ErrLatestVersion if one of the versions is the latest one.
+	DeleteVersions(ctx context.Context, path string, versions ...string) error
 	// Watch a prefix for changes and return a list of events.
+	// It returns ErrRevisionCompacted if the events that occurred after the given revision are no longer available.
 	Watch(ctx context.Context, prefix string, revision string) (*RulesetEvents, error)
-	// Put is used to store a ruleset version.
-	Put(ctx context.Context, path string, ruleset *regula.Ruleset) (*RulesetEntry, error)
+	// Put is used to store a ruleset version. The change is recorded in the history of the path
+	// along with the audit information carried by ctx, see WithAudit.
+	// If an expected version is given, see ExpectedVersion, and the latest version of the ruleset
+	// is a different one, the latest entry, if any, is returned with ErrVersionMismatch.
+	Put(ctx context.Context, path string, ruleset *regula.Ruleset, opts ...PutOption) (*RulesetEntry, error)
+	// PutBatch stores a version of every given ruleset atomically, under a single revision, the versions
+	// of the entries being ignored. Either all the changed rulesets are stored or none of them.
+	// It returns the latest entry of every path, in the same order, or ErrNotModified with the entries
+	// if none of the rulesets changed.
+	// Batches of more than MaxBatchSize rulesets are rejected with a ValidationError.
+	PutBatch(ctx context.Context, entries []RulesetEntry) ([]RulesetEntry, error)
 	// Eval evaluates a ruleset given a path and a set of parameters. It implements the regula.Evaluator interface.
 	Eval(ctx context.Context, path string, params rule.Params) (*regula.EvalResult, error)
 	// EvalVersion evaluates a ruleset given a path and a set of parameters. It implements the regula.Evaluator interface.
 	EvalVersion(ctx context.Context, path, version string, params rule.Params) (*regula.EvalResult, error)
 }
 
+// PutOptions contains the options of RulesetService.Put.
+type PutOptions struct {
+	// ExpectedVersion, if not empty, must be the latest version of the ruleset.
+	ExpectedVersion string
+}
+
+// PutOption customizes a call to RulesetService.Put.
+type PutOption func(*PutOptions)
+
+// ExpectedVersion makes RulesetService.Put fail with ErrVersionMismatch
+// unless the given version is the latest version of the ruleset when the new one is created.
+func ExpectedVersion(version string) PutOption {
+	return func(o *PutOptions) {
+		o.ExpectedVersion = version
+	}
+}
+
+// NewPutOptions returns the options resulting from applying opts in order.
+func NewPutOptions(opts ...PutOption) PutOptions {
+	var o PutOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// Limits of the paginated methods of RulesetService.
+const (
+	// DefaultLimit is the number of items returned when no valid limit is given.
+	DefaultLimit = 50
+	// MaxLimit is the maximum number of items returned at once.
+	MaxLimit = 100
+)
+
+// DefaultEventsRetention is the number of revisions whose events are kept for the watchers
+// by the stores recording them themselves. Older events are dropped and can't be watched anymore.
+const DefaultEventsRetention = 1000
+
+// NormalizeLimit returns the number of items to return for the given limit:
+// limits that are not positive or exceed MaxLimit are replaced by DefaultLimit.
+func NormalizeLimit(limit int) int {
+	if limit <= 0 || limit > MaxLimit {
+		return DefaultLimit
+	}
+
+	return limit
+}
+
+// ListOptions contains the options of RulesetService.List.
+type ListOptions struct {
+	// Limit is normalized with NormalizeLimit, except zero which returns all the entries.
+	Limit         int
+	ContinueToken string
+	// LatestOnly returns only the latest version of each ruleset instead of all of them.
+	LatestOnly bool
+}
+
 // RulesetEntry holds a ruleset and its metadata.
 type RulesetEntry struct {
 	Path    string
@@ -65,9 +153,23 @@ type RulesetEntries struct {
 	Continue string // token of the next page, if any
 }
 
+// RulesetVersion describes a version of a ruleset.
+type RulesetVersion struct {
+	Version   string
+	CreatedAt time.Time
+}
+
+// RulesetVersions holds a list of versions of a ruleset.
+type RulesetVersions struct {
+	Path     string
+	Versions []RulesetVersion
+	Continue string // token of the next page, if any
+}
+
 // List of possible events executed against a ruleset.
 const (
-	RulesetPutEvent = "PUT"
+	RulesetPutEvent    = "PUT"
+	RulesetDeleteEvent = "DELETE"
 )
 
 // RulesetEvent describes an event that occured on a ruleset.
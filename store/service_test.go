@@ -0,0 +1,24 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/heetch/regula/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeLimit(t *testing.T) {
+	tests := []struct {
+		limit, expected int
+	}{
+		{-1, store.DefaultLimit},
+		{0, store.DefaultLimit},
+		{1, 1},
+		{store.MaxLimit, store.MaxLimit},
+		{store.MaxLimit + 1, store.DefaultLimit},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.expected, store.NormalizeLimit(test.limit), "limit %d", test.limit)
+	}
+}
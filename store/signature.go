@@ -0,0 +1,250 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/heetch/regula"
+	"github.com/heetch/regula/rule"
+)
+
+// Signature describes the return type and the parameters of the rulesets stored on a path.
+// It is created by the first version of a ruleset and can only evolve through a SignatureMigration.
+type Signature struct {
+	ReturnType string
+	ParamTypes map[string]string
+	// Optional params may be omitted by the callers, rules reading a missing optional param don't match.
+	Optional []string `json:",omitempty"`
+	// Deprecated params can't be used by new versions of the ruleset.
+	Deprecated []string `json:",omitempty"`
+}
+
+// NewSignature returns the signature of the given ruleset.
+// The params declared by its schema are part of the signature even if the rules don't use them.
+func NewSignature(rs *regula.Ruleset) *Signature {
+	pt := make(map[string]string)
+	for _, p := range rs.Params() {
+		pt[p.Name] = p.Type
+	}
+
+	var optional []string
+	for _, spec := range rs.Schema {
+		if spec == nil {
+			continue
+		}
+
+		if _, ok := pt[spec.Name]; !ok {
+			pt[spec.Name] = spec.Type
+		}
+
+		if spec.Optional {
+			optional = append(optional, spec.Name)
+		}
+	}
+	sort.Strings(optional)
+
+	return &Signature{
+		ParamTypes: pt,
+		ReturnType: rs.Type,
+		Optional:   optional,
+	}
+}
+
+// MatchWith makes sure the other signature, generated from a new version of a ruleset, is compatible with s.
+func (s *Signature) MatchWith(other *Signature) error {
+	if s.ReturnType != other.ReturnType {
+		return &ValidationError{
+			Field:  "return type",
+			Value:  other.ReturnType,
+			Reason: fmt.Sprintf("signature mismatch: return type must be of type %s", s.ReturnType),
+		}
+	}
+
+	for name, tp := range other.ParamTypes {
+		stp, ok := s.ParamTypes[name]
+		if !ok {
+			return &ValidationError{
+				Field:  "param",
+				Value:  name,
+				Reason: "signature mismatch: unknown parameter",
+			}
+		}
+
+		if tp != stp {
+			return &ValidationError{
+				Field:  "param type",
+				Value:  tp,
+				Reason: fmt.Sprintf("signature mismatch: param must be of type %s", stp),
+			}
+		}
+
+		if contains(s.Deprecated, name) {
+			return &ValidationError{
+				Field:  "param",
+				Value:  name,
+				Reason: "signature mismatch: deprecated parameter",
+			}
+		}
+	}
+
+	return nil
+}
+
+// Evaluable makes sure an existing version of a ruleset can still be evaluated with params matching s.
+// Unlike MatchWith, it accepts deprecated params.
+func (s *Signature) Evaluable(rs *regula.Ruleset) error {
+	other := NewSignature(rs)
+
+	if s.ReturnType != other.ReturnType {
+		return &ValidationError{
+			Field:  "return type",
+			Value:  other.ReturnType,
+			Reason: fmt.Sprintf("signature mismatch: return type must be of type %s", s.ReturnType),
+		}
+	}
+
+	for name, tp := range other.ParamTypes {
+		stp, ok := s.ParamTypes[name]
+		if !ok {
+			return &ValidationError{
+				Field:  "param",
+				Value:  name,
+				Reason: "signature mismatch: unknown parameter",
+			}
+		}
+
+		if tp == "int64" && stp == "float64" {
+			return &ValidationError{
+				Field:  "param type",
+				Value:  tp,
+				Reason: fmt.Sprintf("signature mismatch: param %s can't be widened to float64 while it is used as int64", name),
+			}
+		}
+
+		if tp != stp {
+			return &ValidationError{
+				Field:  "param type",
+				Value:  tp,
+				Reason: fmt.Sprintf("signature mismatch: param must be of type %s", stp),
+			}
+		}
+	}
+
+	return nil
+}
+
+// SignatureMigration describes the changes to apply to a signature.
+type SignatureMigration struct {
+	// AddParams lists the optional params to add, by name and type.
+	AddParams map[string]string
+	// WidenParams lists the params whose type must be widened, by name and new type.
+	// Only int64 params can be widened to float64, once the versions using them as int64 are deleted
+	// since these versions can't be evaluated with float64 values.
+	WidenParams map[string]string
+	// DeprecateParams lists the params that can't be used by new versions anymore.
+	DeprecateParams []string
+}
+
+// Migrate returns a copy of s with the given migration applied.
+func (s *Signature) Migrate(m *SignatureMigration) (*Signature, error) {
+	if len(m.AddParams) == 0 && len(m.WidenParams) == 0 && len(m.DeprecateParams) == 0 {
+		return nil, &ValidationError{
+			Field:  "migration",
+			Reason: "empty migration",
+		}
+	}
+
+	sig := Signature{
+		ReturnType: s.ReturnType,
+		ParamTypes: make(map[string]string, len(s.ParamTypes)+len(m.AddParams)),
+		Optional:   append([]string(nil), s.Optional...),
+		Deprecated: append([]string(nil), s.Deprecated...),
+	}
+
+	for name, tp := range s.ParamTypes {
+		sig.ParamTypes[name] = tp
+	}
+
+	for name, tp := range m.AddParams {
+		if _, ok := sig.ParamTypes[name]; ok {
+			return nil, &ValidationError{
+				Field:  "param",
+				Value:  name,
+				Reason: "parameter already exists",
+			}
+		}
+
+		if tp != "string" && tp != "bool" && tp != "int64" && tp != "float64" {
+			return nil, &ValidationError{
+				Field:  "param type",
+				Value:  tp,
+				Reason: "unsupported type",
+			}
+		}
+
+		sig.ParamTypes[name] = tp
+		sig.Optional = append(sig.Optional, name)
+	}
+
+	for name, tp := range m.WidenParams {
+		stp, ok := sig.ParamTypes[name]
+		if !ok {
+			return nil, &ValidationError{
+				Field:  "param",
+				Value:  name,
+				Reason: "unknown parameter",
+			}
+		}
+
+		if stp != "int64" || tp != "float64" {
+			return nil, &ValidationError{
+				Field:  "param type",
+				Value:  tp,
+				Reason: fmt.Sprintf("param of type %s can't be widened to %s", stp, tp),
+			}
+		}
+
+		sig.ParamTypes[name] = tp
+	}
+
+	for _, name := range m.DeprecateParams {
+		if _, ok := sig.ParamTypes[name]; !ok {
+			return nil, &ValidationError{
+				Field:  "param",
+				Value:  name,
+				Reason: "unknown parameter",
+			}
+		}
+
+		if contains(sig.Deprecated, name) {
+			return nil, &ValidationError{
+				Field:  "param",
+				Value:  name,
+				Reason: "parameter already deprecated",
+			}
+		}
+
+		sig.Deprecated = append(sig.Deprecated, name)
+	}
+
+	sort.Strings(sig.Optional)
+	sort.Strings(sig.Deprecated)
+
+	return &sig, nil
+}
+
+// Params wraps the given params so that reading a missing optional param
+// makes the rule being evaluated not match.
+func (s *Signature) Params(params rule.Params) rule.Params {
+	return regula.OptionalParams(params, s.Optional)
+}
+
+func contains(l []string, s string) bool {
+	for i := range l {
+		if l[i] == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,107 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/heetch/regula"
+	"github.com/heetch/regula/rule"
+	"github.com/heetch/regula/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignatureMigrate(t *testing.T) {
+	sig := store.Signature{
+		ReturnType: "string",
+		ParamTypes: map[string]string{"a": "int64", "b": "string"},
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		newSig, err := sig.Migrate(&store.SignatureMigration{
+			AddParams:       map[string]string{"c": "bool"},
+			WidenParams:     map[string]string{"a": "float64"},
+			DeprecateParams: []string{"b"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, &store.Signature{
+			ReturnType: "string",
+			ParamTypes: map[string]string{"a": "float64", "b": "string", "c": "bool"},
+			Optional:   []string{"c"},
+			Deprecated: []string{"b"},
+		}, newSig)
+
+		// the original signature is left untouched
+		require.Equal(t, map[string]string{"a": "int64", "b": "string"}, sig.ParamTypes)
+	})
+
+	t.Run("NOK", func(t *testing.T) {
+		migrations := []store.SignatureMigration{
+			{},
+			{AddParams: map[string]string{"a": "bool"}},
+			{AddParams: map[string]string{"c": "duration"}},
+			{WidenParams: map[string]string{"b": "float64"}},
+			{WidenParams: map[string]string{"a": "string"}},
+			{WidenParams: map[string]string{"c": "float64"}},
+			{DeprecateParams: []string{"c"}},
+		}
+
+		for _, m := range migrations {
+			_, err := sig.Migrate(&m)
+			require.True(t, store.IsValidationError(err))
+		}
+	})
+}
+
+func TestSignatureMatchWith(t *testing.T) {
+	sig := store.Signature{
+		ReturnType: "string",
+		ParamTypes: map[string]string{"a": "float64", "b": "string"},
+		Deprecated: []string{"b"},
+	}
+
+	rs, _ := regula.NewStringRuleset(rule.New(rule.GT(rule.Float64Param("a"), rule.Float64Value(1)), rule.StringValue("ok")))
+	require.NoError(t, sig.MatchWith(store.NewSignature(rs)))
+	require.NoError(t, sig.Evaluable(rs))
+
+	// versions using int64 params widened to float64 can't be evaluated anymore
+	rs, _ = regula.NewStringRuleset(rule.New(rule.GT(rule.Int64Param("a"), rule.Int64Value(1)), rule.StringValue("ok")))
+	require.True(t, store.IsValidationError(sig.MatchWith(store.NewSignature(rs))))
+	require.True(t, store.IsValidationError(sig.Evaluable(rs)))
+
+	// deprecated params remain evaluable but can't be used by new versions
+	rs, _ = regula.NewStringRuleset(rule.New(rule.Eq(rule.StringParam("b"), rule.StringValue("b")), rule.StringValue("ok")))
+	require.True(t, store.IsValidationError(sig.MatchWith(store.NewSignature(rs))))
+	require.NoError(t, sig.Evaluable(rs))
+
+	rs, _ = regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(true)))
+	require.True(t, store.IsValidationError(sig.MatchWith(store.NewSignature(rs))))
+	require.True(t, store.IsValidationError(sig.Evaluable(rs)))
+}
+
+func TestSignatureParams(t *testing.T) {
+	sig := store.Signature{
+		ReturnType: "string",
+		ParamTypes: map[string]string{"a": "string", "b": "string"},
+		Optional:   []string{"b"},
+	}
+
+	rs, _ := regula.NewStringRuleset(
+		rule.New(rule.Eq(rule.StringParam("b"), rule.StringValue("b")), rule.StringValue("first")),
+		rule.New(rule.Eq(rule.StringParam("a"), rule.StringValue("a")), rule.StringValue("second")),
+	)
+
+	// without the signature, missing params make the evaluation fail
+	_, err := rs.Eval(regula.Params{"a": "a"})
+	require.Equal(t, rule.ErrParamNotFound, err)
+
+	v, err := rs.Eval(sig.Params(regula.Params{"a": "a"}))
+	require.NoError(t, err)
+	require.Equal(t, rule.StringValue("second"), v)
+
+	v, err = rs.Eval(sig.Params(regula.Params{"a": "a", "b": "b"}))
+	require.NoError(t, err)
+	require.Equal(t, rule.StringValue("first"), v)
+
+	// required params are still required
+	_, err = rs.Eval(sig.Params(regula.Params{"b": "c"}))
+	require.Equal(t, rule.ErrParamNotFound, err)
+}
@@ -0,0 +1,101 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/heetch/regula"
+	"github.com/heetch/regula/rule"
+	"github.com/heetch/regula/store"
+	"github.com/stretchr/testify/require"
+)
+
+// NewNamespaceServiceFunc returns an empty NamespaceService and a function releasing its resources.
+type NewNamespaceServiceFunc func(t *testing.T) (store.NamespaceService, func())
+
+// RunNamespaces runs the conformance test suite of the namespaces against the services returned by newService.
+func RunNamespaces(t *testing.T, newService NewNamespaceServiceFunc) {
+	tests := []struct {
+		name string
+		fn   func(*testing.T, store.NamespaceService)
+	}{
+		{"Create", testNamespaceCreate},
+		{"Rulesets", testNamespaceRulesets},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, cleanup := newService(t)
+			defer cleanup()
+
+			test.fn(t, s)
+		})
+	}
+}
+
+func testNamespaceCreate(t *testing.T, s store.NamespaceService) {
+	names, err := s.List(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, names)
+
+	for _, name := range []string{"b", "a", "ab"} {
+		require.NoError(t, s.Create(context.Background(), name))
+	}
+
+	err = s.Create(context.Background(), "a")
+	require.Equal(t, store.ErrNotModified, err)
+
+	for _, name := range []string{"", "A", "a/b", "_namespaces"} {
+		err = s.Create(context.Background(), name)
+		require.True(t, store.IsValidationError(err), name)
+	}
+
+	names, err = s.List(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "ab", "b"}, names)
+}
+
+func testNamespaceRulesets(t *testing.T, s store.NamespaceService) {
+	_, err := s.Rulesets(context.Background(), "a")
+	require.Equal(t, store.ErrNotFound, err)
+
+	for _, name := range []string{"a", "ab"} {
+		require.NoError(t, s.Create(context.Background(), name))
+	}
+
+	a, err := s.Rulesets(context.Background(), "a")
+	require.NoError(t, err)
+	ab, err := s.Rulesets(context.Background(), "ab")
+	require.NoError(t, err)
+
+	rs, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("a")))
+	entry, err := a.Put(context.Background(), "rules", rs)
+	require.NoError(t, err)
+
+	// the services of a namespace share the same rulesets
+	again, err := s.Rulesets(context.Background(), "a")
+	require.NoError(t, err)
+	latest, err := again.Latest(context.Background(), "rules")
+	require.NoError(t, err)
+	require.Equal(t, entry.Version, latest.Version)
+
+	// but the namespaces are isolated, even when their names share a prefix
+	_, err = ab.Latest(context.Background(), "rules")
+	require.Equal(t, store.ErrNotFound, err)
+
+	list, err := ab.List(context.Background(), "", store.ListOptions{})
+	require.NoError(t, err)
+	require.Empty(t, list.Entries)
+
+	// signatures too
+	other, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(true)))
+	_, err = ab.Put(context.Background(), "rules", other)
+	require.NoError(t, err)
+
+	res, err := a.Eval(context.Background(), "rules", nil)
+	require.NoError(t, err)
+	require.Equal(t, rule.StringValue("a"), res.Value)
+}
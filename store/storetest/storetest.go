@@ -0,0 +1,1252 @@
+// Package storetest provides a conformance test suite for store.RulesetService implementations.
+// It specifies the behavior every backend must share with the etcd implementation.
+package storetest
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/heetch/regula"
+	"github.com/heetch/regula/rule"
+	"github.com/heetch/regula/store"
+	"github.com/stretchr/testify/require"
+)
+
+// NewServiceFunc returns an empty RulesetService and a function releasing its resources.
+type NewServiceFunc func(t *testing.T) (store.RulesetService, func())
+
+// Run runs the conformance test suite against the services returned by newService.
+// Every test uses its own service and runs in parallel with the others.
+func Run(t *testing.T, newService NewServiceFunc) {
+	tests := []struct {
+		name string
+		fn   func(*testing.T, store.RulesetService)
+	}{
+		{"List", testList},
+		{"Latest", testLatest},
+		{"OneByVersion", testOneByVersion},
+		{"Versions", testVersions},
+		{"History", testHistory},
+		{"Put", testPut},
+		{"PutBatch", testPutBatch},
+		{"Rollback", testRollback},
+		{"Delete", testDelete},
+		{"DeleteVersions", testDeleteVersions},
+		{"Prune", testPrune},
+		{"MigrateSignature", testMigrateSignature},
+		{"Watch", testWatch},
+		{"Eval", testEval},
+		{"EvalVersion", testEvalVersion},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, cleanup := newService(t)
+			defer cleanup()
+
+			test.fn(t, s)
+		})
+	}
+}
+
+func createRuleset(t *testing.T, s store.RulesetService, path string, r *regula.Ruleset) *store.RulesetEntry {
+	t.Helper()
+
+	e, err := s.Put(context.Background(), path, r)
+	if err != nil && err != store.ErrNotModified {
+		require.NoError(t, err)
+	}
+	return e
+}
+
+func paths(entries []store.RulesetEntry) []string {
+	l := make([]string, len(entries))
+	for i := range entries {
+		l[i] = entries[i].Path
+	}
+	return l
+}
+
+func testList(t *testing.T, s store.RulesetService) {
+	rs, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(true)))
+
+	t.Run("Root", func(t *testing.T) {
+		createRuleset(t, s, "c", rs)
+		createRuleset(t, s, "a", rs)
+		createRuleset(t, s, "b", rs)
+		createRuleset(t, s, "a", rs)
+
+		entries, err := s.List(context.Background(), "", store.ListOptions{})
+		require.NoError(t, err)
+		require.Equal(t, []string{"a", "b", "c"}, paths(entries.Entries))
+		require.NotEmpty(t, entries.Revision)
+	})
+
+	t.Run("Prefix", func(t *testing.T) {
+		createRuleset(t, s, "x", rs)
+		createRuleset(t, s, "xx", rs)
+		createRuleset(t, s, "x/1", rs)
+		createRuleset(t, s, "x/2", rs)
+
+		entries, err := s.List(context.Background(), "x", store.ListOptions{})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"x", "x/1", "x/2", "xx"}, paths(entries.Entries))
+		require.Equal(t, "xx", entries.Entries[3].Path)
+		require.NotEmpty(t, entries.Revision)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := s.List(context.Background(), "doesntexist", store.ListOptions{})
+		require.Equal(t, store.ErrNotFound, err)
+	})
+
+	t.Run("Paging", func(t *testing.T) {
+		for _, path := range []string{"y1", "y2", "y3", "y4", "y5"} {
+			createRuleset(t, s, path, rs)
+		}
+
+		entries, err := s.List(context.Background(), "y", store.ListOptions{Limit: 2})
+		require.NoError(t, err)
+		require.Equal(t, []string{"y1", "y2"}, paths(entries.Entries))
+		require.NotEmpty(t, entries.Continue)
+
+		token := entries.Continue
+		entries, err = s.List(context.Background(), "y", store.ListOptions{Limit: 2, ContinueToken: entries.Continue})
+		require.NoError(t, err)
+		require.Equal(t, []string{"y3", "y4"}, paths(entries.Entries))
+		require.NotEmpty(t, entries.Continue)
+
+		entries, err = s.List(context.Background(), "y", store.ListOptions{Limit: 2, ContinueToken: entries.Continue})
+		require.NoError(t, err)
+		require.Equal(t, []string{"y5"}, paths(entries.Entries))
+		require.Empty(t, entries.Continue)
+
+		entries, err = s.List(context.Background(), "y", store.ListOptions{Limit: 3, ContinueToken: token})
+		require.NoError(t, err)
+		require.Equal(t, []string{"y3", "y4", "y5"}, paths(entries.Entries))
+		require.Empty(t, entries.Continue)
+
+		_, err = s.List(context.Background(), "y", store.ListOptions{Limit: 3, ContinueToken: "some token"})
+		require.Equal(t, store.ErrInvalidContinueToken, err)
+
+		entries, err = s.List(context.Background(), "y", store.ListOptions{Limit: -10})
+		require.NoError(t, err)
+		require.Len(t, entries.Entries, 5)
+	})
+
+	t.Run("LatestOnly", func(t *testing.T) {
+		r1, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("1")))
+		r2, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("2")))
+
+		z1 := createRuleset(t, s, "z", r1)
+		createRuleset(t, s, "z", r2)
+		createRuleset(t, s, "z/a", r1)
+		za2 := createRuleset(t, s, "z/a", r2)
+		zz := createRuleset(t, s, "zz", r1)
+		createRuleset(t, s, "zd", r1)
+
+		_, err := s.Rollback(context.Background(), "z", z1.Version)
+		require.NoError(t, err)
+		require.NoError(t, s.Delete(context.Background(), "zd", false))
+
+		entries, err := s.List(context.Background(), "z", store.ListOptions{LatestOnly: true})
+		require.NoError(t, err)
+		require.Equal(t, []string{"z", "z/a", "zz"}, paths(entries.Entries))
+		require.Equal(t, z1.Version, entries.Entries[0].Version)
+		require.Equal(t, za2.Version, entries.Entries[1].Version)
+		require.Equal(t, zz.Version, entries.Entries[2].Version)
+		require.Equal(t, r2, entries.Entries[1].Ruleset)
+		require.NotEmpty(t, entries.Revision)
+
+		entries, err = s.List(context.Background(), "z", store.ListOptions{Limit: 2, LatestOnly: true})
+		require.NoError(t, err)
+		require.Equal(t, []string{"z", "z/a"}, paths(entries.Entries))
+		require.NotEmpty(t, entries.Continue)
+
+		entries, err = s.List(context.Background(), "z", store.ListOptions{Limit: 2, ContinueToken: entries.Continue, LatestOnly: true})
+		require.NoError(t, err)
+		require.Equal(t, []string{"zz"}, paths(entries.Entries))
+		require.Empty(t, entries.Continue)
+
+		_, err = s.List(context.Background(), "zd", store.ListOptions{LatestOnly: true})
+		require.Equal(t, store.ErrNotFound, err)
+	})
+}
+
+func testLatest(t *testing.T, s store.RulesetService) {
+	oldRse, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("a")))
+	newRse, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("b")))
+
+	createRuleset(t, s, "a", oldRse)
+	// sleep 1 second because ksuid doesn't guarantee the order within the same second since it's based on a 32 bits timestamp (second).
+	time.Sleep(time.Second)
+	createRuleset(t, s, "a", newRse)
+
+	rs, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(true)))
+	rs2, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(false)))
+	createRuleset(t, s, "b", rs)
+	createRuleset(t, s, "abc", rs)
+	createRuleset(t, s, "abcd", rs)
+	createRuleset(t, s, "abcd/e", rs2)
+
+	t.Run("OK", func(t *testing.T) {
+		entry, err := s.Latest(context.Background(), "a")
+		require.NoError(t, err)
+		require.Equal(t, "a", entry.Path)
+		require.Equal(t, newRse, entry.Ruleset)
+
+		entry, err = s.Latest(context.Background(), "b")
+		require.NoError(t, err)
+		require.Equal(t, rs, entry.Ruleset)
+	})
+
+	t.Run("SubPath", func(t *testing.T) {
+		entry, err := s.Latest(context.Background(), "abcd")
+		require.NoError(t, err)
+		require.Equal(t, rs, entry.Ruleset)
+
+		entry, err = s.Latest(context.Background(), "abcd/e")
+		require.NoError(t, err)
+		require.Equal(t, rs2, entry.Ruleset)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		for _, path := range []string{"aa", "", "ab"} {
+			_, err := s.Latest(context.Background(), path)
+			require.Equal(t, store.ErrNotFound, err)
+		}
+	})
+}
+
+func testOneByVersion(t *testing.T, s store.RulesetService) {
+	oldRse, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("a")))
+	newRse, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("b")))
+
+	version := createRuleset(t, s, "a", oldRse).Version
+	createRuleset(t, s, "a", newRse)
+
+	rs, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(true)))
+	createRuleset(t, s, "abc", rs)
+
+	t.Run("OK", func(t *testing.T) {
+		entry, err := s.OneByVersion(context.Background(), "a", version)
+		require.NoError(t, err)
+		require.Equal(t, "a", entry.Path)
+		require.Equal(t, version, entry.Version)
+		require.Equal(t, oldRse, entry.Ruleset)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		for _, path := range []string{"a", "ab", ""} {
+			_, err := s.OneByVersion(context.Background(), path, "123version")
+			require.Equal(t, store.ErrNotFound, err)
+		}
+	})
+}
+
+func testVersions(t *testing.T, s store.RulesetService) {
+	// versions are returned newest first.
+	var versions []string
+	for i, v := range []string{"a", "b", "c"} {
+		if i > 0 {
+			// sleep 1 second because ksuid doesn't guarantee the order within the same second.
+			time.Sleep(time.Second)
+		}
+
+		rs, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue(v)))
+		versions = append([]string{createRuleset(t, s, "a", rs).Version}, versions...)
+	}
+
+	rs, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(true)))
+	createRuleset(t, s, "a/b", rs)
+	createRuleset(t, s, "ab", rs)
+
+	t.Run("OK", func(t *testing.T) {
+		vs, err := s.Versions(context.Background(), "a", 0, "")
+		require.NoError(t, err)
+		require.Equal(t, "a", vs.Path)
+		require.Empty(t, vs.Continue)
+		require.Len(t, vs.Versions, len(versions))
+		for i := range versions {
+			require.Equal(t, versions[i], vs.Versions[i].Version)
+			require.False(t, vs.Versions[i].CreatedAt.IsZero())
+		}
+	})
+
+	t.Run("Paging", func(t *testing.T) {
+		vs, err := s.Versions(context.Background(), "a", 2, "")
+		require.NoError(t, err)
+		require.Len(t, vs.Versions, 2)
+		require.Equal(t, versions[0], vs.Versions[0].Version)
+		require.Equal(t, versions[1], vs.Versions[1].Version)
+		require.NotEmpty(t, vs.Continue)
+
+		vs, err = s.Versions(context.Background(), "a", 2, vs.Continue)
+		require.NoError(t, err)
+		require.Len(t, vs.Versions, 1)
+		require.Equal(t, versions[2], vs.Versions[0].Version)
+		require.Empty(t, vs.Continue)
+
+		_, err = s.Versions(context.Background(), "a", 2, "some token")
+		require.Equal(t, store.ErrInvalidContinueToken, err)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		for _, path := range []string{"b", "a/b/c", ""} {
+			_, err := s.Versions(context.Background(), path, 0, "")
+			require.Equal(t, store.ErrNotFound, err)
+		}
+	})
+}
+
+func testHistory(t *testing.T, s store.RulesetService) {
+	// entries are returned newest first.
+	var versions []string
+	for _, v := range []string{"a", "b", "c"} {
+		ctx := store.WithAudit(context.Background(), store.Audit{Author: "author " + v, Message: "message " + v})
+		rs, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue(v)))
+		entry, err := s.Put(ctx, "a", rs)
+		require.NoError(t, err)
+		versions = append([]string{entry.Version}, versions...)
+
+		// unchanged rulesets are not recorded
+		_, err = s.Put(ctx, "a", rs)
+		require.Equal(t, store.ErrNotModified, err)
+	}
+
+	rs, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(true)))
+	createRuleset(t, s, "a/b", rs)
+	createRuleset(t, s, "ab", rs)
+
+	t.Run("OK", func(t *testing.T) {
+		h, err := s.History(context.Background(), "a", 0, "")
+		require.NoError(t, err)
+		require.Equal(t, "a", h.Path)
+		require.Empty(t, h.Continue)
+		require.Len(t, h.Entries, len(versions))
+		for i, v := range []string{"c", "b", "a"} {
+			require.Equal(t, "a", h.Entries[i].Path)
+			require.Equal(t, store.AuditPut, h.Entries[i].Action)
+			require.Equal(t, versions[i], h.Entries[i].Version)
+			require.Equal(t, "author "+v, h.Entries[i].Author)
+			require.Equal(t, "message "+v, h.Entries[i].Message)
+			require.False(t, h.Entries[i].Time.IsZero())
+			if i < len(versions)-1 {
+				require.Equal(t, versions[i+1], h.Entries[i].PreviousVersion)
+			} else {
+				require.Empty(t, h.Entries[i].PreviousVersion)
+			}
+		}
+	})
+
+	t.Run("Paging", func(t *testing.T) {
+		h, err := s.History(context.Background(), "a", 2, "")
+		require.NoError(t, err)
+		require.Len(t, h.Entries, 2)
+		require.Equal(t, versions[0], h.Entries[0].Version)
+		require.Equal(t, versions[1], h.Entries[1].Version)
+		require.NotEmpty(t, h.Continue)
+
+		h, err = s.History(context.Background(), "a", 2, h.Continue)
+		require.NoError(t, err)
+		require.Len(t, h.Entries, 1)
+		require.Equal(t, versions[2], h.Entries[0].Version)
+		require.Empty(t, h.Continue)
+
+		_, err = s.History(context.Background(), "a", 2, "some token")
+		require.Equal(t, store.ErrInvalidContinueToken, err)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		for _, path := range []string{"b", "a/b/c", ""} {
+			_, err := s.History(context.Background(), path, 0, "")
+			require.Equal(t, store.ErrNotFound, err)
+		}
+	})
+
+	t.Run("Actions", func(t *testing.T) {
+		ctx := context.Background()
+		first, err := s.Latest(ctx, "ab")
+		require.NoError(t, err)
+
+		rs, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(false)))
+		second, err := s.Put(ctx, "ab", rs)
+		require.NoError(t, err)
+
+		_, err = s.Rollback(ctx, "ab", first.Version)
+		require.NoError(t, err)
+
+		_, err = s.MigrateSignature(ctx, "ab", &store.SignatureMigration{
+			AddParams: map[string]string{"x": "string"},
+		})
+		require.NoError(t, err)
+
+		// the history survives soft and hard deletes
+		ctx = store.WithAudit(ctx, store.Audit{Author: "deleter"})
+		require.NoError(t, s.Delete(ctx, "ab", false))
+		require.NoError(t, s.Delete(ctx, "ab", true))
+
+		h, err := s.History(ctx, "ab", 0, "")
+		require.NoError(t, err)
+
+		expected := []struct {
+			action, version, previous string
+		}{
+			{store.AuditHardDelete, "", ""},
+			{store.AuditDelete, "", first.Version},
+			{store.AuditMigrateSignature, first.Version, first.Version},
+			{store.AuditRollback, first.Version, second.Version},
+			{store.AuditPut, second.Version, first.Version},
+			{store.AuditPut, first.Version, ""},
+		}
+		require.Len(t, h.Entries, len(expected))
+		for i, e := range expected {
+			require.Equal(t, "ab", h.Entries[i].Path)
+			require.Equal(t, e.action, h.Entries[i].Action)
+			require.Equal(t, e.version, h.Entries[i].Version)
+			require.Equal(t, e.previous, h.Entries[i].PreviousVersion)
+		}
+		require.Equal(t, "deleter", h.Entries[0].Author)
+	})
+}
+
+func testPut(t *testing.T, s store.RulesetService) {
+	t.Run("OK", func(t *testing.T) {
+		rs, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(true)))
+
+		entry, err := s.Put(context.Background(), "a", rs)
+		require.NoError(t, err)
+		require.Equal(t, "a", entry.Path)
+		require.NotEmpty(t, entry.Version)
+		require.Equal(t, rs, entry.Ruleset)
+
+		// the same ruleset must not create a new version
+		entry2, err := s.Put(context.Background(), "a", rs)
+		require.Equal(t, store.ErrNotModified, err)
+		require.Equal(t, entry, entry2)
+
+		rs, _ = regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(false)))
+		entry2, err = s.Put(context.Background(), "a", rs)
+		require.NoError(t, err)
+		require.NotEqual(t, entry.Version, entry2.Version)
+	})
+
+	t.Run("Signatures", func(t *testing.T) {
+		rs, _ := regula.NewBoolRuleset(
+			rule.New(rule.Eq(rule.StringParam("a"), rule.BoolParam("b"), rule.Int64Param("c")), rule.BoolValue(true)),
+		)
+		createRuleset(t, s, "b", rs)
+
+		invalid := []*regula.Ruleset{
+			// same params, different return type
+			mustRuleset(regula.NewStringRuleset(
+				rule.New(rule.Eq(rule.StringParam("a"), rule.BoolParam("b"), rule.Int64Param("c")), rule.StringValue("true")),
+			)),
+			// adding new params
+			mustRuleset(regula.NewBoolRuleset(
+				rule.New(rule.Eq(rule.StringParam("a"), rule.BoolParam("b"), rule.Int64Param("c"), rule.BoolParam("d")), rule.BoolValue(true)),
+			)),
+			// changing param types
+			mustRuleset(regula.NewBoolRuleset(
+				rule.New(rule.Eq(rule.StringParam("a"), rule.StringParam("b"), rule.Int64Param("c")), rule.BoolValue(true)),
+			)),
+		}
+
+		for _, rs := range invalid {
+			_, err := s.Put(context.Background(), "b", rs)
+			require.True(t, store.IsValidationError(err))
+		}
+
+		// using less params is allowed
+		rs, _ = regula.NewBoolRuleset(
+			rule.New(rule.Eq(rule.StringParam("a"), rule.BoolParam("b")), rule.BoolValue(true)),
+		)
+		_, err := s.Put(context.Background(), "b", rs)
+		require.NoError(t, err)
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		rs, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(true)))
+		for _, path := range []string{"", "/a", "a/", "a b", "A"} {
+			_, err := s.Put(context.Background(), path, rs)
+			require.True(t, store.IsValidationError(err), path)
+		}
+
+		rs, _ = regula.NewBoolRuleset(rule.New(rule.BoolParam("version"), rule.BoolValue(true)))
+		_, err := s.Put(context.Background(), "v", rs)
+		require.True(t, store.IsValidationError(err))
+	})
+
+	t.Run("Tests", func(t *testing.T) {
+		rs, _ := regula.NewStringRuleset(
+			rule.New(rule.Eq(rule.StringParam("city"), rule.StringValue("paris")), rule.StringValue("vip")),
+		)
+		rs.Tests = []*regula.TestCase{
+			{Params: regula.Params{"city": "paris"}, Result: rule.StringValue("vip")},
+			{Params: regula.Params{"city": "london"}, NoMatch: true},
+		}
+
+		_, err := s.Put(context.Background(), "c", rs)
+		require.NoError(t, err)
+
+		rs.Tests = append(rs.Tests,
+			&regula.TestCase{Params: regula.Params{"city": "london"}, Result: rule.StringValue("vip")},
+			&regula.TestCase{Params: regula.Params{"city": "paris"}, NoMatch: true},
+		)
+
+		_, err = s.Put(context.Background(), "c", rs)
+		require.True(t, store.IsValidationError(err))
+		verr := err.(*store.ValidationError)
+		require.Equal(t, "tests", verr.Field)
+		require.Equal(t, "2,3", verr.Value)
+
+		// nil test cases are rejected
+		rs.Tests = []*regula.TestCase{rs.Tests[0], nil}
+		_, err = s.Put(context.Background(), "c", rs)
+		require.True(t, store.IsValidationError(err))
+		verr = err.(*store.ValidationError)
+		require.Equal(t, "tests", verr.Field)
+		require.Equal(t, "1", verr.Value)
+
+		// the failed versions must not be created
+		entry, err := s.Latest(context.Background(), "c")
+		require.NoError(t, err)
+		require.Len(t, entry.Ruleset.Tests, 2)
+	})
+
+	t.Run("Schema", func(t *testing.T) {
+		rs, _ := regula.NewStringRuleset(
+			rule.New(rule.Eq(rule.StringParam("city"), rule.StringValue("paris")), rule.StringValue("vip")),
+		)
+		rs.Schema = []*regula.ParamSpec{
+			{Name: "city", Type: "int64"},
+		}
+
+		_, err := s.Put(context.Background(), "d", rs)
+		require.True(t, store.IsValidationError(err))
+		verr := err.(*store.ValidationError)
+		require.Equal(t, "schema", verr.Field)
+		require.Equal(t, "city", verr.Value)
+
+		// declared params must have valid names
+		rs.Schema = []*regula.ParamSpec{
+			{Name: "city", Type: "string"},
+			{Name: "version", Type: "string", Optional: true},
+		}
+
+		_, err = s.Put(context.Background(), "d", rs)
+		require.True(t, store.IsValidationError(err))
+		verr = err.(*store.ValidationError)
+		require.Equal(t, "param", verr.Field)
+		require.Equal(t, "version", verr.Value)
+
+		rs.Schema = []*regula.ParamSpec{
+			{Name: "city", Type: "string", Enum: []string{"paris", "milan"}},
+			{Name: "age", Type: "int64", Optional: true},
+		}
+
+		_, err = s.Put(context.Background(), "d", rs)
+		require.NoError(t, err)
+
+		// declared params are part of the signature, even if the rules don't use them
+		sigs, err := s.Signatures(context.Background(), "d")
+		require.NoError(t, err)
+		require.Len(t, sigs, 1)
+		require.Equal(t, map[string]string{"city": "string", "age": "int64"}, sigs[0].ParamTypes)
+		require.Equal(t, []string{"age"}, sigs[0].Optional)
+
+		_, err = s.Eval(context.Background(), "d", regula.Params{"city": "rome"})
+		require.IsType(t, new(regula.ParamError), err)
+
+		entry, err := s.Latest(context.Background(), "d")
+		require.NoError(t, err)
+		require.Equal(t, rs.Schema, entry.Ruleset.Schema)
+	})
+
+	t.Run("ExpectedVersion", func(t *testing.T) {
+		rs1, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("1")))
+		rs2, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("2")))
+		rs3, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("3")))
+
+		// the ruleset doesn't exist yet
+		_, err := s.Put(context.Background(), "e", rs1, store.ExpectedVersion("v"))
+		require.Equal(t, store.ErrVersionMismatch, err)
+
+		e1 := createRuleset(t, s, "e", rs1)
+
+		e2, err := s.Put(context.Background(), "e", rs2, store.ExpectedVersion(e1.Version))
+		require.NoError(t, err)
+
+		// the ruleset changed since e1 was read, the latest entry is returned
+		entry, err := s.Put(context.Background(), "e", rs3, store.ExpectedVersion(e1.Version))
+		require.Equal(t, store.ErrVersionMismatch, err)
+		require.Equal(t, e2, entry)
+
+		latest, err := s.Latest(context.Background(), "e")
+		require.NoError(t, err)
+		require.Equal(t, e2.Version, latest.Version)
+	})
+}
+
+func testPutBatch(t *testing.T, s store.RulesetService) {
+	rs1, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(true)))
+	rs2, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(false)))
+
+	t.Run("OK", func(t *testing.T) {
+		createRuleset(t, s, "z", rs1)
+
+		list, err := s.List(context.Background(), "", store.ListOptions{})
+		require.NoError(t, err)
+
+		entries, err := s.PutBatch(context.Background(), []store.RulesetEntry{
+			{Path: "a", Ruleset: rs1},
+			{Path: "b", Ruleset: rs1},
+		})
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		require.Equal(t, "a", entries[0].Path)
+		require.Equal(t, "b", entries[1].Path)
+		require.NotEmpty(t, entries[0].Version)
+		require.Equal(t, rs1, entries[1].Ruleset)
+
+		latest, err := s.Latest(context.Background(), "b")
+		require.NoError(t, err)
+		require.Equal(t, entries[1].Version, latest.Version)
+
+		// the whole batch is seen at once by the watchers
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		events, err := s.Watch(ctx, "", list.Revision)
+		require.NoError(t, err)
+		require.Len(t, events.Events, 2)
+		require.ElementsMatch(t, []string{"a", "b"}, []string{events.Events[0].Path, events.Events[1].Path})
+
+		// unchanged rulesets are left untouched
+		entries2, err := s.PutBatch(context.Background(), []store.RulesetEntry{
+			{Path: "a", Ruleset: rs1},
+			{Path: "b", Ruleset: rs2},
+		})
+		require.NoError(t, err)
+		require.Equal(t, entries[0], entries2[0])
+		require.NotEqual(t, entries[1].Version, entries2[1].Version)
+
+		entries3, err := s.PutBatch(context.Background(), []store.RulesetEntry{
+			{Path: "a", Ruleset: rs1},
+			{Path: "b", Ruleset: rs2},
+		})
+		require.Equal(t, store.ErrNotModified, err)
+		require.Equal(t, entries2, entries3)
+	})
+
+	t.Run("Atomic", func(t *testing.T) {
+		createRuleset(t, s, "c", rs1)
+
+		// the signature of c can't change, d must not be created either
+		_, err := s.PutBatch(context.Background(), []store.RulesetEntry{
+			{Path: "d", Ruleset: rs1},
+			{Path: "c", Ruleset: mustRuleset(regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("c"))))},
+		})
+		require.True(t, store.IsValidationError(err))
+
+		_, err = s.Latest(context.Background(), "d")
+		require.Equal(t, store.ErrNotFound, err)
+
+		versions, err := s.Versions(context.Background(), "c", 0, "")
+		require.NoError(t, err)
+		require.Len(t, versions.Versions, 1)
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		_, err := s.PutBatch(context.Background(), []store.RulesetEntry{
+			{Path: "e", Ruleset: rs1},
+			{Path: "e", Ruleset: rs2},
+		})
+		require.True(t, store.IsValidationError(err))
+
+		_, err = s.PutBatch(context.Background(), []store.RulesetEntry{
+			{Path: "e", Ruleset: rs1},
+			{Path: "A", Ruleset: rs1},
+		})
+		require.True(t, store.IsValidationError(err))
+
+		_, err = s.Latest(context.Background(), "e")
+		require.Equal(t, store.ErrNotFound, err)
+	})
+
+	t.Run("MaxBatchSize", func(t *testing.T) {
+		batch := make([]store.RulesetEntry, store.MaxBatchSize+1)
+		for i := range batch {
+			batch[i] = store.RulesetEntry{Path: fmt.Sprintf("max/%d", i), Ruleset: rs1}
+		}
+
+		_, err := s.PutBatch(context.Background(), batch)
+		require.True(t, store.IsValidationError(err))
+
+		// new rulesets require the most operations.
+		entries, err := s.PutBatch(context.Background(), batch[:store.MaxBatchSize])
+		require.NoError(t, err)
+		require.Len(t, entries, store.MaxBatchSize)
+	})
+}
+
+func mustRuleset(rs *regula.Ruleset, err error) *regula.Ruleset {
+	if err != nil {
+		panic(err)
+	}
+
+	return rs
+}
+
+func testRollback(t *testing.T, s store.RulesetService) {
+	r1, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("a")))
+	r2, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("b")))
+
+	e1 := createRuleset(t, s, "a", r1)
+	createRuleset(t, s, "a", r2)
+
+	t.Run("OK", func(t *testing.T) {
+		var wg sync.WaitGroup
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			time.Sleep(time.Second)
+
+			entry, err := s.Rollback(context.Background(), "a", e1.Version)
+			require.NoError(t, err)
+			require.Equal(t, e1, entry)
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		events, err := s.Watch(ctx, "a", "")
+		require.NoError(t, err)
+		require.Len(t, events.Events, 1)
+		require.Equal(t, store.RulesetPutEvent, events.Events[0].Type)
+		require.Equal(t, e1.Version, events.Events[0].Version)
+
+		wg.Wait()
+
+		latest, err := s.Latest(context.Background(), "a")
+		require.NoError(t, err)
+		require.Equal(t, e1.Version, latest.Version)
+
+		// putting the rolled back ruleset again must not create a new version
+		_, err = s.Put(context.Background(), "a", r1)
+		require.Equal(t, store.ErrNotModified, err)
+
+		// putting the previous latest ruleset must make it the latest again
+		entry, err := s.Put(context.Background(), "a", r2)
+		require.NoError(t, err)
+		require.Equal(t, r2, entry.Ruleset)
+
+		latest, err = s.Latest(context.Background(), "a")
+		require.NoError(t, err)
+		require.Equal(t, entry.Version, latest.Version)
+	})
+
+	t.Run("NotModified", func(t *testing.T) {
+		latest, err := s.Latest(context.Background(), "a")
+		require.NoError(t, err)
+
+		_, err = s.Rollback(context.Background(), "a", latest.Version)
+		require.Equal(t, store.ErrNotModified, err)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := s.Rollback(context.Background(), "a", "someversion")
+		require.Equal(t, store.ErrNotFound, err)
+
+		_, err = s.Rollback(context.Background(), "b", e1.Version)
+		require.Equal(t, store.ErrNotFound, err)
+	})
+}
+
+func testDelete(t *testing.T, s store.RulesetService) {
+	r1, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("a")))
+	r2, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("b")))
+
+	t.Run("Soft", func(t *testing.T) {
+		e1 := createRuleset(t, s, "a", r1)
+		createRuleset(t, s, "a", r2)
+		createRuleset(t, s, "a/b", r1)
+
+		var wg sync.WaitGroup
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			time.Sleep(time.Second)
+
+			err := s.Delete(context.Background(), "a", false)
+			require.NoError(t, err)
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		events, err := s.Watch(ctx, "a", "")
+		require.NoError(t, err)
+		require.Len(t, events.Events, 2)
+		for _, ev := range events.Events {
+			require.Equal(t, store.RulesetDeleteEvent, ev.Type)
+			require.Equal(t, "a", ev.Path)
+			require.NotEmpty(t, ev.Version)
+		}
+
+		wg.Wait()
+
+		_, err = s.Latest(context.Background(), "a")
+		require.Equal(t, store.ErrNotFound, err)
+		_, err = s.OneByVersion(context.Background(), "a", e1.Version)
+		require.Equal(t, store.ErrNotFound, err)
+
+		// sub paths are not deleted
+		_, err = s.Latest(context.Background(), "a/b")
+		require.NoError(t, err)
+
+		// the signature is kept
+		bad, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(true)))
+		_, err = s.Put(context.Background(), "a", bad)
+		require.True(t, store.IsValidationError(err))
+
+		err = s.Delete(context.Background(), "a", false)
+		require.Equal(t, store.ErrNotFound, err)
+	})
+
+	t.Run("Hard", func(t *testing.T) {
+		err := s.Delete(context.Background(), "a", true)
+		require.NoError(t, err)
+
+		// the signature is removed
+		rs, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(true)))
+		createRuleset(t, s, "a", rs)
+
+		err = s.Delete(context.Background(), "a", true)
+		require.NoError(t, err)
+
+		_, err = s.Latest(context.Background(), "a")
+		require.Equal(t, store.ErrNotFound, err)
+
+		_, err = s.Latest(context.Background(), "a/b")
+		require.NoError(t, err)
+
+		err = s.Delete(context.Background(), "a", true)
+		require.Equal(t, store.ErrNotFound, err)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		for _, path := range []string{"b", ""} {
+			err := s.Delete(context.Background(), path, false)
+			require.Equal(t, store.ErrNotFound, err)
+		}
+	})
+}
+
+func testDeleteVersions(t *testing.T, s store.RulesetService) {
+	r1, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("a")))
+	r2, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("b")))
+	r3, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue("c")))
+
+	e1 := createRuleset(t, s, "a", r1)
+	e2 := createRuleset(t, s, "a", r2)
+	e3 := createRuleset(t, s, "a", r3)
+	createRuleset(t, s, "a/b", r1)
+
+	t.Run("Latest", func(t *testing.T) {
+		err := s.DeleteVersions(context.Background(), "a", e1.Version, e3.Version)
+		require.Equal(t, store.ErrLatestVersion, err)
+
+		// nothing is removed
+		_, err = s.OneByVersion(context.Background(), "a", e1.Version)
+		require.NoError(t, err)
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		list, err := s.List(context.Background(), "", store.ListOptions{})
+		require.NoError(t, err)
+
+		err = s.DeleteVersions(context.Background(), "a", e1.Version, "unknown")
+		require.NoError(t, err)
+
+		_, err = s.OneByVersion(context.Background(), "a", e1.Version)
+		require.Equal(t, store.ErrNotFound, err)
+
+		versions, err := s.Versions(context.Background(), "a", 0, "")
+		require.NoError(t, err)
+		require.Len(t, versions.Versions, 2)
+
+		latest, err := s.Latest(context.Background(), "a")
+		require.NoError(t, err)
+		require.Equal(t, e3.Version, latest.Version)
+
+		// sub paths are not affected
+		_, err = s.Latest(context.Background(), "a/b")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		events, err := s.Watch(ctx, "a", list.Revision)
+		require.NoError(t, err)
+		require.Len(t, events.Events, 1)
+		require.Equal(t, store.RulesetDeleteEvent, events.Events[0].Type)
+		require.Equal(t, "a", events.Events[0].Path)
+		require.Equal(t, e1.Version, events.Events[0].Version)
+
+		// the removed versions can't be rolled back to
+		_, err = s.Rollback(context.Background(), "a", e1.Version)
+		require.Equal(t, store.ErrNotFound, err)
+		_, err = s.Rollback(context.Background(), "a", e2.Version)
+		require.NoError(t, err)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		for _, path := range []string{"b", ""} {
+			err := s.DeleteVersions(context.Background(), path, e1.Version)
+			require.Equal(t, store.ErrNotFound, err)
+		}
+	})
+}
+
+func testPrune(t *testing.T, s store.RulesetService) {
+	var entries []*store.RulesetEntry
+	for _, v := range []string{"a", "b", "c", "d", "e"} {
+		rs, _ := regula.NewStringRuleset(rule.New(rule.True(), rule.StringValue(v)))
+		entries = append(entries, createRuleset(t, s, "a", rs))
+		createRuleset(t, s, "b/"+v, rs)
+
+		// versions created in the same second have no defined order
+		time.Sleep(time.Second)
+	}
+
+	// the oldest version becomes the latest one
+	_, err := s.Rollback(context.Background(), "a", entries[0].Version)
+	require.NoError(t, err)
+
+	p := store.RetentionPolicy{
+		KeepLast: 2,
+		Pinned: map[string][]string{
+			"a": {entries[1].Version},
+		},
+	}
+
+	n, err := store.Prune(context.Background(), s, "", &p, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	versions, err := s.Versions(context.Background(), "a", 0, "")
+	require.NoError(t, err)
+	var got []string
+	for _, v := range versions.Versions {
+		got = append(got, v.Version)
+	}
+	require.Equal(t, []string{entries[4].Version, entries[3].Version, entries[1].Version, entries[0].Version}, got)
+
+	// rulesets with a single version are left untouched
+	list, err := s.List(context.Background(), "b", store.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, list.Entries, 5)
+
+	// versions newer than KeepFor are kept
+	p = store.RetentionPolicy{KeepFor: time.Hour}
+	n, err = store.Prune(context.Background(), s, "", &p, time.Now())
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	// a disabled policy doesn't remove anything
+	n, err = store.Prune(context.Background(), s, "", new(store.RetentionPolicy), time.Now())
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func testMigrateSignature(t *testing.T, s store.RulesetService) {
+	rs, _ := regula.NewStringRuleset(
+		rule.New(rule.Eq(rule.StringParam("city"), rule.StringValue("paris")), rule.StringValue("a")),
+		rule.New(rule.GT(rule.Int64Param("age"), rule.Int64Value(18)), rule.StringValue("b")),
+	)
+	createRuleset(t, s, "a", rs)
+
+	t.Run("OK", func(t *testing.T) {
+		sig, err := s.MigrateSignature(context.Background(), "a", &store.SignatureMigration{
+			AddParams:       map[string]string{"vip": "bool"},
+			DeprecateParams: []string{"city"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, &store.Signature{
+			ReturnType: "string",
+			ParamTypes: map[string]string{"city": "string", "age": "int64", "vip": "bool"},
+			Optional:   []string{"vip"},
+			Deprecated: []string{"city"},
+		}, sig)
+
+		sigs, err := s.Signatures(context.Background(), "a")
+		require.NoError(t, err)
+		require.Len(t, sigs, 2)
+		require.Equal(t, map[string]string{"city": "string", "age": "int64"}, sigs[0].ParamTypes)
+		require.Equal(t, *sig, sigs[1])
+
+		// deprecated params can't be used anymore
+		bad, _ := regula.NewStringRuleset(
+			rule.New(rule.Eq(rule.StringParam("city"), rule.StringValue("milan")), rule.StringValue("a")),
+		)
+		_, err = s.Put(context.Background(), "a", bad)
+		require.True(t, store.IsValidationError(err))
+
+		rs, _ := regula.NewStringRuleset(
+			rule.New(rule.BoolParam("vip"), rule.StringValue("vip")),
+			rule.New(rule.GT(rule.Int64Param("age"), rule.Int64Value(18)), rule.StringValue("b")),
+			rule.New(rule.True(), rule.StringValue("c")),
+		)
+		createRuleset(t, s, "a", rs)
+
+		// the optional param can be omitted
+		res, err := s.Eval(context.Background(), "a", regula.Params{"age": int64(20)})
+		require.NoError(t, err)
+		require.Equal(t, rule.StringValue("b"), res.Value)
+
+		res, err = s.Eval(context.Background(), "a", regula.Params{"age": int64(20), "vip": true})
+		require.NoError(t, err)
+		require.Equal(t, rule.StringValue("vip"), res.Value)
+	})
+
+	t.Run("Widen", func(t *testing.T) {
+		// the existing versions use age as an int64
+		_, err := s.MigrateSignature(context.Background(), "a", &store.SignatureMigration{
+			WidenParams: map[string]string{"age": "float64"},
+		})
+		require.True(t, store.IsValidationError(err))
+
+		rs, _ := regula.NewStringRuleset(
+			rule.New(rule.BoolParam("vip"), rule.StringValue("vip")),
+			rule.New(rule.True(), rule.StringValue("c")),
+		)
+		latest := createRuleset(t, s, "a", rs)
+
+		versions, err := s.Versions(context.Background(), "a", 0, "")
+		require.NoError(t, err)
+		var old []string
+		for _, v := range versions.Versions {
+			if v.Version != latest.Version {
+				old = append(old, v.Version)
+			}
+		}
+		require.NoError(t, s.DeleteVersions(context.Background(), "a", old...))
+
+		sig, err := s.MigrateSignature(context.Background(), "a", &store.SignatureMigration{
+			WidenParams: map[string]string{"age": "float64"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "float64", sig.ParamTypes["age"])
+
+		rs, _ = regula.NewStringRuleset(
+			rule.New(rule.GT(rule.Float64Param("age"), rule.Float64Value(18)), rule.StringValue("b")),
+			rule.New(rule.True(), rule.StringValue("c")),
+		)
+		createRuleset(t, s, "a", rs)
+
+		res, err := s.Eval(context.Background(), "a", regula.Params{"age": 20.5})
+		require.NoError(t, err)
+		require.Equal(t, rule.StringValue("b"), res.Value)
+	})
+
+	t.Run("ManyVersions", func(t *testing.T) {
+		// more versions than a single etcd transaction can read.
+		for i := 0; i < 130; i++ {
+			rs, _ := regula.NewStringRuleset(
+				rule.New(rule.GT(rule.Int64Param("age"), rule.Int64Value(int64(i))), rule.StringValue("b")),
+			)
+			createRuleset(t, s, "many", rs)
+		}
+
+		_, err := s.MigrateSignature(context.Background(), "many", &store.SignatureMigration{
+			WidenParams: map[string]string{"age": "float64"},
+		})
+		require.True(t, store.IsValidationError(err))
+
+		sig, err := s.MigrateSignature(context.Background(), "many", &store.SignatureMigration{
+			AddParams: map[string]string{"vip": "bool"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"age": "int64", "vip": "bool"}, sig.ParamTypes)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := s.MigrateSignature(context.Background(), "a", &store.SignatureMigration{
+			WidenParams: map[string]string{"vip": "float64"},
+		})
+		require.True(t, store.IsValidationError(err))
+
+		_, err = s.MigrateSignature(context.Background(), "a", &store.SignatureMigration{
+			AddParams: map[string]string{"version": "string"},
+		})
+		require.True(t, store.IsValidationError(err))
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := s.MigrateSignature(context.Background(), "b", &store.SignatureMigration{
+			AddParams: map[string]string{"vip": "bool"},
+		})
+		require.Equal(t, store.ErrNotFound, err)
+
+		_, err = s.Signatures(context.Background(), "b")
+		require.Equal(t, store.ErrNotFound, err)
+	})
+}
+
+func testWatch(t *testing.T, s store.RulesetService) {
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		time.Sleep(time.Second)
+
+		rs, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(true)))
+
+		createRuleset(t, s, "aa", rs)
+		createRuleset(t, s, "b", rs)
+		createRuleset(t, s, "ab", rs)
+		createRuleset(t, s, "a/1", rs)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	events, err := s.Watch(ctx, "a", "")
+	require.NoError(t, err)
+	require.NotEmpty(t, events.Events)
+	require.NotEmpty(t, events.Revision)
+	require.Equal(t, "aa", events.Events[0].Path)
+	require.Equal(t, store.RulesetPutEvent, events.Events[0].Type)
+
+	wg.Wait()
+
+	// the revision returned by List is the starting point of the next Watch.
+	list, err := s.List(context.Background(), "", store.ListOptions{})
+	require.NoError(t, err)
+
+	rs, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(false)))
+	createRuleset(t, s, "ab", rs)
+	createRuleset(t, s, "b", rs)
+	createRuleset(t, s, "a/1", rs)
+
+	events, err = s.Watch(ctx, "a", list.Revision)
+	require.NoError(t, err)
+	require.Len(t, events.Events, 2)
+	require.NotEmpty(t, events.Revision)
+	require.Equal(t, store.RulesetPutEvent, events.Events[0].Type)
+	require.Equal(t, "ab", events.Events[0].Path)
+	require.Equal(t, store.RulesetPutEvent, events.Events[1].Type)
+	require.Equal(t, "a/1", events.Events[1].Path)
+
+	err = s.Delete(context.Background(), "aa", false)
+	require.NoError(t, err)
+
+	events, err = s.Watch(ctx, "a", events.Revision)
+	require.NoError(t, err)
+	require.Len(t, events.Events, 1)
+	require.Equal(t, store.RulesetDeleteEvent, events.Events[0].Type)
+	require.Equal(t, "aa", events.Events[0].Path)
+
+	// no events since the last revision, the watch blocks until the context is done.
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err = s.Watch(ctx, "a", events.Revision)
+	require.Equal(t, context.DeadlineExceeded, err)
+}
+
+func testEval(t *testing.T, s store.RulesetService) {
+	rs, _ := regula.NewBoolRuleset(
+		rule.New(rule.Eq(rule.StringParam("id"), rule.StringValue("123")), rule.BoolValue(true)),
+		rule.New(rule.Eq(rule.StringParam("id"), rule.StringValue("789")), rule.BoolValue(false)),
+	)
+	entry := createRuleset(t, s, "a", rs)
+
+	t.Run("OK", func(t *testing.T) {
+		res, err := s.Eval(context.Background(), "a", regula.Params{"id": "123"})
+		require.NoError(t, err)
+		require.Equal(t, entry.Version, res.Version)
+		require.Equal(t, rule.BoolValue(true), res.Value)
+		require.NotNil(t, res.Rule)
+		require.Equal(t, 0, *res.Rule)
+
+		res, err = s.Eval(context.Background(), "a", regula.Params{"id": "789"})
+		require.NoError(t, err)
+		require.Equal(t, rule.BoolValue(false), res.Value)
+		require.NotNil(t, res.Rule)
+		require.Equal(t, 1, *res.Rule)
+	})
+
+	t.Run("NoMatch", func(t *testing.T) {
+		_, err := s.Eval(context.Background(), "a", regula.Params{"id": "456"})
+		require.Equal(t, rule.ErrNoMatch, err)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := s.Eval(context.Background(), "notexists", regula.Params{"id": "123"})
+		require.Equal(t, regula.ErrRulesetNotFound, err)
+	})
+}
+
+func testEvalVersion(t *testing.T, s store.RulesetService) {
+	rs, _ := regula.NewBoolRuleset(
+		rule.New(rule.Eq(rule.StringParam("id"), rule.StringValue("123")), rule.BoolValue(true)),
+	)
+	entry := createRuleset(t, s, "a", rs)
+
+	t.Run("OK", func(t *testing.T) {
+		res, err := s.EvalVersion(context.Background(), "a", entry.Version, regula.Params{"id": "123"})
+		require.NoError(t, err)
+		require.Equal(t, entry.Version, res.Version)
+		require.Equal(t, rule.BoolValue(true), res.Value)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := s.EvalVersion(context.Background(), "b", entry.Version, regula.Params{"id": "123"})
+		require.Equal(t, regula.ErrRulesetNotFound, err)
+
+		_, err = s.EvalVersion(context.Background(), "a", "someversion", regula.Params{"id": "123"})
+		require.Equal(t, regula.ErrRulesetNotFound, err)
+	})
+}
+
+// RunEventsRetention checks that a service keeping the events of the given number of revisions
+// rejects the watches starting from the revisions whose events were dropped, or from unknown revisions.
+func RunEventsRetention(t *testing.T, s store.RulesetService, retention int) {
+	ctx := context.Background()
+
+	rs, _ := regula.NewBoolRuleset(rule.New(rule.True(), rule.BoolValue(true)))
+	for i := 0; i < retention+2; i++ {
+		createRuleset(t, s, fmt.Sprintf("a%d", i), rs)
+	}
+
+	entries, err := s.List(ctx, "", store.ListOptions{})
+	require.NoError(t, err)
+	rev, err := strconv.Atoi(entries.Revision)
+	require.NoError(t, err)
+
+	_, err = s.Watch(ctx, "", strconv.Itoa(rev-retention-1))
+	require.Equal(t, store.ErrRevisionCompacted, err)
+
+	events, err := s.Watch(ctx, "", strconv.Itoa(rev-retention))
+	require.NoError(t, err)
+	require.Len(t, events.Events, retention)
+	require.Equal(t, entries.Revision, events.Revision)
+
+	_, err = s.Watch(ctx, "", strconv.Itoa(rev+1))
+	require.Equal(t, store.ErrRevisionCompacted, err)
+}
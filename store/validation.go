@@ -0,0 +1,214 @@
+package store
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/heetch/regula"
+	"github.com/heetch/regula/rule"
+)
+
+// ValidateRuleset makes sure the ruleset can be stored on the given path: the path and the param names
+// must be valid, the rules must match the schema and the test cases must pass.
+// It returns the signature of the ruleset or a *ValidationError.
+func ValidateRuleset(path string, rs *regula.Ruleset) (*Signature, error) {
+	err := validateRulesetName(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sig := NewSignature(rs)
+
+	for _, r := range rs.Rules {
+		params := r.Params()
+		err = ValidateParamNames(params)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = validateSchema(rs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateTests(rs)
+	if err != nil {
+		return nil, err
+	}
+
+	return sig, nil
+}
+
+// MaxBatchSize is the maximum number of rulesets of a batch. Stores commit a batch in a single transaction
+// and etcd limits transactions to 128 operations by default. Every ruleset requires up to 8 of them:
+// the transaction compares the 3 keys read and the 5 keys written for every new ruleset.
+const MaxBatchSize = 128 / 8
+
+// ValidateBatch validates every entry of a batch with ValidateRuleset and makes sure
+// that no path appears twice and that the batch doesn't exceed MaxBatchSize.
+// It returns the signatures of the rulesets, in the same order.
+func ValidateBatch(entries []RulesetEntry) ([]*Signature, error) {
+	if len(entries) > MaxBatchSize {
+		return nil, &ValidationError{
+			Field:  "rulesets",
+			Value:  strconv.Itoa(len(entries)),
+			Reason: fmt.Sprintf("too many rulesets in batch, the maximum is %d", MaxBatchSize),
+		}
+	}
+
+	sigs := make([]*Signature, len(entries))
+	seen := make(map[string]bool, len(entries))
+
+	for i, e := range entries {
+		if seen[e.Path] {
+			return nil, &ValidationError{
+				Field:  "path",
+				Value:  e.Path,
+				Reason: "duplicate path in batch",
+			}
+		}
+		seen[e.Path] = true
+
+		if e.Ruleset == nil {
+			return nil, &ValidationError{
+				Field:  "ruleset",
+				Value:  e.Path,
+				Reason: "missing ruleset",
+			}
+		}
+
+		sig, err := ValidateRuleset(e.Path, e.Ruleset)
+		if err != nil {
+			return nil, err
+		}
+		sigs[i] = sig
+	}
+
+	return sigs, nil
+}
+
+// validateSchema makes sure the schema of the ruleset is well formed, that the names of its params are valid
+// and that the params used by the rules match it.
+func validateSchema(rs *regula.Ruleset) error {
+	err := rs.ValidateSchema()
+	if err != nil {
+		pe, ok := err.(*regula.ParamError)
+		if !ok {
+			return err
+		}
+
+		return &ValidationError{
+			Field:  "schema",
+			Value:  pe.Name,
+			Reason: pe.Reason,
+		}
+	}
+
+	// declared params become part of the signature, they follow the same naming rules as the params of the rules.
+	params := make([]rule.Param, len(rs.Schema))
+	for i, spec := range rs.Schema {
+		params[i] = rule.Param{Name: spec.Name, Type: spec.Type}
+	}
+
+	return ValidateParamNames(params)
+}
+
+// validateTests runs the test cases of the ruleset and returns
+// a validation error listing the ones that failed.
+func validateTests(rs *regula.Ruleset) error {
+	err := rs.Test()
+	if err == nil {
+		return nil
+	}
+
+	te, ok := err.(*regula.TestError)
+	if !ok {
+		return err
+	}
+
+	idx := make([]string, len(te.Failures))
+	for i, f := range te.Failures {
+		idx[i] = strconv.Itoa(f.Index)
+	}
+
+	return &ValidationError{
+		Field:  "tests",
+		Value:  strings.Join(idx, ","),
+		Reason: te.Error(),
+	}
+}
+
+// regex used to validate ruleset names.
+var rgxRuleset = regexp.MustCompile(`^[a-z]+(?:[a-z0-9-\/]?[a-z0-9])*$`)
+
+func validateRulesetName(path string) error {
+	if !rgxRuleset.MatchString(path) {
+		return &ValidationError{
+			Field:  "path",
+			Value:  path,
+			Reason: "invalid format",
+		}
+	}
+
+	return nil
+}
+
+// regex used to validate namespace names. Unlike ruleset paths, they can't contain slashes.
+var rgxNamespace = regexp.MustCompile(`^[a-z]+(?:[a-z0-9-]?[a-z0-9])*$`)
+
+// ValidateNamespace makes sure the given namespace name is valid.
+func ValidateNamespace(name string) error {
+	if !rgxNamespace.MatchString(name) {
+		return &ValidationError{
+			Field:  "namespace",
+			Value:  name,
+			Reason: "invalid format",
+		}
+	}
+
+	return nil
+}
+
+// regex used to validate parameters name.
+var rgxParam = regexp.MustCompile(`^[a-z]+(?:[a-z0-9-]?[a-z0-9])*$`)
+
+// list of reserved words that shouldn't be used as parameters.
+var reservedWords = []string{
+	"version",
+	"list",
+	"eval",
+	"watch",
+	"revision",
+	"diff",
+	"versions",
+	"rollback",
+	"signature",
+}
+
+// ValidateParamNames makes sure the given params have a valid name that doesn't conflict with the API.
+func ValidateParamNames(params []rule.Param) error {
+	for i := range params {
+		if !rgxParam.MatchString(params[i].Name) {
+			return &ValidationError{
+				Field:  "param",
+				Value:  params[i].Name,
+				Reason: "invalid format",
+			}
+		}
+
+		for _, w := range reservedWords {
+			if params[i].Name == w {
+				return &ValidationError{
+					Field:  "param",
+					Value:  params[i].Name,
+					Reason: "forbidden value",
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -1,11 +1,10 @@
-package etcd
+package store
 
 import (
 	"testing"
 
 	"github.com/heetch/regula"
 	"github.com/heetch/regula/rule"
-	"github.com/heetch/regula/store"
 	"github.com/stretchr/testify/require"
 )
 
@@ -37,7 +36,7 @@ func TestValidation(t *testing.T) {
 
 		for _, n := range names {
 			err := validateRulesetName(n)
-			require.True(t, store.IsValidationError(err))
+			require.True(t, IsValidationError(err))
 		}
 	})
 
@@ -60,7 +59,7 @@ func TestValidation(t *testing.T) {
 
 			for _, r := range rs.Rules {
 				params := r.Params()
-				err := validateParamNames(params)
+				err := ValidateParamNames(params)
 				require.NoError(t, err)
 			}
 		}
@@ -88,9 +87,36 @@ func TestValidation(t *testing.T) {
 
 			for _, r := range rs.Rules {
 				params := r.Params()
-				err := validateParamNames(params)
-				require.True(t, store.IsValidationError(err))
+				err := ValidateParamNames(params)
+				require.True(t, IsValidationError(err))
 			}
 		}
 	})
+	t.Run("OK - namespaces", func(t *testing.T) {
+		names := []string{
+			"a",
+			"team-a",
+			"team-123",
+		}
+
+		for _, n := range names {
+			require.NoError(t, ValidateNamespace(n))
+		}
+	})
+
+	t.Run("NOK - namespaces", func(t *testing.T) {
+		names := []string{
+			"",
+			"Team",
+			"team/a",
+			"team_a",
+			"team-",
+			"1team",
+			"_namespaces",
+		}
+
+		for _, n := range names {
+			require.True(t, IsValidationError(ValidateNamespace(n)))
+		}
+	})
 }
@@ -0,0 +1,186 @@
+package regula
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/heetch/regula/rule"
+	"github.com/pkg/errors"
+)
+
+// A TestCase describes the result expected from the evaluation of a ruleset
+// with a given set of params. If NoMatch is true, the evaluation is expected to
+// return rule.ErrNoMatch and Result is ignored.
+type TestCase struct {
+	Params  Params      `json:"params"`
+	Result  *rule.Value `json:"result,omitempty"`
+	NoMatch bool        `json:"noMatch,omitempty"`
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// JSON numbers are decoded as int64 if they represent an integer, float64 otherwise.
+func (tc *TestCase) UnmarshalJSON(data []byte) error {
+	var tree struct {
+		Params  map[string]interface{} `json:"params"`
+		Result  *rule.Value            `json:"result"`
+		NoMatch bool                   `json:"noMatch"`
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	err := dec.Decode(&tree)
+	if err != nil {
+		return err
+	}
+
+	params := make(Params, len(tree.Params))
+	for k, v := range tree.Params {
+		switch t := v.(type) {
+		case string, bool:
+			params[k] = t
+		case json.Number:
+			if i, err := t.Int64(); err == nil {
+				params[k] = i
+				continue
+			}
+
+			f, err := t.Float64()
+			if err != nil {
+				return errors.Wrapf(err, "invalid value for param '%s'", k)
+			}
+			params[k] = f
+		default:
+			return errors.Errorf("unsupported type for param '%s'", k)
+		}
+	}
+
+	tc.Params = params
+	tc.Result = tree.Result
+	tc.NoMatch = tree.NoMatch
+	return nil
+}
+
+// coerce converts the integers passed to float64 params to float64.
+func (tc *TestCase) coerce(params []rule.Param) {
+	for _, p := range params {
+		if p.Type != "float64" {
+			continue
+		}
+
+		if i, ok := tc.Params[p.Name].(int64); ok {
+			tc.Params[p.Name] = float64(i)
+		}
+	}
+}
+
+// Run evaluates the given ruleset with the params of the test case and returns
+// an error describing the difference if the outcome is not the one expected.
+func (tc *TestCase) Run(rs *Ruleset) error {
+	res, err := rs.Eval(tc.Params)
+
+	if tc.NoMatch {
+		switch err {
+		case rule.ErrNoMatch:
+			return nil
+		case nil:
+			return fmt.Errorf("expected no match, got %s", formatValue(res))
+		default:
+			return err
+		}
+	}
+
+	if tc.Result == nil {
+		return errors.New("no expected result")
+	}
+
+	if err != nil {
+		if err == rule.ErrNoMatch {
+			return fmt.Errorf("expected %s, got no match", formatValue(tc.Result))
+		}
+
+		return err
+	}
+
+	if !sameValue(res, tc.Result) {
+		return fmt.Errorf("expected %s, got %s", formatValue(tc.Result), formatValue(res))
+	}
+
+	return nil
+}
+
+// sameValue reports whether both values have the same type and represent the same data,
+// regardless of their kind or of the formatting of numbers.
+func sameValue(v1, v2 *rule.Value) bool {
+	if v1.Type != v2.Type {
+		return false
+	}
+
+	if v1.Data == v2.Data {
+		return true
+	}
+
+	if v1.Type != "int64" && v1.Type != "float64" {
+		return false
+	}
+
+	gte, err := v1.GTE(v2)
+	if err != nil {
+		return false
+	}
+	lte, err := v1.LTE(v2)
+	if err != nil {
+		return false
+	}
+
+	return gte && lte
+}
+
+func formatValue(v *rule.Value) string {
+	return fmt.Sprintf("%s %s", v.Type, strconv.Quote(v.Data))
+}
+
+// TestFailure describes a test case that failed.
+type TestFailure struct {
+	Index int // position of the test case in the ruleset
+	Err   error
+}
+
+// TestError is returned when one or more test cases of a ruleset fail.
+type TestError struct {
+	Failures []TestFailure
+}
+
+func (e *TestError) Error() string {
+	msgs := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		msgs[i] = fmt.Sprintf("test case #%d failed: %s", f.Index, f.Err)
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// Test runs all the test cases of the ruleset and returns a *TestError listing the ones
+// that failed, if any. Nil test cases are reported as failures.
+func (r *Ruleset) Test() error {
+	var failures []TestFailure
+
+	for i, tc := range r.Tests {
+		if tc == nil {
+			failures = append(failures, TestFailure{Index: i, Err: errors.New("invalid empty test case")})
+			continue
+		}
+
+		if err := tc.Run(r); err != nil {
+			failures = append(failures, TestFailure{Index: i, Err: err})
+		}
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+
+	return &TestError{Failures: failures}
+}
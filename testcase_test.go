@@ -0,0 +1,95 @@
+package regula
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/heetch/regula/rule"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRulesetTest(t *testing.T) {
+	r, err := NewStringRuleset(
+		rule.New(
+			rule.And(
+				rule.Eq(rule.StringParam("city"), rule.StringValue("paris")),
+				rule.GTE(rule.Int64Param("age"), rule.Int64Value(18)),
+			),
+			rule.StringValue("vip"),
+		),
+		rule.New(rule.Eq(rule.StringParam("city"), rule.StringValue("paris")), rule.StringValue("regular")),
+	)
+	require.NoError(t, err)
+
+	t.Run("OK", func(t *testing.T) {
+		r.Tests = []*TestCase{
+			{Params: Params{"city": "paris", "age": int64(20)}, Result: rule.StringValue("vip")},
+			{Params: Params{"city": "paris", "age": int64(10)}, Result: rule.StringValue("regular")},
+			{Params: Params{"city": "london", "age": int64(20)}, NoMatch: true},
+		}
+
+		require.NoError(t, r.Test())
+	})
+
+	t.Run("Failures", func(t *testing.T) {
+		r.Tests = []*TestCase{
+			{Params: Params{"city": "paris", "age": int64(20)}, Result: rule.StringValue("regular")},
+			{Params: Params{"city": "paris", "age": int64(20)}, Result: rule.StringValue("vip")},
+			{Params: Params{"city": "paris", "age": int64(20)}, NoMatch: true},
+			{Params: Params{"city": "london", "age": int64(20)}, Result: rule.StringValue("vip")},
+			{Params: Params{"city": "paris"}, Result: rule.StringValue("vip")},
+			{Params: Params{"city": "paris", "age": int64(20)}},
+			nil,
+		}
+
+		err := r.Test()
+		require.Error(t, err)
+		te, ok := err.(*TestError)
+		require.True(t, ok)
+		require.Len(t, te.Failures, 6)
+		require.Equal(t, 0, te.Failures[0].Index)
+		require.EqualError(t, te.Failures[0].Err, `expected string "regular", got string "vip"`)
+		require.Equal(t, 2, te.Failures[1].Index)
+		require.EqualError(t, te.Failures[1].Err, `expected no match, got string "vip"`)
+		require.Equal(t, 3, te.Failures[2].Index)
+		require.EqualError(t, te.Failures[2].Err, `expected string "vip", got no match`)
+		require.Equal(t, 4, te.Failures[3].Index)
+		require.Equal(t, rule.ErrParamNotFound, te.Failures[3].Err)
+		require.Equal(t, 5, te.Failures[4].Index)
+		require.Equal(t, 6, te.Failures[5].Index)
+		require.EqualError(t, te.Failures[5].Err, "invalid empty test case")
+	})
+}
+
+func TestTestCaseEncDec(t *testing.T) {
+	raw := []byte(`{
+		"type": "float64",
+		"rules": [
+			{
+				"expr": {"kind": "gt", "operands": [{"kind": "param", "type": "float64", "name": "amount"}, {"kind": "param", "type": "float64", "name": "limit"}]},
+				"result": {"kind": "value", "type": "float64", "data": "1.5"}
+			}
+		],
+		"tests": [
+			{"params": {"amount": 20, "limit": 10.5}, "result": {"type": "float64", "data": "1.5"}},
+			{"params": {"amount": 1, "limit": 10}, "noMatch": true}
+		]
+	}`)
+
+	var r Ruleset
+	err := json.Unmarshal(raw, &r)
+	require.NoError(t, err)
+	require.Len(t, r.Tests, 2)
+	require.Equal(t, Params{"amount": float64(20), "limit": 10.5}, r.Tests[0].Params)
+	require.Equal(t, Params{"amount": float64(1), "limit": float64(10)}, r.Tests[1].Params)
+	require.True(t, r.Tests[1].NoMatch)
+	require.NoError(t, r.Test())
+
+	raw, err = json.Marshal(&r)
+	require.NoError(t, err)
+
+	var r2 Ruleset
+	err = json.Unmarshal(raw, &r2)
+	require.NoError(t, err)
+	require.Equal(t, &r, &r2)
+}